@@ -1,25 +1,300 @@
 package main
 
 import (
+	"cache-engine/internal/acl"
 	"cache-engine/internal/api/cli"
+	grpcapi "cache-engine/internal/api/grpc"
+	httpapi "cache-engine/internal/api/http"
+	"cache-engine/internal/api/memcache"
+	"cache-engine/internal/api/resp"
 	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"cache-engine/internal/config"
+	"cache-engine/internal/metrics"
+	"cache-engine/internal/netutil"
+	"crypto/tls"
 	"flag"
 	"fmt"
+	"os"
+	"strings"
+	"time"
 )
 
 func main() {
-	// Definir flags de línea de comandos
-	maxEntries := flag.Int("max", 1000, "Número máximo de entradas en el cache")
+	// "cache-engine cli" conecta la CLI con un servidor remoto
+	if len(os.Args) > 1 && os.Args[1] == "cli" {
+		os.Exit(runRemote(os.Args[2:]))
+	}
+
+	// Definir flags de línea de comandos. Los que se indican explícitamente
+	// prevalecen sobre el fichero de configuración y las variables de entorno.
+	configFile := flag.String("config", "", "Fichero de configuración (.json, .yaml o .toml)")
+	flag.Int("max", 1000, "Número máximo de entradas en el cache")
+	flag.Duration("cleanup-interval", time.Second, "Frecuencia del barrido de claves expiradas")
+	flag.String("metrics", "", "Dirección para exponer /metrics de Prometheus (ej. :9100)")
+	flag.String("mode", "cli", "Modo de ejecución: cli, http, resp, memcache o grpc")
+	flag.Int("port", 0, "Puerto del servidor (por defecto 8080 en http, 6379 en resp, 11211 en memcache y 50051 en grpc)")
+	flag.Bool("cors", false, "Habilitar CORS en el servidor HTTP")
+	flag.String("requirepass", "", "Contraseña del usuario default (vacío = sin autenticación)")
+	flag.String("aclfile", "", "Fichero de usuarios con líneas 'user <nombre> <reglas...>'")
+	flag.String("tls-cert", "", "Certificado TLS del servidor (PEM); habilita TLS en todos los listeners")
+	flag.String("tls-key", "", "Clave privada TLS del servidor (PEM)")
+	flag.String("tls-ca", "", "CA para verificar certificados de cliente (PEM)")
+	flag.String("tls-min-version", "1.2", "Versión mínima de TLS: 1.2 o 1.3")
+	flag.Bool("tls-client-auth", false, "Exigir certificado de cliente (mTLS)")
+	flag.String("unixsocket", "", "Ruta de un socket Unix en el que escuchar además del puerto TCP")
+	flag.String("unixsocketperm", "700", "Permisos del socket Unix en octal")
+	flag.Bool("no-tcp", false, "Escuchar solo en el socket Unix")
+	flag.Int("maxclients", 0, "Conexiones simultáneas máximas en resp y memcache (0 = sin límite)")
+	flag.Duration("idle-timeout", 0, "Cerrar las conexiones inactivas en resp y memcache tras este tiempo (0 = nunca)")
+	flag.Int("client-output-limit", 0, "Bytes de respuestas pendientes por cliente RESP antes de desconectarlo (0 = sin límite)")
+	flag.Duration("client-output-timeout", 0, "Tiempo máximo para que un cliente lento acepte sus respuestas (0 = sin límite)")
+	scriptFile := flag.String("file", "", "Ejecutar los comandos de este fichero en modo cli y salir")
+	output := flag.String("output", "text", "Formato de salida del modo cli no interactivo: text o json")
 
 	flag.Parse()
 
-	// Crear instancia del cache
-	cacheEngine := cache.NewCacheEngine(*maxEntries)
+	cfg, err := loadConfig(*configFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Usuarios y permisos de los servidores de red
+	users := acl.NewStore()
+	if cfg.ACL.RequirePass != "" {
+		users.SetUser(acl.DefaultUser, "resetpass", ">"+cfg.ACL.RequirePass)
+	}
+	if cfg.ACL.File != "" {
+		if err := users.LoadFile(cfg.ACL.File); err != nil {
+			fmt.Fprintf(os.Stderr, "Error al cargar los usuarios: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// TLS común a todos los listeners; los certificados se recargan al cambiar
+	var tlsConfig *tls.Config
+	var tlsReloader *netutil.TLSReloader
+	tlsOptions := netutil.TLSConfig{
+		CertFile:   cfg.TLS.Cert,
+		KeyFile:    cfg.TLS.Key,
+		CAFile:     cfg.TLS.CA,
+		MinVersion: cfg.TLS.MinVersion,
+		ClientAuth: cfg.TLS.ClientAuth,
+		OnReloadError: func(err error) {
+			fmt.Fprintf(os.Stderr, "Error al recargar los certificados TLS: %v\n", err)
+		},
+	}
+	if tlsOptions.Enabled() {
+		tlsReloader, err = netutil.NewTLSReloader(tlsOptions)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error en la configuración TLS: %v\n", err)
+			os.Exit(1)
+		}
+		tlsConfig = tlsReloader.ServerConfig()
+	}
+
+	// Crear instancia del cache y aplicar la configuración, que CONFIG SET
+	// y SIGHUP pueden cambiar en marcha
+	cacheEngine := cache.NewCacheEngine(cfg.MaxEntries)
+	live := &applier{engine: cacheEngine, users: users}
+	if err := live.apply(nil, cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Error en la persistencia: %v\n", err)
+		os.Exit(1)
+	}
+	manager := config.NewManager(cfg, *configFile, func() (*config.Config, error) {
+		return loadConfig(*configFile)
+	}, live.apply)
+	if err := command.Register(manager.Command()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error al registrar CONFIG: %v\n", err)
+		live.close(cfg) // El log ya está abierto: asegurarlo antes de salir
+		os.Exit(1)
+	}
+
+	// CLI no interactiva: "exec <comando>...", -file o comandos por tubería
+	if cfg.Mode == "cli" {
+		if code, batch := runBatch(cli.NewSession(cacheEngine), flag.Args(), *scriptFile, *output); batch {
+			if err := live.close(manager.Config()); err != nil {
+				fmt.Fprintf(os.Stderr, "Error al cerrar: %v\n", err)
+				code = 1
+			}
+			os.Exit(code)
+		}
+	}
+
+	fmt.Printf("Cache Engine iniciado (límite: %d entradas)\n", cfg.MaxEntries)
+	fmt.Printf("Modo: %s\n", strings.ToUpper(cfg.Mode))
 
-	fmt.Printf("Cache Engine iniciado (límite: %d entradas)\n", *maxEntries)
-	fmt.Println("Modo: CLI")
+	// Exportador de métricas opcional
+	if cfg.Metrics != "" {
+		go func() {
+			if err := metrics.ListenAndServe(cfg.Metrics, cacheEngine, tlsConfig); err != nil {
+				fmt.Fprintf(os.Stderr, "Error en el servidor de métricas: %v\n", err)
+			}
+		}()
+		scheme := "http"
+		if tlsConfig != nil {
+			scheme = "https"
+		}
+		fmt.Printf("Métricas en %s://%s/metrics\n", scheme, cfg.Metrics)
+	}
 	fmt.Println()
 
-	// Ejecutar CLI
-	cli.Run(cacheEngine)
+	if cfg.Mode != "cli" {
+		watchReload(manager, tlsReloader)
+	}
+
+	var srv server
+	var name string
+	switch cfg.Mode {
+	case "cli":
+		// run ejecuta la CLI interactiva cuando no hay servidor
+
+	case "http":
+		srv = httpapi.NewServer(cacheEngine, httpapi.Config{
+			Port:           cfg.Port(),
+			EnableCORS:     cfg.HTTP.EnableCORS,
+			ACL:            users,
+			TLS:            tlsConfig,
+			UnixSocket:     cfg.UnixSocket,
+			UnixSocketPerm: cfg.SocketPerm(),
+			NoTCP:          cfg.NoTCP,
+		})
+		name = "HTTP"
+
+	case "resp":
+		srv = resp.NewServer(cacheEngine, resp.Config{
+			Port:            cfg.Port(),
+			ACL:             users,
+			TLS:             tlsConfig,
+			UnixSocket:      cfg.UnixSocket,
+			UnixSocketPerm:  cfg.SocketPerm(),
+			NoTCP:           cfg.NoTCP,
+			MaxClients:      cfg.Clients.Max,
+			IdleTimeout:     cfg.Clients.IdleTimeout,
+			MaxOutputBuffer: cfg.Clients.OutputLimit,
+			OutputTimeout:   cfg.Clients.OutputTimeout,
+		})
+		name = "RESP"
+
+	case "memcache":
+		srv = memcache.NewServer(cacheEngine, memcache.Config{
+			Port:           cfg.Port(),
+			ACL:            users,
+			TLS:            tlsConfig,
+			UnixSocket:     cfg.UnixSocket,
+			UnixSocketPerm: cfg.SocketPerm(),
+			NoTCP:          cfg.NoTCP,
+			MaxClients:     cfg.Clients.Max,
+			IdleTimeout:    cfg.Clients.IdleTimeout,
+			OutputTimeout:  cfg.Clients.OutputTimeout,
+		})
+		name = "memcached"
+
+	case "grpc":
+		srv = grpcapi.NewServer(cacheEngine, grpcapi.Config{
+			Port:           cfg.Port(),
+			ACL:            users,
+			TLS:            tlsConfig,
+			UnixSocket:     cfg.UnixSocket,
+			UnixSocketPerm: cfg.SocketPerm(),
+			NoTCP:          cfg.NoTCP,
+		})
+		name = "gRPC"
+
+	default:
+		fmt.Fprintf(os.Stderr, "Modo desconocido: %s\n", cfg.Mode)
+		os.Exit(2)
+	}
+
+	code := run(srv, name, func() { cli.Run(cacheEngine) }, live, manager)
+	if tlsReloader != nil {
+		tlsReloader.Close()
+	}
+	os.Exit(code)
+}
+
+// flagKeys relaciona los flags con las claves de configuración que
+// sobrescriben. -port se aplica al front-end del modo elegido.
+var flagKeys = map[string]string{
+	"max":                   "max_entries",
+	"cleanup-interval":      "cleanup_interval",
+	"metrics":               "metrics",
+	"mode":                  "mode",
+	"cors":                  "http.enable_cors",
+	"requirepass":           "acl.requirepass",
+	"aclfile":               "acl.file",
+	"tls-cert":              "tls.cert",
+	"tls-key":               "tls.key",
+	"tls-ca":                "tls.ca",
+	"tls-min-version":       "tls.min_version",
+	"tls-client-auth":       "tls.client_auth",
+	"unixsocket":            "unix_socket",
+	"unixsocketperm":        "unix_socket_perm",
+	"no-tcp":                "no_tcp",
+	"maxclients":            "clients.max",
+	"idle-timeout":          "clients.idle_timeout",
+	"client-output-limit":   "clients.output_limit",
+	"client-output-timeout": "clients.output_timeout",
+}
+
+// loadConfig combina, de menor a mayor prioridad, los valores por defecto,
+// el fichero, las variables de entorno y los flags indicados, y valida el
+// resultado
+func loadConfig(path string) (*config.Config, error) {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var port string
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "port" {
+			port = f.Value.String()
+		} else if key, exists := flagKeys[f.Name]; exists && err == nil {
+			if setErr := cfg.Set(key, f.Value.String()); setErr != nil {
+				err = fmt.Errorf("-%s: %v", f.Name, setErr)
+			}
+		}
+	})
+	if err != nil {
+		return nil, err
+	}
+	if port != "" {
+		if err := cfg.SetPort(port); err != nil {
+			return nil, fmt.Errorf("-port: %v", err)
+		}
+	}
+	return cfg, cfg.Validate()
+}
+
+// runBatch ejecuta la CLI sin prompt cuando los comandos llegan como
+// argumentos (exec), en un fichero (-file) o por una tubería. batch es
+// false si la entrada estándar es un terminal y debe usarse el modo
+// interactivo.
+func runBatch(exec cli.Executor, args []string, scriptFile, output string) (code int, batch bool) {
+	opts := cli.Options{Output: output}
+
+	if len(args) > 0 {
+		if args[0] != "exec" || len(args) == 1 {
+			fmt.Fprintln(os.Stderr, "Uso: cache-engine [flags] exec <comando> [comando...]")
+			return cli.ExitUsage, true
+		}
+		return cli.Exec(exec, args[1:], opts), true
+	}
+
+	if scriptFile != "" {
+		file, err := os.Open(scriptFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error al abrir el script: %v\n", err)
+			return cli.ExitUsage, true
+		}
+		defer file.Close()
+		return cli.RunScript(exec, file, opts), true
+	}
+
+	if info, err := os.Stdin.Stat(); err == nil && info.Mode()&os.ModeCharDevice == 0 {
+		return cli.RunScript(exec, os.Stdin, opts), true
+	}
+	return 0, false
 }
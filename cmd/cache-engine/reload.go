@@ -0,0 +1,178 @@
+package main
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/config"
+	"cache-engine/internal/netutil"
+	"cache-engine/internal/persistence"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// applier aplica la configuración en caliente al motor, a los usuarios y a
+// la persistencia
+type applier struct {
+	engine *cache.CacheEngine
+	users  *acl.Store
+
+	mu            sync.Mutex
+	stopSnapshots chan struct{} // Detiene las instantáneas periódicas (nil = no hay)
+	closed        bool
+}
+
+// apply implementa config.ApplyFunc. Al arrancar (old nil) recupera el log
+// de persistencia.
+func (a *applier) apply(old, cfg *config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if a.closed {
+		return nil // Apagando: los cambios ya no se aplican
+	}
+
+	if old == nil || persistenceChanged(old.Persistence, cfg.Persistence) {
+		if err := a.persistence(cfg.Persistence, old == nil); err != nil {
+			return err
+		}
+	}
+
+	a.engine.SetMaxEntries(cfg.MaxEntries)
+	a.engine.SetCleanupInterval(cfg.CleanupInterval)
+	policy, _ := cache.ParseEvictionPolicy(cfg.Eviction)
+	a.engine.SetEvictionPolicy(policy)
+
+	// Al arrancar la contraseña se asigna antes de cargar el fichero de ACL
+	if old != nil && cfg.ACL.RequirePass != old.ACL.RequirePass {
+		if cfg.ACL.RequirePass == "" {
+			a.users.SetUser(acl.DefaultUser, "resetpass", "nopass")
+		} else {
+			a.users.SetUser(acl.DefaultUser, "resetpass", ">"+cfg.ACL.RequirePass)
+		}
+	}
+	return nil
+}
+
+// persistenceChanged indica si cambia el modo o el fichero de la
+// persistencia; snapshot_on_exit solo se consulta al apagar
+func persistenceChanged(old, cfg config.PersistenceConfig) bool {
+	return old.Enabled != cfg.Enabled || old.LogFile != cfg.LogFile || old.SnapshotInterval != cfg.SnapshotInterval
+}
+
+// persistence registra cada operación en el log o guarda una instantánea
+// del estado en cada intervalo. Un log existente se continúa; uno nuevo
+// empieza con una instantánea del estado actual.
+func (a *applier) persistence(cfg config.PersistenceConfig, startup bool) error {
+	if cfg.Enabled && startup {
+		if _, err := os.Stat(cfg.LogFile); err == nil {
+			if err := persistence.LoadFromLog(a.engine, cfg.LogFile); err != nil {
+				return err
+			}
+		}
+	}
+
+	if a.stopSnapshots != nil {
+		close(a.stopSnapshots)
+		a.stopSnapshots = nil
+	}
+
+	switch {
+	case !cfg.Enabled:
+		return persistence.DisableLogging(a.engine)
+	case cfg.SnapshotInterval == 0:
+		// Al arrancar el log ya está cargado, así que basta con continuarlo
+		return persistence.EnableLogging(a.engine, cfg.LogFile)
+	}
+
+	if err := persistence.DisableLogging(a.engine); err != nil {
+		return err
+	}
+	if !startup {
+		if err := persistence.Snapshot(a.engine, cfg.LogFile); err != nil {
+			return err
+		}
+	}
+	a.stopSnapshots = make(chan struct{})
+	go snapshots(a.engine, cfg.LogFile, cfg.SnapshotInterval, a.stopSnapshots)
+	return nil
+}
+
+// close detiene la persistencia y el motor al apagar. Cerrar el journal
+// asegura en disco las operaciones registradas; en modo instantánea, o con
+// snapshot_on_exit, además se guarda el estado final.
+func (a *applier) close(cfg *config.Config) error {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.closed = true
+
+	if a.stopSnapshots != nil {
+		close(a.stopSnapshots)
+		a.stopSnapshots = nil
+	}
+
+	err := persistence.DisableLogging(a.engine)
+	if p := cfg.Persistence; p.Enabled && (p.SnapshotInterval > 0 || p.SnapshotOnExit) {
+		if snapErr := persistence.Snapshot(a.engine, p.LogFile); snapErr != nil && err == nil {
+			err = snapErr
+		}
+	}
+	if closeErr := a.engine.Close(context.Background()); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// snapshots reemplaza el log por el estado actual en cada intervalo hasta
+// que se cierra stop
+func snapshots(engine *cache.CacheEngine, file string, interval time.Duration, stop chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			if err := persistence.Snapshot(engine, file); err != nil {
+				fmt.Fprintf(os.Stderr, "Error al guardar la instantánea: %v\n", err)
+			}
+		case <-stop:
+			return
+		}
+	}
+}
+
+// watchReload recarga la configuración, y los certificados TLS si los hay,
+// cada vez que el proceso recibe SIGHUP
+func watchReload(manager *config.Manager, tlsReloader *netutil.TLSReloader) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			reload(manager, tlsReloader)
+		}
+	}()
+}
+
+// reload recarga la configuración y muestra los cambios
+func reload(manager *config.Manager, tlsReloader *netutil.TLSReloader) {
+	changes, err := manager.Reload()
+	switch {
+	case err != nil:
+		fmt.Fprintf(os.Stderr, "Error al recargar la configuración: %v\n", err)
+	case len(changes) == 0:
+		fmt.Println("Configuración recargada sin cambios")
+	default:
+		fmt.Println("Configuración recargada:")
+		for _, change := range changes {
+			fmt.Printf("  %s\n", change)
+		}
+	}
+
+	if tlsReloader != nil {
+		if err := tlsReloader.Reload(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error al recargar los certificados TLS: %v\n", err)
+		}
+	}
+}
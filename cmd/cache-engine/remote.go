@@ -0,0 +1,72 @@
+package main
+
+import (
+	"cache-engine/internal/api/cli"
+	"cache-engine/internal/netutil"
+	"cache-engine/pkg/client"
+	"flag"
+	"fmt"
+	"net"
+	"os"
+	"strconv"
+)
+
+// runRemote implementa "cache-engine cli": la misma CLI conectada a un
+// servidor en modo resp. Retorna el código de salida.
+func runRemote(args []string) int {
+	flags := flag.NewFlagSet("cli", flag.ExitOnError)
+	host := flags.String("host", "localhost", "Host del servidor")
+	port := flags.Int("port", 6379, "Puerto RESP del servidor")
+	socket := flags.String("socket", "", "Socket Unix del servidor (sustituye a -host y -port)")
+	user := flags.String("user", "", "Usuario ACL (vacío = default)")
+	pass := flags.String("pass", "", "Contraseña (por defecto la variable de entorno CACHE_ENGINE_AUTH)")
+	namespace := flags.String("n", "", "Namespace inicial")
+	useTLS := flags.Bool("tls", false, "Conectar con TLS")
+	caFile := flags.String("cacert", "", "CA con la que verificar el servidor (PEM; por defecto las del sistema)")
+	certFile := flags.String("cert", "", "Certificado de cliente para mTLS (PEM)")
+	keyFile := flags.String("key", "", "Clave privada del certificado de cliente (PEM)")
+	insecure := flags.Bool("insecure", false, "No verificar el certificado del servidor")
+	scriptFile := flags.String("file", "", "Ejecutar los comandos de este fichero y salir")
+	output := flags.String("output", "text", "Formato de salida del modo no interactivo: text o json")
+	history := flags.String("history", cli.DefaultHistoryFile(), "Fichero de historial (vacío = sin historial)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "Uso: cache-engine cli [flags] [exec <comando> [comando...]]")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+
+	config := client.Config{
+		Addr:      net.JoinHostPort(*host, strconv.Itoa(*port)),
+		Namespace: *namespace,
+		Username:  *user,
+		Password:  *pass,
+	}
+	if config.Password == "" {
+		config.Password = os.Getenv("CACHE_ENGINE_AUTH")
+	}
+	if *socket != "" {
+		config.Network, config.Addr = "unix", *socket
+	}
+	if *useTLS || *caFile != "" || *certFile != "" {
+		tlsConfig, err := netutil.ClientTLSConfig(*host, *caFile, *certFile, *keyFile, *insecure)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error en la configuración TLS: %v\n", err)
+			return cli.ExitUsage
+		}
+		config.TLS = tlsConfig
+	}
+
+	remote, err := cli.NewRemote(config)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "No se pudo conectar con %s: %v\n", config.Addr, err)
+		return cli.ExitError
+	}
+	defer remote.Close()
+
+	if code, batch := runBatch(remote, flags.Args(), *scriptFile, *output); batch {
+		return code
+	}
+	fmt.Printf("Conectado a %s. Escribe EXIT para salir.\n", config.Addr)
+	cli.Interactive(remote, *history)
+	return cli.ExitOK
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"cache-engine/internal/config"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// server es un front-end de red
+type server interface {
+	Addr() string
+	ListenAndServe() error
+	Shutdown(ctx context.Context) error
+}
+
+// run atiende con el servidor, o con la CLI si server es nil, hasta que
+// termina o llega SIGINT o SIGTERM. Después apaga de forma ordenada y
+// retorna el código de salida. Una segunda señal fuerza la salida.
+func run(server server, name string, serveCLI func(), live *applier, manager *config.Manager) int {
+	stop := make(chan os.Signal, 2)
+	signal.Notify(stop, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan error, 1)
+	if server == nil {
+		go func() {
+			serveCLI()
+			done <- nil
+		}()
+	} else {
+		fmt.Printf("Servidor %s escuchando en %s\n", name, server.Addr())
+		go func() { done <- server.ListenAndServe() }()
+	}
+
+	code := 0
+	select {
+	case err := <-done:
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error en el servidor %s: %v\n", name, err)
+			code = 1
+		}
+	case sig := <-stop:
+		fmt.Printf("\nRecibida la señal %s\n", sig)
+		go func() {
+			<-stop
+			fmt.Fprintln(os.Stderr, "Cierre forzado")
+			os.Exit(1)
+		}()
+	}
+
+	fmt.Println("Cerrando cache engine...")
+	if err := shutdown(server, live, manager.Config()); err != nil {
+		fmt.Fprintf(os.Stderr, "Error al cerrar: %v\n", err)
+		code = 1
+	}
+	return code
+}
+
+// shutdown deja de aceptar clientes, espera como máximo shutdown_timeout a
+// que terminen las peticiones en curso y cierra la persistencia y el motor
+func shutdown(server server, live *applier, cfg *config.Config) error {
+	var err error
+	if server != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.ShutdownTimeout)
+		err = server.Shutdown(ctx)
+		cancel()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("las peticiones en curso no terminaron en %s", cfg.ShutdownTimeout)
+		}
+	}
+	if closeErr := live.close(cfg); closeErr != nil && err == nil {
+		err = closeErr
+	}
+	return err
+}
@@ -0,0 +1,250 @@
+// Package acl gestiona los usuarios del servidor, sus contraseñas y los
+// comandos y claves a los que tiene acceso cada uno. La sintaxis de las
+// reglas es la de ACL SETUSER de Redis.
+package acl
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// DefaultUser es el usuario con el que empiezan las conexiones. Mientras
+// acepte cualquier contraseña (nopass) no hace falta autenticarse.
+const DefaultUser = "default"
+
+var (
+	// ErrAuthRequired indica que la conexión no está autenticada
+	ErrAuthRequired = errors.New("autenticación requerida")
+
+	// ErrWrongPass indica credenciales inválidas o un usuario deshabilitado
+	ErrWrongPass = errors.New("usuario o contraseña inválidos, o usuario deshabilitado")
+
+	// ErrNoPermission indica que el usuario no puede ejecutar el comando o
+	// acceder a alguna de sus claves
+	ErrNoPermission = errors.New("permiso denegado")
+)
+
+// UserInfo describe un usuario (ACL GETUSER)
+type UserInfo struct {
+	Name      string
+	Flags     []string // on/off y nopass
+	Passwords []string // Hashes, nunca las contraseñas
+	Commands  string   // Reglas de comandos (+@all -flushall)
+	Keys      []string // Patrones de claves (~*, %R~user:*)
+}
+
+// Rules retorna las reglas que reconstruyen el usuario (ACL LIST)
+func (info UserInfo) Rules() []string {
+	rules := append([]string(nil), info.Flags...)
+	for _, hash := range info.Passwords {
+		rules = append(rules, "#"+hash)
+	}
+	rules = append(rules, info.Keys...)
+	return append(rules, strings.Fields(info.Commands)...)
+}
+
+// Store contiene los usuarios. Es seguro para uso concurrente.
+type Store struct {
+	mu    sync.RWMutex
+	users map[string]*user
+}
+
+// NewStore crea un almacén con el usuario por defecto sin contraseña y
+// con todos los permisos, de modo que sin configuración todo está abierto
+func NewStore() *Store {
+	s := &Store{users: make(map[string]*user)}
+	s.SetUser(DefaultUser, "on", "nopass", "allkeys", "allcommands")
+	return s
+}
+
+// SetUser crea o modifica un usuario aplicando las reglas en orden. Si
+// alguna regla es inválida el usuario no cambia.
+func (s *Store) SetUser(name string, rules ...string) error {
+	if name == "" || strings.ContainsAny(name, " \t\r\n") {
+		return fmt.Errorf("nombre de usuario inválido '%s'", name)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	u := newUser(name)
+	if existing, exists := s.users[name]; exists {
+		u = existing.clone()
+	}
+	for _, rule := range rules {
+		if err := u.apply(rule); err != nil {
+			return fmt.Errorf("error en la regla '%s': %v", rule, err)
+		}
+	}
+	s.users[name] = u
+	return nil
+}
+
+// DelUser elimina usuarios y retorna cuántos existían. El usuario por
+// defecto no se puede eliminar.
+func (s *Store) DelUser(names ...string) (int, error) {
+	if slices.Contains(names, DefaultUser) {
+		return 0, fmt.Errorf("el usuario '%s' no se puede eliminar", DefaultUser)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	deleted := 0
+	for _, name := range names {
+		if _, exists := s.users[name]; exists {
+			delete(s.users, name)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
+
+// GetUser describe un usuario
+func (s *Store) GetUser(name string) (UserInfo, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, exists := s.users[name]
+	if !exists {
+		return UserInfo{}, false
+	}
+	return u.info(), true
+}
+
+// Users retorna los nombres de los usuarios, ordenados
+func (s *Store) Users() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := slices.Collect(maps.Keys(s.users))
+	sort.Strings(names)
+	return names
+}
+
+// List describe todos los usuarios con el formato de ACL LIST
+func (s *Store) List() []string {
+	lines := make([]string, 0)
+	for _, name := range s.Users() {
+		if info, exists := s.GetUser(name); exists {
+			lines = append(lines, "user "+name+" "+strings.Join(info.Rules(), " "))
+		}
+	}
+	return lines
+}
+
+// Authenticate comprueba las credenciales de un usuario habilitado
+func (s *Store) Authenticate(name, password string) error {
+	sum := sha256.Sum256([]byte(password))
+
+	s.mu.RLock()
+	u, exists := s.users[name]
+	if !exists || !u.enabled {
+		s.mu.RUnlock()
+		return ErrWrongPass
+	}
+	if u.nopass {
+		s.mu.RUnlock()
+		return nil
+	}
+	if _, ok := u.verified[sum]; ok {
+		s.mu.RUnlock()
+		return nil
+	}
+	hashes, gen := slices.Clone(u.passwords), u.gen
+	s.mu.RUnlock()
+
+	// PBKDF2 es deliberadamente lento: se calcula sin el lock
+	for _, hash := range hashes {
+		if !checkPassword(hash, password) {
+			continue
+		}
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.users[name] != u || u.gen != gen || !u.enabled {
+			// Las contraseñas cambiaron mientras tanto
+			return ErrWrongPass
+		}
+		u.verified[sum] = struct{}{}
+		return nil
+	}
+	return ErrWrongPass
+}
+
+// Check comprueba que el usuario puede ejecutar un comando sobre las claves
+// indicadas. Un usuario vacío o eliminado requiere autenticarse de nuevo.
+func (s *Store) Check(name, command string, keys ...string) error {
+	if name == "" {
+		return ErrAuthRequired
+	}
+	command = strings.ToUpper(command)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, exists := s.users[name]
+	if !exists {
+		return ErrAuthRequired
+	}
+	if !u.canRun(command) {
+		return fmt.Errorf("%w: el usuario '%s' no puede ejecutar '%s'", ErrNoPermission, name, strings.ToLower(command))
+	}
+
+	access := lookupCommand(command).access
+	for _, key := range keys {
+		if !u.canAccess(key, access) {
+			return fmt.Errorf("%w: el usuario '%s' no puede acceder a la clave '%s'", ErrNoPermission, name, key)
+		}
+	}
+	return nil
+}
+
+// CanAccess indica si el usuario tiene el acceso indicado a una clave
+func (s *Store) CanAccess(name, key string, access Access) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	u, exists := s.users[name]
+	return exists && u.canAccess(key, access)
+}
+
+// Load aplica las líneas "user <nombre> <reglas...>" de un fichero de
+// usuarios. Las líneas vacías y las que empiezan por # se ignoran.
+func (s *Store) Load(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	for lineNum := 1; scanner.Scan(); lineNum++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) < 2 || fields[0] != "user" {
+			return fmt.Errorf("línea %d: se esperaba 'user <nombre> <reglas...>'", lineNum)
+		}
+		if err := s.SetUser(fields[1], fields[2:]...); err != nil {
+			return fmt.Errorf("línea %d: %v", lineNum, err)
+		}
+	}
+	return scanner.Err()
+}
+
+// LoadFile aplica un fichero de usuarios
+func (s *Store) LoadFile(filename string) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return fmt.Errorf("error al abrir el fichero de usuarios: %v", err)
+	}
+	defer file.Close()
+	return s.Load(file)
+}
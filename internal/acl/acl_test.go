@@ -0,0 +1,173 @@
+package acl
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestDefaultUser prueba que sin configuración todo está permitido
+func TestDefaultUser(t *testing.T) {
+	store := NewStore()
+
+	if err := store.Authenticate(DefaultUser, ""); err != nil {
+		t.Errorf("El usuario por defecto no debería pedir contraseña: %v", err)
+	}
+	if err := store.Check(DefaultUser, "FLUSHALL"); err != nil {
+		t.Errorf("El usuario por defecto debería poder ejecutar todo: %v", err)
+	}
+	if err := store.Check("", "GET", "a"); !errors.Is(err, ErrAuthRequired) {
+		t.Errorf("Esperaba ErrAuthRequired, obtuve %v", err)
+	}
+	if _, err := store.DelUser(DefaultUser); err == nil {
+		t.Error("No debería poder eliminarse el usuario por defecto")
+	}
+}
+
+// TestPasswords prueba el alta, la baja y la comprobación de contraseñas
+func TestPasswords(t *testing.T) {
+	store := NewStore()
+
+	if err := store.SetUser("alice", "on", ">secreta", ">otra"); err != nil {
+		t.Fatalf("No se pudo crear el usuario: %v", err)
+	}
+	if err := store.Authenticate("alice", "secreta"); err != nil {
+		t.Errorf("La contraseña debería ser válida: %v", err)
+	}
+	if err := store.Authenticate("alice", "mala"); !errors.Is(err, ErrWrongPass) {
+		t.Errorf("Esperaba ErrWrongPass, obtuve %v", err)
+	}
+
+	info, _ := store.GetUser("alice")
+	for _, hash := range info.Passwords {
+		if strings.Contains(hash, "secreta") {
+			t.Error("Las contraseñas no deberían guardarse en claro")
+		}
+	}
+
+	// Una contraseña ya verificada deja de valer al eliminarla
+	store.SetUser("alice", "<secreta")
+	if err := store.Authenticate("alice", "secreta"); !errors.Is(err, ErrWrongPass) {
+		t.Errorf("La contraseña eliminada no debería valer: %v", err)
+	}
+
+	// Los hashes de GETUSER sirven para recrear el usuario
+	store.SetUser("bob", append([]string{"on"}, "#"+info.Passwords[1])...)
+	if err := store.Authenticate("bob", "otra"); err != nil {
+		t.Errorf("El hash copiado debería ser válido: %v", err)
+	}
+
+	store.SetUser("alice", "off")
+	if err := store.Authenticate("alice", "otra"); !errors.Is(err, ErrWrongPass) {
+		t.Errorf("Un usuario deshabilitado no debería autenticarse: %v", err)
+	}
+}
+
+// TestCommandRules prueba el orden de las reglas de comandos y categorías
+func TestCommandRules(t *testing.T) {
+	store := NewStore()
+	store.SetUser("app", "on", "nopass", "allkeys", "+@all", "-@dangerous", "+flushdb")
+
+	if err := store.Check("app", "SET", "k"); err != nil {
+		t.Errorf("SET debería estar permitido: %v", err)
+	}
+	if err := store.Check("app", "FLUSHALL"); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("FLUSHALL debería estar denegado: %v", err)
+	}
+	if err := store.Check("app", "FLUSHDB"); err != nil {
+		t.Errorf("FLUSHDB debería estar permitido: %v", err)
+	}
+
+	store.SetUser("reader", "on", "nopass", "allkeys", "+@read", "+@connection")
+	if err := store.Check("reader", "GET", "k"); err != nil {
+		t.Errorf("GET debería estar permitido: %v", err)
+	}
+	if err := store.Check("reader", "SET", "k"); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("SET debería estar denegado: %v", err)
+	}
+
+	if err := store.SetUser("reader", "-get", "+@nope"); err == nil {
+		t.Error("Esperaba error por categoría desconocida")
+	}
+	if err := store.Check("reader", "GET", "k"); err != nil {
+		t.Errorf("Una regla inválida no debería modificar el usuario: %v", err)
+	}
+}
+
+// TestSubcommandRules prueba los permisos de los subcomandos
+func TestSubcommandRules(t *testing.T) {
+	store := NewStore()
+	store.SetUser("app", "on", "nopass", "+@connection")
+	if err := store.Check("app", "CLIENT|ID"); err != nil {
+		t.Errorf("CLIENT ID debería heredar el permiso de CLIENT: %v", err)
+	}
+	if err := store.Check("app", "CLIENT|KILL"); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("CLIENT KILL debería requerir @admin: %v", err)
+	}
+
+	store.SetUser("app", "+client|kill")
+	if err := store.Check("app", "CLIENT|KILL"); err != nil {
+		t.Errorf("CLIENT KILL debería estar permitido: %v", err)
+	}
+
+	store.SetUser("ops", "on", "nopass", "+@all", "-client")
+	if err := store.Check("ops", "CLIENT|LIST"); !errors.Is(err, ErrNoPermission) {
+		t.Errorf("Retirar CLIENT debería retirar sus subcomandos: %v", err)
+	}
+}
+
+// TestKeyPatterns prueba los patrones de claves de lectura y escritura
+func TestKeyPatterns(t *testing.T) {
+	store := NewStore()
+	store.SetUser("app", "on", "nopass", "+@all", "~app:*", "%R~shared:*")
+
+	tests := []struct {
+		command string
+		key     string
+		allowed bool
+	}{
+		{"GET", "app:1", true},
+		{"SET", "app:1", true},
+		{"GET", "shared:1", true},
+		{"SET", "shared:1", false},
+		{"GET", "other", false},
+	}
+
+	for _, tt := range tests {
+		err := store.Check("app", tt.command, tt.key)
+		if (err == nil) != tt.allowed {
+			t.Errorf("%s %s: permitido=%v, error=%v", tt.command, tt.key, tt.allowed, err)
+		}
+	}
+
+	info, _ := store.GetUser("app")
+	if strings.Join(info.Keys, " ") != "~app:* %R~shared:*" {
+		t.Errorf("Patrones inesperados: %v", info.Keys)
+	}
+}
+
+// TestLoad prueba la carga de un fichero de usuarios
+func TestLoad(t *testing.T) {
+	store := NewStore()
+	file := `# usuarios
+user default on >admin ~* +@all
+user app on nopass ~app:* +@read
+`
+	if err := store.Load(strings.NewReader(file)); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+
+	if err := store.Authenticate(DefaultUser, "admin"); err != nil {
+		t.Errorf("La contraseña del usuario por defecto debería ser válida: %v", err)
+	}
+	if err := store.Authenticate(DefaultUser, ""); !errors.Is(err, ErrWrongPass) {
+		t.Errorf("El usuario por defecto ya no debería aceptar cualquier contraseña: %v", err)
+	}
+	if users := store.Users(); len(users) != 2 {
+		t.Errorf("Esperaba 2 usuarios, obtuve %v", users)
+	}
+
+	if err := store.Load(strings.NewReader("usuario x on\n")); err == nil {
+		t.Error("Esperaba error por línea inválida")
+	}
+}
@@ -0,0 +1,170 @@
+package acl
+
+import (
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Category agrupa comandos para concederlos o retirarlos juntos (+@read)
+type Category uint8
+
+const (
+	CategoryRead       Category = 1 << iota // Leen claves
+	CategoryWrite                           // Modifican claves
+	CategoryKeyspace                        // Operan sobre el namespace completo
+	CategoryAdmin                           // Administración del servidor
+	CategoryConnection                      // Estado de la conexión
+	CategoryDangerous                       // Pueden afectar a todos los datos o a los usuarios
+)
+
+// categoryNames asocia los nombres de las reglas (@read) con su categoría
+var categoryNames = map[string]Category{
+	"read":       CategoryRead,
+	"write":      CategoryWrite,
+	"keyspace":   CategoryKeyspace,
+	"admin":      CategoryAdmin,
+	"connection": CategoryConnection,
+	"dangerous":  CategoryDangerous,
+}
+
+// Access indica cómo usa un comando sus claves
+type Access uint8
+
+const (
+	AccessRead      Access = 1 << iota // Solo lee las claves
+	AccessWrite                        // Modifica las claves
+	AccessReadWrite = AccessRead | AccessWrite
+)
+
+// commandInfo describe un comando a efectos de permisos
+type commandInfo struct {
+	categories Category
+	access     Access
+}
+
+// commandTable contiene los comandos conocidos con nombres canónicos de
+// Redis. Los front-ends que no hablan RESP traducen sus operaciones a estos
+// nombres antes de comprobar permisos. La tabla de comandos compartida
+// añade los suyos con RegisterCommand.
+var (
+	commandMu    sync.RWMutex
+	commandTable = map[string]commandInfo{
+		"PING":    {CategoryConnection, 0},
+		"ECHO":    {CategoryConnection, 0},
+		"HELLO":   {CategoryConnection, 0},
+		"AUTH":    {CategoryConnection, 0},
+		"QUIT":    {CategoryConnection, 0},
+		"SELECT":  {CategoryConnection, 0},
+		"CLIENT":  {CategoryConnection, 0},
+		"COMMAND": {CategoryConnection, 0},
+
+		"GET":    {CategoryRead, AccessRead},
+		"MGET":   {CategoryRead, AccessRead},
+		"EXISTS": {CategoryRead, AccessRead},
+		"TTL":    {CategoryRead, AccessRead},
+		"TYPE":   {CategoryRead, AccessRead},
+		"WATCH":  {CategoryRead, AccessRead},
+		"KEYS":   {CategoryRead | CategoryKeyspace, AccessRead},
+		"SCAN":   {CategoryRead | CategoryKeyspace, AccessRead},
+		"DBSIZE": {CategoryRead | CategoryKeyspace, AccessRead},
+
+		"SET":    {CategoryWrite, AccessWrite},
+		"MSET":   {CategoryWrite, AccessWrite},
+		"MSETNX": {CategoryWrite, AccessWrite},
+		"DEL":    {CategoryWrite, AccessWrite},
+		"EXPIRE": {CategoryWrite, AccessWrite},
+		"MOVE":   {CategoryWrite | CategoryKeyspace, AccessWrite},
+
+		"FLUSHDB":  {CategoryWrite | CategoryKeyspace | CategoryDangerous, AccessWrite},
+		"FLUSHALL": {CategoryWrite | CategoryKeyspace | CategoryDangerous, AccessWrite},
+		"SWAPDB":   {CategoryWrite | CategoryKeyspace | CategoryDangerous, AccessWrite},
+
+		"INFO": {CategoryAdmin, 0},
+		"ACL":  {CategoryAdmin | CategoryDangerous, 0},
+
+		// Subcomandos con permisos propios; el resto hereda los de su comando
+		"CLIENT|LIST":    {CategoryAdmin, 0},
+		"CLIENT|KILL":    {CategoryAdmin | CategoryDangerous, 0},
+		"CLIENT|PAUSE":   {CategoryAdmin | CategoryDangerous, 0},
+		"CLIENT|UNPAUSE": {CategoryAdmin | CategoryDangerous, 0},
+	}
+)
+
+// RegisterCommand añade un comando o cambia sus categorías y su acceso a
+// las claves
+func RegisterCommand(name string, categories Category, access Access) {
+	commandMu.Lock()
+	defer commandMu.Unlock()
+	commandTable[strings.ToUpper(name)] = commandInfo{categories: categories, access: access}
+}
+
+// lookupCommand retorna la descripción de un comando. Los desconocidos no
+// pertenecen a ninguna categoría y requieren lectura y escritura.
+func lookupCommand(name string) commandInfo {
+	commandMu.RLock()
+	defer commandMu.RUnlock()
+	if info, exists := commandTable[name]; exists {
+		return info
+	}
+	return commandInfo{access: AccessReadWrite}
+}
+
+// knownCommand indica si un comando tiene permisos propios
+func knownCommand(name string) bool {
+	commandMu.RLock()
+	defer commandMu.RUnlock()
+	_, exists := commandTable[name]
+	return exists
+}
+
+// CommandCategories retorna las categorías de un comando (0 si no se conoce)
+func CommandCategories(name string) Category {
+	return lookupCommand(name).categories
+}
+
+// Names retorna los nombres de las categorías incluidas, ordenados
+func (c Category) Names() []string {
+	names := []string{}
+	for name, category := range categoryNames {
+		if c&category != 0 {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Categories retorna los nombres de las categorías, ordenados
+func Categories() []string {
+	names := make([]string, 0, len(categoryNames))
+	for name := range categoryNames {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CommandsIn retorna los comandos de una categoría en minúsculas, ordenados
+func CommandsIn(category string) ([]string, bool) {
+	cat, exists := categoryNames[strings.ToLower(category)]
+	if !exists {
+		return nil, false
+	}
+	return commandsIn(cat), true
+}
+
+// commandsIn retorna los comandos que pertenecen a una categoría
+func commandsIn(category Category) []string {
+	commandMu.RLock()
+	defer commandMu.RUnlock()
+
+	var names []string
+	for name, info := range commandTable {
+		if info.categories&category != 0 {
+			names = append(names, strings.ToLower(name))
+		}
+	}
+	sort.Strings(names)
+	return names
+}
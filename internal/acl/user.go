@@ -0,0 +1,335 @@
+package acl
+
+import (
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"maps"
+	"path"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+const (
+	// hashScheme identifica el formato de las contraseñas guardadas
+	hashScheme = "pbkdf2-sha256"
+
+	// hashIterations es el coste de PBKDF2 para las contraseñas nuevas
+	hashIterations = 4096
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// user guarda un usuario con sus credenciales y permisos
+type user struct {
+	name      string
+	enabled   bool
+	nopass    bool     // Acepta cualquier contraseña
+	passwords []string // Hashes codificados con encodeHash
+	gen       uint64   // Cambia con cada modificación de las contraseñas
+
+	// verified recuerda las contraseñas ya comprobadas (por su SHA-256)
+	// para no repetir PBKDF2 en cada petición HTTP o gRPC
+	verified map[[sha256.Size]byte]struct{}
+
+	allCommands bool
+	commands    map[string]bool // Excepciones a allCommands: +cmd / -cmd
+	keys        []keyPattern
+}
+
+// keyPattern es un patrón de claves con el acceso que concede
+type keyPattern struct {
+	pattern string
+	access  Access
+}
+
+// newUser crea un usuario deshabilitado y sin permisos
+func newUser(name string) *user {
+	return &user{
+		name:     name,
+		verified: make(map[[sha256.Size]byte]struct{}),
+		commands: make(map[string]bool),
+	}
+}
+
+// clone copia el usuario para modificarlo sin afectar al original
+func (u *user) clone() *user {
+	c := *u
+	c.passwords = slices.Clone(u.passwords)
+	c.verified = maps.Clone(u.verified)
+	c.commands = maps.Clone(u.commands)
+	c.keys = slices.Clone(u.keys)
+	return &c
+}
+
+// apply aplica una regla con la sintaxis de ACL SETUSER de Redis
+func (u *user) apply(rule string) error {
+	lower := strings.ToLower(rule)
+	switch lower {
+	case "on":
+		u.enabled = true
+		return nil
+	case "off":
+		u.enabled = false
+		return nil
+	case "nopass":
+		u.nopass = true
+		u.resetPasswords()
+		return nil
+	case "resetpass":
+		u.nopass = false
+		u.resetPasswords()
+		return nil
+	case "allcommands", "+@all":
+		u.allCommands = true
+		clear(u.commands)
+		return nil
+	case "nocommands", "-@all":
+		u.allCommands = false
+		clear(u.commands)
+		return nil
+	case "allkeys":
+		u.keys = []keyPattern{{"*", AccessReadWrite}}
+		return nil
+	case "resetkeys":
+		u.keys = nil
+		return nil
+	case "reset":
+		gen := u.gen
+		*u = *newUser(u.name)
+		u.gen = gen + 1
+		return nil
+	}
+
+	switch {
+	case rule == "":
+		return fmt.Errorf("regla vacía")
+	case rule[0] == '>':
+		u.addPassword(hashPassword(rule[1:]))
+	case rule[0] == '<':
+		return u.removePassword(func(hash string) bool { return checkPassword(hash, rule[1:]) })
+	case rule[0] == '#':
+		if _, _, _, err := decodeHash(rule[1:]); err != nil {
+			return err
+		}
+		u.addPassword(rule[1:])
+	case rule[0] == '!':
+		return u.removePassword(func(hash string) bool { return hash == rule[1:] })
+	case rule[0] == '~':
+		u.keys = append(u.keys, keyPattern{rule[1:], AccessReadWrite})
+	case rule[0] == '%':
+		return u.addKeyPattern(rule)
+	case strings.HasPrefix(rule, "+@"), strings.HasPrefix(rule, "-@"):
+		category, exists := categoryNames[lower[2:]]
+		if !exists {
+			return fmt.Errorf("categoría desconocida '%s'", rule[2:])
+		}
+		for _, name := range commandsIn(category) {
+			u.setCommand(strings.ToUpper(name), rule[0] == '+')
+		}
+	case rule[0] == '+', rule[0] == '-':
+		if len(rule) == 1 {
+			return fmt.Errorf("regla inválida '%s'", rule)
+		}
+		u.setCommand(strings.ToUpper(rule[1:]), rule[0] == '+')
+	default:
+		return fmt.Errorf("regla desconocida '%s'", rule)
+	}
+	return nil
+}
+
+// addKeyPattern interpreta %R~patrón, %W~patrón y %RW~patrón
+func (u *user) addKeyPattern(rule string) error {
+	flags, pattern, found := strings.Cut(rule[1:], "~")
+	if !found || flags == "" {
+		return fmt.Errorf("patrón de claves inválido '%s'", rule)
+	}
+
+	var access Access
+	for _, flag := range strings.ToUpper(flags) {
+		switch flag {
+		case 'R':
+			access |= AccessRead
+		case 'W':
+			access |= AccessWrite
+		default:
+			return fmt.Errorf("patrón de claves inválido '%s'", rule)
+		}
+	}
+	u.keys = append(u.keys, keyPattern{pattern, access})
+	return nil
+}
+
+// setCommand concede o retira un comando respetando el orden de las reglas
+func (u *user) setCommand(name string, allowed bool) {
+	if allowed == u.allCommands {
+		delete(u.commands, name)
+	} else {
+		u.commands[name] = allowed
+	}
+}
+
+// addPassword añade un hash si no estaba ya
+func (u *user) addPassword(hash string) {
+	u.nopass = false
+	for _, existing := range u.passwords {
+		if existing == hash {
+			return
+		}
+	}
+	u.passwords = append(u.passwords, hash)
+	u.gen++
+}
+
+// removePassword elimina las contraseñas que cumplan match
+func (u *user) removePassword(match func(hash string) bool) error {
+	kept := u.passwords[:0]
+	for _, hash := range u.passwords {
+		if !match(hash) {
+			kept = append(kept, hash)
+		}
+	}
+	if len(kept) == len(u.passwords) {
+		return fmt.Errorf("la contraseña no existe para el usuario '%s'", u.name)
+	}
+	u.passwords = kept
+	u.resetVerified()
+	return nil
+}
+
+// resetPasswords elimina todas las contraseñas
+func (u *user) resetPasswords() {
+	u.passwords = nil
+	u.resetVerified()
+}
+
+// resetVerified invalida las contraseñas ya comprobadas
+func (u *user) resetVerified() {
+	clear(u.verified)
+	u.gen++
+}
+
+// canRun indica si el usuario puede ejecutar un comando
+func (u *user) canRun(name string) bool {
+	if allowed, exists := u.commands[name]; exists {
+		return allowed
+	}
+	// Retirar un comando retira sus subcomandos; los que no tienen permisos
+	// propios (CLIENT|ID) heredan los del comando
+	if parent, _, found := strings.Cut(name, "|"); found {
+		if !knownCommand(name) || !u.canRun(parent) {
+			return u.canRun(parent)
+		}
+	}
+	return u.allCommands
+}
+
+// canAccess indica si algún patrón concede el acceso pedido a la clave
+func (u *user) canAccess(key string, access Access) bool {
+	for _, p := range u.keys {
+		if p.access&access != access {
+			continue
+		}
+		if matched, _ := path.Match(p.pattern, key); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// info describe el usuario para ACL GETUSER y ACL LIST
+func (u *user) info() UserInfo {
+	info := UserInfo{Name: u.name}
+
+	if u.enabled {
+		info.Flags = append(info.Flags, "on")
+	} else {
+		info.Flags = append(info.Flags, "off")
+	}
+	if u.nopass {
+		info.Flags = append(info.Flags, "nopass")
+	}
+	info.Passwords = append([]string(nil), u.passwords...)
+
+	rules := []string{"-@all"}
+	if u.allCommands {
+		rules[0] = "+@all"
+	}
+	names := make([]string, 0, len(u.commands))
+	for name := range u.commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if u.commands[name] {
+			rules = append(rules, "+"+strings.ToLower(name))
+		} else {
+			rules = append(rules, "-"+strings.ToLower(name))
+		}
+	}
+	info.Commands = strings.Join(rules, " ")
+
+	for _, p := range u.keys {
+		switch p.access {
+		case AccessRead:
+			info.Keys = append(info.Keys, "%R~"+p.pattern)
+		case AccessWrite:
+			info.Keys = append(info.Keys, "%W~"+p.pattern)
+		default:
+			info.Keys = append(info.Keys, "~"+p.pattern)
+		}
+	}
+	return info
+}
+
+// hashPassword deriva una contraseña con PBKDF2 y una sal aleatoria
+func hashPassword(password string) string {
+	salt := make([]byte, saltSize)
+	rand.Read(salt)
+	key, _ := pbkdf2.Key(sha256.New, password, salt, hashIterations, keySize)
+	return encodeHash(hashIterations, salt, key)
+}
+
+// encodeHash codifica un hash como pbkdf2-sha256$<iteraciones>$<sal>$<clave>
+func encodeHash(iterations int, salt, key []byte) string {
+	return fmt.Sprintf("%s$%d$%s$%s", hashScheme, iterations,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key))
+}
+
+// decodeHash interpreta un hash generado por encodeHash
+func decodeHash(encoded string) (int, []byte, []byte, error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 4 || parts[0] != hashScheme {
+		return 0, nil, nil, fmt.Errorf("hash de contraseña inválido")
+	}
+
+	iterations, err := strconv.Atoi(parts[1])
+	if err != nil || iterations <= 0 {
+		return 0, nil, nil, fmt.Errorf("hash de contraseña inválido")
+	}
+	salt, errSalt := base64.RawStdEncoding.DecodeString(parts[2])
+	key, errKey := base64.RawStdEncoding.DecodeString(parts[3])
+	if errSalt != nil || errKey != nil || len(key) == 0 {
+		return 0, nil, nil, fmt.Errorf("hash de contraseña inválido")
+	}
+	return iterations, salt, key, nil
+}
+
+// checkPassword compara una contraseña con un hash en tiempo constante
+func checkPassword(encoded, password string) bool {
+	iterations, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return false
+	}
+	derived, err := pbkdf2.Key(sha256.New, password, salt, iterations, len(key))
+	if err != nil {
+		return false
+	}
+	return subtle.ConstantTimeCompare(derived, key) == 1
+}
@@ -3,170 +3,358 @@ package cli
 import (
 	"bufio"
 	"cache-engine/internal/cache"
-	"cache-engine/internal/persistence"
+	"cache-engine/internal/command"
+	"cache-engine/pkg/client"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
-	"time"
+	"unicode/utf8"
 )
 
-// Run ejecuta la interfaz de línea de comandos
-func Run(cacheEngine *cache.CacheEngine) {
-	reader := bufio.NewReader(os.Stdin)
+// Códigos de salida del modo no interactivo
+const (
+	ExitOK    = 0 // Todos los comandos se ejecutaron
+	ExitError = 1 // Un comando falló; los siguientes no se ejecutan
+	ExitUsage = 2 // Opciones inválidas o script ilegible
+)
+
+// Formatos de salida del modo no interactivo
+const (
+	OutputText = "text"
+	OutputJSON = "json"
+)
+
+// Options configura el modo no interactivo
+type Options struct {
+	Output string    // OutputText (por defecto) u OutputJSON
+	Stdout io.Writer // Por defecto os.Stdout
+	Stderr io.Writer // Por defecto os.Stderr
+}
+
+// Executor ejecuta los comandos de la CLI: *Session en el motor local o
+// *Remote en un servidor
+type Executor interface {
+	// Namespace retorna el namespace seleccionado con SELECT
+	Namespace() string
 
+	execute(args [][]byte) (reply, error)
+	prompt() string
+	commandNames() []string
+	keys(prefix string) []string
+}
+
+// Run ejecuta la interfaz de línea de comandos interactiva sobre el motor
+// local. Termina con EXIT o al cerrarse la entrada estándar; cerrar el
+// motor queda a cargo de quien lo creó.
+func Run(cacheEngine *cache.CacheEngine) {
+	session := NewSession(cacheEngine)
 	fmt.Println("=== Custom Cache Engine CLI ===")
-	fmt.Println("Comandos disponibles:")
-	fmt.Println("  SET <key> <value>    - Establecer clave-valor")
-	fmt.Println("  GET <key>            - Obtener valor")
-	fmt.Println("  DEL <key>            - Eliminar clave")
-	fmt.Println("  EXPIRE <key> <secs>  - Establecer expiración")
-	fmt.Println("  ENABLELOG [archivo]  - Habilitar logging automático")
-	fmt.Println("  DISABLELOG           - Deshabilitar logging automático")
-	fmt.Println("  SAVE [archivo]       - Guardar estado actual a log")
-	fmt.Println("  LOAD [archivo]       - Cargar desde log")
-	fmt.Println("  STATS                - Mostrar estadísticas")
-	fmt.Println("  EXIT                 - Salir")
+	fmt.Println("Comandos disponibles (HELP <comando> muestra su ayuda):")
+	for _, line := range strings.Split(command.Index(session.ctx.Commands()), "\n") {
+		fmt.Println("  " + line)
+	}
+	fmt.Printf("  %-40s %s\n", "EXIT", "Salir")
+	fmt.Println()
+	fmt.Println(`Los argumentos con espacios van entre comillas ("Juan Pérez"); entre comillas`)
+	fmt.Println(`dobles se admiten \n, \t, \" y \xHH para valores binarios.`)
 	fmt.Println()
 
+	Interactive(session, DefaultHistoryFile())
+}
+
+// Interactive ejecuta el bucle interactivo de la CLI hasta EXIT o el fin
+// de la entrada estándar. En un terminal se editan las líneas con
+// historial (guardado en historyFile si no está vacío) y autocompletado.
+func Interactive(exec Executor, historyFile string) {
+	stdin := int(os.Stdin.Fd())
+	var editor *lineEditor
+	var reader *bufio.Reader
+	if isTerminal(stdin) {
+		editor = newLineEditor(stdin, os.Stdin, os.Stdout, completer(exec))
+		if historyFile != "" {
+			editor.history = loadHistory(historyFile)
+		}
+	} else {
+		reader = bufio.NewReader(os.Stdin)
+	}
+
 	for {
-		fmt.Print("cache> ")
-		input, err := reader.ReadString('\n')
+		var input string
+		var err error
+		if editor != nil {
+			input, err = editor.readLine(exec.prompt())
+			if errors.Is(err, errInterrupted) {
+				continue
+			}
+		} else {
+			fmt.Print(exec.prompt())
+			input, err = reader.ReadString('\n')
+			if err == io.EOF && input != "" {
+				err = nil
+			} else if err == io.EOF {
+				fmt.Println()
+			}
+		}
+		if err == io.EOF {
+			return
+		}
 		if err != nil {
 			fmt.Println("Error al leer entrada:", err)
-			continue
+			return
 		}
 
-		// Limpiar entrada
-		input = strings.TrimSpace(input)
-		if input == "" {
+		// Separar comando y argumentos respetando comillas y escapes
+		args, err := splitArgs(strings.TrimSpace(input))
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			continue
+		}
+		if len(args) == 0 {
 			continue
 		}
+		if editor != nil && !sensitive(args) {
+			line := strings.TrimSpace(input)
+			editor.addHistory(line)
+			if historyFile != "" {
+				appendHistory(historyFile, line)
+			}
+		}
+
+		result, err := exec.execute(args)
+		if errors.Is(err, errExit) {
+			return
+		}
+		switch {
+		case errors.Is(err, errUnknownCommand):
+			fmt.Println("Comando desconocido. Escribe EXIT para salir.")
+		case err != nil:
+			fmt.Printf("Error: %v\n", err)
+		default:
+			fmt.Println(result.text)
+		}
+	}
+}
 
-		// Separar comando y argumentos
-		parts := strings.Fields(input)
-		command := strings.ToUpper(parts[0])
+// Exec ejecuta cada elemento de commands como una línea de comando y
+// retorna el código de salida. Se detiene en el primer comando que falla.
+func Exec(exec Executor, commands []string, opts Options) int {
+	r, code := newRunner(exec, opts)
+	if r == nil {
+		return code
+	}
+	for _, line := range commands {
+		if code, stop := r.line(line, 0); stop {
+			return code
+		}
+	}
+	return ExitOK
+}
 
-		// Procesar comando
-		switch command {
-		case "SET":
-			if len(parts) < 3 {
-				fmt.Println("Error: Uso: SET <key> <value>")
-				continue
-			}
-			key := parts[1]
-			value := strings.Join(parts[2:], " ")
-			cacheEngine.Set(key, value)
+// RunScript ejecuta las líneas de input hasta EOF (un fichero o una
+// tubería) y retorna el código de salida. Las líneas vacías y las que
+// empiezan por # se ignoran. Se detiene en el primer comando que falla.
+func RunScript(exec Executor, input io.Reader, opts Options) int {
+	r, code := newRunner(exec, opts)
+	if r == nil {
+		return code
+	}
 
-			// Log automático si está habilitado
-			if logFile := getLogFile(cacheEngine); logFile != "" {
-				persistence.LogOperation(logFile, "SET", key, value, 0)
+	reader := bufio.NewReader(input)
+	for number := 1; ; number++ {
+		line, err := reader.ReadString('\n')
+		if err != nil && err != io.EOF {
+			fmt.Fprintf(r.stderr, "Error al leer la entrada: %v\n", err)
+			return ExitUsage
+		}
+		if line != "" {
+			if code, stop := r.line(line, number); stop {
+				return code
 			}
+		}
+		if err == io.EOF {
+			return ExitOK
+		}
+	}
+}
 
-			fmt.Println("OK")
+// runner ejecuta líneas en modo no interactivo
+type runner struct {
+	exec   Executor
+	json   bool
+	stdout io.Writer
+	stderr io.Writer
+}
 
-		case "GET":
-			if len(parts) < 2 {
-				fmt.Println("Error: Uso: GET <key>")
-				continue
-			}
-			key := parts[1]
-			value, exists := cacheEngine.Get(key)
-			if !exists {
-				fmt.Println("(nil)")
-			} else {
-				fmt.Printf("%v\n", value)
-			}
+// newRunner valida las opciones. Si son inválidas retorna nil y el código
+// de salida.
+func newRunner(exec Executor, opts Options) (*runner, int) {
+	r := &runner{exec: exec, stdout: opts.Stdout, stderr: opts.Stderr}
+	if r.stdout == nil {
+		r.stdout = os.Stdout
+	}
+	if r.stderr == nil {
+		r.stderr = os.Stderr
+	}
 
-		case "DEL":
-			if len(parts) < 2 {
-				fmt.Println("Error: Uso: DEL <key>")
-				continue
-			}
-			key := parts[1]
-			deleted := cacheEngine.Delete(key)
-			if deleted {
-				// Log automático si está habilitado
-				if logFile := getLogFile(cacheEngine); logFile != "" {
-					persistence.LogOperation(logFile, "DEL", key, nil, 0)
-				}
-				fmt.Println("OK")
-			} else {
-				fmt.Println("Clave no encontrada")
-			}
+	switch strings.ToLower(opts.Output) {
+	case "", OutputText:
+	case OutputJSON:
+		r.json = true
+	default:
+		fmt.Fprintf(r.stderr, "Formato de salida desconocido: %s (text o json)\n", opts.Output)
+		return nil, ExitUsage
+	}
+	return r, ExitOK
+}
 
-		case "EXPIRE":
-			if len(parts) < 3 {
-				fmt.Println("Error: Uso: EXPIRE <key> <seconds>")
-				continue
-			}
-			key := parts[1]
-			seconds, err := strconv.Atoi(parts[2])
-			if err != nil {
-				fmt.Println("Error: segundos debe ser un número")
-				continue
-			}
-			success := cacheEngine.Expire(key, seconds)
-			if success {
-				// Log automático si está habilitado
-				if logFile := getLogFile(cacheEngine); logFile != "" {
-					persistence.LogOperation(logFile, "EXPIRE", key, nil, time.Now().Unix()+int64(seconds))
-				}
-				fmt.Println("OK")
-			} else {
-				fmt.Println("Clave no encontrada")
-			}
+// line ejecuta una línea y escribe su resultado. number es el número de
+// línea del script (0 en Exec). stop indica que no deben ejecutarse más
+// líneas y code el código de salida en ese caso.
+func (r *runner) line(line string, number int) (code int, stop bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || (number > 0 && strings.HasPrefix(line, "#")) {
+		return ExitOK, false
+	}
 
-		case "ENABLELOG":
-			filename := persistence.DefaultLogFile
-			if len(parts) > 1 {
-				filename = parts[1]
-			}
-			cacheEngine.EnableLogging(filename)
-			fmt.Printf("Logging automático habilitado en: %s\n", filename)
+	args, err := splitArgs(line)
+	if err == nil && len(args) == 0 {
+		return ExitOK, false
+	}
+	var result reply
+	if err == nil {
+		result, err = r.exec.execute(args)
+	}
 
-		case "DISABLELOG":
-			cacheEngine.DisableLogging()
-			fmt.Println("Logging automático deshabilitado")
+	switch {
+	case errors.Is(err, errExit):
+		return ExitOK, true
+	case err != nil:
+		r.fail(line, number, err)
+		return ExitError, true
+	}
 
-		case "SAVE":
-			filename := persistence.DefaultLogFile
-			if len(parts) > 1 {
-				filename = parts[1]
-			}
-			if err := persistence.SaveToLog(cacheEngine, filename); err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("Guardado en %s\n", filename)
-			}
+	if r.json {
+		r.writeJSON(map[string]interface{}{"command": line, "result": result.value})
+	} else {
+		fmt.Fprintln(r.stdout, result.text)
+	}
+	return ExitOK, false
+}
 
-		case "LOAD":
-			filename := persistence.DefaultLogFile
-			if len(parts) > 1 {
-				filename = parts[1]
-			}
-			if err := persistence.LoadFromLog(cacheEngine, filename); err != nil {
-				fmt.Printf("Error: %v\n", err)
-			} else {
-				fmt.Printf("Cargado desde %s\n", filename)
-			}
+// fail informa del error de una línea: en texto por la salida de errores y
+// en JSON como un resultado más
+func (r *runner) fail(line string, number int, err error) {
+	if r.json {
+		entry := map[string]interface{}{"command": line, "error": err.Error()}
+		if number > 0 {
+			entry["line"] = number
+		}
+		r.writeJSON(entry)
+		return
+	}
 
-		case "STATS":
-			fmt.Printf("Entradas en cache: %d\n", cacheEngine.Size())
-			fmt.Printf("Límite máximo: %d\n", cacheEngine.MaxEntries())
+	if number > 0 {
+		fmt.Fprintf(r.stderr, "Error en la línea %d (%s): %v\n", number, line, err)
+	} else {
+		fmt.Fprintf(r.stderr, "Error en '%s': %v\n", line, err)
+	}
+}
 
-		case "EXIT":
-			fmt.Println("Cerrando cache engine...")
-			cacheEngine.Close()
-			return
+// writeJSON escribe un objeto JSON por línea
+func (r *runner) writeJSON(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		fmt.Fprintf(r.stderr, "Error al codificar JSON: %v\n", err)
+		return
+	}
+	r.stdout.Write(append(data, '\n'))
+}
 
-		default:
-			fmt.Println("Comando desconocido. Escribe EXIT para salir.")
+// formatReply representa una respuesta del servidor como redis-cli. indent
+// es la sangría de los elementos anidados.
+func formatReply(result interface{}, indent string) string {
+	switch v := result.(type) {
+	case nil:
+		return "(nil)"
+	case string:
+		return v
+	case []byte:
+		return quoteBytes(v)
+	case int64:
+		return "(integer) " + strconv.FormatInt(v, 10)
+	case float64:
+		return "(double) " + strconv.FormatFloat(v, 'g', -1, 64)
+	case bool:
+		return fmt.Sprintf("(%t)", v)
+	case client.Error:
+		return "(error) " + string(v)
+	case []interface{}:
+		if len(v) == 0 {
+			return "(empty array)"
+		}
+		lines := make([]string, len(v))
+		for i, item := range v {
+			prefix := fmt.Sprintf("%d) ", i+1)
+			lines[i] = prefix + formatReply(item, indent+strings.Repeat(" ", utf8.RuneCountInString(prefix)))
+		}
+		return strings.Join(lines, "\n"+indent)
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return "(empty hash)"
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		lines := make([]string, len(keys))
+		for i, key := range keys {
+			prefix := fmt.Sprintf("%d# %s => ", i+1, quoteBytes([]byte(key)))
+			lines[i] = prefix + formatReply(v[key], indent+strings.Repeat(" ", utf8.RuneCountInString(prefix)))
+		}
+		return strings.Join(lines, "\n"+indent)
+	}
+	return fmt.Sprint(result)
+}
+
+// jsonReply representa una respuesta del servidor en JSON
+func jsonReply(result interface{}) interface{} {
+	switch v := result.(type) {
+	case []byte:
+		return jsonValue(v)
+	case client.Error:
+		return map[string]string{"error": string(v)}
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = jsonReply(item)
 		}
+		return items
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = jsonReply(item)
+		}
+		return m
 	}
+	return result
 }
 
-// getLogFile obtiene el archivo de log actual del cache de forma segura
-func getLogFile(c *cache.CacheEngine) string {
-	return c.GetLogFile()
+// jsonValue representa un valor almacenado en JSON. Los bytes que no son
+// UTF-8 válido se codifican como {"base64": "..."}.
+func jsonValue(value interface{}) interface{} {
+	data, ok := value.([]byte)
+	if !ok {
+		return value
+	}
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return map[string]string{"base64": base64.StdEncoding.EncodeToString(data)}
 }
@@ -0,0 +1,120 @@
+package cli
+
+import (
+	"bytes"
+	"cache-engine/internal/cache"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// runExec ejecuta comandos con Exec y retorna el código y las salidas
+func runExec(t *testing.T, engine *cache.CacheEngine, output string, commands ...string) (int, string, string) {
+	t.Helper()
+
+	var stdout, stderr bytes.Buffer
+	code := Exec(NewSession(engine), commands, Options{Output: output, Stdout: &stdout, Stderr: &stderr})
+	return code, stdout.String(), stderr.String()
+}
+
+// TestExec prueba la ejecución de comandos pasados como argumentos
+func TestExec(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	code, stdout, stderr := runExec(t, engine, "", `SET saludo "hola mundo"`, "GET saludo", "SELECT otro", "GET saludo")
+	if code != ExitOK || stderr != "" {
+		t.Fatalf("Exec falló: %d %q", code, stderr)
+	}
+	if want := "OK\nhola mundo\nOK\n(nil)\n"; stdout != want {
+		t.Errorf("Esperaba %q, obtuve %q", want, stdout)
+	}
+
+	// Se detiene en el primer error
+	code, stdout, stderr = runExec(t, engine, "text", "SET a 1", "EXPIRE a x", "SET b 2")
+	if code != ExitError || stdout != "OK\n" || !strings.Contains(stderr, "no es un entero") {
+		t.Errorf("Error inesperado: %d %q %q", code, stdout, stderr)
+	}
+	if _, exists := engine.Get("b"); exists {
+		t.Error("No debería ejecutarse nada tras el error")
+	}
+
+	// DEL y EXPIRE responden como redis-cli
+	code, stdout, _ = runExec(t, engine, "", "SET k v", "EXPIRE k 10", "DEL k", "DEL k", "EXPIRE k 10")
+	if want := "OK\n(integer) 1\n(integer) 1\n(integer) 0\n(integer) 0\n"; code != ExitOK || stdout != want {
+		t.Errorf("Esperaba %q, obtuve %d %q", want, code, stdout)
+	}
+
+	// Un valor con espacios va entre comillas
+	for _, line := range []string{"NOEXISTE", `SET k "abierta`, "SET k a b"} {
+		if code, _, stderr := runExec(t, engine, "", line); code != ExitError || stderr == "" {
+			t.Errorf("%q debería fallar: %d %q", line, code, stderr)
+		}
+	}
+	if code, _, _ := runExec(t, engine, "yaml", "GET a"); code != ExitUsage {
+		t.Errorf("Un formato desconocido debería retornar ExitUsage, obtuve %d", code)
+	}
+}
+
+// TestRunScript prueba scripts con comentarios, líneas vacías y EXIT
+func TestRunScript(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	script := "# datos de prueba\r\nMSET a 1 b 2\n\n  MGET a b c\nEXIT\nSET c 3"
+	var stdout, stderr bytes.Buffer
+	code := RunScript(NewSession(engine), strings.NewReader(script), Options{Stdout: &stdout, Stderr: &stderr})
+	if code != ExitOK || stderr.Len() != 0 {
+		t.Fatalf("RunScript falló: %d %q", code, stderr.String())
+	}
+	if want := "OK\n1) 1\n2) 2\n3) (nil)\n"; stdout.String() != want {
+		t.Errorf("Esperaba %q, obtuve %q", want, stdout.String())
+	}
+	if _, exists := engine.Get("c"); exists {
+		t.Error("EXIT debería terminar el script")
+	}
+
+	// Sin salto de línea final y con el número de línea del error
+	stderr.Reset()
+	code = RunScript(NewSession(engine), strings.NewReader("GET a\nDBLIMIT -1"), Options{Stdout: &stdout, Stderr: &stderr})
+	if code != ExitError || !strings.Contains(stderr.String(), "línea 2") {
+		t.Errorf("Error inesperado: %d %q", code, stderr.String())
+	}
+}
+
+// TestJSONOutput prueba la salida JSON, incluidos valores binarios
+func TestJSONOutput(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	code, stdout, stderr := runExec(t, engine, OutputJSON,
+		`SET texto "Juan Pérez"`, `SET bin "\x00\xff"`, "MGET texto bin nada", "DEL nada", "EXPIRE texto x")
+	if code != ExitError || stderr != "" {
+		t.Fatalf("Resultado inesperado: %d %q", code, stderr)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(stdout, "\n"), "\n")
+	if len(lines) != 5 {
+		t.Fatalf("Esperaba 5 líneas JSON, obtuve %q", stdout)
+	}
+	want := []string{
+		`{"command":"SET texto \"Juan Pérez\"","result":"OK"}`,
+		`{"command":"SET bin \"\\x00\\xff\"","result":"OK"}`,
+		`{"command":"MGET texto bin nada","result":["Juan Pérez",{"base64":"AP8="},null]}`,
+		`{"command":"DEL nada","result":0}`,
+		`{"command":"EXPIRE texto x","error":"el valor no es un entero o está fuera de rango"}`,
+	}
+	for i, line := range lines {
+		var got, expected interface{}
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Fatalf("No se pudo decodificar %q: %v", line, err)
+		}
+		json.Unmarshal([]byte(want[i]), &expected)
+		gotJSON, _ := json.Marshal(got)
+		wantJSON, _ := json.Marshal(expected)
+		if !bytes.Equal(gotJSON, wantJSON) {
+			t.Errorf("Línea %d: esperaba %s, obtuve %s", i+1, wantJSON, gotJSON)
+		}
+	}
+}
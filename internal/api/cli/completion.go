@@ -0,0 +1,92 @@
+package cli
+
+import (
+	"cache-engine/internal/command"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// maxCompletions limita las claves que se ofrecen al autocompletar
+const maxCompletions = 100
+
+// commandNames retorna los nombres de los comandos más EXIT, para
+// autocompletar
+func commandNames(commands []*command.Command) []string {
+	names := make([]string, 0, len(commands)+1)
+	for _, cmd := range commands {
+		names = append(names, cmd.Name)
+	}
+	return append(names, "EXIT")
+}
+
+// localCommands retorna los comandos de la tabla compartida que se pueden
+// ejecutar en un servidor
+func localCommands() []string {
+	ctx := &command.Context{Registry: command.Default}
+	return commandNames(ctx.Commands())
+}
+
+// completer autocompleta nombres de comando y, en las posiciones de clave,
+// las claves del namespace actual
+func completer(exec Executor) completeFunc {
+	return func(args []string, word string) []string {
+		if strings.HasPrefix(word, `"`) || strings.HasPrefix(word, "'") {
+			return nil
+		}
+		if len(args) == 0 {
+			return matchCommands(exec.commandNames(), word)
+		}
+
+		// Las posiciones de las claves vienen de la tabla de comandos
+		cmd, ok := command.Lookup(args[0])
+		index := len(args)
+		if !ok || cmd.FirstKey == 0 || index < cmd.FirstKey ||
+			(cmd.LastKey >= 0 && index > cmd.LastKey) || (index-cmd.FirstKey)%cmd.KeyStep != 0 {
+			return nil
+		}
+
+		keys := exec.keys(word)
+		if len(keys) > maxCompletions {
+			keys = keys[:maxCompletions]
+		}
+		candidates := make([]string, len(keys))
+		for i, key := range keys {
+			candidates[i] = quoteArg(key)
+		}
+		return candidates
+	}
+}
+
+// matchCommands retorna los comandos que empiezan por prefix, en
+// minúsculas si el usuario escribe en minúsculas
+func matchCommands(names []string, prefix string) []string {
+	lower := strings.IndexFunc(prefix, unicode.IsLower) >= 0
+	var matches []string
+	for _, name := range names {
+		if !strings.HasPrefix(strings.ToUpper(name), strings.ToUpper(prefix)) {
+			continue
+		}
+		if lower {
+			matches = append(matches, strings.ToLower(name))
+		} else {
+			matches = append(matches, strings.ToUpper(name))
+		}
+	}
+	sort.Strings(matches)
+	return matches
+}
+
+// globPrefix retorna un patrón de path.Match que selecciona las claves que
+// empiezan por prefix
+func globPrefix(prefix string) string {
+	var b strings.Builder
+	for _, r := range prefix {
+		if strings.ContainsRune(`*?[\`, r) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(r)
+	}
+	b.WriteByte('*')
+	return b.String()
+}
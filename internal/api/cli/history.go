@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// historyFileName es el fichero de historial dentro del directorio personal
+const historyFileName = ".cache_engine_history"
+
+// DefaultHistoryFile retorna la ruta del historial de la CLI interactiva
+// (vacía si no se conoce el directorio personal)
+func DefaultHistoryFile() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, historyFileName)
+}
+
+// loadHistory lee las últimas maxHistory líneas del historial. Si el
+// fichero creció por encima del límite se reescribe recortado.
+func loadHistory(path string) []string {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) > maxHistory {
+		lines = lines[len(lines)-maxHistory:]
+		os.WriteFile(path, []byte(strings.Join(lines, "\n")+"\n"), 0600)
+	}
+	return lines
+}
+
+// appendHistory añade una línea al fichero de historial
+func appendHistory(path, line string) error {
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// sensitive indica si una línea lleva contraseñas y no debe guardarse en
+// el historial (AUTH, HELLO ... AUTH, ACL SETUSER)
+func sensitive(args [][]byte) bool {
+	name := strings.ToUpper(string(args[0]))
+	switch {
+	case name == "AUTH":
+		return true
+	case name == "HELLO":
+		for _, arg := range args[1:] {
+			if strings.EqualFold(string(arg), "AUTH") {
+				return true
+			}
+		}
+	case name == "ACL" && len(args) > 1:
+		return strings.EqualFold(string(args[1]), "SETUSER")
+	}
+	return false
+}
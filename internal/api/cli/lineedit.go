@@ -0,0 +1,332 @@
+package cli
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// errInterrupted indica que se canceló la línea con Ctrl+C
+var errInterrupted = errors.New("interrumpido")
+
+// maxHistory es el número de líneas que se conservan en el historial
+const maxHistory = 1000
+
+// Teclas especiales que produce readKey, en el área de uso privado de
+// Unicode para no chocar con caracteres reales
+const (
+	keyUnknown rune = 0xe000 + iota
+	keyUp
+	keyDown
+	keyLeft
+	keyRight
+	keyHome
+	keyEnd
+	keyDelete
+)
+
+// completeFunc retorna los candidatos para la palabra word, que ocupa la
+// posición len(args) de la línea (0 = nombre del comando)
+type completeFunc func(args []string, word string) []string
+
+// lineEditor lee líneas de un terminal en modo raw con edición, historial
+// y autocompletado con Tab. Las teclas siguen a readline: flechas,
+// Inicio/Fin, Ctrl+A/E/B/F/K/U/W/L/P/N, Ctrl+C cancela la línea y Ctrl+D
+// en una línea vacía termina.
+type lineEditor struct {
+	fd       int
+	in       *bufio.Reader
+	out      io.Writer
+	history  []string
+	complete completeFunc
+
+	// Estado de la línea en edición
+	prompt    string
+	buf       []rune
+	pos       int
+	width     int
+	histIndex int    // Posición en el historial (len(history) = línea nueva)
+	saved     string // Línea nueva guardada al recorrer el historial
+}
+
+// newLineEditor crea un editor sobre el terminal fd
+func newLineEditor(fd int, in io.Reader, out io.Writer, complete completeFunc) *lineEditor {
+	return &lineEditor{fd: fd, in: bufio.NewReader(in), out: out, complete: complete}
+}
+
+// addHistory añade una línea al historial, salvo que repita la anterior
+func (e *lineEditor) addHistory(line string) {
+	if line == "" || (len(e.history) > 0 && e.history[len(e.history)-1] == line) {
+		return
+	}
+	e.history = append(e.history, line)
+	if len(e.history) > maxHistory {
+		e.history = e.history[len(e.history)-maxHistory:]
+	}
+}
+
+// readLine muestra el prompt y lee una línea. Retorna io.EOF con Ctrl+D y
+// errInterrupted con Ctrl+C.
+func (e *lineEditor) readLine(prompt string) (string, error) {
+	state, err := makeRaw(e.fd)
+	if err != nil {
+		return "", err
+	}
+	defer restore(e.fd, state)
+	return e.edit(prompt)
+}
+
+// edit procesa las teclas de una línea con el terminal ya en modo raw
+func (e *lineEditor) edit(prompt string) (string, error) {
+	e.prompt, e.buf, e.pos = prompt, nil, 0
+	e.histIndex, e.saved = len(e.history), ""
+	e.width = terminalWidth(e.fd)
+	e.refresh()
+
+	lastTab := false
+	for {
+		key, err := e.readKey()
+		if err != nil {
+			return "", err
+		}
+
+		tab := false
+		switch key {
+		case '\r', '\n':
+			e.write("\r\n")
+			return string(e.buf), nil
+		case ctrl('C'):
+			e.write("^C\r\n")
+			return "", errInterrupted
+		case ctrl('D'):
+			if len(e.buf) == 0 {
+				e.write("\r\n")
+				return "", io.EOF
+			}
+			e.deleteAt(e.pos)
+		case ctrl('A'), keyHome:
+			e.pos = 0
+		case ctrl('E'), keyEnd:
+			e.pos = len(e.buf)
+		case ctrl('B'), keyLeft:
+			e.pos = max(e.pos-1, 0)
+		case ctrl('F'), keyRight:
+			e.pos = min(e.pos+1, len(e.buf))
+		case ctrl('H'), 127:
+			if e.pos > 0 {
+				e.pos--
+				e.deleteAt(e.pos)
+			}
+		case keyDelete:
+			e.deleteAt(e.pos)
+		case ctrl('K'):
+			e.buf = e.buf[:e.pos]
+		case ctrl('U'):
+			e.buf = e.buf[e.pos:]
+			e.pos = 0
+		case ctrl('W'):
+			// Borra los espacios previos al cursor y la palabra anterior
+			start := e.pos
+			for start > 0 && e.buf[start-1] == ' ' {
+				start--
+			}
+			start = e.wordStart(start)
+			e.buf = append(e.buf[:start], e.buf[e.pos:]...)
+			e.pos = start
+		case ctrl('L'):
+			e.write("\x1b[H\x1b[2J")
+		case ctrl('P'), keyUp:
+			e.historyMove(-1)
+		case ctrl('N'), keyDown:
+			e.historyMove(1)
+		case '\t':
+			tab = true
+			e.completeWord(lastTab)
+		default:
+			if key >= ' ' && key < keyUnknown {
+				e.buf = append(e.buf[:e.pos], append([]rune{key}, e.buf[e.pos:]...)...)
+				e.pos++
+			}
+		}
+		lastTab = tab
+		e.refresh()
+	}
+}
+
+// ctrl retorna el código de la combinación Ctrl+letra
+func ctrl(letter byte) rune {
+	return rune(letter & 0x1f)
+}
+
+// readKey lee un carácter o una secuencia de escape de tecla especial
+func (e *lineEditor) readKey() (rune, error) {
+	r, _, err := e.in.ReadRune()
+	if err != nil || r != 0x1b {
+		return r, err
+	}
+
+	// ESC [ x, ESC O x o ESC [ n ~
+	prefix, err := e.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	if prefix != '[' && prefix != 'O' {
+		return keyUnknown, nil
+	}
+	b, err := e.in.ReadByte()
+	if err != nil {
+		return 0, err
+	}
+	switch b {
+	case 'A':
+		return keyUp, nil
+	case 'B':
+		return keyDown, nil
+	case 'C':
+		return keyRight, nil
+	case 'D':
+		return keyLeft, nil
+	case 'H':
+		return keyHome, nil
+	case 'F':
+		return keyEnd, nil
+	}
+	if b < '0' || b > '9' {
+		return keyUnknown, nil
+	}
+
+	seq := []byte{b}
+	for {
+		b, err := e.in.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if b == '~' {
+			break
+		}
+		if (b < '0' || b > '9') && b != ';' {
+			return keyUnknown, nil
+		}
+		seq = append(seq, b)
+	}
+	switch string(seq) {
+	case "1", "7":
+		return keyHome, nil
+	case "4", "8":
+		return keyEnd, nil
+	case "3":
+		return keyDelete, nil
+	}
+	return keyUnknown, nil
+}
+
+// deleteAt borra el carácter de la posición i si existe
+func (e *lineEditor) deleteAt(i int) {
+	if i < len(e.buf) {
+		e.buf = append(e.buf[:i], e.buf[i+1:]...)
+	}
+}
+
+// wordStart retorna el inicio de la palabra que termina en end
+func (e *lineEditor) wordStart(end int) int {
+	start := end
+	for start > 0 && e.buf[start-1] != ' ' {
+		start--
+	}
+	return start
+}
+
+// historyMove recorre el historial: -1 hacia atrás y 1 hacia delante
+func (e *lineEditor) historyMove(delta int) {
+	index := e.histIndex + delta
+	if index < 0 || index > len(e.history) {
+		return
+	}
+	if e.histIndex == len(e.history) {
+		e.saved = string(e.buf)
+	}
+	e.histIndex = index
+
+	line := e.saved
+	if index < len(e.history) {
+		line = e.history[index]
+	}
+	e.buf = []rune(line)
+	e.pos = len(e.buf)
+}
+
+// completeWord completa la palabra del cursor. Con varios candidatos
+// completa el prefijo común y, si se pulsa Tab dos veces, los lista.
+func (e *lineEditor) completeWord(list bool) {
+	if e.complete == nil {
+		return
+	}
+	start := e.wordStart(e.pos)
+	word := string(e.buf[start:e.pos])
+	candidates := e.complete(strings.Fields(string(e.buf[:start])), word)
+
+	switch len(candidates) {
+	case 0:
+		e.write("\a")
+	case 1:
+		e.replace(start, candidates[0]+" ")
+	default:
+		if prefix := commonPrefix(candidates); utf8.RuneCountInString(prefix) > e.pos-start {
+			e.replace(start, prefix)
+			return
+		}
+		if !list {
+			e.write("\a")
+			return
+		}
+		e.write("\r\n" + strings.Join(candidates, "  ") + "\r\n")
+	}
+}
+
+// replace sustituye el texto entre start y el cursor
+func (e *lineEditor) replace(start int, text string) {
+	rest := append([]rune(text), e.buf[e.pos:]...)
+	e.buf = append(e.buf[:start], rest...)
+	e.pos = start + utf8.RuneCountInString(text)
+}
+
+// commonPrefix retorna el prefijo común de los candidatos
+func commonPrefix(candidates []string) string {
+	prefix := []rune(candidates[0])
+	for _, candidate := range candidates[1:] {
+		runes := []rune(candidate)
+		n := 0
+		for n < len(prefix) && n < len(runes) && prefix[n] == runes[n] {
+			n++
+		}
+		prefix = prefix[:n]
+	}
+	return string(prefix)
+}
+
+// refresh redibuja la línea. Si no cabe en el terminal se muestra la
+// parte que rodea al cursor.
+func (e *lineEditor) refresh() {
+	promptLen := utf8.RuneCountInString(e.prompt)
+	start, end := 0, len(e.buf)
+	if avail := e.width - promptLen - 1; e.width > 0 && avail > 0 {
+		if e.pos > avail {
+			start = e.pos - avail
+		}
+		end = min(end, start+avail)
+	}
+
+	var b strings.Builder
+	b.WriteString("\r" + e.prompt + string(e.buf[start:end]) + "\x1b[K\r")
+	if col := promptLen + e.pos - start; col > 0 {
+		fmt.Fprintf(&b, "\x1b[%dC", col)
+	}
+	e.write(b.String())
+}
+
+func (e *lineEditor) write(s string) {
+	io.WriteString(e.out, s)
+}
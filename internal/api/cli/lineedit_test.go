@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// editLine procesa las teclas de input y retorna la línea resultante
+func editLine(t *testing.T, e *lineEditor, input string) string {
+	t.Helper()
+
+	e.in.Reset(strings.NewReader(input))
+	line, err := e.edit("> ")
+	if err != nil {
+		t.Fatalf("Error al editar %q: %v", input, err)
+	}
+	return line
+}
+
+// TestLineEditor prueba la edición, el historial y el autocompletado
+func TestLineEditor(t *testing.T) {
+	var out bytes.Buffer
+	complete := func(args []string, word string) []string {
+		if len(args) == 0 {
+			return matchCommands(localCommands(), word)
+		}
+		return []string{"usuario:1", "usuario:2"}
+	}
+	e := newLineEditor(-1, strings.NewReader(""), &out, complete)
+
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"GET a\r", "GET a"},
+		{"GET ñandú\x7f\x7fú\r", "GET ñanú"},
+		{"GET b\x1b[D\x1b[DX\r", "GETX b"},
+		{"SET k v\x01\x1b[3~G\r", "GET k v"},
+		{"SET k v\x17\x17valor\r", "SET valor"},
+		{"SET k v\x1b[H\x1b[C\x0b\x05ET\r", "SET"},
+		{"a b c\x02\x02\x15\r", " c"},
+		{"ge\t\r", "get "},
+		{"GET \t\r", "GET usuario:"},
+		{"DEL \t\t1\r", "DEL usuario:1"},
+		{"bien\x04\r", "bien"},
+	}
+	for _, tt := range tests {
+		if got := editLine(t, e, tt.input); got != tt.want {
+			t.Errorf("%q: esperaba %q, obtuve %q", tt.input, tt.want, got)
+		}
+	}
+	if !strings.Contains(out.String(), "usuario:1  usuario:2") {
+		t.Error("El segundo Tab debería listar los candidatos")
+	}
+
+	// Historial: flechas y Ctrl+P/N, sin perder la línea en edición
+	e.addHistory("GET a")
+	e.addHistory("GET b")
+	e.addHistory("GET b")
+	if len(e.history) != 2 {
+		t.Errorf("Las líneas repetidas no deberían añadirse: %q", e.history)
+	}
+	if got := editLine(t, e, "\x1b[A\x1b[A\r"); got != "GET a" {
+		t.Errorf("Esperaba GET a, obtuve %q", got)
+	}
+	if got := editLine(t, e, "nueva\x10\x0e\r"); got != "nueva" {
+		t.Errorf("Esperaba la línea en edición, obtuve %q", got)
+	}
+
+	e.in.Reset(strings.NewReader("\x04"))
+	if _, err := e.edit("> "); err != io.EOF {
+		t.Errorf("Ctrl+D en línea vacía debería retornar EOF, obtuve %v", err)
+	}
+	e.in.Reset(strings.NewReader("abc\x03"))
+	if _, err := e.edit("> "); !errors.Is(err, errInterrupted) {
+		t.Errorf("Ctrl+C debería cancelar la línea, obtuve %v", err)
+	}
+}
+
+// TestHistoryFile prueba el historial persistente
+func TestHistoryFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history")
+	if lines := loadHistory(path); lines != nil {
+		t.Errorf("Un historial inexistente debería estar vacío: %q", lines)
+	}
+
+	for i := 0; i < maxHistory+5; i++ {
+		appendHistory(path, strings.Repeat("x", i%3+1))
+	}
+	lines := loadHistory(path)
+	if len(lines) != maxHistory {
+		t.Fatalf("Esperaba %d líneas, obtuve %d", maxHistory, len(lines))
+	}
+	if again := loadHistory(path); !reflect.DeepEqual(again, lines) {
+		t.Error("El historial recortado debería reescribirse")
+	}
+
+	for _, line := range []string{"AUTH secreto", "hello 3 auth u p", "acl setuser u >pw"} {
+		args, _ := splitArgs(line)
+		if !sensitive(args) {
+			t.Errorf("%q no debería guardarse en el historial", line)
+		}
+	}
+	if args, _ := splitArgs("ACL WHOAMI"); sensitive(args) {
+		t.Error("ACL WHOAMI puede guardarse en el historial")
+	}
+}
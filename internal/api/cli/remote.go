@@ -0,0 +1,142 @@
+package cli
+
+import (
+	"cache-engine/internal/cache"
+	"cache-engine/pkg/client"
+	"context"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Remote ejecuta los comandos de la CLI en un servidor RESP. Los comandos
+// se envían tal cual, de modo que también están disponibles los propios
+// del servidor (CLIENT, ACL...).
+type Remote struct {
+	config client.Config
+	client *client.Client
+	names  []string // Comandos del servidor, para autocompletar
+}
+
+// NewRemote conecta con el servidor y comprueba las credenciales
+func NewRemote(config client.Config) (*Remote, error) {
+	// Una sola conexión: los comandos de la sesión van en orden
+	config.PoolSize = 1
+	r := &Remote{config: config, client: client.New(config)}
+
+	ctx := context.Background()
+	if err := r.client.Ping(ctx); err != nil {
+		r.client.Close()
+		return nil, err
+	}
+
+	// La lista de comandos es opcional: el usuario puede no tener permiso
+	if names, err := r.client.Do(ctx, "COMMAND"); err == nil {
+		if items, ok := names.([]interface{}); ok {
+			for _, item := range items {
+				r.names = append(r.names, strings.ToUpper(replyText(item)))
+			}
+		}
+	}
+	if len(r.names) == 0 {
+		r.names = localCommands()
+	}
+	return r, nil
+}
+
+// Close cierra la conexión con el servidor
+func (r *Remote) Close() error {
+	return r.client.Close()
+}
+
+// Namespace retorna el nombre del namespace seleccionado
+func (r *Remote) Namespace() string {
+	if r.config.Namespace == "" {
+		return cache.DefaultNamespace
+	}
+	return r.config.Namespace
+}
+
+// prompt retorna el prompt de la CLI interactiva, con la dirección del
+// servidor
+func (r *Remote) prompt() string {
+	if r.Namespace() == cache.DefaultNamespace {
+		return fmt.Sprintf("cache@%s> ", r.config.Addr)
+	}
+	return fmt.Sprintf("cache@%s[%s]> ", r.config.Addr, r.Namespace())
+}
+
+// commandNames retorna los comandos que se autocompletan
+func (r *Remote) commandNames() []string {
+	return r.names
+}
+
+// keys retorna las claves del namespace que empiezan por prefix
+func (r *Remote) keys(prefix string) []string {
+	result, err := r.client.Do(context.Background(), "KEYS", globPrefix(prefix))
+	if err != nil {
+		return nil
+	}
+	items, _ := result.([]interface{})
+	keys := make([]string, 0, len(items))
+	for _, item := range items {
+		keys = append(keys, replyText(item))
+	}
+	return keys
+}
+
+// execute envía un comando al servidor
+func (r *Remote) execute(args [][]byte) (reply, error) {
+	name := strings.ToUpper(string(args[0]))
+	if name == "EXIT" || name == "QUIT" {
+		return reply{}, errExit
+	}
+
+	cmd := make([]interface{}, len(args))
+	for i, arg := range args {
+		cmd[i] = arg
+	}
+	result, err := r.client.Do(context.Background(), cmd...)
+	if err != nil {
+		return reply{}, err
+	}
+
+	// El namespace y las credenciales se guardan en la configuración para
+	// que sobrevivan a una reconexión
+	switch {
+	case name == "SELECT" && len(args) == 2:
+		r.reconfigure(func(config *client.Config) { config.Namespace = string(args[1]) })
+	case name == "AUTH" && len(args) == 2:
+		r.reconfigure(func(config *client.Config) { config.Password = string(args[1]) })
+	case name == "AUTH" && len(args) == 3:
+		r.reconfigure(func(config *client.Config) {
+			config.Username, config.Password = string(args[1]), string(args[2])
+		})
+	}
+
+	text := formatReply(result, "")
+	if report, ok := result.([]byte); ok && textReports[name] && utf8.Valid(report) {
+		// Los informes de texto se muestran sin comillas, como en la CLI local
+		text = strings.TrimRight(strings.ReplaceAll(string(report), "\r\n", "\n"), "\n")
+	}
+	return reply{text: text, value: jsonReply(result)}, nil
+}
+
+// textReports son los comandos que responden con un informe de varias
+// líneas (INFO, CLIENT LIST...)
+var textReports = map[string]bool{"INFO": true, "STATS": true, "HELP": true, "CLIENT": true}
+
+// reconfigure sustituye el cliente por uno con la configuración modificada
+func (r *Remote) reconfigure(change func(config *client.Config)) {
+	change(&r.config)
+	r.client.Close()
+	r.client = client.New(r.config)
+}
+
+// replyText convierte una respuesta simple en texto
+func replyText(result interface{}) string {
+	if data, ok := result.([]byte); ok {
+		return string(data)
+	}
+	return fmt.Sprint(result)
+}
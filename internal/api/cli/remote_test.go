@@ -0,0 +1,103 @@
+package cli
+
+import (
+	"bytes"
+	"cache-engine/internal/acl"
+	"cache-engine/internal/api/resp"
+	"cache-engine/internal/cache"
+	"cache-engine/pkg/client"
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// startRemote arranca un servidor RESP y conecta la CLI con él
+func startRemote(t *testing.T, users *acl.Store, config client.Config) (*cache.CacheEngine, *Remote) {
+	t.Helper()
+
+	engine := cache.NewCacheEngine(100)
+	server := resp.NewServer(engine, resp.Config{ACL: users})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	go server.Serve(listener)
+
+	config.Addr = listener.Addr().String()
+	remote, err := NewRemote(config)
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+	t.Cleanup(func() {
+		remote.Close()
+		server.Close()
+		engine.Close(context.Background())
+	})
+	return engine, remote
+}
+
+// TestRemote prueba la CLI contra un servidor RESP
+func TestRemote(t *testing.T) {
+	engine, remote := startRemote(t, nil, client.Config{})
+
+	var stdout, stderr bytes.Buffer
+	code := Exec(remote, []string{
+		`SET saludo "hola mundo"`, `MSET bin "\x00\xff" n 1`, "GET saludo", "MGET saludo bin nada",
+		"MDEL n", "SELECT otro", "KEYS *", "GET saludo",
+	}, Options{Stdout: &stdout, Stderr: &stderr})
+	if code != ExitOK {
+		t.Fatalf("Exec falló: %d %q", code, stderr.String())
+	}
+	want := "OK\nOK\nhola mundo\n1) hola mundo\n2) \"\\x00\\xff\"\n3) (nil)\n(integer) 1\nOK\n(empty array)\n(nil)\n"
+	if stdout.String() != want {
+		t.Errorf("Esperaba %q, obtuve %q", want, stdout.String())
+	}
+	if remote.prompt() != "cache@"+remote.config.Addr+"[otro]> " {
+		t.Errorf("Prompt inesperado: %q", remote.prompt())
+	}
+
+	// El namespace se conserva al reconectar
+	remote.reconfigure(func(*client.Config) {})
+	engine.Namespace("otro").Set("k", "v")
+	if result, err := remote.execute([][]byte{[]byte("GET"), []byte("k")}); err != nil || result.text != "v" {
+		t.Errorf("GET tras reconectar: %q %v", result.text, err)
+	}
+
+	// Informes de texto sin comillas y errores del servidor
+	if result, _ := remote.execute([][]byte{[]byte("STATS")}); !strings.HasPrefix(result.text, "Entradas en cache: ") {
+		t.Errorf("STATS debería mostrar el resumen sin comillas: %q", result.text)
+	}
+	if code := Exec(remote, []string{"NOEXISTE"}, Options{Stdout: &stdout, Stderr: &stderr}); code != ExitError {
+		t.Errorf("Un error del servidor debería retornar ExitError, obtuve %d", code)
+	}
+
+	// Autocompletado con los comandos del servidor y las claves
+	complete := completer(remote)
+	if got := complete(nil, "cli"); !reflect.DeepEqual(got, []string{"client"}) {
+		t.Errorf("Esperaba client, obtuve %q", got)
+	}
+	engine.Namespace("otro").Set("con espacio", "v")
+	if got := complete([]string{"GET"}, "c"); !reflect.DeepEqual(got, []string{`"con espacio"`}) {
+		t.Errorf("Esperaba la clave entre comillas, obtuve %q", got)
+	}
+	if got := complete([]string{"SET", "k"}, ""); got != nil {
+		t.Errorf("El valor de SET no es una clave: %q", got)
+	}
+}
+
+// TestRemoteAuth prueba las credenciales de la CLI remota
+func TestRemoteAuth(t *testing.T) {
+	users := acl.NewStore()
+	users.SetUser(acl.DefaultUser, "resetpass", ">secreto")
+
+	if _, err := NewRemote(client.Config{Addr: "127.0.0.1:1", MaxRetries: -1}); err == nil {
+		t.Error("Esperaba error al conectar con un puerto cerrado")
+	}
+
+	_, remote := startRemote(t, users, client.Config{Password: "secreto"})
+	if result, err := remote.execute([][]byte{[]byte("PING")}); err != nil || result.text != "PONG" {
+		t.Errorf("PING autenticado: %q %v", result.text, err)
+	}
+}
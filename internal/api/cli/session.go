@@ -0,0 +1,90 @@
+package cli
+
+import (
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// errExit indica que se pidió EXIT
+var errExit = errors.New("exit")
+
+// errUnknownCommand indica un comando que la CLI no conoce
+var errUnknownCommand = errors.New("comando desconocido")
+
+// reply es la respuesta a un comando en sus dos representaciones
+type reply struct {
+	text  string      // Salida de texto, una o varias líneas sin salto final
+	value interface{} // Salida JSON
+}
+
+// Session ejecuta comandos de la tabla compartida sobre el motor y
+// recuerda el namespace seleccionado con SELECT
+type Session struct {
+	ctx *command.Context
+}
+
+// NewSession crea una sesión sobre el namespace por defecto. La sesión
+// admite también los comandos que acceden a archivos (SAVE, LOAD...).
+func NewSession(engine *cache.CacheEngine) *Session {
+	return &Session{ctx: command.NewContext(engine, command.Default, true)}
+}
+
+// Namespace retorna el nombre del namespace seleccionado
+func (s *Session) Namespace() string {
+	return s.ctx.DB.Name()
+}
+
+// prompt retorna el prompt de la CLI interactiva
+func (s *Session) prompt() string {
+	if s.Namespace() == cache.DefaultNamespace {
+		return "cache> "
+	}
+	return fmt.Sprintf("cache[%s]> ", s.Namespace())
+}
+
+// commandNames retorna los comandos que se autocompletan
+func (s *Session) commandNames() []string {
+	return commandNames(s.ctx.Commands())
+}
+
+// keys retorna las claves del namespace que empiezan por prefix
+func (s *Session) keys(prefix string) []string {
+	return s.ctx.DB.Keys(globPrefix(prefix))
+}
+
+// execute ejecuta un comando ya dividido en argumentos
+func (s *Session) execute(args [][]byte) (reply, error) {
+	name := strings.ToUpper(string(args[0]))
+	if name == "EXIT" || name == "QUIT" {
+		return reply{}, errExit
+	}
+
+	cmd, exists := s.ctx.Lookup(name)
+	if !exists {
+		return reply{}, fmt.Errorf("%w: %s", errUnknownCommand, args[0])
+	}
+	result, err := cmd.Call(s.ctx, args)
+	var arityErr *command.ArityError
+	if errors.As(err, &arityErr) {
+		return reply{}, errors.New("Uso: " + cmd.Synopsis())
+	}
+	if err != nil {
+		return reply{}, err
+	}
+	return commandReply(result), nil
+}
+
+// commandReply representa la respuesta de un comando de la tabla
+// compartida como redis-cli; los informes se muestran tal cual
+func commandReply(result interface{}) reply {
+	switch v := result.(type) {
+	case command.Status:
+		return reply{text: string(v), value: string(v)}
+	case command.Text:
+		return reply{text: strings.TrimRight(string(v), "\n"), value: string(v)}
+	}
+	return reply{text: formatReply(result, ""), value: command.JSONValue(result)}
+}
@@ -0,0 +1,24 @@
+//go:build !(linux || darwin || freebsd || netbsd || openbsd)
+
+package cli
+
+import "errors"
+
+// En el resto de sistemas la CLI lee líneas sin edición
+type termState struct{}
+
+func isTerminal(fd int) bool {
+	return false
+}
+
+func makeRaw(fd int) (*termState, error) {
+	return nil, errors.ErrUnsupported
+}
+
+func restore(fd int, state *termState) error {
+	return errors.ErrUnsupported
+}
+
+func terminalWidth(fd int) int {
+	return 0
+}
@@ -0,0 +1,192 @@
+package cli
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrUnbalancedQuotes indica comillas sin cerrar en la línea
+var ErrUnbalancedQuotes = errors.New("comillas sin cerrar")
+
+// splitArgs divide una línea en argumentos como redis-cli:
+//   - Los espacios separan argumentos salvo entre comillas.
+//   - Entre comillas dobles se admiten \n, \r, \t, \b, \a, \\, \" y \xHH.
+//   - Entre comillas simples solo se escapa \'.
+//
+// Una comilla de cierre debe ir seguida de un espacio o del final de la
+// línea. Los argumentos son bytes: \xHH permite valores binarios.
+func splitArgs(line string) ([][]byte, error) {
+	var args [][]byte
+	i := 0
+	for {
+		for i < len(line) && isSpace(line[i]) {
+			i++
+		}
+		if i == len(line) {
+			return args, nil
+		}
+
+		var arg []byte
+		inDouble, inSingle := false, false
+		for done := false; !done; {
+			if i == len(line) {
+				if inDouble || inSingle {
+					return nil, ErrUnbalancedQuotes
+				}
+				break
+			}
+
+			ch := line[i]
+			switch {
+			case inDouble:
+				switch {
+				case ch == '\\' && i+3 < len(line) && line[i+1] == 'x' && isHex(line[i+2]) && isHex(line[i+3]):
+					arg = append(arg, unhex(line[i+2])<<4|unhex(line[i+3]))
+					i += 3
+				case ch == '\\' && i+1 < len(line):
+					i++
+					arg = append(arg, unescape(line[i]))
+				case ch == '"':
+					// La comilla de cierre debe separar argumentos
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					arg = append(arg, ch)
+				}
+			case inSingle:
+				switch {
+				case ch == '\\' && i+1 < len(line) && line[i+1] == '\'':
+					i++
+					arg = append(arg, '\'')
+				case ch == '\'':
+					if i+1 < len(line) && !isSpace(line[i+1]) {
+						return nil, ErrUnbalancedQuotes
+					}
+					done = true
+				default:
+					arg = append(arg, ch)
+				}
+			default:
+				switch {
+				case isSpace(ch):
+					done = true
+				case ch == '"' && arg == nil:
+					inDouble = true
+					arg = []byte{}
+				case ch == '\'' && arg == nil:
+					inSingle = true
+					arg = []byte{}
+				default:
+					arg = append(arg, ch)
+				}
+			}
+			if i < len(line) {
+				i++
+			}
+		}
+		args = append(args, arg)
+	}
+}
+
+// unescape traduce el carácter que sigue a una barra entre comillas dobles
+func unescape(ch byte) byte {
+	switch ch {
+	case 'n':
+		return '\n'
+	case 'r':
+		return '\r'
+	case 't':
+		return '\t'
+	case 'b':
+		return '\b'
+	case 'a':
+		return '\a'
+	}
+	return ch
+}
+
+func isSpace(ch byte) bool {
+	return ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r'
+}
+
+func isHex(ch byte) bool {
+	return (ch >= '0' && ch <= '9') || (ch >= 'a' && ch <= 'f') || (ch >= 'A' && ch <= 'F')
+}
+
+func unhex(ch byte) byte {
+	switch {
+	case ch >= 'a':
+		return ch - 'a' + 10
+	case ch >= 'A':
+		return ch - 'A' + 10
+	}
+	return ch - '0'
+}
+
+// quoteBytes representa un valor para mostrarlo. Los valores UTF-8
+// imprimibles se muestran tal cual; el resto entre comillas dobles con los
+// escapes que entiende splitArgs, de modo que se pueden copiar y pegar.
+func quoteBytes(data []byte) string {
+	if utf8.Valid(data) && printable(string(data)) {
+		return string(data)
+	}
+	return quoted(data)
+}
+
+// quoteArg representa un argumento para escribirlo en una línea de
+// comando: entre comillas si contiene espacios, comillas o caracteres no
+// imprimibles
+func quoteArg(s string) string {
+	if utf8.ValidString(s) && printable(s) && !strings.ContainsAny(s, " '") {
+		return s
+	}
+	return quoted([]byte(s))
+}
+
+// quoted retorna data entre comillas dobles con los escapes de splitArgs
+func quoted(data []byte) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(data); {
+		r, size := utf8.DecodeRune(data[i:])
+		switch {
+		case r == '"' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\n':
+			b.WriteString(`\n`)
+		case r == '\r':
+			b.WriteString(`\r`)
+		case r == '\t':
+			b.WriteString(`\t`)
+		case r == utf8.RuneError && size == 1, !unicode.IsPrint(r):
+			for _, ch := range data[i : i+size] {
+				b.WriteString(`\x`)
+				b.WriteByte("0123456789abcdef"[ch>>4])
+				b.WriteByte("0123456789abcdef"[ch&0xf])
+			}
+		default:
+			b.Write(data[i : i+size])
+		}
+		i += size
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// printable indica si una cadena puede mostrarse sin comillas
+func printable(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsPrint(r) || r == '"' || r == '\\' {
+			return false
+		}
+	}
+	return true
+}
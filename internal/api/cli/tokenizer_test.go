@@ -0,0 +1,68 @@
+package cli
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+// TestSplitArgs prueba comillas, escapes y errores del tokenizer
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{`SET usuario:123 "Juan Pérez"`, []string{"SET", "usuario:123", "Juan Pérez"}},
+		{`  GET   k  `, []string{"GET", "k"}},
+		{`SET k "a  b\tc\n"`, []string{"SET", "k", "a  b\tc\n"}},
+		{`SET k "\x00\xff\x41"`, []string{"SET", "k", "\x00\xffA"}},
+		{`SET k "dice \"hola\" \\ fin"`, []string{"SET", "k", `dice "hola" \ fin`}},
+		{`SET k 'sin \n escapes \'ok\''`, []string{"SET", "k", `sin \n escapes 'ok'`}},
+		{`SET k ""`, []string{"SET", "k", ""}},
+		{`SET k a"b`, []string{"SET", "k", `a"b`}},
+		{`SET k "\xZZ"`, []string{"SET", "k", "xZZ"}},
+	}
+
+	for _, tt := range tests {
+		args, err := splitArgs(tt.line)
+		if err != nil {
+			t.Errorf("splitArgs(%q): error inesperado: %v", tt.line, err)
+			continue
+		}
+		if len(args) != len(tt.want) {
+			t.Errorf("splitArgs(%q): esperaba %q, obtuve %q", tt.line, tt.want, args)
+			continue
+		}
+		for i := range args {
+			if string(args[i]) != tt.want[i] {
+				t.Errorf("splitArgs(%q)[%d]: esperaba %q, obtuve %q", tt.line, i, tt.want[i], args[i])
+			}
+		}
+	}
+
+	for _, line := range []string{`SET k "abierta`, `SET k 'abierta`, `SET k "a"b`, `SET k "fin\`} {
+		if _, err := splitArgs(line); !errors.Is(err, ErrUnbalancedQuotes) {
+			t.Errorf("splitArgs(%q): esperaba ErrUnbalancedQuotes, obtuve %v", line, err)
+		}
+	}
+}
+
+// TestQuoteBytes prueba que los valores mostrados se pueden volver a leer
+func TestQuoteBytes(t *testing.T) {
+	if got := quoteBytes([]byte("Juan Pérez")); got != "Juan Pérez" {
+		t.Errorf("Un valor imprimible debería mostrarse tal cual, obtuve %q", got)
+	}
+
+	for _, value := range [][]byte{
+		{0x00, 0xff, 'a'},
+		[]byte("línea\nsiguiente\t\"citada\" \\"),
+		{},
+		[]byte("ñ\xc3"),
+	} {
+		quoted := quoteBytes(value)
+		args, err := splitArgs("SET k " + quoted)
+		if err != nil || len(args) != 3 || !bytes.Equal(args[2], value) {
+			t.Errorf("El valor %q no se recupera desde %s: %q %v", value, quoted, args, err)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package grpc
+
+import (
+	"cache-engine/internal/acl"
+	"context"
+	"encoding/base64"
+	"errors"
+	"strings"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Las credenciales viajan en los metadatos como "authorization: Basic
+// base64(usuario:contraseña)". Sin ellas se usa el usuario por defecto si
+// no tiene contraseña.
+
+// userKey es la clave del usuario autenticado en el contexto de la llamada
+type userKey struct{}
+
+// authUnary autentica cada llamada unaria
+func (s *Server) authUnary(ctx context.Context, req any, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (any, error) {
+	user, err := s.authenticate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return handler(context.WithValue(ctx, userKey{}, user), req)
+}
+
+// authStream autentica cada stream
+func (s *Server) authStream(srv any, stream grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+	user, err := s.authenticate(stream.Context())
+	if err != nil {
+		return err
+	}
+	ctx := context.WithValue(stream.Context(), userKey{}, user)
+	return handler(srv, &userStream{ServerStream: stream, ctx: ctx})
+}
+
+// userStream sustituye el contexto de un stream por el que lleva el usuario
+type userStream struct {
+	grpclib.ServerStream
+	ctx context.Context
+}
+
+// Context retorna el contexto con el usuario autenticado
+func (us *userStream) Context() context.Context {
+	return us.ctx
+}
+
+// authenticate obtiene el usuario de los metadatos de la llamada
+func (s *Server) authenticate(ctx context.Context) (string, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		if s.acl.Authenticate(acl.DefaultUser, "") != nil {
+			return "", status.Error(codes.Unauthenticated, acl.ErrAuthRequired.Error())
+		}
+		return acl.DefaultUser, nil
+	}
+
+	user, password, ok := parseBasicAuth(values[0])
+	if !ok {
+		return "", status.Error(codes.Unauthenticated, "cabecera authorization inválida")
+	}
+	if err := s.acl.Authenticate(user, password); err != nil {
+		return "", status.Error(codes.Unauthenticated, err.Error())
+	}
+	return user, nil
+}
+
+// check comprueba los permisos del usuario de la llamada
+func (s *Server) check(ctx context.Context, command string, keys ...string) error {
+	user, _ := ctx.Value(userKey{}).(string)
+	err := s.acl.Check(user, command, keys...)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, acl.ErrNoPermission):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Error(codes.Unauthenticated, err.Error())
+	}
+}
+
+// parseBasicAuth interpreta "Basic base64(usuario:contraseña)"
+func parseBasicAuth(header string) (string, string, bool) {
+	const prefix = "Basic "
+	if len(header) < len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", "", false
+	}
+	decoded, err := base64.StdEncoding.DecodeString(header[len(prefix):])
+	if err != nil {
+		return "", "", false
+	}
+	return strings.Cut(string(decoded), ":")
+}
+
+// basicAuth envía usuario y contraseña en cada llamada
+type basicAuth struct {
+	header string
+}
+
+// BasicAuth crea las credenciales por llamada para un usuario del servidor.
+// Se usan con grpc.WithPerRPCCredentials.
+func BasicAuth(username, password string) credentials.PerRPCCredentials {
+	token := base64.StdEncoding.EncodeToString([]byte(username + ":" + password))
+	return basicAuth{header: "Basic " + token}
+}
+
+// GetRequestMetadata implementa credentials.PerRPCCredentials
+func (b basicAuth) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": b.header}, nil
+}
+
+// RequireTransportSecurity implementa credentials.PerRPCCredentials. Sin
+// TLS las credenciales viajan en claro.
+func (b basicAuth) RequireTransportSecurity() bool {
+	return false
+}
@@ -0,0 +1,79 @@
+package grpc
+
+import (
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"context"
+	"errors"
+	"sort"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Command ejecuta cualquier comando de la tabla compartida, incluidos los
+// registrados por el programa que embebe el motor
+func (svc *service) Command(ctx context.Context, in *CommandRequest) (*Reply, error) {
+	if len(in.Args) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "falta el comando")
+	}
+
+	cctx := &command.Context{Engine: svc.engine, DB: svc.namespace(in.Namespace), Registry: command.Default}
+	cmd, exists := cctx.Lookup(string(in.Args[0]))
+	if !exists {
+		return nil, status.Errorf(codes.InvalidArgument, "%v '%s'", command.ErrUnknown, in.Args[0])
+	}
+	if err := cmd.CheckArity(in.Args); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := svc.server.check(ctx, cmd.Name, cmd.Keys(in.Args)...); err != nil {
+		return nil, err
+	}
+
+	result, err := cmd.Call(cctx, in.Args)
+	switch {
+	case errors.Is(err, cache.ErrQuotaExceeded):
+		return nil, setError(err)
+	case err != nil:
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return newReply(result), nil
+}
+
+// newReply convierte la respuesta de un comando en un mensaje Reply
+func newReply(result interface{}) *Reply {
+	switch v := result.(type) {
+	case nil:
+		return &Reply{Type: ReplyType_REPLY_NULL}
+	case command.Status:
+		return &Reply{Type: ReplyType_REPLY_STATUS, Status: string(v)}
+	case command.Text:
+		return &Reply{Type: ReplyType_REPLY_TEXT, Status: string(v)}
+	case []byte:
+		return &Reply{Type: ReplyType_REPLY_BULK, Data: v}
+	case string:
+		return &Reply{Type: ReplyType_REPLY_BULK, Data: []byte(v)}
+	case int64:
+		return &Reply{Type: ReplyType_REPLY_INTEGER, Integer: v}
+	case int:
+		return &Reply{Type: ReplyType_REPLY_INTEGER, Integer: int64(v)}
+	case []interface{}:
+		reply := &Reply{Type: ReplyType_REPLY_ARRAY, Elements: make([]*Reply, len(v))}
+		for i, item := range v {
+			reply.Elements[i] = newReply(item)
+		}
+		return reply
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		reply := &Reply{Type: ReplyType_REPLY_MAP, Elements: make([]*Reply, 0, 2*len(keys))}
+		for _, key := range keys {
+			reply.Elements = append(reply.Elements, newReply(key), newReply(v[key]))
+		}
+		return reply
+	}
+	return &Reply{Type: ReplyType_REPLY_BULK, Data: cache.ValueBytes(result)}
+}
@@ -0,0 +1,426 @@
+// Package grpc expone el CacheEngine como el servicio gRPC definido en
+// cache.proto: operaciones get/set/delete/expire/batch/scan, comandos de la
+// tabla compartida (Command) y un stream Watch con los cambios del espacio
+// de claves. Los mensajes y el servicio se generan a partir de cache.proto.
+package grpc
+
+//go:generate protoc --go_out=. --go_opt=paths=source_relative --go-grpc_out=. --go-grpc_opt=paths=source_relative cache.proto
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/netutil"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// DefaultPort es el puerto por defecto del servidor gRPC
+	DefaultPort = 50051
+
+	// defaultScanCount es el tamaño de página de Scan si no se indica
+	defaultScanCount = 100
+
+	// watchBuffer es el tamaño del buffer de eventos de cada Watch.
+	// Si el cliente no consume a tiempo, los eventos se descartan.
+	watchBuffer = 1024
+)
+
+// Config contiene las opciones del servidor gRPC
+type Config struct {
+	Port           int         // Puerto de escucha
+	ACL            *acl.Store  // Usuarios y permisos (nil = sin autenticación)
+	TLS            *tls.Config // Cifrado de las conexiones (nil = texto plano)
+	UnixSocket     string      // Ruta de un socket Unix adicional (vacío = ninguno)
+	UnixSocketPerm os.FileMode // Permisos del socket (0 = 0700)
+	NoTCP          bool        // Escuchar solo en el socket Unix
+}
+
+// Server expone el CacheEngine mediante gRPC
+type Server struct {
+	engine *cache.CacheEngine
+	config Config
+	acl    *acl.Store
+	grpc   *grpclib.Server
+
+	stopping     chan struct{} // Se cierra al empezar Shutdown para terminar los Watch
+	stoppingOnce sync.Once
+}
+
+// NewServer crea un servidor gRPC para el motor indicado
+func NewServer(engine *cache.CacheEngine, config Config) *Server {
+	if config.Port <= 0 {
+		config.Port = DefaultPort
+	}
+	if config.ACL == nil {
+		config.ACL = acl.NewStore()
+	}
+
+	s := &Server{engine: engine, config: config, acl: config.ACL, stopping: make(chan struct{})}
+	opts := []grpclib.ServerOption{
+		grpclib.ChainUnaryInterceptor(s.recordUnary, s.authUnary),
+		grpclib.ChainStreamInterceptor(s.recordStream, s.authStream),
+	}
+	if config.TLS != nil {
+		opts = append(opts, grpclib.Creds(credentials.NewTLS(config.TLS)))
+	}
+	s.grpc = grpclib.NewServer(opts...)
+	RegisterCacheServer(s.grpc, &service{engine: engine, server: s})
+	return s
+}
+
+// Addr retorna las direcciones de escucha configuradas
+func (s *Server) Addr() string {
+	return s.listenConfig().Describe()
+}
+
+// listenConfig retorna el puerto TCP y el socket Unix configurados
+func (s *Server) listenConfig() netutil.ListenConfig {
+	return netutil.ListenConfig{
+		Addr:       fmt.Sprintf(":%d", s.config.Port),
+		NoTCP:      s.config.NoTCP,
+		UnixSocket: s.config.UnixSocket,
+		SocketPerm: s.config.UnixSocketPerm,
+	}
+}
+
+// ListenAndServe escucha en el puerto y el socket Unix configurados y
+// atiende conexiones
+func (s *Server) ListenAndServe() error {
+	listeners, err := netutil.Listen(s.listenConfig())
+	if err != nil {
+		return err
+	}
+	return netutil.ServeAll(listeners, s.Serve)
+}
+
+// Serve atiende conexiones del listener hasta que se cierre el servidor
+func (s *Server) Serve(listener net.Listener) error {
+	err := s.grpc.Serve(listener)
+	if errors.Is(err, grpclib.ErrServerStopped) {
+		return nil
+	}
+	return err
+}
+
+// Close cierra el listener y todas las llamadas en curso, incluidos los Watch
+func (s *Server) Close() error {
+	s.grpc.Stop()
+	return nil
+}
+
+// Shutdown deja de aceptar conexiones, termina los Watch y espera a que
+// acaben las llamadas en curso. Si ctx vence antes, las cierra como Close y
+// retorna ctx.Err().
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.stoppingOnce.Do(func() { close(s.stopping) })
+
+	stopped := make(chan struct{})
+	go func() {
+		s.grpc.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		s.grpc.Stop()
+		return ctx.Err()
+	}
+}
+
+// recordUnary registra la latencia de cada llamada unaria
+func (s *Server) recordUnary(ctx context.Context, req any, info *grpclib.UnaryServerInfo, handler grpclib.UnaryHandler) (any, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	s.engine.RecordCommand("GRPC "+methodName(info.FullMethod), time.Since(start))
+	return resp, err
+}
+
+// recordStream registra la duración de cada stream
+func (s *Server) recordStream(srv any, stream grpclib.ServerStream, info *grpclib.StreamServerInfo, handler grpclib.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, stream)
+	s.engine.RecordCommand("GRPC "+methodName(info.FullMethod), time.Since(start))
+	return err
+}
+
+// methodName extrae el método de "/paquete.Servicio/Metodo"
+func methodName(fullMethod string) string {
+	return fullMethod[strings.LastIndex(fullMethod, "/")+1:]
+}
+
+// service implementa CacheServer sobre el motor
+type service struct {
+	UnimplementedCacheServer
+	engine *cache.CacheEngine
+	server *Server
+}
+
+// namespace retorna el namespace de una petición (vacío = por defecto)
+func (svc *service) namespace(name string) *cache.Namespace {
+	if name == "" {
+		name = cache.DefaultNamespace
+	}
+	return svc.engine.Namespace(name)
+}
+
+// setError traduce un error de escritura del motor a un status gRPC
+func setError(err error) error {
+	if errors.Is(err, cache.ErrQuotaExceeded) {
+		return status.Error(codes.ResourceExhausted, err.Error())
+	}
+	return status.Error(codes.Internal, err.Error())
+}
+
+// Get retorna el valor de una clave
+func (svc *service) Get(ctx context.Context, in *GetRequest) (*GetResponse, error) {
+	if in.Key == "" {
+		return nil, status.Error(codes.InvalidArgument, "la clave no puede estar vacía")
+	}
+	if err := svc.server.check(ctx, "GET", in.Key); err != nil {
+		return nil, err
+	}
+
+	ns := svc.namespace(in.Namespace)
+	value, ttl, exists := ns.GetTTL(in.Key)
+	if !exists {
+		return &GetResponse{}, nil
+	}
+	return &GetResponse{Found: true, Value: cache.ValueBytes(value), Ttl: ttl}, nil
+}
+
+// Set escribe una clave con expiración y condición opcionales
+func (svc *service) Set(ctx context.Context, in *SetRequest) (*SetResponse, error) {
+	if err := svc.server.check(ctx, "SET", in.Key); err != nil {
+		return nil, err
+	}
+	ns := svc.namespace(in.Namespace)
+	stored, err := svc.set(ns, in.Key, in.Value, in.Ttl, in.Condition)
+	if err != nil {
+		return nil, err
+	}
+	return &SetResponse{Stored: stored}, nil
+}
+
+// set valida y aplica una escritura
+func (svc *service) set(ns *cache.Namespace, key string, value []byte, ttl int64, condition Condition) (bool, error) {
+	if key == "" {
+		return false, status.Error(codes.InvalidArgument, "la clave no puede estar vacía")
+	}
+	if ttl < 0 {
+		return false, status.Error(codes.InvalidArgument, "el TTL no puede ser negativo")
+	}
+
+	var cond cache.SetCondition
+	switch condition {
+	case Condition_ALWAYS:
+		cond = cache.SetAlways
+	case Condition_IF_ABSENT:
+		cond = cache.SetIfAbsent
+	case Condition_IF_PRESENT:
+		cond = cache.SetIfPresent
+	default:
+		return false, status.Errorf(codes.InvalidArgument, "condición desconocida: %d", condition)
+	}
+
+	// El valor y su expiración se escriben en una sola operación
+	stored, err := ns.SetEx(key, value, int(ttl), cond)
+	if err != nil {
+		return false, setError(err)
+	}
+	return stored, nil
+}
+
+// Delete elimina las claves indicadas
+func (svc *service) Delete(ctx context.Context, in *DeleteRequest) (*DeleteResponse, error) {
+	if err := svc.server.check(ctx, "DEL", in.Keys...); err != nil {
+		return nil, err
+	}
+	ns := svc.namespace(in.Namespace)
+	deleted := ns.MDelete(in.Keys...)
+	return &DeleteResponse{Deleted: int64(deleted)}, nil
+}
+
+// Expire cambia la expiración de una clave
+func (svc *service) Expire(ctx context.Context, in *ExpireRequest) (*ExpireResponse, error) {
+	if err := svc.server.check(ctx, "EXPIRE", in.Key); err != nil {
+		return nil, err
+	}
+	updated, err := svc.expire(svc.namespace(in.Namespace), in.Key, in.Ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &ExpireResponse{Updated: updated}, nil
+}
+
+// expire valida y aplica un cambio de expiración
+func (svc *service) expire(ns *cache.Namespace, key string, ttl int64) (bool, error) {
+	if ttl <= 0 {
+		return false, status.Error(codes.InvalidArgument, "el TTL debe ser mayor que cero")
+	}
+	return ns.Expire(key, int(ttl)), nil
+}
+
+// batchCommands asocia cada operación de Batch con su comando a efectos
+// de permisos
+var batchCommands = map[OpType]string{
+	OpType_GET:    "GET",
+	OpType_SET:    "SET",
+	OpType_DELETE: "DEL",
+	OpType_EXPIRE: "EXPIRE",
+}
+
+// Batch ejecuta las operaciones en orden. Un fallo en una operación se
+// informa en su resultado y no detiene las siguientes.
+func (svc *service) Batch(ctx context.Context, in *BatchRequest) (*BatchResponse, error) {
+	ns := svc.namespace(in.Namespace)
+	results := make([]*OperationResult, len(in.Operations))
+
+	for i, op := range in.Operations {
+		result := &OperationResult{}
+		var err error
+		if command, known := batchCommands[op.Type]; !known {
+			err = fmt.Errorf("operación desconocida: %d", op.Type)
+		} else {
+			err = svc.server.check(ctx, command, op.Key)
+		}
+
+		if err == nil {
+			switch op.Type {
+			case OpType_GET:
+				var value interface{}
+				if value, result.Ok = ns.Get(op.Key); result.Ok {
+					result.Value = cache.ValueBytes(value)
+				}
+			case OpType_SET:
+				result.Ok, err = svc.set(ns, op.Key, op.Value, op.Ttl, Condition_ALWAYS)
+			case OpType_DELETE:
+				result.Ok = ns.Delete(op.Key)
+			case OpType_EXPIRE:
+				result.Ok, err = svc.expire(ns, op.Key, op.Ttl)
+			}
+		}
+
+		if err != nil {
+			result.Ok = false
+			if st, ok := status.FromError(err); ok {
+				result.Error = st.Message()
+			} else {
+				result.Error = err.Error()
+			}
+		}
+		results[i] = result
+	}
+
+	return &BatchResponse{Results: results}, nil
+}
+
+// Scan lista las claves en orden a partir del cursor
+func (svc *service) Scan(ctx context.Context, in *ScanRequest) (*ScanResponse, error) {
+	if err := svc.server.check(ctx, "SCAN"); err != nil {
+		return nil, err
+	}
+	count := int(in.Count)
+	if count < 0 {
+		return nil, status.Error(codes.InvalidArgument, "count no puede ser negativo")
+	}
+	if count == 0 {
+		count = defaultScanCount
+	}
+
+	keys := svc.namespace(in.Namespace).Keys(in.Pattern)
+
+	// Las claves vienen ordenadas: saltar hasta la primera posterior al cursor
+	start := 0
+	if in.Cursor != "" {
+		for start < len(keys) && keys[start] <= in.Cursor {
+			start++
+		}
+	}
+
+	end := start + count
+	resp := &ScanResponse{}
+	if end < len(keys) {
+		resp.Keys = keys[start:end]
+		resp.NextCursor = keys[end-1]
+	} else {
+		resp.Keys = keys[start:]
+	}
+	return resp, nil
+}
+
+// eventTypes traduce los tipos de evento del motor a los del servicio
+var eventTypes = map[cache.EventType]EventType{
+	cache.EventSet:     EventType_EVENT_SET,
+	cache.EventDelete:  EventType_EVENT_DELETE,
+	cache.EventExpire:  EventType_EVENT_EXPIRE,
+	cache.EventExpired: EventType_EVENT_EXPIRED,
+	cache.EventEvicted: EventType_EVENT_EVICTED,
+	cache.EventFlush:   EventType_EVENT_FLUSH,
+}
+
+// Watch envía los cambios del espacio de claves hasta que el cliente cancele
+func (svc *service) Watch(in *WatchRequest, stream grpclib.ServerStreamingServer[WatchEvent]) error {
+	ctx := stream.Context()
+	if err := svc.server.check(ctx, "WATCH"); err != nil {
+		return err
+	}
+	user, _ := ctx.Value(userKey{}).(string)
+
+	events, cancel := svc.engine.Subscribe(watchBuffer)
+	defer cancel()
+
+	// Enviar las cabeceras indica al cliente que la suscripción está activa
+	if err := stream.SendHeader(metadata.MD{}); err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-svc.server.stopping:
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return nil
+			}
+			if in.Namespace != "" && event.Namespace != in.Namespace {
+				continue
+			}
+			// Un flush afecta a todas las claves, también a las del prefijo
+			if event.Type != cache.EventFlush && !strings.HasPrefix(event.Key, in.KeyPrefix) {
+				continue
+			}
+			// Solo se notifican las claves que el usuario puede leer
+			if event.Key != "" && !svc.server.acl.CanAccess(user, event.Key, acl.AccessRead) {
+				continue
+			}
+
+			err := stream.Send(&WatchEvent{
+				Type:              eventTypes[event.Type],
+				Namespace:         event.Namespace,
+				Key:               event.Key,
+				TimestampUnixNano: event.Time.UnixNano(),
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+}
@@ -0,0 +1,381 @@
+package grpc
+
+import (
+	"bytes"
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	grpclib "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+	"google.golang.org/protobuf/types/dynamicpb"
+)
+
+// startServer arranca un servidor en un puerto libre y retorna un cliente
+func startServer(t *testing.T) (*cache.CacheEngine, CacheClient) {
+	t.Helper()
+	return startServerWith(t, Config{})
+}
+
+// startServerWith arranca un servidor con la configuración indicada
+func startServerWith(t *testing.T, config Config, opts ...grpclib.DialOption) (*cache.CacheEngine, CacheClient) {
+	t.Helper()
+	engine, conn := dial(t, config, opts...)
+	return engine, NewCacheClient(conn)
+}
+
+// dial arranca un servidor y retorna una conexión sin cliente generado
+func dial(t *testing.T, config Config, opts ...grpclib.DialOption) (*cache.CacheEngine, *grpclib.ClientConn) {
+	t.Helper()
+
+	engine := cache.NewCacheEngine(100)
+	server := NewServer(engine, config)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	go server.Serve(listener)
+
+	opts = append(opts, grpclib.WithTransportCredentials(insecure.NewCredentials()))
+	conn, err := grpclib.NewClient(listener.Addr().String(), opts...)
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		engine.Close(context.Background())
+	})
+	return engine, conn
+}
+
+// testContext retorna un contexto con timeout para una llamada
+func testContext(t *testing.T) context.Context {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	t.Cleanup(cancel)
+	return ctx
+}
+
+// TestGetSetDelete prueba las operaciones básicas
+func TestGetSetDelete(t *testing.T) {
+	_, client := startServer(t)
+	ctx := testContext(t)
+
+	set, err := client.Set(ctx, &SetRequest{Key: "user", Value: []byte("Juan"), Ttl: 60})
+	if err != nil || !set.Stored {
+		t.Fatalf("Set falló: %v %v", set, err)
+	}
+
+	get, err := client.Get(ctx, &GetRequest{Key: "user"})
+	if err != nil {
+		t.Fatalf("Get falló: %v", err)
+	}
+	if !get.Found || string(get.Value) != "Juan" {
+		t.Errorf("Get: esperaba Juan, obtuve %+v", get)
+	}
+	if get.Ttl <= 0 || get.Ttl > 60 {
+		t.Errorf("TTL inesperado: %d", get.Ttl)
+	}
+
+	set, _ = client.Set(ctx, &SetRequest{Key: "user", Value: []byte("Ana"), Condition: Condition_IF_ABSENT})
+	if set.Stored {
+		t.Error("IF_ABSENT no debería sobrescribir una clave existente")
+	}
+
+	// Los namespaces están aislados
+	get, _ = client.Get(ctx, &GetRequest{Namespace: "1", Key: "user"})
+	if get.Found {
+		t.Error("La clave no debería existir en el namespace 1")
+	}
+
+	del, err := client.Delete(ctx, &DeleteRequest{Keys: []string{"user", "nope"}})
+	if err != nil || del.Deleted != 1 {
+		t.Errorf("Delete: esperaba 1, obtuve %v %v", del, err)
+	}
+
+	_, err = client.Get(ctx, &GetRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Errorf("Clave vacía: esperaba InvalidArgument, obtuve %v", err)
+	}
+}
+
+// TestExpireAndQuota prueba Expire y el error de cuota
+func TestExpireAndQuota(t *testing.T) {
+	engine, client := startServer(t)
+	ctx := testContext(t)
+
+	exp, _ := client.Expire(ctx, &ExpireRequest{Key: "nope", Ttl: 10})
+	if exp.Updated {
+		t.Error("Expire no debería actualizar una clave inexistente")
+	}
+
+	engine.Namespace("limited").SetQuota(cache.Quota{MaxEntries: 1, Reject: true})
+	client.Set(ctx, &SetRequest{Namespace: "limited", Key: "a", Value: []byte("1")})
+	_, err := client.Set(ctx, &SetRequest{Namespace: "limited", Key: "b", Value: []byte("2")})
+	if status.Code(err) != codes.ResourceExhausted {
+		t.Errorf("Cuota: esperaba ResourceExhausted, obtuve %v", err)
+	}
+}
+
+// TestBatch prueba la ejecución de operaciones en lote
+func TestBatch(t *testing.T) {
+	_, client := startServer(t)
+	ctx := testContext(t)
+
+	resp, err := client.Batch(ctx, &BatchRequest{Operations: []*Operation{
+		{Type: OpType_SET, Key: "a", Value: []byte("1")},
+		{Type: OpType_GET, Key: "a"},
+		{Type: OpType_EXPIRE, Key: "a", Ttl: -1},
+		{Type: OpType_DELETE, Key: "a"},
+		{Type: OpType_GET, Key: "a"},
+	}})
+	if err != nil {
+		t.Fatalf("Batch falló: %v", err)
+	}
+	if len(resp.Results) != 5 {
+		t.Fatalf("Esperaba 5 resultados, obtuve %d", len(resp.Results))
+	}
+
+	r := resp.Results
+	if !r[0].Ok || !r[1].Ok || string(r[1].Value) != "1" {
+		t.Errorf("Set/Get en lote incorrectos: %+v %+v", r[0], r[1])
+	}
+	if r[2].Ok || r[2].Error == "" {
+		t.Error("Un TTL negativo debería informar un error en su resultado")
+	}
+	if !r[3].Ok || r[4].Ok {
+		t.Errorf("Delete/Get en lote incorrectos: %+v %+v", r[3], r[4])
+	}
+}
+
+// TestScan prueba el recorrido paginado de claves
+func TestScan(t *testing.T) {
+	engine, client := startServer(t)
+	ctx := testContext(t)
+
+	for _, key := range []string{"user:1", "user:2", "user:3", "user:4", "session:1"} {
+		engine.Set(key, "x")
+	}
+
+	var keys []string
+	cursor := ""
+	for pages := 0; ; pages++ {
+		resp, err := client.Scan(ctx, &ScanRequest{Pattern: "user:*", Cursor: cursor, Count: 3})
+		if err != nil {
+			t.Fatalf("Scan falló: %v", err)
+		}
+		keys = append(keys, resp.Keys...)
+		if resp.NextCursor == "" {
+			break
+		}
+		if pages > 2 {
+			t.Fatal("Scan no termina")
+		}
+		cursor = resp.NextCursor
+	}
+
+	if len(keys) != 4 || keys[0] != "user:1" || keys[3] != "user:4" {
+		t.Errorf("Scan: claves inesperadas %v", keys)
+	}
+}
+
+// TestWatch prueba el stream de cambios del espacio de claves
+func TestWatch(t *testing.T) {
+	engine, client := startServer(t)
+	ctx := testContext(t)
+
+	stream, err := client.Watch(ctx, &WatchRequest{Namespace: cache.DefaultNamespace, KeyPrefix: "user:"})
+	if err != nil {
+		t.Fatalf("Watch falló: %v", err)
+	}
+
+	// Las cabeceras llegan cuando la suscripción ya está registrada
+	if _, err := stream.Header(); err != nil {
+		t.Fatalf("Header falló: %v", err)
+	}
+
+	engine.Set("session:1", "x")             // Filtrado por prefijo
+	engine.Namespace("1").Set("user:9", "x") // Filtrado por namespace
+	engine.Set("user:1", "Juan")
+	engine.Delete("user:1")
+
+	expected := []EventType{EventType_EVENT_SET, EventType_EVENT_DELETE}
+	for _, want := range expected {
+		ev, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("Recv falló: %v", err)
+		}
+		if ev.Type != want || ev.Key != "user:1" || ev.Namespace != cache.DefaultNamespace {
+			t.Errorf("Evento inesperado: %+v (esperaba tipo %d)", ev, want)
+		}
+		if ev.TimestampUnixNano == 0 {
+			t.Error("El evento debería incluir la marca de tiempo")
+		}
+	}
+}
+
+// TestCommand prueba la ejecución de comandos de la tabla compartida
+func TestCommand(t *testing.T) {
+	engine, client := startServer(t)
+	ctx := testContext(t)
+
+	args := func(parts ...string) [][]byte {
+		b := make([][]byte, len(parts))
+		for i, part := range parts {
+			b[i] = []byte(part)
+		}
+		return b
+	}
+
+	reply, err := client.Command(ctx, &CommandRequest{Namespace: "app", Args: args("MSET", "a", "1", "b", "")})
+	if err != nil || reply.Type != ReplyType_REPLY_STATUS || reply.Status != "OK" {
+		t.Fatalf("MSET falló: %+v %v", reply, err)
+	}
+	if value, _ := engine.Namespace("app").Get("b"); value != "" {
+		t.Errorf("Un argumento vacío debería conservarse, obtuve %q", value)
+	}
+
+	reply, err = client.Command(ctx, &CommandRequest{Namespace: "app", Args: args("MGET", "a", "nada")})
+	if err != nil || reply.Type != ReplyType_REPLY_ARRAY || len(reply.Elements) != 2 ||
+		string(reply.Elements[0].Data) != "1" || reply.Elements[1].Type != ReplyType_REPLY_NULL {
+		t.Errorf("MGET inesperado: %+v %v", reply, err)
+	}
+
+	for _, tt := range []struct {
+		args [][]byte
+		code codes.Code
+	}{
+		{nil, codes.InvalidArgument},
+		{args("NOPE"), codes.InvalidArgument},
+		{args("GET"), codes.InvalidArgument},
+		{args("LOAD"), codes.InvalidArgument},
+		{args("EXPIRE", "a", "x"), codes.InvalidArgument},
+	} {
+		if _, err := client.Command(ctx, &CommandRequest{Args: tt.args}); status.Code(err) != tt.code {
+			t.Errorf("%q: esperaba %v, obtuve %v", tt.args, tt.code, err)
+		}
+	}
+}
+
+// TestWireFormat prueba que un cliente que solo conoce cache.proto, sin
+// código generado en Go, se entiende con el servidor. Los mensajes se
+// construyen con dynamicpb a partir del descriptor, como haría un cliente
+// en otro lenguaje.
+func TestWireFormat(t *testing.T) {
+	_, conn := dial(t, Config{})
+	messages := File_cache_proto.Messages()
+	newMessage := func(name string) *dynamicpb.Message {
+		return dynamicpb.NewMessage(messages.ByName(protoreflect.Name(name)))
+	}
+
+	req := newMessage("SetRequest")
+	fields := req.Descriptor().Fields()
+	req.Set(fields.ByName("namespace"), protoreflect.ValueOfString("1"))
+	req.Set(fields.ByName("key"), protoreflect.ValueOfString("k"))
+	req.Set(fields.ByName("value"), protoreflect.ValueOfBytes([]byte{0xff}))
+	req.Set(fields.ByName("ttl"), protoreflect.ValueOfInt64(300))
+	req.Set(fields.ByName("condition"), protoreflect.ValueOfEnum(1))
+
+	// Bytes esperados según cache.proto: campos 1..5
+	expected := []byte{
+		0x0a, 0x01, '1', // namespace
+		0x12, 0x01, 'k', // key
+		0x1a, 0x01, 0xff, // value
+		0x20, 0xac, 0x02, // ttl = 300
+		0x28, 0x01, // condition = IF_ABSENT
+	}
+	data, err := proto.MarshalOptions{Deterministic: true}.Marshal(req)
+	if err != nil || !bytes.Equal(data, expected) {
+		t.Errorf("Codificación: esperaba %x, obtuve %x %v", expected, data, err)
+	}
+
+	ctx := testContext(t)
+	set := newMessage("SetResponse")
+	if err := conn.Invoke(ctx, "/cacheengine.v1.Cache/Set", req, set); err != nil {
+		t.Fatalf("Set: %v", err)
+	}
+	if !set.Get(set.Descriptor().Fields().ByName("stored")).Bool() {
+		t.Error("Set debería haber escrito la clave")
+	}
+
+	get := newMessage("GetRequest")
+	get.Set(get.Descriptor().Fields().ByName("namespace"), protoreflect.ValueOfString("1"))
+	get.Set(get.Descriptor().Fields().ByName("key"), protoreflect.ValueOfString("k"))
+	resp := newMessage("GetResponse")
+	if err := conn.Invoke(ctx, "/cacheengine.v1.Cache/Get", get, resp); err != nil {
+		t.Fatalf("Get: %v", err)
+	}
+	respFields := resp.Descriptor().Fields()
+	ttl := resp.Get(respFields.ByName("ttl")).Int()
+	if !bytes.Equal(resp.Get(respFields.ByName("value")).Bytes(), []byte{0xff}) || ttl <= 0 || ttl > 300 {
+		t.Errorf("Get: respuesta inesperada %v", resp)
+	}
+
+	// Mensajes anidados y repetidos
+	cmd := newMessage("CommandRequest")
+	args := cmd.NewField(cmd.Descriptor().Fields().ByName("args")).List()
+	for _, arg := range []string{"MGET", "k", "x"} {
+		args.Append(protoreflect.ValueOfBytes([]byte(arg)))
+	}
+	cmd.Set(cmd.Descriptor().Fields().ByName("namespace"), protoreflect.ValueOfString("1"))
+	cmd.Set(cmd.Descriptor().Fields().ByName("args"), protoreflect.ValueOfList(args))
+	reply := newMessage("Reply")
+	if err := conn.Invoke(ctx, "/cacheengine.v1.Cache/Command", cmd, reply); err != nil {
+		t.Fatalf("Command: %v", err)
+	}
+	elements := reply.Get(reply.Descriptor().Fields().ByName("elements")).List()
+	if elements.Len() != 2 {
+		t.Fatalf("Command: esperaba 2 elementos, obtuve %v", reply)
+	}
+	first := elements.Get(0).Message()
+	if !bytes.Equal(first.Get(first.Descriptor().Fields().ByName("data")).Bytes(), []byte{0xff}) {
+		t.Errorf("Command: elemento inesperado %v", first)
+	}
+}
+
+// TestAuthentication prueba las credenciales Basic y los permisos
+func TestAuthentication(t *testing.T) {
+	users := acl.NewStore()
+	users.SetUser(acl.DefaultUser, "off")
+	users.SetUser("app", "on", ">pw", "~app:*", "+@read", "+set")
+
+	_, anonymous := startServerWith(t, Config{ACL: users})
+	ctx := testContext(t)
+	if _, err := anonymous.Get(ctx, &GetRequest{Key: "app:1"}); status.Code(err) != codes.Unauthenticated {
+		t.Errorf("Esperaba Unauthenticated, obtuve %v", err)
+	}
+
+	engine, client := startServerWith(t, Config{ACL: users}, grpclib.WithPerRPCCredentials(BasicAuth("app", "pw")))
+	if _, err := client.Set(ctx, &SetRequest{Key: "app:1", Value: []byte("x")}); err != nil {
+		t.Errorf("Set falló: %v", err)
+	}
+	if _, err := client.Set(ctx, &SetRequest{Key: "other", Value: []byte("x")}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Esperaba PermissionDenied, obtuve %v", err)
+	}
+	if _, err := client.Delete(ctx, &DeleteRequest{Keys: []string{"app:1"}}); status.Code(err) != codes.PermissionDenied {
+		t.Errorf("Esperaba PermissionDenied en Delete, obtuve %v", err)
+	}
+
+	batch, err := client.Batch(ctx, &BatchRequest{Operations: []*Operation{
+		{Type: OpType_GET, Key: "app:1"},
+		{Type: OpType_DELETE, Key: "app:1"},
+	}})
+	if err != nil {
+		t.Fatalf("Batch falló: %v", err)
+	}
+	if !batch.Results[0].Ok || batch.Results[1].Ok || batch.Results[1].Error == "" {
+		t.Errorf("Resultados inesperados: %+v %+v", batch.Results[0], batch.Results[1])
+	}
+	if _, exists := engine.Get("app:1"); !exists {
+		t.Error("La operación denegada no debería ejecutarse")
+	}
+}
@@ -0,0 +1,60 @@
+package http
+
+import (
+	"cache-engine/internal/acl"
+	"errors"
+	nethttp "net/http"
+)
+
+// userKey es la clave del usuario autenticado en el contexto de la petición
+type userKey struct{}
+
+// authenticate obtiene el usuario de la petición a partir de la cabecera
+// Authorization (Basic). Sin credenciales se usa el usuario por defecto
+// si no tiene contraseña.
+func (s *Server) authenticate(r *nethttp.Request) (string, error) {
+	user, password, ok := r.BasicAuth()
+	if !ok {
+		user, password = acl.DefaultUser, ""
+		if s.acl.Authenticate(user, password) != nil {
+			return "", acl.ErrAuthRequired
+		}
+		return user, nil
+	}
+
+	if err := s.acl.Authenticate(user, password); err != nil {
+		return "", err
+	}
+	return user, nil
+}
+
+// allowed comprueba los permisos del usuario de la petición; si se
+// deniegan responde con el error y retorna false
+func (s *Server) allowed(w nethttp.ResponseWriter, r *nethttp.Request, command string, keys ...string) bool {
+	user, _ := r.Context().Value(userKey{}).(string)
+	if err := s.acl.Check(user, command, keys...); err != nil {
+		writeAuthError(w, err)
+		return false
+	}
+	return true
+}
+
+// require protege un handler con el permiso de un comando sin claves
+func (s *Server) require(command string, next nethttp.Handler) nethttp.Handler {
+	return nethttp.HandlerFunc(func(w nethttp.ResponseWriter, r *nethttp.Request) {
+		if s.allowed(w, r, command) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// writeAuthError responde 401 si faltan credenciales válidas y 403 si el
+// usuario no tiene permiso
+func writeAuthError(w nethttp.ResponseWriter, err error) {
+	if errors.Is(err, acl.ErrNoPermission) {
+		writeError(w, nethttp.StatusForbidden, err)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Basic realm="cache-engine"`)
+	writeError(w, nethttp.StatusUnauthorized, err)
+}
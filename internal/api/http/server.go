@@ -0,0 +1,516 @@
+package http
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"cache-engine/internal/metrics"
+	"cache-engine/internal/netutil"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net"
+	nethttp "net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPort es el puerto por defecto del servidor HTTP
+	DefaultPort = 8080
+
+	// TTLHeader permite indicar la expiración en segundos de un PUT
+	TTLHeader = "X-Cache-TTL"
+
+	// NamespaceHeader permite seleccionar el namespace de una petición
+	NamespaceHeader = "X-Cache-Namespace"
+
+	// maxBodySize limita el tamaño de los cuerpos aceptados (8 MiB)
+	maxBodySize = 8 << 20
+)
+
+// Config contiene las opciones del servidor HTTP
+type Config struct {
+	Port           int         // Puerto de escucha
+	EnableCORS     bool        // Añadir cabeceras CORS a las respuestas
+	ACL            *acl.Store  // Usuarios y permisos (nil = sin autenticación)
+	TLS            *tls.Config // HTTPS (nil = HTTP en texto plano)
+	UnixSocket     string      // Ruta de un socket Unix adicional (vacío = ninguno)
+	UnixSocketPerm os.FileMode // Permisos del socket (0 = 0700)
+	NoTCP          bool        // Escuchar solo en el socket Unix
+}
+
+// Server expone el CacheEngine mediante una API REST
+type Server struct {
+	engine *cache.CacheEngine
+	config Config
+	acl    *acl.Store
+	mux    *nethttp.ServeMux
+
+	mu      sync.Mutex
+	servers []*nethttp.Server // Uno por listener
+	closed  bool
+}
+
+// NewServer crea un servidor HTTP para el motor indicado
+func NewServer(engine *cache.CacheEngine, config Config) *Server {
+	if config.Port <= 0 {
+		config.Port = DefaultPort
+	}
+	if config.ACL == nil {
+		config.ACL = acl.NewStore()
+	}
+
+	s := &Server{
+		engine: engine,
+		config: config,
+		acl:    config.ACL,
+		mux:    nethttp.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("GET /keys/{key}", s.handleGet)
+	s.mux.HandleFunc("PUT /keys/{key}", s.handlePut)
+	s.mux.HandleFunc("DELETE /keys/{key}", s.handleDelete)
+	s.mux.HandleFunc("POST /bulk/get", s.handleBulkGet)
+	s.mux.HandleFunc("POST /bulk/set", s.handleBulkSet)
+	s.mux.HandleFunc("POST /bulk/delete", s.handleBulkDelete)
+	s.mux.HandleFunc("POST /command", s.handleCommand)
+	s.mux.HandleFunc("GET /health", s.handleHealth)
+	s.mux.Handle("GET /metrics", s.require("INFO", metrics.Handler(engine)))
+
+	return s
+}
+
+// Addr retorna las direcciones de escucha configuradas
+func (s *Server) Addr() string {
+	return s.listenConfig().Describe()
+}
+
+// listenConfig retorna el puerto TCP y el socket Unix configurados
+func (s *Server) listenConfig() netutil.ListenConfig {
+	return netutil.ListenConfig{
+		Addr:       fmt.Sprintf(":%d", s.config.Port),
+		NoTCP:      s.config.NoTCP,
+		UnixSocket: s.config.UnixSocket,
+		SocketPerm: s.config.UnixSocketPerm,
+	}
+}
+
+// ServeHTTP implementa http.Handler
+func (s *Server) ServeHTTP(w nethttp.ResponseWriter, r *nethttp.Request) {
+	if s.config.EnableCORS {
+		w.Header().Set("Access-Control-Allow-Origin", "*")
+		w.Header().Set("Access-Control-Allow-Methods", "GET, PUT, DELETE, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Authorization, Content-Type, "+TTLHeader+", "+NamespaceHeader)
+		w.Header().Set("Access-Control-Expose-Headers", TTLHeader)
+
+		// Respuesta a las peticiones preflight
+		if r.Method == nethttp.MethodOptions {
+			w.WriteHeader(nethttp.StatusNoContent)
+			return
+		}
+	}
+
+	// /health queda abierto para las sondas de disponibilidad
+	if r.URL.Path != "/health" {
+		user, err := s.authenticate(r)
+		if err != nil {
+			writeAuthError(w, err)
+			return
+		}
+		r = r.WithContext(context.WithValue(r.Context(), userKey{}, user))
+	}
+
+	start := time.Now()
+	s.mux.ServeHTTP(w, r)
+	if r.Pattern != "" {
+		s.engine.RecordCommand("HTTP "+r.Pattern, time.Since(start))
+	}
+}
+
+// ListenAndServe escucha en el puerto y el socket Unix configurados y
+// atiende conexiones
+func (s *Server) ListenAndServe() error {
+	listeners, err := netutil.Listen(s.listenConfig())
+	if err != nil {
+		return err
+	}
+	return netutil.ServeAll(listeners, s.Serve)
+}
+
+// Serve atiende peticiones del listener, con TLS si está configurado,
+// hasta que se cierre el servidor
+func (s *Server) Serve(listener net.Listener) error {
+	server := &nethttp.Server{Handler: s, TLSConfig: s.config.TLS}
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return net.ErrClosed
+	}
+	s.servers = append(s.servers, server)
+	s.mu.Unlock()
+
+	var err error
+	if s.config.TLS != nil {
+		// Los certificados vienen de TLSConfig
+		err = server.ServeTLS(listener, "", "")
+	} else {
+		err = server.Serve(listener)
+	}
+	if errors.Is(err, nethttp.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Close deja de aceptar peticiones y cierra las conexiones existentes
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.closed = true
+	var err error
+	for _, server := range s.servers {
+		if closeErr := server.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// Shutdown deja de aceptar peticiones y espera a que terminen las que están
+// en curso. Si ctx vence antes, cierra las conexiones y retorna ctx.Err().
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	s.closed = true
+	servers := s.servers
+	s.mu.Unlock()
+
+	var err error
+	for _, server := range servers {
+		if shutdownErr := server.Shutdown(ctx); shutdownErr != nil && err == nil {
+			err = shutdownErr
+		}
+	}
+	if err != nil {
+		s.Close()
+	}
+	return err
+}
+
+// namespace obtiene el namespace de la petición (cabecera o parámetro ns)
+func (s *Server) namespace(r *nethttp.Request) *cache.Namespace {
+	name := r.Header.Get(NamespaceHeader)
+	if ns := r.URL.Query().Get("ns"); ns != "" {
+		name = ns
+	}
+	return s.engine.Namespace(name)
+}
+
+// parseTTL obtiene la expiración en segundos de la cabecera o del parámetro ttl
+func parseTTL(r *nethttp.Request) (int, error) {
+	raw := r.Header.Get(TTLHeader)
+	if q := r.URL.Query().Get("ttl"); q != "" {
+		raw = q
+	}
+	if raw == "" {
+		return 0, nil
+	}
+
+	ttl, err := strconv.Atoi(raw)
+	if err != nil || ttl <= 0 {
+		return 0, fmt.Errorf("ttl inválido: %s", raw)
+	}
+	return ttl, nil
+}
+
+// isJSON indica si el cuerpo de la petición es JSON
+func isJSON(r *nethttp.Request) bool {
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	return mediaType == "application/json"
+}
+
+// wantsJSON indica si el cliente pide la respuesta en JSON
+func wantsJSON(r *nethttp.Request) bool {
+	return strings.Contains(r.Header.Get("Accept"), "application/json")
+}
+
+// errorResponse es el cuerpo de las respuestas de error
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// writeJSON escribe una respuesta JSON con el código indicado
+func writeJSON(w nethttp.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// writeError escribe un error en formato JSON
+func writeError(w nethttp.ResponseWriter, status int, err error) {
+	writeJSON(w, status, errorResponse{Error: err.Error()})
+}
+
+// setStatus traduce un error de escritura del motor a un código HTTP
+func setStatus(err error) int {
+	if errors.Is(err, cache.ErrQuotaExceeded) {
+		return nethttp.StatusInsufficientStorage
+	}
+	return nethttp.StatusInternalServerError
+}
+
+// keyResponse es la representación JSON de una clave
+type keyResponse struct {
+	Key   string      `json:"key"`
+	Value interface{} `json:"value"`
+	TTL   int64       `json:"ttl"`
+}
+
+// handleGet responde GET /keys/{key}
+func (s *Server) handleGet(w nethttp.ResponseWriter, r *nethttp.Request) {
+	key := r.PathValue("key")
+	if !s.allowed(w, r, "GET", key) {
+		return
+	}
+	ns := s.namespace(r)
+
+	value, ttl, exists := ns.GetTTL(key)
+	if !exists {
+		writeError(w, nethttp.StatusNotFound, fmt.Errorf("clave no encontrada: %s", key))
+		return
+	}
+	value = plainValue(value)
+	w.Header().Set(TTLHeader, strconv.FormatInt(ttl, 10))
+
+	if wantsJSON(r) {
+		if raw, ok := value.([]byte); ok {
+			value = string(raw)
+		}
+		writeJSON(w, nethttp.StatusOK, keyResponse{Key: key, Value: value, TTL: ttl})
+		return
+	}
+
+	switch v := value.(type) {
+	case []byte:
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Write(v)
+	case string:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		io.WriteString(w, v)
+	default:
+		writeJSON(w, nethttp.StatusOK, v)
+	}
+}
+
+// plainValue retorna los datos de los valores con metadatos de otro
+// front-end, como los de memcached con flags
+func plainValue(value interface{}) interface{} {
+	if payload, ok := value.(cache.Payload); ok {
+		return payload.Bytes()
+	}
+	return value
+}
+
+// handlePut responde PUT /keys/{key}
+func (s *Server) handlePut(w nethttp.ResponseWriter, r *nethttp.Request) {
+	key := r.PathValue("key")
+	if !s.allowed(w, r, "SET", key) {
+		return
+	}
+	ns := s.namespace(r)
+
+	ttl, err := parseTTL(r)
+	if err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+
+	body, err := io.ReadAll(nethttp.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		writeError(w, nethttp.StatusRequestEntityTooLarge, err)
+		return
+	}
+
+	// Los cuerpos JSON se decodifican; el resto se guarda como bytes crudos
+	var value interface{} = body
+	if isJSON(r) {
+		if err := json.Unmarshal(body, &value); err != nil {
+			writeError(w, nethttp.StatusBadRequest, fmt.Errorf("JSON inválido: %v", err))
+			return
+		}
+	}
+
+	created, err := ns.Upsert(key, value, ttl)
+	if err != nil {
+		writeError(w, setStatus(err), err)
+		return
+	}
+
+	if created {
+		w.WriteHeader(nethttp.StatusCreated)
+	} else {
+		w.WriteHeader(nethttp.StatusNoContent)
+	}
+}
+
+// handleDelete responde DELETE /keys/{key}
+func (s *Server) handleDelete(w nethttp.ResponseWriter, r *nethttp.Request) {
+	key := r.PathValue("key")
+	if !s.allowed(w, r, "DEL", key) {
+		return
+	}
+	ns := s.namespace(r)
+
+	if !ns.Delete(key) {
+		writeError(w, nethttp.StatusNotFound, fmt.Errorf("clave no encontrada: %s", key))
+		return
+	}
+	w.WriteHeader(nethttp.StatusNoContent)
+}
+
+// bulkRequest es el cuerpo de las operaciones masivas
+type bulkRequest struct {
+	Keys    []string               `json:"keys,omitempty"`    // Para get y delete
+	Entries map[string]interface{} `json:"entries,omitempty"` // Para set
+	TTL     int                    `json:"ttl,omitempty"`     // Expiración opcional para set
+}
+
+// decodeBulk lee el cuerpo JSON de una operación masiva
+func decodeBulk(w nethttp.ResponseWriter, r *nethttp.Request) (bulkRequest, bool) {
+	var req bulkRequest
+	decoder := json.NewDecoder(nethttp.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, nethttp.StatusBadRequest, fmt.Errorf("JSON inválido: %v", err))
+		return req, false
+	}
+	return req, true
+}
+
+// handleBulkGet responde POST /bulk/get
+func (s *Server) handleBulkGet(w nethttp.ResponseWriter, r *nethttp.Request) {
+	req, ok := decodeBulk(w, r)
+	if !ok || !s.allowed(w, r, "MGET", req.Keys...) {
+		return
+	}
+	ns := s.namespace(r)
+
+	found, exists := ns.MGet(req.Keys...)
+	values := make(map[string]interface{}, len(req.Keys))
+	for i, key := range req.Keys {
+		if !exists[i] {
+			continue
+		}
+		value := plainValue(found[i])
+		if raw, ok := value.([]byte); ok {
+			value = string(raw)
+		}
+		values[key] = value
+	}
+
+	writeJSON(w, nethttp.StatusOK, map[string]interface{}{"values": values})
+}
+
+// handleBulkSet responde POST /bulk/set
+func (s *Server) handleBulkSet(w nethttp.ResponseWriter, r *nethttp.Request) {
+	req, ok := decodeBulk(w, r)
+	if !ok {
+		return
+	}
+	if req.TTL < 0 {
+		writeError(w, nethttp.StatusBadRequest, fmt.Errorf("ttl inválido: %d", req.TTL))
+		return
+	}
+	keys := make([]string, 0, len(req.Entries))
+	for key := range req.Entries {
+		keys = append(keys, key)
+	}
+	if !s.allowed(w, r, "MSET", keys...) {
+		return
+	}
+	ns := s.namespace(r)
+
+	// El lote se escribe entero o nada, con su expiración
+	if err := ns.MSetEx(req.Entries, req.TTL); err != nil {
+		writeJSON(w, setStatus(err), map[string]interface{}{"error": err.Error(), "stored": 0})
+		return
+	}
+
+	writeJSON(w, nethttp.StatusOK, map[string]interface{}{"stored": len(req.Entries)})
+}
+
+// handleBulkDelete responde POST /bulk/delete
+func (s *Server) handleBulkDelete(w nethttp.ResponseWriter, r *nethttp.Request) {
+	req, ok := decodeBulk(w, r)
+	if !ok || !s.allowed(w, r, "DEL", req.Keys...) {
+		return
+	}
+	ns := s.namespace(r)
+
+	deleted := ns.MDelete(req.Keys...)
+	writeJSON(w, nethttp.StatusOK, map[string]interface{}{"deleted": deleted})
+}
+
+// commandRequest es el cuerpo de POST /command
+type commandRequest struct {
+	Command []string `json:"command"` // Nombre y argumentos
+}
+
+// handleCommand responde POST /command ejecutando cualquier comando de la
+// tabla compartida, incluidos los registrados por el programa
+func (s *Server) handleCommand(w nethttp.ResponseWriter, r *nethttp.Request) {
+	var req commandRequest
+	decoder := json.NewDecoder(nethttp.MaxBytesReader(w, r.Body, maxBodySize))
+	if err := decoder.Decode(&req); err != nil {
+		writeError(w, nethttp.StatusBadRequest, fmt.Errorf("JSON inválido: %v", err))
+		return
+	}
+	if len(req.Command) == 0 {
+		writeError(w, nethttp.StatusBadRequest, errors.New("falta el comando"))
+		return
+	}
+
+	ctx := &command.Context{Engine: s.engine, DB: s.namespace(r), Registry: command.Default}
+	cmd, exists := ctx.Lookup(req.Command[0])
+	if !exists {
+		writeError(w, nethttp.StatusNotFound, fmt.Errorf("%w '%s'", command.ErrUnknown, req.Command[0]))
+		return
+	}
+	args := make([][]byte, len(req.Command))
+	for i, arg := range req.Command {
+		args[i] = []byte(arg)
+	}
+	if err := cmd.CheckArity(args); err != nil {
+		writeError(w, nethttp.StatusBadRequest, err)
+		return
+	}
+	if !s.allowed(w, r, cmd.Name, cmd.Keys(args)...) {
+		return
+	}
+
+	result, err := cmd.Call(ctx, args)
+	switch {
+	case errors.Is(err, cache.ErrQuotaExceeded):
+		writeError(w, nethttp.StatusInsufficientStorage, err)
+	case err != nil:
+		writeError(w, nethttp.StatusBadRequest, err)
+	default:
+		writeJSON(w, nethttp.StatusOK, map[string]interface{}{"result": command.JSONValue(result)})
+	}
+}
+
+// handleHealth responde GET /health
+func (s *Server) handleHealth(w nethttp.ResponseWriter, r *nethttp.Request) {
+	writeJSON(w, nethttp.StatusOK, map[string]interface{}{
+		"status": "ok",
+		"keys":   s.engine.Size(),
+	})
+}
@@ -0,0 +1,133 @@
+package memcache
+
+import (
+	"cache-engine/internal/acl"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// Autenticación al estilo del protocolo de texto de memcached (-Y): sin
+// usuario, el primer set lleva como datos "usuario contraseña" y cualquier
+// otro comando responde CLIENT_ERROR unauthenticated.
+
+// authorize comprueba la autenticación y los permisos de un comando. Si
+// lo rechaza, descarta sus datos y responde; retorna false.
+func (s *Server) authorize(c *conn, fields []string) bool {
+	name := fields[0]
+	if c.user == "" && name != "quit" {
+		if name == "set" {
+			s.cmdAuth(c, fields)
+		} else {
+			skipData(c, fields)
+			clientError(c, "unauthenticated")
+		}
+		return false
+	}
+
+	commands, keys := aclCommands(fields)
+	for _, command := range commands {
+		err := s.acl.Check(c.user, command, keys...)
+		if err == nil {
+			continue
+		}
+
+		skipData(c, fields)
+		if errors.Is(err, acl.ErrAuthRequired) {
+			// El usuario se eliminó: hay que autenticarse de nuevo
+			c.user = ""
+			clientError(c, "unauthenticated")
+		} else {
+			clientError(c, "permission denied")
+		}
+		return false
+	}
+	return true
+}
+
+// cmdAuth autentica con set <key> <flags> <exptime> <bytes> y los datos
+// "usuario contraseña"
+func (s *Server) cmdAuth(c *conn, fields []string) {
+	if len(fields) < 5 {
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+	size, err := strconv.Atoi(fields[4])
+	if err != nil || size < 0 || size > maxItemSize {
+		clientError(c, "bad data chunk")
+		return
+	}
+
+	data, ok := readData(c, size)
+	if !ok {
+		return
+	}
+
+	user, password, found := strings.Cut(string(data), " ")
+	if !found || s.acl.Authenticate(user, password) != nil {
+		clientError(c, "authentication failure")
+		return
+	}
+	c.user = user
+	c.writer.WriteString("STORED\r\n")
+}
+
+// aclCommands traduce un comando de memcached a los comandos canónicos
+// cuyos permisos requiere y a las claves que usa
+func aclCommands(fields []string) ([]string, []string) {
+	keys := func(from int) []string {
+		if len(fields) <= from {
+			return nil
+		}
+		return fields[from : from+1]
+	}
+
+	switch fields[0] {
+	case "get", "gets":
+		return []string{"GET"}, fields[1:]
+	case "gat", "gats":
+		if len(fields) < 3 {
+			return nil, nil
+		}
+		return []string{"GET", "EXPIRE"}, fields[2:]
+	case "mg":
+		for _, flag := range fields[min(2, len(fields)):] {
+			if strings.HasPrefix(flag, "T") {
+				return []string{"GET", "EXPIRE"}, keys(1)
+			}
+		}
+		return []string{"GET"}, keys(1)
+	case "set", "add", "replace", "append", "prepend", "cas", "ms", "incr", "decr":
+		return []string{"SET"}, keys(1)
+	case "delete", "md":
+		return []string{"DEL"}, keys(1)
+	case "touch":
+		return []string{"EXPIRE"}, keys(1)
+	case "flush_all":
+		return []string{"FLUSHDB"}, nil
+	case "stats":
+		return []string{"INFO"}, nil
+	}
+	return nil, nil
+}
+
+// skipData descarta el bloque de datos de un comando de escritura
+// rechazado para mantener la conexión sincronizada
+func skipData(c *conn, fields []string) {
+	pos := 0
+	switch fields[0] {
+	case "set", "add", "replace", "append", "prepend", "cas":
+		pos = 4
+	case "ms":
+		pos = 2
+	}
+	if pos == 0 || len(fields) <= pos {
+		return
+	}
+
+	size, err := strconv.Atoi(fields[pos])
+	if err != nil || size < 0 {
+		return
+	}
+	c.reader.Discard(size + 2)
+}
@@ -0,0 +1,301 @@
+package memcache
+
+import (
+	"cache-engine/internal/cache"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+)
+
+// storeResult traduce el resultado de una escritura a la respuesta de memcached
+func storeResult(err error, written bool) string {
+	switch {
+	case errors.Is(err, cache.ErrCASMismatch):
+		return "EXISTS"
+	case errors.Is(err, cache.ErrKeyNotFound):
+		return "NOT_FOUND"
+	case errors.Is(err, cache.ErrQuotaExceeded):
+		return "SERVER_ERROR out of memory storing object"
+	case err != nil:
+		return "SERVER_ERROR " + err.Error()
+	case !written:
+		return "NOT_STORED"
+	}
+	return "STORED"
+}
+
+// cmdStore implementa set/add/replace/append/prepend/cas:
+// <cmd> <key> <flags> <exptime> <bytes> [<cas>] [noreply]
+func (s *Server) cmdStore(c *conn, fields []string) {
+	name := fields[0]
+	expected := 5
+	if name == "cas" {
+		expected = 6
+	}
+
+	noreply := hasNoReply(fields)
+	args := fields
+	if noreply {
+		args = fields[:len(fields)-1]
+	}
+	if len(args) != expected {
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+
+	key := args[1]
+	flags, errFlags := strconv.ParseUint(args[2], 10, 32)
+	exptime, errExp := strconv.ParseInt(args[3], 10, 64)
+	size, errSize := strconv.Atoi(args[4])
+	if errSize != nil || size < 0 {
+		clientError(c, "bad data chunk")
+		return
+	}
+
+	if size > maxItemSize {
+		// Descartar los datos para mantener la conexión sincronizada
+		readData(c, size)
+		c.writer.WriteString("SERVER_ERROR object too large for cache\r\n")
+		return
+	}
+
+	data, ok := readData(c, size)
+	if !ok {
+		return
+	}
+	if errFlags != nil || errExp != nil {
+		clientError(c, "bad command line format")
+		return
+	}
+
+	var cas uint64
+	if name == "cas" {
+		var err error
+		if cas, err = strconv.ParseUint(args[5], 10, 64); err != nil {
+			clientError(c, "bad command line format")
+			return
+		}
+	}
+
+	value := encodeItem(data, uint32(flags))
+	ttl := int(ttlFromExptime(exptime))
+	written := true
+	var err error
+
+	// El valor y su expiración se escriben en una sola operación
+	switch name {
+	case "set":
+		_, err = s.db.SetEx(key, value, ttl, cache.SetAlways)
+	case "add":
+		written, err = s.db.SetEx(key, value, ttl, cache.SetIfAbsent)
+	case "replace":
+		written, err = s.db.SetEx(key, value, ttl, cache.SetIfPresent)
+	case "cas":
+		err = s.db.CompareAndSwapEx(key, value, cas, ttl)
+	case "append", "prepend":
+		// append y prepend ignoran exptime y conservan la expiración
+		value, err = s.db.Update(key, func(current interface{}) (interface{}, error) {
+			old, oldFlags := decodeItem(current)
+			joined := make([]byte, 0, len(old)+len(data))
+			if name == "append" {
+				joined = append(append(joined, old...), data...)
+			} else {
+				joined = append(append(joined, data...), old...)
+			}
+			return encodeItem(joined, oldFlags), nil
+		})
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			// append/prepend sobre una clave inexistente responde NOT_STORED
+			err, written = nil, false
+		}
+	}
+
+	if !noreply {
+		c.writer.WriteString(storeResult(err, written) + "\r\n")
+	}
+}
+
+// cmdGet implementa get/gets <key>* y gat/gats <exptime> <key>*
+func (s *Server) cmdGet(c *conn, fields []string, withCAS, touch bool) {
+	keys := fields[1:]
+	var exptime int64
+	if touch {
+		if len(keys) < 2 {
+			c.writer.WriteString("ERROR\r\n")
+			return
+		}
+		var err error
+		if exptime, err = strconv.ParseInt(keys[0], 10, 64); err != nil {
+			clientError(c, "invalid exptime argument")
+			return
+		}
+		keys = keys[1:]
+	}
+	if len(keys) == 0 {
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+
+	for _, key := range keys {
+		value, cas, exists := s.db.GetCAS(key)
+		if !exists {
+			continue
+		}
+		if touch {
+			s.touch(key, exptime)
+		}
+
+		data, flags := decodeItem(value)
+		if withCAS {
+			fmt.Fprintf(c.writer, "VALUE %s %d %d %d\r\n", key, flags, len(data), cas)
+		} else {
+			fmt.Fprintf(c.writer, "VALUE %s %d %d\r\n", key, flags, len(data))
+		}
+		c.writer.Write(data)
+		c.writer.WriteString("\r\n")
+	}
+	c.writer.WriteString("END\r\n")
+}
+
+// touch cambia la expiración de una clave existente
+func (s *Server) touch(key string, exptime int64) bool {
+	ttl := ttlFromExptime(exptime)
+	switch {
+	case ttl < 0:
+		return s.db.Delete(key)
+	case ttl == 0:
+		return s.db.Persist(key)
+	}
+	return s.db.Expire(key, int(ttl))
+}
+
+// cmdTouch implementa touch <key> <exptime> [noreply]
+func (s *Server) cmdTouch(c *conn, fields []string) {
+	noreply := hasNoReply(fields)
+	if len(fields) < 3 {
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+
+	exptime, err := strconv.ParseInt(fields[2], 10, 64)
+	if err != nil {
+		clientError(c, "invalid exptime argument")
+		return
+	}
+
+	touched := s.touch(fields[1], exptime)
+	if noreply {
+		return
+	}
+	if touched {
+		c.writer.WriteString("TOUCHED\r\n")
+	} else {
+		c.writer.WriteString("NOT_FOUND\r\n")
+	}
+}
+
+// cmdDelete implementa delete <key> [0] [noreply]
+func (s *Server) cmdDelete(c *conn, fields []string) {
+	noreply := hasNoReply(fields)
+	if len(fields) < 2 {
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+
+	key := fields[1]
+	deleted := s.db.Delete(key)
+
+	if noreply {
+		return
+	}
+	if deleted {
+		c.writer.WriteString("DELETED\r\n")
+	} else {
+		c.writer.WriteString("NOT_FOUND\r\n")
+	}
+}
+
+// errNotNumeric indica que el valor no es un entero sin signo
+var errNotNumeric = errors.New("cannot increment or decrement non-numeric value")
+
+// cmdIncr implementa incr/decr <key> <value> [noreply]. incr da la vuelta
+// en 2^64 y decr se detiene en cero, como memcached.
+func (s *Server) cmdIncr(c *conn, fields []string) {
+	noreply := hasNoReply(fields)
+	if len(fields) < 3 {
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+
+	key := fields[1]
+	delta, err := strconv.ParseUint(fields[2], 10, 64)
+	if err != nil {
+		clientError(c, "invalid numeric delta argument")
+		return
+	}
+
+	var result uint64
+	_, err = s.db.Update(key, func(current interface{}) (interface{}, error) {
+		data, flags := decodeItem(current)
+		number, err := strconv.ParseUint(string(data), 10, 64)
+		if err != nil {
+			return nil, errNotNumeric
+		}
+
+		if fields[0] == "incr" {
+			result = number + delta
+		} else if delta > number {
+			result = 0
+		} else {
+			result = number - delta
+		}
+		return encodeItem([]byte(strconv.FormatUint(result, 10)), flags), nil
+	})
+
+	switch {
+	case errors.Is(err, cache.ErrKeyNotFound):
+		if !noreply {
+			c.writer.WriteString("NOT_FOUND\r\n")
+		}
+	case errors.Is(err, errNotNumeric):
+		clientError(c, err.Error())
+	case err != nil:
+		if !noreply {
+			c.writer.WriteString(storeResult(err, false) + "\r\n")
+		}
+	default:
+		if !noreply {
+			c.writer.WriteString(strconv.FormatUint(result, 10) + "\r\n")
+		}
+	}
+}
+
+// cmdStats implementa stats con las estadísticas generales
+func (s *Server) cmdStats(c *conn) {
+	stats := s.engine.Stats()
+	lines := []struct {
+		name  string
+		value interface{}
+	}{
+		{"pid", os.Getpid()},
+		{"uptime", int64(stats.Uptime.Seconds())},
+		{"time", time.Now().Unix()},
+		{"version", Version},
+		{"curr_items", stats.Keys},
+		{"bytes", stats.Bytes},
+		{"limit_items", stats.MaxEntries},
+		{"get_hits", stats.Hits},
+		{"get_misses", stats.Misses},
+		{"cmd_set", stats.Sets},
+		{"delete_hits", stats.Deletes},
+		{"evictions", stats.Evictions},
+		{"expired_unfetched", stats.Expirations},
+	}
+
+	for _, line := range lines {
+		fmt.Fprintf(c.writer, "STAT %s %v\r\n", line.name, line.value)
+	}
+	c.writer.WriteString("END\r\n")
+}
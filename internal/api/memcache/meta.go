@@ -0,0 +1,260 @@
+package memcache
+
+import (
+	"cache-engine/internal/cache"
+	"errors"
+	"strconv"
+	"strings"
+)
+
+// metaFlags son los flags de un comando meta (un carácter más un token opcional)
+type metaFlags map[byte]string
+
+// parseMetaFlags interpreta los flags de un comando meta
+func parseMetaFlags(tokens []string) metaFlags {
+	flags := make(metaFlags, len(tokens))
+	for _, token := range tokens {
+		if token == "" {
+			continue
+		}
+		flags[token[0]] = token[1:]
+	}
+	return flags
+}
+
+// has indica si el flag está presente
+func (f metaFlags) has(flag byte) bool {
+	_, exists := f[flag]
+	return exists
+}
+
+// echo añade a la respuesta los flags que se devuelven tal cual (O y k)
+func (f metaFlags) echo(out *[]string, key string) {
+	if opaque, exists := f['O']; exists {
+		*out = append(*out, "O"+opaque)
+	}
+	if f.has('k') {
+		*out = append(*out, "k"+key)
+	}
+}
+
+// writeMeta escribe una respuesta meta con sus flags de retorno
+func writeMeta(c *conn, code string, flags []string) {
+	if len(flags) == 0 {
+		c.writer.WriteString(code + "\r\n")
+		return
+	}
+	c.writer.WriteString(code + " " + strings.Join(flags, " ") + "\r\n")
+}
+
+// cmdMetaGet implementa mg <key> <flags>*
+func (s *Server) cmdMetaGet(c *conn, fields []string) {
+	if len(fields) < 2 {
+		clientError(c, "bad command line format")
+		return
+	}
+
+	key := fields[1]
+	flags := parseMetaFlags(fields[2:])
+
+	value, cas, exists := s.db.GetCAS(key)
+	if !exists {
+		if !flags.has('q') {
+			c.writer.WriteString("EN\r\n")
+		}
+		return
+	}
+
+	if ttl, ok := flags['T']; ok {
+		exptime, err := strconv.ParseInt(ttl, 10, 64)
+		if err != nil {
+			clientError(c, "bad token in command line format")
+			return
+		}
+		s.touch(key, exptime)
+	}
+
+	data, clientFlags := decodeItem(value)
+	var ret []string
+	if flags.has('c') {
+		ret = append(ret, "c"+strconv.FormatUint(cas, 10))
+	}
+	if flags.has('f') {
+		ret = append(ret, "f"+strconv.FormatUint(uint64(clientFlags), 10))
+	}
+	if flags.has('s') {
+		ret = append(ret, "s"+strconv.Itoa(len(data)))
+	}
+	if flags.has('t') {
+		ttl, _ := s.db.TTL(key)
+		ret = append(ret, "t"+strconv.FormatInt(ttl, 10))
+	}
+	flags.echo(&ret, key)
+
+	if !flags.has('v') {
+		writeMeta(c, "HD", ret)
+		return
+	}
+
+	writeMeta(c, "VA "+strconv.Itoa(len(data)), ret)
+	c.writer.Write(data)
+	c.writer.WriteString("\r\n")
+}
+
+// cmdMetaSet implementa ms <key> <datalen> <flags>*
+func (s *Server) cmdMetaSet(c *conn, fields []string) {
+	if len(fields) < 3 {
+		clientError(c, "bad command line format")
+		return
+	}
+
+	key := fields[1]
+	size, err := strconv.Atoi(fields[2])
+	if err != nil || size < 0 {
+		clientError(c, "bad data chunk")
+		return
+	}
+	if size > maxItemSize {
+		readData(c, size)
+		c.writer.WriteString("SERVER_ERROR object too large for cache\r\n")
+		return
+	}
+
+	data, ok := readData(c, size)
+	if !ok {
+		return
+	}
+
+	flags := parseMetaFlags(fields[3:])
+	clientFlags, errFlags := strconv.ParseUint(valueOr(flags['F'], "0"), 10, 32)
+	exptime, errExp := strconv.ParseInt(valueOr(flags['T'], "0"), 10, 64)
+	if errFlags != nil || errExp != nil {
+		clientError(c, "bad token in command line format")
+		return
+	}
+
+	value := encodeItem(data, uint32(clientFlags))
+	ttl := int(ttlFromExptime(exptime))
+	mode := valueOr(flags['M'], "S")
+	written := true
+
+	if compare, exists := flags['C']; exists {
+		cas, err := strconv.ParseUint(compare, 10, 64)
+		if err != nil {
+			clientError(c, "bad token in command line format")
+			return
+		}
+		err = s.db.CompareAndSwapEx(key, value, cas, ttl)
+		s.finishMetaSet(c, key, flags, err, true)
+		return
+	}
+
+	switch strings.ToUpper(mode) {
+	case "S":
+		_, err = s.db.SetEx(key, value, ttl, cache.SetAlways)
+	case "E":
+		written, err = s.db.SetEx(key, value, ttl, cache.SetIfAbsent)
+	case "R":
+		written, err = s.db.SetEx(key, value, ttl, cache.SetIfPresent)
+	case "A", "P":
+		appendMode := strings.ToUpper(mode) == "A"
+		value, err = s.db.Update(key, func(current interface{}) (interface{}, error) {
+			old, oldFlags := decodeItem(current)
+			joined := make([]byte, 0, len(old)+len(data))
+			if appendMode {
+				joined = append(append(joined, old...), data...)
+			} else {
+				joined = append(append(joined, data...), old...)
+			}
+			return encodeItem(joined, oldFlags), nil
+		})
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			err, written = nil, false
+		}
+		// El modo append/prepend conserva la expiración
+	default:
+		clientError(c, "invalid mode for ms")
+		return
+	}
+
+	s.finishMetaSet(c, key, flags, err, written)
+}
+
+// finishMetaSet responde a un ms
+func (s *Server) finishMetaSet(c *conn, key string, flags metaFlags, err error, written bool) {
+	var code string
+	switch {
+	case errors.Is(err, cache.ErrCASMismatch):
+		code = "EX"
+	case errors.Is(err, cache.ErrKeyNotFound):
+		code = "NF"
+	case err != nil:
+		c.writer.WriteString(storeResult(err, false) + "\r\n")
+		return
+	case !written:
+		code = "NS"
+	default:
+		code = "HD"
+	}
+
+	if code == "HD" && flags.has('q') {
+		return
+	}
+
+	var ret []string
+	if code == "HD" && flags.has('c') {
+		if cas, exists := s.db.CASToken(key); exists {
+			ret = append(ret, "c"+strconv.FormatUint(cas, 10))
+		}
+	}
+	flags.echo(&ret, key)
+	writeMeta(c, code, ret)
+}
+
+// cmdMetaDelete implementa md <key> <flags>*
+func (s *Server) cmdMetaDelete(c *conn, fields []string) {
+	if len(fields) < 2 {
+		clientError(c, "bad command line format")
+		return
+	}
+
+	key := fields[1]
+	flags := parseMetaFlags(fields[2:])
+
+	var err error
+	if compare, exists := flags['C']; exists {
+		cas, parseErr := strconv.ParseUint(compare, 10, 64)
+		if parseErr != nil {
+			clientError(c, "bad token in command line format")
+			return
+		}
+		err = s.db.CompareAndDelete(key, cas)
+	} else if !s.db.Delete(key) {
+		err = cache.ErrKeyNotFound
+	}
+
+	var code string
+	switch {
+	case err == nil:
+		if flags.has('q') {
+			return
+		}
+		code = "HD"
+	case errors.Is(err, cache.ErrCASMismatch):
+		code = "EX"
+	default:
+		code = "NF"
+	}
+
+	var ret []string
+	flags.echo(&ret, key)
+	writeMeta(c, code, ret)
+}
+
+// valueOr retorna value o def si está vacío
+func valueOr(value, def string) string {
+	if value == "" {
+		return def
+	}
+	return value
+}
@@ -0,0 +1,418 @@
+package memcache
+
+import (
+	"bufio"
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/netutil"
+	"cache-engine/internal/persistence"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultPort es el puerto por defecto del servidor memcached
+	DefaultPort = 11211
+
+	// Version es la versión anunciada por el comando version
+	Version = "1.6.0-cache-engine"
+
+	// maxItemSize limita el tamaño de un valor (1 MiB, como memcached)
+	maxItemSize = 1 << 20
+
+	// relativeExptimeLimit separa exptime relativo (segundos) de absoluto
+	// (timestamp Unix): 30 días, igual que memcached
+	relativeExptimeLimit = 60 * 60 * 24 * 30
+)
+
+// Item es el valor guardado cuando el cliente envía flags distintos de cero.
+// Con flags cero se guarda directamente []byte para que los demás
+// front-ends lean el mismo valor.
+type Item struct {
+	Flags uint32
+	Data  []byte
+}
+
+// Bytes implementa cache.Payload: los demás front-ends leen los datos sin
+// los flags
+func (i Item) Bytes() []byte {
+	return i.Data
+}
+
+// Los Item se recuperan del log de persistencia con su tipo
+func init() {
+	if err := persistence.RegisterType("memcache.item", Item{}); err != nil {
+		panic(err)
+	}
+}
+
+// Config contiene las opciones del servidor memcached
+type Config struct {
+	Port           int         // Puerto de escucha
+	ACL            *acl.Store  // Usuarios y permisos (nil = sin autenticación)
+	TLS            *tls.Config // Cifrado de las conexiones (nil = texto plano)
+	UnixSocket     string      // Ruta de un socket Unix adicional (vacío = ninguno)
+	UnixSocketPerm os.FileMode // Permisos del socket (0 = 0700)
+	NoTCP          bool        // Escuchar solo en el socket Unix
+
+	MaxClients    int           // Conexiones simultáneas máximas (0 = sin límite)
+	IdleTimeout   time.Duration // Cierra las conexiones sin actividad durante este tiempo (0 = nunca)
+	OutputTimeout time.Duration // Tiempo máximo para que un cliente lento acepte sus respuestas (0 = sin límite)
+}
+
+// Server expone el CacheEngine mediante el protocolo de texto de memcached
+type Server struct {
+	engine *cache.CacheEngine
+	db     *cache.Namespace // memcached no tiene namespaces: siempre el de por defecto
+	config Config
+	acl    *acl.Store
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	conns     map[net.Conn]bool // Conexiones abiertas; true si ejecutan un comando
+	closed    bool
+	active    sync.WaitGroup // Conexiones en curso, para Shutdown
+}
+
+// conn guarda el estado de una conexión
+type conn struct {
+	net.Conn
+	reader *bufio.Reader
+	writer *bufio.Writer
+	user   string // Usuario autenticado ("" = ninguno)
+	quit   bool
+}
+
+// NewServer crea un servidor memcached para el motor indicado
+func NewServer(engine *cache.CacheEngine, config Config) *Server {
+	if config.Port <= 0 {
+		config.Port = DefaultPort
+	}
+	if config.ACL == nil {
+		config.ACL = acl.NewStore()
+	}
+
+	return &Server{
+		engine: engine,
+		db:     engine.Namespace(cache.DefaultNamespace),
+		config: config,
+		acl:    config.ACL,
+		conns:  make(map[net.Conn]bool),
+	}
+}
+
+// Addr retorna las direcciones de escucha configuradas
+func (s *Server) Addr() string {
+	return s.listenConfig().Describe()
+}
+
+// listenConfig retorna el puerto TCP y el socket Unix configurados
+func (s *Server) listenConfig() netutil.ListenConfig {
+	return netutil.ListenConfig{
+		Addr:       fmt.Sprintf(":%d", s.config.Port),
+		NoTCP:      s.config.NoTCP,
+		UnixSocket: s.config.UnixSocket,
+		SocketPerm: s.config.UnixSocketPerm,
+	}
+}
+
+// ListenAndServe escucha en el puerto y el socket Unix configurados y
+// atiende conexiones
+func (s *Server) ListenAndServe() error {
+	listeners, err := netutil.Listen(s.listenConfig())
+	if err != nil {
+		return err
+	}
+	return netutil.ServeAll(listeners, s.Serve)
+}
+
+// Serve atiende conexiones del listener hasta que se cierre el servidor
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return net.ErrClosed
+	}
+	if s.config.TLS != nil {
+		listener = tls.NewListener(listener, s.config.TLS)
+	}
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+
+	for {
+		nc, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(nc)
+	}
+}
+
+// Close deja de aceptar conexiones y cierra las existentes
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.stopListening()
+	for nc := range s.conns {
+		nc.Close()
+	}
+	return err
+}
+
+// Shutdown deja de aceptar conexiones, cierra las inactivas y espera a que
+// las demás terminen el comando en curso y envíen su respuesta. Si ctx
+// vence antes, las cierra como Close y retorna ctx.Err().
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	err := s.stopListening()
+	for nc, busy := range s.conns {
+		if !busy {
+			nc.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.active.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// stopListening marca el servidor como cerrado y cierra los listeners.
+// Requiere s.mu tomado.
+func (s *Server) stopListening() error {
+	s.closed = true
+	var err error
+	for _, listener := range s.listeners {
+		if closeErr := listener.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	s.listeners = nil
+	return err
+}
+
+// setBusy marca si la conexión está ejecutando comandos o tiene respuestas
+// sin enviar. Retorna false si el servidor se está cerrando.
+func (s *Server) setBusy(nc net.Conn, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conns[nc] = busy
+	return !s.closed
+}
+
+// handleConn atiende los comandos de una conexión
+func (s *Server) handleConn(nc net.Conn) {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		nc.Close()
+		return
+	}
+	if s.config.MaxClients > 0 && len(s.conns) >= s.config.MaxClients {
+		s.mu.Unlock()
+		nc.SetWriteDeadline(time.Now().Add(time.Second))
+		nc.Write([]byte("SERVER_ERROR too many open connections\r\n"))
+		nc.Close()
+		return
+	}
+	s.conns[nc] = false
+	s.active.Add(1)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.conns, nc)
+		s.mu.Unlock()
+		s.active.Done()
+		nc.Close()
+	}()
+
+	c := &conn{
+		Conn:   nc,
+		reader: bufio.NewReader(nc),
+		writer: bufio.NewWriter(nc),
+	}
+	if s.acl.Authenticate(acl.DefaultUser, "") == nil {
+		// El usuario por defecto no tiene contraseña
+		c.user = acl.DefaultUser
+	}
+
+	for !c.quit {
+		if s.config.IdleTimeout > 0 {
+			nc.SetReadDeadline(time.Now().Add(s.config.IdleTimeout))
+		}
+		line, err := c.reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			continue
+		}
+		if !s.setBusy(nc, true) {
+			// El servidor se está cerrando: enviar lo pendiente y salir
+			c.writer.Flush()
+			return
+		}
+
+		// El plazo cubre también las respuestas grandes que se envían
+		// durante el comando
+		if s.config.OutputTimeout > 0 {
+			nc.SetWriteDeadline(time.Now().Add(s.config.OutputTimeout))
+		}
+		s.execute(c, strings.Fields(line))
+
+		// Enviar cuando no quedan comandos encolados; hasta entonces la
+		// conexión sigue ocupada
+		if c.reader.Buffered() == 0 || c.quit {
+			if err := c.writer.Flush(); err != nil || !s.setBusy(nc, false) {
+				return
+			}
+		}
+	}
+}
+
+// execute despacha una línea de comando
+func (s *Server) execute(c *conn, fields []string) {
+	name := fields[0]
+	if !s.authorize(c, fields) {
+		return
+	}
+	start := time.Now()
+
+	switch name {
+	case "get", "gets":
+		s.cmdGet(c, fields, name == "gets", false)
+	case "gat", "gats":
+		s.cmdGet(c, fields, name == "gats", true)
+	case "set", "add", "replace", "append", "prepend", "cas":
+		s.cmdStore(c, fields)
+	case "delete":
+		s.cmdDelete(c, fields)
+	case "incr", "decr":
+		s.cmdIncr(c, fields)
+	case "touch":
+		s.cmdTouch(c, fields)
+	case "mg":
+		s.cmdMetaGet(c, fields)
+	case "ms":
+		s.cmdMetaSet(c, fields)
+	case "md":
+		s.cmdMetaDelete(c, fields)
+	case "mn":
+		c.writer.WriteString("MN\r\n")
+	case "flush_all":
+		s.engine.Namespace(cache.DefaultNamespace).Flush()
+		if !hasNoReply(fields) {
+			c.writer.WriteString("OK\r\n")
+		}
+	case "version":
+		c.writer.WriteString("VERSION " + Version + "\r\n")
+	case "verbosity":
+		if !hasNoReply(fields) {
+			c.writer.WriteString("OK\r\n")
+		}
+	case "stats":
+		s.cmdStats(c)
+	case "quit":
+		c.quit = true
+		return
+	default:
+		c.writer.WriteString("ERROR\r\n")
+		return
+	}
+
+	s.engine.RecordCommand("MC "+name, time.Since(start))
+}
+
+// hasNoReply indica si el último argumento es "noreply"
+func hasNoReply(fields []string) bool {
+	return len(fields) > 1 && fields[len(fields)-1] == "noreply"
+}
+
+// clientError responde CLIENT_ERROR con el mensaje indicado
+func clientError(c *conn, msg string) {
+	c.writer.WriteString("CLIENT_ERROR " + msg + "\r\n")
+}
+
+// ttlFromExptime convierte un exptime de memcached en segundos relativos.
+// 0 significa sin expiración; un resultado negativo, ya expirado.
+func ttlFromExptime(exptime int64) int64 {
+	if exptime == 0 {
+		return 0
+	}
+	if exptime < 0 {
+		return -1
+	}
+	if exptime > relativeExptimeLimit {
+		// Timestamp Unix absoluto
+		ttl := exptime - time.Now().Unix()
+		if ttl <= 0 {
+			return -1
+		}
+		return ttl
+	}
+	return exptime
+}
+
+// encodeItem construye el valor almacenado para unos datos y flags
+func encodeItem(data []byte, flags uint32) interface{} {
+	if flags == 0 {
+		return data
+	}
+	return Item{Flags: flags, Data: data}
+}
+
+// decodeItem obtiene datos y flags de un valor del motor
+func decodeItem(value interface{}) ([]byte, uint32) {
+	switch v := value.(type) {
+	case Item:
+		return v.Data, v.Flags
+	case []byte:
+		return v, 0
+	}
+	// Los valores escritos desde otros front-ends se muestran como en ellos
+	return cache.ValueBytes(value), 0
+}
+
+// readData lee un bloque de datos de n bytes seguido de \r\n
+func readData(c *conn, n int) ([]byte, bool) {
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(c.reader, buf); err != nil {
+		c.quit = true
+		return nil, false
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		clientError(c, "bad data chunk")
+		return nil, false
+	}
+	return buf[:n], true
+}
@@ -0,0 +1,248 @@
+package memcache
+
+import (
+	"bufio"
+	"cache-engine/internal/acl"
+	httpapi "cache-engine/internal/api/http"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"context"
+	"io"
+	"net"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer arranca un servidor en un puerto libre y retorna una conexión
+func startServer(t *testing.T) (*cache.CacheEngine, net.Conn, *bufio.Reader) {
+	t.Helper()
+	return startServerWith(t, Config{})
+}
+
+// startServerWith arranca un servidor con la configuración indicada
+func startServerWith(t *testing.T, config Config) (*cache.CacheEngine, net.Conn, *bufio.Reader) {
+	t.Helper()
+
+	engine := cache.NewCacheEngine(100)
+	server := NewServer(engine, config)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		engine.Close(context.Background())
+	})
+	return engine, conn, bufio.NewReader(conn)
+}
+
+// expect envía una petición y comprueba las líneas de respuesta
+func expect(t *testing.T, conn net.Conn, reader *bufio.Reader, request string, lines ...string) {
+	t.Helper()
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatalf("Error al escribir: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	for _, expected := range lines {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error al leer (esperaba %q): %v", expected, err)
+		}
+		if strings.TrimRight(line, "\r\n") != expected {
+			t.Errorf("Respuesta a %q: esperaba %q, obtuve %q", request, expected, line)
+		}
+	}
+}
+
+// TestStorageCommands prueba set, add, replace, append y get
+func TestStorageCommands(t *testing.T) {
+	engine, conn, reader := startServer(t)
+
+	expect(t, conn, reader, "set user 5 0 4\r\nJuan\r\n", "STORED")
+	expect(t, conn, reader, "get user\r\n", "VALUE user 5 4", "Juan", "END")
+	expect(t, conn, reader, "add user 0 0 1\r\nx\r\n", "NOT_STORED")
+	expect(t, conn, reader, "replace missing 0 0 1\r\nx\r\n", "NOT_STORED")
+	expect(t, conn, reader, "append user 0 0 7\r\n Pérez\r\n", "STORED")
+	expect(t, conn, reader, "get user missing\r\n", "VALUE user 5 11", "Juan Pérez", "END")
+	expect(t, conn, reader, "set quiet 0 0 1 noreply\r\nq\r\nget quiet\r\n", "VALUE quiet 0 1", "q", "END")
+
+	// Con flags cero el valor es []byte compartido con los demás front-ends
+	if value, _ := engine.Get("quiet"); string(value.([]byte)) != "q" {
+		t.Errorf("Valor inesperado en el motor: %v", value)
+	}
+}
+
+// TestCAS prueba gets y cas
+func TestCAS(t *testing.T) {
+	engine, conn, reader := startServer(t)
+
+	expect(t, conn, reader, "set k 0 0 1\r\na\r\n", "STORED")
+	cas, _ := engine.Namespace(cache.DefaultNamespace).CASToken("k")
+	token := itoa(cas)
+
+	expect(t, conn, reader, "gets k\r\n", "VALUE k 0 1 "+token, "a", "END")
+	expect(t, conn, reader, "cas k 0 0 1 "+token+"\r\nb\r\n", "STORED")
+	expect(t, conn, reader, "cas k 0 0 1 "+token+"\r\nc\r\n", "EXISTS")
+	expect(t, conn, reader, "cas missing 0 0 1 1\r\nc\r\n", "NOT_FOUND")
+
+	// cas fija la expiración como set: exptime 0 la quita
+	expect(t, conn, reader, "touch k 100\r\n", "TOUCHED")
+	cas, _ = engine.Namespace(cache.DefaultNamespace).CASToken("k")
+	expect(t, conn, reader, "cas k 0 0 1 "+itoa(cas)+"\r\nd\r\n", "STORED")
+	if ttl, _ := engine.TTL("k"); ttl != -1 {
+		t.Errorf("cas con exptime 0 debería quitar la expiración, obtuve %d", ttl)
+	}
+	cas, _ = engine.Namespace(cache.DefaultNamespace).CASToken("k")
+	expect(t, conn, reader, "cas k 0 50 1 "+itoa(cas)+"\r\ne\r\n", "STORED")
+	if ttl, _ := engine.TTL("k"); ttl <= 0 || ttl > 50 {
+		t.Errorf("cas debería fijar la expiración, obtuve %d", ttl)
+	}
+}
+
+// TestForeignValues prueba que los valores escritos desde otros front-ends
+// se leen con la misma representación que en ellos
+func TestForeignValues(t *testing.T) {
+	engine, conn, reader := startServer(t)
+
+	engine.Set("json", map[string]interface{}{"a": 1.0})
+	engine.Set("num", 42)
+	expect(t, conn, reader, "get json num\r\n", `VALUE json 0 7`, `{"a":1}`, "VALUE num 0 2", "42", "END")
+}
+
+// TestFlagsElsewhere prueba que los demás front-ends leen los datos de un
+// valor guardado con flags, sin los flags
+func TestFlagsElsewhere(t *testing.T) {
+	engine, conn, reader := startServer(t)
+	expect(t, conn, reader, "set k 5 0 5\r\nhello\r\n", "STORED")
+
+	ctx := command.NewContext(engine, command.Default, false)
+	cmd, _ := ctx.Lookup("GET")
+	if value, err := cmd.Call(ctx, [][]byte{[]byte("GET"), []byte("k")}); err != nil || string(value.([]byte)) != "hello" {
+		t.Errorf("GET por RESP: esperaba hello, obtuve %q %v", value, err)
+	}
+
+	rec := httptest.NewRecorder()
+	httpapi.NewServer(engine, httpapi.Config{}).ServeHTTP(rec, httptest.NewRequest("GET", "/keys/k", nil))
+	if rec.Body.String() != "hello" {
+		t.Errorf("GET por HTTP: esperaba hello, obtuve %q", rec.Body.String())
+	}
+
+	expect(t, conn, reader, "get k\r\n", "VALUE k 5 5", "hello", "END")
+}
+
+// TestIncrDecrDeleteTouch prueba incr, decr, delete y touch
+func TestIncrDecrDeleteTouch(t *testing.T) {
+	engine, conn, reader := startServer(t)
+
+	expect(t, conn, reader, "set n 0 0 2\r\n10\r\n", "STORED")
+	expect(t, conn, reader, "incr n 5\r\n", "15")
+	expect(t, conn, reader, "decr n 100\r\n", "0")
+	expect(t, conn, reader, "incr missing 1\r\n", "NOT_FOUND")
+	expect(t, conn, reader, "set s 0 0 1\r\nx\r\nincr s 1\r\n", "STORED", "CLIENT_ERROR cannot increment or decrement non-numeric value")
+
+	expect(t, conn, reader, "touch n 100\r\n", "TOUCHED")
+	if ttl, _ := engine.TTL("n"); ttl <= 0 || ttl > 100 {
+		t.Errorf("TTL relativo inesperado: %d", ttl)
+	}
+
+	// exptime absoluto (timestamp Unix) en el futuro
+	future := itoa(uint64(time.Now().Unix() + 3600))
+	expect(t, conn, reader, "touch n "+future+"\r\n", "TOUCHED")
+	if ttl, _ := engine.TTL("n"); ttl < 3500 || ttl > 3600 {
+		t.Errorf("TTL absoluto inesperado: %d", ttl)
+	}
+
+	// exptime negativo: expira inmediatamente
+	expect(t, conn, reader, "set gone 0 -1 1\r\nx\r\nget gone\r\n", "STORED", "END")
+
+	expect(t, conn, reader, "delete n\r\n", "DELETED")
+	expect(t, conn, reader, "delete n\r\n", "NOT_FOUND")
+	expect(t, conn, reader, "bogus\r\n", "ERROR")
+}
+
+// TestMetaCommands prueba mg, ms, md y mn
+func TestMetaCommands(t *testing.T) {
+	engine, conn, reader := startServer(t)
+
+	expect(t, conn, reader, "ms k 5 F7 T60\r\nhello\r\n", "HD")
+	expect(t, conn, reader, "mg k v f s Oabc k\r\n", "VA 5 f7 s5 Oabc kk", "hello")
+	expect(t, conn, reader, "mg missing v\r\n", "EN")
+	expect(t, conn, reader, "mg missing v q\r\nmn\r\n", "MN")
+
+	cas, _ := engine.Namespace(cache.DefaultNamespace).CASToken("k")
+	expect(t, conn, reader, "ms k 1 C999999\r\nx\r\n", "EX")
+	expect(t, conn, reader, "ms k 1 C"+itoa(cas)+"\r\nx\r\n", "HD")
+	expect(t, conn, reader, "ms k 1 ME\r\ny\r\n", "NS")
+	expect(t, conn, reader, "ms k 1 MA\r\ny\r\nmg k v\r\n", "HD", "VA 2", "xy")
+
+	// ms con C y sin T quita la expiración, como en memcached
+	expect(t, conn, reader, "ms k 1 T60\r\nz\r\n", "HD")
+	cas, _ = engine.Namespace(cache.DefaultNamespace).CASToken("k")
+	expect(t, conn, reader, "ms k 1 C"+itoa(cas)+"\r\nw\r\n", "HD")
+	if ttl, _ := engine.TTL("k"); ttl != -1 {
+		t.Errorf("ms con C sin T debería quitar la expiración, obtuve %d", ttl)
+	}
+
+	expect(t, conn, reader, "md k q\r\nmd k\r\n", "NF")
+}
+
+// itoa formatea un entero sin signo
+func itoa(n uint64) string {
+	return strconv.FormatUint(n, 10)
+}
+
+// TestAuthentication prueba la autenticación con set y los permisos
+func TestAuthentication(t *testing.T) {
+	users := acl.NewStore()
+	users.SetUser(acl.DefaultUser, "off")
+	users.SetUser("app", "on", ">pw", "~app:*", "+@read", "+@write")
+	engine, conn, reader := startServerWith(t, Config{ACL: users})
+
+	expect(t, conn, reader, "get app:1\r\n", "CLIENT_ERROR unauthenticated")
+	expect(t, conn, reader, "add app:1 0 0 1\r\nx\r\n", "CLIENT_ERROR unauthenticated")
+	expect(t, conn, reader, "set auth 0 0 6\r\napp no\r\n", "CLIENT_ERROR authentication failure")
+	expect(t, conn, reader, "set auth 0 0 6\r\napp pw\r\n", "STORED")
+
+	expect(t, conn, reader, "set app:1 0 0 1\r\nx\r\n", "STORED")
+	expect(t, conn, reader, "set other 0 0 1\r\nx\r\nget app:1\r\n",
+		"CLIENT_ERROR permission denied", "VALUE app:1 0 1", "x", "END")
+	expect(t, conn, reader, "stats\r\n", "CLIENT_ERROR permission denied")
+
+	if _, exists := engine.Get("auth"); exists {
+		t.Error("El set de autenticación no debería guardar la clave")
+	}
+}
+
+// TestConnectionLimits prueba el máximo de conexiones y el cierre por
+// inactividad
+func TestConnectionLimits(t *testing.T) {
+	_, conn, reader := startServerWith(t, Config{MaxClients: 1, IdleTimeout: 200 * time.Millisecond})
+	expect(t, conn, reader, "version\r\n", "VERSION "+Version)
+
+	other, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+	defer other.Close()
+	expect(t, other, bufio.NewReader(other), "", "SERVER_ERROR too many open connections")
+
+	// Sin comandos durante IdleTimeout el servidor cierra la conexión
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, err := reader.ReadString('\n'); err != io.EOF {
+		t.Errorf("Esperaba que se cerrara la conexión inactiva, obtuve %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+package resp
+
+import (
+	"cache-engine/internal/acl"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// writeACLError traduce un error de autenticación o de permisos
+func writeACLError(c *client, err error) {
+	switch {
+	case errors.Is(err, acl.ErrAuthRequired):
+		c.writer.WriteError("NOAUTH " + err.Error())
+	case errors.Is(err, acl.ErrWrongPass):
+		c.writer.WriteError("WRONGPASS " + err.Error())
+	case errors.Is(err, acl.ErrNoPermission):
+		c.writer.WriteError("NOPERM " + err.Error())
+	default:
+		c.writer.WriteError("ERR " + err.Error())
+	}
+}
+
+// cmdAuth implementa AUTH [username] password. Sin usuario se autentica
+// como el usuario por defecto.
+func cmdAuth(s *Server, c *client, args [][]byte) {
+	if len(args) > 3 {
+		c.writer.WriteError("ERR número de argumentos incorrecto para 'auth'")
+		return
+	}
+
+	user, password := acl.DefaultUser, string(args[1])
+	if len(args) == 3 {
+		user, password = string(args[1]), string(args[2])
+	}
+
+	if err := s.acl.Authenticate(user, password); err != nil {
+		writeACLError(c, err)
+		return
+	}
+	c.user = user
+	c.writer.WriteSimple("OK")
+}
+
+// cmdACL implementa ACL SETUSER | GETUSER | DELUSER | LIST | USERS | WHOAMI | CAT
+func cmdACL(s *Server, c *client, args [][]byte) {
+	sub := strings.ToUpper(string(args[1]))
+	switch sub {
+	case "SETUSER":
+		if len(args) < 3 {
+			c.writer.WriteError("ERR número de argumentos incorrecto para 'acl setuser'")
+			return
+		}
+		rules := make([]string, 0, len(args)-3)
+		for _, rule := range args[3:] {
+			rules = append(rules, string(rule))
+		}
+		if err := s.acl.SetUser(string(args[2]), rules...); err != nil {
+			c.writer.WriteError("ERR " + err.Error())
+			return
+		}
+		c.writer.WriteSimple("OK")
+
+	case "GETUSER":
+		if len(args) != 3 {
+			c.writer.WriteError("ERR número de argumentos incorrecto para 'acl getuser'")
+			return
+		}
+		info, exists := s.acl.GetUser(string(args[2]))
+		if !exists {
+			c.writer.WriteNull()
+			return
+		}
+		c.writer.WriteMap(4)
+		c.writer.WriteBulkString("flags")
+		writeStrings(c, info.Flags)
+		c.writer.WriteBulkString("passwords")
+		writeStrings(c, info.Passwords)
+		c.writer.WriteBulkString("commands")
+		c.writer.WriteBulkString(info.Commands)
+		c.writer.WriteBulkString("keys")
+		c.writer.WriteBulkString(strings.Join(info.Keys, " "))
+
+	case "DELUSER":
+		if len(args) < 3 {
+			c.writer.WriteError("ERR número de argumentos incorrecto para 'acl deluser'")
+			return
+		}
+		names := make([]string, 0, len(args)-2)
+		for _, name := range args[2:] {
+			names = append(names, string(name))
+		}
+		deleted, err := s.acl.DelUser(names...)
+		if err != nil {
+			c.writer.WriteError("ERR " + err.Error())
+			return
+		}
+		c.writer.WriteInt(int64(deleted))
+
+	case "LIST":
+		writeStrings(c, s.acl.List())
+
+	case "USERS":
+		writeStrings(c, s.acl.Users())
+
+	case "WHOAMI":
+		c.writer.WriteBulkString(c.user)
+
+	case "CAT":
+		if len(args) == 2 {
+			writeStrings(c, acl.Categories())
+			return
+		}
+		names, exists := acl.CommandsIn(string(args[2]))
+		if !exists {
+			c.writer.WriteError(fmt.Sprintf("ERR categoría desconocida '%s'", args[2]))
+			return
+		}
+		writeStrings(c, names)
+
+	default:
+		c.writer.WriteError(fmt.Sprintf("ERR subcomando desconocido '%s'", args[1]))
+	}
+}
+
+// writeStrings escribe una lista de cadenas como array de bulk strings
+func writeStrings(c *client, values []string) {
+	c.writer.WriteArray(len(values))
+	for _, value := range values {
+		c.writer.WriteBulkString(value)
+	}
+}
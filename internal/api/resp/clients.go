@@ -0,0 +1,349 @@
+package resp
+
+import (
+	"cache-engine/internal/acl"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// clientInfo es el estado de una conexión que ven las demás en CLIENT LIST.
+// La propia conexión lo actualiza tras cada comando.
+type clientInfo struct {
+	name  string
+	user  string
+	db    string
+	cmd   string // Último comando ejecutado
+	flags string
+	proto int
+	qbuf  int // Bytes de comandos recibidos sin procesar
+	omem  int // Bytes de respuestas sin enviar
+}
+
+// pauseState es el estado de CLIENT PAUSE
+type pauseState struct {
+	until time.Time
+	all   bool          // Retener todos los comandos, no solo las escrituras
+	wake  chan struct{} // Se cierra al cambiar la pausa
+}
+
+// snapshot actualiza la información visible en CLIENT LIST
+func (c *client) snapshot(cmd string) {
+	flags := "N"
+	if c.tracking {
+		flags = "t"
+		if c.bcast {
+			flags += "B"
+		}
+	}
+
+	c.infoMu.Lock()
+	c.info = clientInfo{
+		name:  c.name,
+		user:  c.user,
+		db:    c.db.Name(),
+		cmd:   cmd,
+		flags: flags,
+		proto: c.writer.Protocol(),
+		qbuf:  c.reader.Buffered(),
+		omem:  c.writer.Buffered(),
+	}
+	c.infoMu.Unlock()
+	c.lastActive.Store(time.Now().UnixNano())
+}
+
+// describe formatea una línea de CLIENT LIST
+func (c *client) describe(now time.Time) string {
+	c.infoMu.Lock()
+	info := c.info
+	c.infoMu.Unlock()
+
+	idle := now.Sub(time.Unix(0, c.lastActive.Load()))
+	return fmt.Sprintf("id=%d addr=%s laddr=%s name=%s age=%d idle=%d flags=%s db=%s qbuf=%d omem=%d cmd=%s user=%s resp=%d",
+		c.id, c.addr, c.laddr, info.name, int64(now.Sub(c.createdAt).Seconds()), int64(idle.Seconds()),
+		info.flags, info.db, info.qbuf, info.omem, info.cmd, info.user, info.proto)
+}
+
+// commandName retorna el nombre de un comando tal como aparece en CLIENT
+// LIST ("get", "client|list")
+func commandName(args [][]byte) string {
+	name := strings.ToLower(string(args[0]))
+	if name == "client" && len(args) > 1 {
+		name += "|" + strings.ToLower(string(args[1]))
+	}
+	return name
+}
+
+// cmdClient implementa CLIENT ID | SETNAME | GETNAME | INFO | LIST | KILL |
+// PAUSE | UNPAUSE | TRACKING
+func cmdClient(s *Server, c *client, args [][]byte) {
+	sub := strings.ToUpper(string(args[1]))
+	if err := s.acl.Check(c.user, "CLIENT|"+sub); err != nil {
+		writeACLError(c, err)
+		return
+	}
+
+	switch sub {
+	case "ID":
+		c.writer.WriteInt(c.id)
+	case "SETNAME":
+		if len(args) != 3 {
+			c.writer.WriteError("ERR número de argumentos incorrecto para 'client setname'")
+			return
+		}
+		if strings.ContainsAny(string(args[2]), " \n") {
+			c.writer.WriteError("ERR el nombre del cliente no puede contener espacios ni saltos de línea")
+			return
+		}
+		c.name = string(args[2])
+		c.writer.WriteSimple("OK")
+	case "GETNAME":
+		if c.name == "" {
+			c.writer.WriteNull()
+			return
+		}
+		c.writer.WriteBulkString(c.name)
+	case "INFO":
+		c.snapshot("client|info")
+		c.writer.WriteVerbatim(c.describe(time.Now()) + "\r\n")
+	case "LIST":
+		cmdClientList(s, c, args)
+	case "KILL":
+		cmdClientKill(s, c, args)
+	case "PAUSE":
+		cmdClientPause(s, c, args)
+	case "UNPAUSE":
+		s.setPause(time.Time{}, false)
+		c.writer.WriteSimple("OK")
+	case "TRACKING":
+		cmdClientTracking(s, c, args)
+	default:
+		c.writer.WriteError(fmt.Sprintf("ERR subcomando desconocido '%s'", args[1]))
+	}
+}
+
+// sortedClients retorna las conexiones ordenadas por id
+func (s *Server) sortedClients() []*client {
+	s.mu.Lock()
+	clients := make([]*client, 0, len(s.clients))
+	for _, other := range s.clients {
+		clients = append(clients, other)
+	}
+	s.mu.Unlock()
+
+	sort.Slice(clients, func(i, j int) bool { return clients[i].id < clients[j].id })
+	return clients
+}
+
+// cmdClientList implementa CLIENT LIST [ID id...]
+func cmdClientList(s *Server, c *client, args [][]byte) {
+	var ids map[int64]bool
+	if len(args) > 2 {
+		if len(args) < 4 || !strings.EqualFold(string(args[2]), "ID") {
+			c.writer.WriteError("ERR error de sintaxis")
+			return
+		}
+		ids = make(map[int64]bool)
+		for _, arg := range args[3:] {
+			id, err := strconv.ParseInt(string(arg), 10, 64)
+			if err != nil {
+				c.writer.WriteError("ERR id de cliente inválido")
+				return
+			}
+			ids[id] = true
+		}
+	}
+
+	// La conexión que pregunta aparece con el comando en curso
+	c.snapshot("client|list")
+
+	now := time.Now()
+	var lines strings.Builder
+	for _, other := range s.sortedClients() {
+		if ids != nil && !ids[other.id] {
+			continue
+		}
+		lines.WriteString(other.describe(now))
+		lines.WriteString("\n")
+	}
+	c.writer.WriteVerbatim(lines.String())
+}
+
+// killFilter selecciona las conexiones de CLIENT KILL
+type killFilter struct {
+	id     int64
+	addr   string
+	laddr  string
+	user   string
+	skipMe bool
+}
+
+// matches indica si una conexión cumple el filtro
+func (f killFilter) matches(c *client) bool {
+	if f.id != 0 && c.id != f.id {
+		return false
+	}
+	if f.addr != "" && c.addr != f.addr {
+		return false
+	}
+	if f.laddr != "" && c.laddr != f.laddr {
+		return false
+	}
+	if f.user != "" {
+		c.infoMu.Lock()
+		user := c.info.user
+		c.infoMu.Unlock()
+		if user != f.user {
+			return false
+		}
+	}
+	return true
+}
+
+// cmdClientKill implementa CLIENT KILL addr y CLIENT KILL [ID id] [ADDR
+// addr] [LADDR addr] [USER usuario] [SKIPME yes|no]
+func cmdClientKill(s *Server, c *client, args [][]byte) {
+	if len(args) < 3 {
+		c.writer.WriteError("ERR número de argumentos incorrecto para 'client kill'")
+		return
+	}
+
+	// Forma antigua: una dirección, responde OK o error
+	if len(args) == 3 {
+		if s.kill(c, killFilter{addr: string(args[2])}) == 0 {
+			c.writer.WriteError("ERR no existe ese cliente")
+			return
+		}
+		c.writer.WriteSimple("OK")
+		return
+	}
+
+	if len(args)%2 != 0 {
+		c.writer.WriteError("ERR error de sintaxis")
+		return
+	}
+	filter := killFilter{skipMe: true}
+	for i := 2; i < len(args); i += 2 {
+		value := string(args[i+1])
+		switch strings.ToUpper(string(args[i])) {
+		case "ID":
+			id, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || id <= 0 {
+				c.writer.WriteError("ERR id de cliente inválido")
+				return
+			}
+			filter.id = id
+		case "ADDR":
+			filter.addr = value
+		case "LADDR":
+			filter.laddr = value
+		case "USER":
+			filter.user = value
+		case "SKIPME":
+			switch strings.ToLower(value) {
+			case "yes":
+				filter.skipMe = true
+			case "no":
+				filter.skipMe = false
+			default:
+				c.writer.WriteError("ERR error de sintaxis")
+				return
+			}
+		default:
+			c.writer.WriteError(fmt.Sprintf("ERR filtro desconocido '%s' en CLIENT KILL", args[i]))
+			return
+		}
+	}
+	c.writer.WriteInt(int64(s.kill(c, filter)))
+}
+
+// kill cierra las conexiones que cumplen el filtro y retorna cuántas. La
+// propia conexión se cierra después de responder.
+func (s *Server) kill(c *client, filter killFilter) int {
+	killed := 0
+	for _, other := range s.sortedClients() {
+		if !filter.matches(other) {
+			continue
+		}
+		if other == c {
+			if filter.skipMe {
+				continue
+			}
+			c.quit = true
+		} else {
+			other.conn.Close()
+		}
+		killed++
+	}
+	return killed
+}
+
+// cmdClientPause implementa CLIENT PAUSE timeout [WRITE|ALL]: retiene los
+// comandos de todos los clientes (o solo las escrituras) durante timeout
+// milisegundos. CLIENT no se retiene para poder levantar la pausa.
+func cmdClientPause(s *Server, c *client, args [][]byte) {
+	if len(args) < 3 || len(args) > 4 {
+		c.writer.WriteError("ERR número de argumentos incorrecto para 'client pause'")
+		return
+	}
+	ms, err := strconv.ParseInt(string(args[2]), 10, 64)
+	if err != nil || ms < 0 {
+		c.writer.WriteError("ERR timeout inválido")
+		return
+	}
+
+	all := true
+	if len(args) == 4 {
+		switch strings.ToUpper(string(args[3])) {
+		case "ALL":
+		case "WRITE":
+			all = false
+		default:
+			c.writer.WriteError("ERR error de sintaxis")
+			return
+		}
+	}
+
+	s.setPause(time.Now().Add(time.Duration(ms)*time.Millisecond), all)
+	c.writer.WriteSimple("OK")
+}
+
+// setPause cambia la pausa y despierta a los comandos retenidos para que
+// la reevalúen
+func (s *Server) setPause(until time.Time, all bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pause.wake != nil {
+		close(s.pause.wake)
+	}
+	s.pause = pauseState{until: until, all: all, wake: make(chan struct{})}
+}
+
+// waitPause espera mientras CLIENT PAUSE retenga el comando
+func (s *Server) waitPause(args [][]byte) {
+	name := strings.ToUpper(string(args[0]))
+	if name == "CLIENT" {
+		return
+	}
+
+	for {
+		s.mu.Lock()
+		pause, closed := s.pause, s.closed
+		s.mu.Unlock()
+
+		wait := time.Until(pause.until)
+		if closed || wait <= 0 || (!pause.all && acl.CommandCategories(name)&acl.CategoryWrite == 0) {
+			return
+		}
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-timer.C:
+		case <-pause.wake:
+		}
+		timer.Stop()
+	}
+}
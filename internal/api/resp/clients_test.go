@@ -0,0 +1,211 @@
+package resp
+
+import (
+	"bufio"
+	"cache-engine/internal/cache"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readBulk envía un comando y lee su respuesta como bulk string
+func readBulk(t *testing.T, conn net.Conn, request string) string {
+	t.Helper()
+
+	io.WriteString(conn, request)
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+	header, err := reader.ReadString('\n')
+	if err != nil || header[0] != '$' {
+		t.Fatalf("Respuesta a %q inesperada: %q %v", request, header, err)
+	}
+	size, _ := strconv.Atoi(strings.TrimSpace(header[1:]))
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(reader, buf); err != nil {
+		t.Fatalf("Error al leer: %v", err)
+	}
+	return string(buf[:size])
+}
+
+// clientID retorna el id de una conexión
+func clientID(t *testing.T, conn net.Conn) string {
+	t.Helper()
+
+	io.WriteString(conn, "CLIENT ID\r\n")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("CLIENT ID inesperado: %q %v", line, err)
+	}
+	return strings.TrimSpace(line[1:])
+}
+
+// expectClosed comprueba que el servidor cerró la conexión
+func expectClosed(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, err := conn.Read(make([]byte, 1024))
+	if err == nil || errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Errorf("Esperaba que se cerrara la conexión, obtuve %v", err)
+	}
+}
+
+// TestClientListAndKill prueba CLIENT SETNAME, LIST, INFO y KILL
+func TestClientListAndKill(t *testing.T) {
+	_, conn := startServer(t)
+	other := dial(t, conn)
+
+	roundTrip(t, other, "CLIENT SETNAME worker\r\n", "+OK\r\n")
+	roundTrip(t, other, "*3\r\n$6\r\nCLIENT\r\n$7\r\nSETNAME\r\n$3\r\na b\r\n", "-ERR el nombre del cliente no puede contener espacios ni saltos de línea\r\n")
+	roundTrip(t, other, "SELECT sesiones\r\n", "+OK\r\n")
+	otherID := clientID(t, other)
+
+	list := readBulk(t, conn, "CLIENT LIST\r\n")
+	lines := strings.Split(strings.TrimSuffix(list, "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Esperaba 2 clientes, obtuve %q", list)
+	}
+	if !strings.Contains(lines[0], "cmd=client|list") || !strings.Contains(lines[0], "user=default") {
+		t.Errorf("Línea de la conexión propia inesperada: %q", lines[0])
+	}
+	for _, field := range []string{"id=" + otherID + " ", "name=worker", "db=sesiones", "cmd=client|id", "laddr=127.0.0.1:"} {
+		if !strings.Contains(lines[1], field) {
+			t.Errorf("Falta %q en %q", field, lines[1])
+		}
+	}
+
+	info := readBulk(t, other, "CLIENT INFO\r\n")
+	if !strings.HasPrefix(info, "id="+otherID+" ") || !strings.Contains(info, "cmd=client|info") {
+		t.Errorf("CLIENT INFO inesperado: %q", info)
+	}
+	if list := readBulk(t, conn, "CLIENT LIST ID "+otherID+"\r\n"); strings.Count(list, "\n") != 1 {
+		t.Errorf("CLIENT LIST ID debería filtrar: %q", list)
+	}
+
+	roundTrip(t, conn, "CLIENT KILL 10.0.0.1:1\r\n", "-ERR no existe ese cliente\r\n")
+	roundTrip(t, conn, "CLIENT KILL USER default\r\n", ":1\r\n")
+	expectClosed(t, other)
+
+	// SKIPME no cierra la propia conexión salvo que se pida
+	roundTrip(t, conn, "CLIENT KILL USER default SKIPME yes\r\n", ":0\r\n")
+	roundTrip(t, conn, "CLIENT KILL USER default SKIPME no\r\n", ":1\r\n")
+	expectClosed(t, conn)
+}
+
+// TestClientCommandPermissions prueba que CLIENT KILL requiere @admin
+func TestClientCommandPermissions(t *testing.T) {
+	_, conn := startServer(t)
+
+	roundTrip(t, conn, "ACL SETUSER app on >pw +@connection\r\n", "+OK\r\n")
+	other := dial(t, conn)
+	roundTrip(t, other, "AUTH app pw\r\n", "+OK\r\n")
+	roundTrip(t, other, "CLIENT SETNAME app\r\n", "+OK\r\n")
+	roundTrip(t, other, "CLIENT KILL ID 1\r\n", "-NOPERM permiso denegado: el usuario 'app' no puede ejecutar 'client|kill'\r\n")
+}
+
+// TestMaxClients prueba el límite de conexiones simultáneas
+func TestMaxClients(t *testing.T) {
+	_, conn := startServerWith(t, Config{MaxClients: 1})
+	roundTrip(t, conn, "PING\r\n", "+PONG\r\n")
+
+	other := dial(t, conn)
+	roundTrip(t, other, "", "-ERR se alcanzó el número máximo de clientes\r\n")
+	expectClosed(t, other)
+}
+
+// TestIdleTimeout prueba el cierre de conexiones inactivas y la exención
+// de las que reciben invalidaciones
+func TestIdleTimeout(t *testing.T) {
+	_, conn := startServerWith(t, Config{IdleTimeout: 100 * time.Millisecond})
+
+	receiver := dial(t, conn)
+	hello3(t, receiver)
+	roundTrip(t, receiver, "CLIENT TRACKING ON BCAST\r\n", "+OK\r\n")
+
+	expectClosed(t, conn)
+	time.Sleep(200 * time.Millisecond)
+	roundTrip(t, receiver, "PING\r\n", "+PONG\r\n")
+}
+
+// TestOutputBufferLimit prueba la desconexión al superar MaxOutputBuffer
+func TestOutputBufferLimit(t *testing.T) {
+	_, conn := startServerWith(t, Config{MaxOutputBuffer: 100})
+
+	value := strings.Repeat("x", 200)
+	roundTrip(t, conn, "SET big "+value+"\r\n", "+OK\r\n")
+	roundTrip(t, conn, "GET missing\r\n", "$-1\r\n")
+	io.WriteString(conn, "GET big\r\n")
+	expectClosed(t, conn)
+}
+
+// TestClientPause prueba CLIENT PAUSE WRITE y CLIENT UNPAUSE
+func TestClientPause(t *testing.T) {
+	_, conn := startServer(t)
+	other := dial(t, conn)
+
+	roundTrip(t, conn, "CLIENT PAUSE 10000 WRITE\r\n", "+OK\r\n")
+	roundTrip(t, other, "GET k\r\n", "$-1\r\n")
+
+	// La escritura queda retenida hasta UNPAUSE
+	io.WriteString(other, "SET k v\r\n")
+	other.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if n, err := other.Read(make([]byte, 16)); !errors.Is(err, os.ErrDeadlineExceeded) {
+		t.Fatalf("SET debería estar retenido: %d %v", n, err)
+	}
+
+	roundTrip(t, conn, "CLIENT UNPAUSE\r\n", "+OK\r\n")
+	roundTrip(t, other, "", "+OK\r\n")
+	roundTrip(t, conn, "CLIENT PAUSE 50\r\n", "+OK\r\n")
+	roundTrip(t, other, "GET k\r\n", "$1\r\nv\r\n")
+}
+
+// TestShutdown prueba que Shutdown cierra las conexiones inactivas, deja de
+// aceptar nuevas y espera a responder el comando en curso
+func TestShutdown(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+	server := NewServer(engine, Config{})
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+	defer conn.Close()
+	busy, idle := dial(t, conn), dial(t, conn)
+
+	// El SET queda retenido por la pausa mientras empieza el cierre
+	roundTrip(t, conn, "CLIENT PAUSE 10000 WRITE\r\n", "+OK\r\n")
+	roundTrip(t, idle, "PING\r\n", "+PONG\r\n")
+	io.WriteString(busy, "SET k v\r\n")
+	time.Sleep(50 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := server.Shutdown(ctx); err != nil {
+		t.Fatalf("No se pudo cerrar el servidor: %v", err)
+	}
+
+	roundTrip(t, busy, "", "+OK\r\n")
+	if value, _ := engine.Get("k"); value != "v" {
+		t.Errorf("El SET en curso debería haberse aplicado, obtuve %v", value)
+	}
+	idle.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := idle.Read(make([]byte, 1)); err == nil {
+		t.Error("La conexión inactiva debería estar cerrada")
+	}
+	if _, err := net.Dial("tcp", listener.Addr().String()); err == nil {
+		t.Error("El servidor no debería aceptar conexiones")
+	}
+}
@@ -0,0 +1,323 @@
+package resp
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Version es la versión anunciada en HELLO e INFO
+const Version = "1.0.0"
+
+// handlerFunc ejecuta un comando propio del servidor RESP; args[0] es el
+// nombre del comando
+type handlerFunc func(s *Server, c *client, args [][]byte)
+
+// commands es la tabla de comandos del servidor RESP: la compartida más
+// los comandos que dependen de la conexión, que se ejecutan con handlers
+var (
+	commands = command.NewFrontendRegistry(command.Default)
+	handlers map[string]handlerFunc
+)
+
+// noAuthCommands se pueden ejecutar sin haberse autenticado
+var noAuthCommands = map[string]bool{"AUTH": true, "HELLO": true, "QUIT": true}
+
+func init() {
+	local := []struct {
+		command.Command
+		handler handlerFunc
+	}{
+		{command.Command{Name: "HELLO", Arity: -1, Flags: command.FlagConnection,
+			Usage: "[protover [AUTH username password] [SETNAME clientname]]", Summary: "Negocia el protocolo y autentica"}, cmdHello},
+		{command.Command{Name: "AUTH", Arity: -2, Flags: command.FlagConnection,
+			Usage: "[username] password", Summary: "Autentica la conexión"}, cmdAuth},
+		{command.Command{Name: "QUIT", Arity: 1, Flags: command.FlagConnection,
+			Summary: "Cierra la conexión"}, cmdQuit},
+		{command.Command{Name: "COMMAND", Arity: -1, Flags: command.FlagConnection,
+			Usage: "[COUNT|INFO [command ...]|DOCS [command ...]]", Summary: "Describe los comandos del servidor"}, cmdCommand},
+		{command.Command{Name: "CLIENT", Arity: -2, Flags: command.FlagConnection,
+			Usage: "ID|SETNAME|GETNAME|INFO|LIST|KILL|PAUSE|UNPAUSE|TRACKING [argument ...]", Summary: "Gestiona las conexiones"}, cmdClient},
+		{command.Command{Name: "ACL", Arity: -2, Flags: command.FlagAdmin | command.FlagDangerous,
+			Usage: "SETUSER|GETUSER|DELUSER|LIST|USERS|WHOAMI|CAT [argument ...]", Summary: "Gestiona los usuarios y sus permisos"}, cmdACL},
+	}
+
+	handlers = make(map[string]handlerFunc, len(local))
+	for _, entry := range local {
+		if err := commands.Register(entry.Command); err != nil {
+			panic(err)
+		}
+		handlers[entry.Name] = entry.handler
+	}
+}
+
+// execute busca y ejecuta un comando, validando su aridad y los permisos
+func (s *Server) execute(c *client, args [][]byte) {
+	ctx := &command.Context{Engine: s.engine, DB: c.db, Registry: commands}
+	cmd, exists := ctx.Lookup(string(args[0]))
+	if !exists {
+		c.writer.WriteError(fmt.Sprintf("ERR comando desconocido '%s'", args[0]))
+		return
+	}
+	if err := cmd.CheckArity(args); err != nil {
+		c.writer.WriteError("ERR " + err.Error())
+		return
+	}
+
+	keys := cmd.Keys(args)
+	if !noAuthCommands[cmd.Name] {
+		if err := s.acl.Check(c.user, cmd.Name, keys...); err != nil {
+			writeACLError(c, err)
+			return
+		}
+	}
+
+	if cmd.Handler == nil {
+		start := time.Now()
+		handlers[cmd.Name](s, c, args)
+		s.engine.RecordCommand(cmd.Name, time.Since(start))
+		return
+	}
+
+	if cmd.Flags&command.FlagReadOnly != 0 {
+		for _, key := range keys {
+			s.trackKey(c, key)
+		}
+	}
+	result, err := cmd.Call(ctx, args)
+	c.db = ctx.DB
+	if err != nil {
+		writeError(c, err)
+		return
+	}
+	writeReply(c.writer, result)
+}
+
+// writeError traduce el error de un comando de la tabla compartida
+func writeError(c *client, err error) {
+	switch {
+	case errors.Is(err, cache.ErrQuotaExceeded):
+		c.writer.WriteError("OOM " + err.Error())
+	case errors.Is(err, acl.ErrAuthRequired), errors.Is(err, acl.ErrWrongPass), errors.Is(err, acl.ErrNoPermission):
+		writeACLError(c, err)
+	default:
+		c.writer.WriteError("ERR " + err.Error())
+	}
+}
+
+// writeReply escribe la respuesta de un comando de la tabla compartida
+func writeReply(w *Writer, result interface{}) {
+	switch v := result.(type) {
+	case nil:
+		w.WriteNull()
+	case command.Status:
+		w.WriteSimple(string(v))
+	case command.Text:
+		w.WriteVerbatim(strings.ReplaceAll(string(v), "\n", "\r\n"))
+	case []byte:
+		w.WriteBulk(v)
+	case string:
+		w.WriteBulkString(v)
+	case int64:
+		w.WriteInt(v)
+	case int:
+		w.WriteInt(int64(v))
+	case bool:
+		w.WriteBool(v)
+	case float64:
+		w.WriteDouble(v)
+	case []string:
+		w.WriteArray(len(v))
+		for _, item := range v {
+			w.WriteBulkString(item)
+		}
+	case []interface{}:
+		w.WriteArray(len(v))
+		for _, item := range v {
+			writeReply(w, item)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		w.WriteMap(len(keys))
+		for _, key := range keys {
+			w.WriteBulkString(key)
+			writeReply(w, v[key])
+		}
+	default:
+		w.WriteBulk(cache.ValueBytes(v))
+	}
+}
+
+// cmdHello negocia la versión del protocolo:
+// HELLO [protover [AUTH username password] [SETNAME name]]
+func cmdHello(s *Server, c *client, args [][]byte) {
+	proto := c.writer.Protocol()
+	if len(args) > 1 {
+		version, err := strconv.Atoi(string(args[1]))
+		if err != nil || (version != 2 && version != 3) {
+			c.writer.WriteError("NOPROTO versión de protocolo no soportada")
+			return
+		}
+		proto = version
+	}
+
+	user, name := c.user, c.name
+	authenticated := false
+	for i := 2; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "AUTH":
+			if i+2 >= len(args) {
+				c.writer.WriteError("ERR faltan el usuario y la contraseña en AUTH")
+				return
+			}
+			if err := s.acl.Authenticate(string(args[i+1]), string(args[i+2])); err != nil {
+				writeACLError(c, err)
+				return
+			}
+			user, authenticated = string(args[i+1]), true
+			i += 2
+		case "SETNAME":
+			if i+1 >= len(args) {
+				c.writer.WriteError("ERR falta el nombre en SETNAME")
+				return
+			}
+			name = string(args[i+1])
+			i++
+		default:
+			c.writer.WriteError(fmt.Sprintf("ERR opción desconocida '%s' en HELLO", args[i]))
+			return
+		}
+	}
+
+	if !authenticated {
+		// Sin AUTH, HELLO es un comando más
+		if err := s.acl.Check(user, "HELLO"); errors.Is(err, acl.ErrAuthRequired) {
+			c.writer.WriteError("NOAUTH HELLO requiere una conexión autenticada o la opción AUTH")
+			return
+		} else if err != nil {
+			writeACLError(c, err)
+			return
+		}
+	}
+	c.user, c.name = user, name
+	c.writer.SetProtocol(proto)
+	c.resp3.Store(proto >= 3)
+
+	c.writer.WriteMap(7)
+	c.writer.WriteBulkString("server")
+	c.writer.WriteBulkString("cache-engine")
+	c.writer.WriteBulkString("version")
+	c.writer.WriteBulkString(Version)
+	c.writer.WriteBulkString("proto")
+	c.writer.WriteInt(int64(proto))
+	c.writer.WriteBulkString("id")
+	c.writer.WriteInt(c.id)
+	c.writer.WriteBulkString("mode")
+	c.writer.WriteBulkString("standalone")
+	c.writer.WriteBulkString("role")
+	c.writer.WriteBulkString("master")
+	c.writer.WriteBulkString("modules")
+	c.writer.WriteArray(0)
+}
+
+func cmdQuit(s *Server, c *client, args [][]byte) {
+	c.writer.WriteSimple("OK")
+	c.quit = true
+}
+
+// cmdCommand implementa COMMAND [COUNT | INFO [command ...] | DOCS [command ...]].
+// Sin subcomando retorna la lista de nombres (suficiente para que
+// redis-cli arranque). Solo se describen los comandos disponibles por red.
+func cmdCommand(s *Server, c *client, args [][]byte) {
+	ctx := &command.Context{Engine: s.engine, DB: c.db, Registry: commands}
+	all := ctx.Commands()
+	if len(args) == 1 {
+		c.writer.WriteArray(len(all))
+		for _, cmd := range all {
+			c.writer.WriteBulkString(strings.ToLower(cmd.Name))
+		}
+		return
+	}
+
+	sub := strings.ToUpper(string(args[1]))
+	if sub == "COUNT" {
+		c.writer.WriteInt(int64(len(all)))
+		return
+	}
+	if sub != "INFO" && sub != "DOCS" {
+		c.writer.WriteError(fmt.Sprintf("ERR subcomando desconocido '%s' en COMMAND", args[1]))
+		return
+	}
+
+	// Sin nombres se describen todos los comandos
+	selected := make([]*command.Command, 0, len(all))
+	if len(args) == 2 {
+		selected = all
+	}
+	for _, name := range args[2:] {
+		cmd, _ := ctx.Lookup(string(name))
+		selected = append(selected, cmd)
+	}
+
+	if sub == "INFO" {
+		c.writer.WriteArray(len(selected))
+		for _, cmd := range selected {
+			writeCommandInfo(c.writer, cmd)
+		}
+		return
+	}
+
+	// DOCS omite los comandos desconocidos
+	docs := make([]*command.Command, 0, len(selected))
+	for _, cmd := range selected {
+		if cmd != nil {
+			docs = append(docs, cmd)
+		}
+	}
+	c.writer.WriteMap(len(docs))
+	for _, cmd := range docs {
+		c.writer.WriteBulkString(strings.ToLower(cmd.Name))
+		c.writer.WriteMap(2)
+		c.writer.WriteBulkString("summary")
+		c.writer.WriteBulkString(cmd.Summary)
+		c.writer.WriteBulkString("syntax")
+		c.writer.WriteBulkString(cmd.Synopsis())
+	}
+}
+
+// writeCommandInfo escribe la descripción de un comando con el formato de
+// COMMAND INFO de Redis: nombre, aridad, flags, claves y categorías de ACL.
+// Un comando desconocido se describe como nulo.
+func writeCommandInfo(w *Writer, cmd *command.Command) {
+	if cmd == nil {
+		w.WriteNull()
+		return
+	}
+
+	w.WriteArray(7)
+	w.WriteBulkString(strings.ToLower(cmd.Name))
+	w.WriteInt(int64(cmd.Arity))
+	flags := cmd.Flags.Names()
+	w.WriteSet(len(flags))
+	for _, flag := range flags {
+		w.WriteSimple(flag)
+	}
+	w.WriteInt(int64(cmd.FirstKey))
+	w.WriteInt(int64(cmd.LastKey))
+	w.WriteInt(int64(cmd.KeyStep))
+	categories := cmd.Flags.Categories().Names()
+	w.WriteSet(len(categories))
+	for _, category := range categories {
+		w.WriteSimple("@" + category)
+	}
+}
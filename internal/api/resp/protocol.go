@@ -0,0 +1,268 @@
+package resp
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+const (
+	// maxBulkLen limita el tamaño de un bulk string (512 MiB, como Redis)
+	maxBulkLen = 512 << 20
+
+	// maxArrayLen limita el número de argumentos de un comando
+	maxArrayLen = 1 << 20
+
+	// maxRetainedOutput es la capacidad del buffer de salida que se conserva
+	// tras enviar una respuesta grande
+	maxRetainedOutput = 1 << 20
+)
+
+// ErrProtocol indica una petición mal formada
+var ErrProtocol = errors.New("error de protocolo")
+
+// Reader lee comandos RESP (arrays de bulk strings o comandos inline)
+type Reader struct {
+	r *bufio.Reader
+}
+
+// NewReader crea un Reader sobre r
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReader(r)}
+}
+
+// Buffered retorna los bytes ya leídos y pendientes de procesar (pipelining)
+func (r *Reader) Buffered() int {
+	return r.r.Buffered()
+}
+
+// readLine lee una línea terminada en \r\n (o \n) sin el terminador
+func (r *Reader) readLine() (string, error) {
+	line, err := r.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+// ReadCommand lee el siguiente comando como lista de argumentos
+func (r *Reader) ReadCommand() ([][]byte, error) {
+	for {
+		line, err := r.readLine()
+		if err != nil {
+			return nil, err
+		}
+		if line == "" {
+			continue
+		}
+
+		if line[0] != '*' {
+			// Comando inline (telnet, nc)
+			fields := strings.Fields(line)
+			args := make([][]byte, len(fields))
+			for i, field := range fields {
+				args[i] = []byte(field)
+			}
+			return args, nil
+		}
+
+		count, err := strconv.Atoi(line[1:])
+		if err != nil || count > maxArrayLen {
+			return nil, fmt.Errorf("%w: longitud de array inválida", ErrProtocol)
+		}
+		if count <= 0 {
+			continue
+		}
+
+		args := make([][]byte, count)
+		for i := range args {
+			if args[i], err = r.readBulk(); err != nil {
+				return nil, err
+			}
+		}
+		return args, nil
+	}
+}
+
+// readBulk lee un bulk string ($<len>\r\n<datos>\r\n)
+func (r *Reader) readBulk() ([]byte, error) {
+	line, err := r.readLine()
+	if err != nil {
+		return nil, err
+	}
+	if line == "" || line[0] != '$' {
+		return nil, fmt.Errorf("%w: se esperaba '$'", ErrProtocol)
+	}
+
+	size, err := strconv.Atoi(line[1:])
+	if err != nil || size < 0 || size > maxBulkLen {
+		return nil, fmt.Errorf("%w: longitud de bulk inválida", ErrProtocol)
+	}
+
+	buf := make([]byte, size+2)
+	if _, err := io.ReadFull(r.r, buf); err != nil {
+		return nil, err
+	}
+	if buf[size] != '\r' || buf[size+1] != '\n' {
+		return nil, fmt.Errorf("%w: bulk sin terminador", ErrProtocol)
+	}
+	return buf[:size], nil
+}
+
+// Writer escribe respuestas RESP2 o RESP3 según la versión negociada. Las
+// respuestas se acumulan en memoria hasta Flush, de modo que Buffered
+// refleja el buffer de salida del cliente.
+type Writer struct {
+	out   io.Writer
+	w     bytes.Buffer
+	proto int // 2 o 3
+}
+
+// NewWriter crea un Writer RESP2 sobre w
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{out: w, proto: 2}
+}
+
+// SetProtocol cambia la versión del protocolo (2 o 3)
+func (w *Writer) SetProtocol(proto int) {
+	w.proto = proto
+}
+
+// Protocol retorna la versión del protocolo en uso
+func (w *Writer) Protocol() int {
+	return w.proto
+}
+
+// Flush envía al cliente las respuestas pendientes
+func (w *Writer) Flush() error {
+	if w.w.Len() == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.w.Bytes())
+	w.Discard()
+	return err
+}
+
+// Discard descarta las respuestas pendientes
+func (w *Writer) Discard() {
+	if w.w.Cap() > maxRetainedOutput {
+		w.w = bytes.Buffer{}
+		return
+	}
+	w.w.Reset()
+}
+
+// Buffered retorna los bytes pendientes de enviar
+func (w *Writer) Buffered() int {
+	return w.w.Len()
+}
+
+// WriteSimple escribe un simple string (+OK)
+func (w *Writer) WriteSimple(s string) {
+	w.w.WriteString("+" + s + "\r\n")
+}
+
+// WriteError escribe un error; msg debe incluir el prefijo (ERR, WRONGTYPE...)
+func (w *Writer) WriteError(msg string) {
+	msg = strings.NewReplacer("\r", " ", "\n", " ").Replace(msg)
+	w.w.WriteString("-" + msg + "\r\n")
+}
+
+// WriteInt escribe un entero
+func (w *Writer) WriteInt(n int64) {
+	w.w.WriteString(":" + strconv.FormatInt(n, 10) + "\r\n")
+}
+
+// WriteBulk escribe un bulk string binario
+func (w *Writer) WriteBulk(b []byte) {
+	w.w.WriteString("$" + strconv.Itoa(len(b)) + "\r\n")
+	w.w.Write(b)
+	w.w.WriteString("\r\n")
+}
+
+// WriteBulkString escribe un bulk string
+func (w *Writer) WriteBulkString(s string) {
+	w.WriteBulk([]byte(s))
+}
+
+// WriteNull escribe un valor nulo ($-1 en RESP2, _ en RESP3)
+func (w *Writer) WriteNull() {
+	if w.proto >= 3 {
+		w.w.WriteString("_\r\n")
+		return
+	}
+	w.w.WriteString("$-1\r\n")
+}
+
+// WriteArray escribe la cabecera de un array de n elementos
+func (w *Writer) WriteArray(n int) {
+	w.w.WriteString("*" + strconv.Itoa(n) + "\r\n")
+}
+
+// WriteMap escribe la cabecera de un mapa de n pares (array plano en RESP2)
+func (w *Writer) WriteMap(n int) {
+	if w.proto >= 3 {
+		w.w.WriteString("%" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	w.WriteArray(n * 2)
+}
+
+// WriteSet escribe la cabecera de un conjunto (array en RESP2)
+func (w *Writer) WriteSet(n int) {
+	if w.proto >= 3 {
+		w.w.WriteString("~" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	w.WriteArray(n)
+}
+
+// WritePush escribe la cabecera de un mensaje push (solo RESP3; array en RESP2)
+func (w *Writer) WritePush(n int) {
+	if w.proto >= 3 {
+		w.w.WriteString(">" + strconv.Itoa(n) + "\r\n")
+		return
+	}
+	w.WriteArray(n)
+}
+
+// WriteDouble escribe un número decimal (bulk string en RESP2)
+func (w *Writer) WriteDouble(f float64) {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if w.proto >= 3 {
+		w.w.WriteString("," + s + "\r\n")
+		return
+	}
+	w.WriteBulkString(s)
+}
+
+// WriteBool escribe un booleano (entero 0/1 en RESP2)
+func (w *Writer) WriteBool(b bool) {
+	if w.proto >= 3 {
+		if b {
+			w.w.WriteString("#t\r\n")
+		} else {
+			w.w.WriteString("#f\r\n")
+		}
+		return
+	}
+	if b {
+		w.WriteInt(1)
+	} else {
+		w.WriteInt(0)
+	}
+}
+
+// WriteVerbatim escribe texto largo (verbatim string en RESP3, bulk en RESP2)
+func (w *Writer) WriteVerbatim(s string) {
+	if w.proto >= 3 {
+		w.w.WriteString("=" + strconv.Itoa(len(s)+4) + "\r\ntxt:" + s + "\r\n")
+		return
+	}
+	w.WriteBulkString(s)
+}
@@ -0,0 +1,349 @@
+package resp
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/netutil"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultPort es el puerto por defecto del servidor RESP (el de Redis)
+const DefaultPort = 6379
+
+// Config contiene las opciones del servidor RESP
+type Config struct {
+	Port           int         // Puerto de escucha
+	ACL            *acl.Store  // Usuarios y permisos (nil = sin autenticación)
+	TLS            *tls.Config // Cifrado de las conexiones (nil = texto plano)
+	UnixSocket     string      // Ruta de un socket Unix adicional (vacío = ninguno)
+	UnixSocketPerm os.FileMode // Permisos del socket (0 = 0700)
+	NoTCP          bool        // Escuchar solo en el socket Unix
+
+	MaxClients      int           // Conexiones simultáneas máximas (0 = sin límite)
+	IdleTimeout     time.Duration // Cierra las conexiones sin actividad durante este tiempo (0 = nunca)
+	MaxOutputBuffer int           // Bytes de respuestas pendientes por cliente antes de desconectarlo (0 = sin límite)
+	OutputTimeout   time.Duration // Tiempo máximo para que un cliente lento acepte sus respuestas (0 = sin límite)
+}
+
+// errOutputLimit indica que un cliente superó MaxOutputBuffer
+var errOutputLimit = errors.New("límite del buffer de salida superado")
+
+// Server expone el CacheEngine mediante el protocolo RESP de Redis
+type Server struct {
+	engine *cache.CacheEngine
+	config Config
+	acl    *acl.Store
+
+	mu        sync.Mutex
+	listeners []net.Listener
+	clients   map[int64]*client
+	closed    bool
+	conns     sync.WaitGroup // Conexiones en curso, para Shutdown
+	nextID    atomic.Int64
+	tracking  tracking
+	pause     pauseState
+}
+
+// client guarda el estado de una conexión
+type client struct {
+	id        int64
+	conn      net.Conn
+	reader    *Reader
+	writer    *Writer
+	db        *cache.Namespace // Namespace seleccionado (SELECT)
+	user      string           // Usuario autenticado ("" = ninguno)
+	name      string           // Nombre asignado con CLIENT SETNAME
+	addr      string           // Dirección del cliente
+	laddr     string           // Dirección local de la conexión
+	createdAt time.Time
+	quit      bool // Cerrar la conexión tras responder
+	busy      bool // Ejecutando un comando; protegido por Server.mu
+
+	infoMu     sync.Mutex
+	info       clientInfo   // Estado visible en CLIENT LIST
+	lastActive atomic.Int64 // UnixNano del último comando
+	keepAlive  atomic.Bool  // Recibe invalidaciones: exento de IdleTimeout
+	resp3      atomic.Bool  // Usa RESP3: puede ser destino de REDIRECT
+
+	// wmu serializa las escrituras: las invalidaciones de CLIENT TRACKING
+	// se envían desde otra goroutine
+	wmu         sync.Mutex
+	tracking    bool  // CLIENT TRACKING activo
+	bcast       bool  // Modo BCAST (por prefijos) en lugar de por claves leídas
+	trackTarget int64 // Cliente que recibe las invalidaciones (REDIRECT)
+}
+
+// NewServer crea un servidor RESP para el motor indicado
+func NewServer(engine *cache.CacheEngine, config Config) *Server {
+	if config.Port <= 0 {
+		config.Port = DefaultPort
+	}
+	if config.ACL == nil {
+		config.ACL = acl.NewStore()
+	}
+
+	return &Server{
+		engine:  engine,
+		config:  config,
+		acl:     config.ACL,
+		clients: make(map[int64]*client),
+		tracking: tracking{
+			keys:  make(map[string]map[string]map[int64]struct{}),
+			bcast: make(map[int64]bcastTracking),
+		},
+	}
+}
+
+// Addr retorna las direcciones de escucha configuradas
+func (s *Server) Addr() string {
+	return s.listenConfig().Describe()
+}
+
+// listenConfig retorna el puerto TCP y el socket Unix configurados
+func (s *Server) listenConfig() netutil.ListenConfig {
+	return netutil.ListenConfig{
+		Addr:       fmt.Sprintf(":%d", s.config.Port),
+		NoTCP:      s.config.NoTCP,
+		UnixSocket: s.config.UnixSocket,
+		SocketPerm: s.config.UnixSocketPerm,
+	}
+}
+
+// ListenAndServe escucha en el puerto y el socket Unix configurados y
+// atiende conexiones
+func (s *Server) ListenAndServe() error {
+	listeners, err := netutil.Listen(s.listenConfig())
+	if err != nil {
+		return err
+	}
+	return netutil.ServeAll(listeners, s.Serve)
+}
+
+// Serve atiende conexiones del listener hasta que se cierre el servidor
+func (s *Server) Serve(listener net.Listener) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		listener.Close()
+		return net.ErrClosed
+	}
+	if s.config.TLS != nil {
+		listener = tls.NewListener(listener, s.config.TLS)
+	}
+	s.listeners = append(s.listeners, listener)
+	s.mu.Unlock()
+
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			s.mu.Lock()
+			closed := s.closed
+			s.mu.Unlock()
+			if closed {
+				return nil
+			}
+			return err
+		}
+		go s.handleConn(conn)
+	}
+}
+
+// Close deja de aceptar conexiones y cierra las existentes
+func (s *Server) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	err := s.stopListening()
+	for _, c := range s.clients {
+		c.conn.Close()
+	}
+	s.stopTracking()
+	return err
+}
+
+// Shutdown deja de aceptar conexiones, cierra las inactivas y espera a que
+// las demás terminen el comando en curso y envíen su respuesta. Si ctx
+// vence antes, las cierra como Close y retorna ctx.Err().
+func (s *Server) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	err := s.stopListening()
+	for _, c := range s.clients {
+		if !c.busy {
+			c.conn.Close()
+		}
+	}
+	s.mu.Unlock()
+
+	drained := make(chan struct{})
+	go func() {
+		s.conns.Wait()
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if closeErr := s.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// stopListening marca el servidor como cerrado, cierra los listeners y
+// libera los comandos retenidos por CLIENT PAUSE. Requiere s.mu tomado.
+func (s *Server) stopListening() error {
+	s.closed = true
+	var err error
+	for _, listener := range s.listeners {
+		if closeErr := listener.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	s.listeners = nil
+	if s.pause.wake != nil {
+		close(s.pause.wake)
+		s.pause = pauseState{}
+	}
+	return err
+}
+
+// handleConn atiende los comandos de una conexión. Las respuestas se
+// acumulan mientras haya comandos en el buffer (pipelining).
+func (s *Server) handleConn(conn net.Conn) {
+	c := &client{
+		id:        s.nextID.Add(1),
+		conn:      conn,
+		reader:    NewReader(conn),
+		writer:    NewWriter(conn),
+		db:        s.engine.Namespace(cache.DefaultNamespace),
+		createdAt: time.Now(),
+	}
+	c.addr, c.laddr = connAddrs(conn)
+	if s.acl.Authenticate(acl.DefaultUser, "") == nil {
+		// El usuario por defecto no tiene contraseña
+		c.user = acl.DefaultUser
+	}
+	c.snapshot("")
+
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		conn.Close()
+		return
+	}
+	if s.config.MaxClients > 0 && len(s.clients) >= s.config.MaxClients {
+		s.mu.Unlock()
+		c.writer.WriteError("ERR se alcanzó el número máximo de clientes")
+		conn.SetWriteDeadline(time.Now().Add(time.Second))
+		c.writer.Flush()
+		conn.Close()
+		return
+	}
+	s.clients[c.id] = c
+	s.conns.Add(1)
+	s.mu.Unlock()
+
+	defer func() {
+		s.mu.Lock()
+		delete(s.clients, c.id)
+		s.mu.Unlock()
+		s.conns.Done()
+		if c.tracking {
+			s.untrack(c)
+		}
+		conn.Close()
+	}()
+
+	for !c.quit {
+		if s.config.IdleTimeout > 0 && !c.keepAlive.Load() {
+			conn.SetReadDeadline(time.Now().Add(s.config.IdleTimeout))
+		}
+		args, err := c.reader.ReadCommand()
+		if err != nil {
+			if errors.Is(err, ErrProtocol) {
+				c.writer.WriteError("ERR " + err.Error())
+				c.writer.Flush()
+			} else if !errors.Is(err, io.EOF) && !errors.Is(err, net.ErrClosed) {
+				c.writer.Flush()
+			}
+			return
+		}
+		if !s.setBusy(c, true) {
+			// El servidor se está cerrando: enviar lo pendiente y salir
+			c.wmu.Lock()
+			s.flush(c, s.config.OutputTimeout)
+			c.wmu.Unlock()
+			return
+		}
+
+		s.waitPause(args)
+
+		c.wmu.Lock()
+		s.execute(c, args)
+		c.snapshot(commandName(args))
+
+		// Solo enviar cuando no quedan comandos encolados; hasta entonces la
+		// conexión sigue ocupada
+		var flushErr error
+		flushed := c.reader.Buffered() == 0 || c.quit || s.outputExceeded(c)
+		if flushed {
+			flushErr = s.flush(c, s.config.OutputTimeout)
+		}
+		c.wmu.Unlock()
+		if flushErr != nil || (flushed && !s.setBusy(c, false)) {
+			return
+		}
+	}
+}
+
+// setBusy marca si el cliente está ejecutando comandos o tiene respuestas
+// sin enviar. Retorna false si el servidor se está cerrando: el comando
+// leído no se ejecuta y la conexión se cierra tras enviar lo pendiente.
+func (s *Server) setBusy(c *client, busy bool) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	c.busy = busy
+	return !s.closed
+}
+
+// outputExceeded indica si las respuestas pendientes superan MaxOutputBuffer
+func (s *Server) outputExceeded(c *client) bool {
+	return s.config.MaxOutputBuffer > 0 && c.writer.Buffered() > s.config.MaxOutputBuffer
+}
+
+// flush envía las respuestas pendientes con el tiempo máximo indicado. Si
+// superan MaxOutputBuffer se descartan y se cierra la conexión.
+func (s *Server) flush(c *client, timeout time.Duration) error {
+	if s.outputExceeded(c) {
+		c.writer.Discard()
+		c.conn.Close()
+		return errOutputLimit
+	}
+	if timeout > 0 {
+		c.conn.SetWriteDeadline(time.Now().Add(timeout))
+		defer c.conn.SetWriteDeadline(time.Time{})
+	}
+	return c.writer.Flush()
+}
+
+// connAddrs retorna las direcciones remota y local de una conexión. En los
+// sockets Unix ambas son la ruta del socket, como en Redis.
+func connAddrs(conn net.Conn) (string, string) {
+	local := conn.LocalAddr()
+	if local.Network() == "unix" {
+		addr := local.String() + ":0"
+		return addr, addr
+	}
+	return conn.RemoteAddr().String(), local.String()
+}
@@ -0,0 +1,316 @@
+package resp
+
+import (
+	"bufio"
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"context"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startServer arranca un servidor en un puerto libre y retorna una conexión
+func startServer(t *testing.T) (*cache.CacheEngine, net.Conn) {
+	t.Helper()
+	return startServerWith(t, Config{})
+}
+
+// startServerWith arranca un servidor con la configuración indicada
+func startServerWith(t *testing.T, config Config) (*cache.CacheEngine, net.Conn) {
+	t.Helper()
+
+	engine := cache.NewCacheEngine(100)
+	server := NewServer(engine, config)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+
+	t.Cleanup(func() {
+		conn.Close()
+		server.Close()
+		engine.Close(context.Background())
+	})
+	return engine, conn
+}
+
+// roundTrip envía datos crudos y lee exactamente len(expected) bytes
+func roundTrip(t *testing.T, conn net.Conn, request, expected string) {
+	t.Helper()
+
+	if _, err := io.WriteString(conn, request); err != nil {
+		t.Fatalf("Error al escribir: %v", err)
+	}
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	buf := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, buf); err != nil {
+		t.Fatalf("Error al leer (esperaba %q): %v", expected, err)
+	}
+
+	if string(buf) != expected {
+		t.Errorf("Respuesta a %q: esperaba %q, obtuve %q", request, expected, buf)
+	}
+}
+
+// TestBasicCommands prueba SET, GET, DEL, EXISTS y TTL
+func TestBasicCommands(t *testing.T) {
+	_, conn := startServer(t)
+
+	roundTrip(t, conn, "*1\r\n$4\r\nPING\r\n", "+PONG\r\n")
+	roundTrip(t, conn, "*3\r\n$3\r\nSET\r\n$4\r\nuser\r\n$12\r\nJuan\r\nPérez\r\n", "+OK\r\n")
+	roundTrip(t, conn, "*2\r\n$3\r\nGET\r\n$4\r\nuser\r\n", "$12\r\nJuan\r\nPérez\r\n")
+	roundTrip(t, conn, "*2\r\n$3\r\nTTL\r\n$4\r\nuser\r\n", ":-1\r\n")
+	roundTrip(t, conn, "*3\r\n$6\r\nEXISTS\r\n$4\r\nuser\r\n$4\r\nnope\r\n", ":1\r\n")
+	roundTrip(t, conn, "*2\r\n$3\r\nDEL\r\n$4\r\nuser\r\n", ":1\r\n")
+	roundTrip(t, conn, "*2\r\n$3\r\nGET\r\n$4\r\nuser\r\n", "$-1\r\n")
+	roundTrip(t, conn, "*1\r\n$4\r\nNOPE\r\n", "-ERR comando desconocido 'NOPE'\r\n")
+	roundTrip(t, conn, "*1\r\n$3\r\nGET\r\n", "-ERR número de argumentos incorrecto para 'get'\r\n")
+}
+
+// TestSetOptions prueba SET con EX, NX y XX
+func TestSetOptions(t *testing.T) {
+	engine, conn := startServer(t)
+
+	roundTrip(t, conn, "SET a 1 XX\r\n", "$-1\r\n")
+	roundTrip(t, conn, "SET a 1 NX EX 60\r\n", "+OK\r\n")
+	roundTrip(t, conn, "SET a 2 NX\r\n", "$-1\r\n")
+
+	ttl, _ := engine.TTL("a")
+	if ttl <= 0 || ttl > 60 {
+		t.Errorf("TTL inesperado: %d", ttl)
+	}
+
+	roundTrip(t, conn, "SET a 1 EX 0\r\n", "-ERR tiempo de expiración inválido en 'set'\r\n")
+}
+
+// TestMultiKeyCommands prueba MSET, MSETNX, MGET y DEL con varias claves
+func TestMultiKeyCommands(t *testing.T) {
+	_, conn := startServer(t)
+
+	roundTrip(t, conn, "MSET a 1 b 2\r\n", "+OK\r\n")
+	roundTrip(t, conn, "MSETNX b 3 c 3\r\n", ":0\r\n")
+	roundTrip(t, conn, "MGET a b c\r\n", "*3\r\n$1\r\n1\r\n$1\r\n2\r\n$-1\r\n")
+	roundTrip(t, conn, "MSETNX c 3 d 4\r\n", ":1\r\n")
+	roundTrip(t, conn, "DEL a c nope\r\n", ":2\r\n")
+	roundTrip(t, conn, "MSETNX a\r\n", "-ERR número de argumentos incorrecto para 'msetnx'\r\n")
+}
+
+// TestCommandTable prueba COMMAND INFO, HELP y los comandos registrados
+func TestCommandTable(t *testing.T) {
+	_, conn := startServer(t)
+
+	roundTrip(t, conn, "COMMAND INFO get nope\r\n",
+		"*2\r\n*7\r\n$3\r\nget\r\n:2\r\n*1\r\n+readonly\r\n:1\r\n:1\r\n:1\r\n*1\r\n+@read\r\n$-1\r\n")
+	roundTrip(t, conn, "COMMAND INFO client\r\n",
+		"*1\r\n*7\r\n$6\r\nclient\r\n:-2\r\n*1\r\n+connection\r\n:0\r\n:0\r\n:0\r\n*1\r\n+@connection\r\n")
+	roundTrip(t, conn, "HELP ttl\r\n", "$97\r\nTTL key\r\n  Segundos hasta la expiración (-1 sin expiración, -2 si no existe)\r\n  flags: readonly\r\n")
+	roundTrip(t, conn, "HELP nope\r\n", "-ERR comando desconocido 'nope'\r\n")
+
+	// Los comandos que acceden a archivos del servidor solo existen en la CLI local
+	roundTrip(t, conn, "SAVE /tmp/x\r\n", "-ERR comando desconocido 'SAVE'\r\n")
+	// y COMMAND no los anuncia
+	roundTrip(t, conn, "COMMAND INFO save disablelog load\r\n", "*3\r\n$-1\r\n$-1\r\n$-1\r\n")
+	roundTrip(t, conn, "COMMAND DOCS enablelog\r\n", "*0\r\n")
+	network := 0
+	for _, cmd := range commands.Commands() {
+		if cmd.Flags&command.FlagLocal == 0 {
+			network++
+		}
+	}
+	roundTrip(t, conn, "COMMAND COUNT\r\n", ":"+strconv.Itoa(network)+"\r\n")
+
+	// Comandos que antes solo tenía la CLI
+	roundTrip(t, conn, "MSET a 1 b 2\r\n", "+OK\r\n")
+	roundTrip(t, conn, "MDEL a b c\r\n", ":2\r\n")
+	roundTrip(t, conn, "DBLIMIT x\r\n", "-ERR el límite debe ser un número no negativo\r\n")
+}
+
+// TestPipelining prueba varios comandos enviados en un solo write
+func TestPipelining(t *testing.T) {
+	_, conn := startServer(t)
+
+	request := strings.Repeat("*3\r\n$3\r\nSET\r\n$1\r\nk\r\n$1\r\nv\r\n", 3) + "*2\r\n$3\r\nGET\r\n$1\r\nk\r\n"
+	roundTrip(t, conn, request, "+OK\r\n+OK\r\n+OK\r\n$1\r\nv\r\n")
+}
+
+// TestSelectPerConnection prueba que SELECT es estado de la conexión
+func TestSelectPerConnection(t *testing.T) {
+	engine, conn := startServer(t)
+
+	roundTrip(t, conn, "SELECT 1\r\n", "+OK\r\n")
+	roundTrip(t, conn, "SET k v\r\n", "+OK\r\n")
+	roundTrip(t, conn, "DBSIZE\r\n", ":1\r\n")
+
+	if _, exists := engine.Get("k"); exists {
+		t.Error("La clave no debería estar en el namespace por defecto")
+	}
+	if _, exists := engine.Namespace("1").Get("k"); !exists {
+		t.Error("La clave debería estar en el namespace 1")
+	}
+}
+
+// TestHelloResp3 prueba la negociación de RESP3
+func TestHelloResp3(t *testing.T) {
+	_, conn := startServer(t)
+
+	io.WriteString(conn, "HELLO 3\r\n")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+	line, _ := reader.ReadString('\n')
+	if line != "%7\r\n" {
+		t.Fatalf("Esperaba un mapa RESP3, obtuve %q", line)
+	}
+
+	// Consumir los 7 pares del mapa
+	for i := 0; i < 14; i++ {
+		line, _ := reader.ReadString('\n')
+		if strings.HasPrefix(line, "$") {
+			reader.ReadString('\n')
+		} else if strings.HasPrefix(line, "*") {
+			continue
+		}
+	}
+
+	io.WriteString(conn, "GET missing\r\n")
+	if line, _ := reader.ReadString('\n'); line != "_\r\n" {
+		t.Errorf("Esperaba el nulo de RESP3, obtuve %q", line)
+	}
+
+	io.WriteString(conn, "HELLO 4\r\n")
+	if line, _ := reader.ReadString('\n'); !strings.HasPrefix(line, "-NOPROTO") {
+		t.Errorf("Esperaba NOPROTO, obtuve %q", line)
+	}
+}
+
+// hello3 cambia la conexión a RESP3 y descarta la respuesta de HELLO
+func hello3(t *testing.T, conn net.Conn) {
+	t.Helper()
+
+	io.WriteString(conn, "HELLO 3\r\n")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	reader := bufio.NewReader(conn)
+	for i := 0; i < 15; i++ {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatalf("Error al leer HELLO: %v", err)
+		}
+		if strings.HasPrefix(line, "$") {
+			reader.ReadString('\n')
+		}
+	}
+	if reader.Buffered() != 0 {
+		t.Fatal("Datos inesperados tras HELLO")
+	}
+}
+
+// dial abre otra conexión al servidor de conn
+func dial(t *testing.T, conn net.Conn) net.Conn {
+	t.Helper()
+
+	other, err := net.Dial("tcp", conn.RemoteAddr().String())
+	if err != nil {
+		t.Fatalf("No se pudo conectar: %v", err)
+	}
+	t.Cleanup(func() { other.Close() })
+	return other
+}
+
+// TestClientTracking prueba las invalidaciones por claves leídas
+func TestClientTracking(t *testing.T) {
+	engine, conn := startServer(t)
+	engine.Set("user", "Juan")
+
+	roundTrip(t, conn, "CLIENT TRACKING ON\r\n", "-ERR CLIENT TRACKING requiere RESP3 (HELLO 3) o REDIRECT\r\n")
+	hello3(t, conn)
+	roundTrip(t, conn, "CLIENT TRACKING ON\r\n", "+OK\r\n")
+	roundTrip(t, conn, "GET user\r\n", "$4\r\nJuan\r\n")
+
+	// Solo se invalidan las claves leídas
+	engine.Set("other", "x")
+	engine.Set("user", "Ana")
+	roundTrip(t, conn, "", ">2\r\n$10\r\ninvalidate\r\n*1\r\n$4\r\nuser\r\n")
+
+	// FLUSHDB invalida todas las claves con un nulo
+	roundTrip(t, conn, "GET user\r\n", "$3\r\nAna\r\n")
+	roundTrip(t, conn, "FLUSHDB\r\n", "+OK\r\n>2\r\n$10\r\ninvalidate\r\n_\r\n")
+
+	roundTrip(t, conn, "CLIENT TRACKING OFF\r\n", "+OK\r\n")
+	roundTrip(t, conn, "CLIENT TRACKING ON PREFIX a\r\n", "-ERR PREFIX solo puede usarse en modo BCAST\r\n")
+}
+
+// TestClientTrackingBcastAndRedirect prueba el modo BCAST y REDIRECT
+func TestClientTrackingBcastAndRedirect(t *testing.T) {
+	engine, conn := startServer(t)
+
+	hello3(t, conn)
+	roundTrip(t, conn, "CLIENT TRACKING ON BCAST PREFIX sess:\r\n", "+OK\r\n")
+	engine.Set("user:1", "x")
+	engine.Set("sess:1", "x")
+	roundTrip(t, conn, "", ">2\r\n$10\r\ninvalidate\r\n*1\r\n$6\r\nsess:1\r\n")
+
+	// Una conexión RESP2 redirige sus invalidaciones a la primera
+	roundTrip(t, conn, "CLIENT TRACKING OFF\r\n", "+OK\r\n")
+	io.WriteString(conn, "CLIENT ID\r\n")
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("CLIENT ID inesperado: %q %v", line, err)
+	}
+	id := strings.TrimSpace(line[1:])
+
+	other := dial(t, conn)
+	roundTrip(t, other, "CLIENT TRACKING ON REDIRECT 999\r\n", "-ERR el cliente de REDIRECT no existe\r\n")
+
+	// Un destinatario RESP2 no puede recibir los push de invalidación
+	io.WriteString(other, "CLIENT ID\r\n")
+	other.SetReadDeadline(time.Now().Add(2 * time.Second))
+	line, err = bufio.NewReader(other).ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, ":") {
+		t.Fatalf("CLIENT ID inesperado: %q %v", line, err)
+	}
+	roundTrip(t, conn, "CLIENT TRACKING ON REDIRECT "+strings.TrimSpace(line[1:])+"\r\n",
+		"-ERR el cliente de REDIRECT debe usar RESP3 (HELLO 3)\r\n")
+
+	roundTrip(t, other, "CLIENT TRACKING ON REDIRECT "+id+"\r\n", "+OK\r\n")
+	roundTrip(t, other, "GET user:1\r\n", "$1\r\nx\r\n")
+	engine.Delete("user:1")
+	roundTrip(t, conn, "", ">2\r\n$10\r\ninvalidate\r\n*1\r\n$6\r\nuser:1\r\n")
+}
+
+// TestAuthAndACL prueba AUTH, los errores NOAUTH/NOPERM y ACL SETUSER
+func TestAuthAndACL(t *testing.T) {
+	users := acl.NewStore()
+	users.SetUser(acl.DefaultUser, "resetpass", ">admin")
+	_, conn := startServerWith(t, Config{ACL: users})
+
+	roundTrip(t, conn, "GET k\r\n", "-NOAUTH autenticación requerida\r\n")
+	roundTrip(t, conn, "AUTH mala\r\n", "-WRONGPASS usuario o contraseña inválidos, o usuario deshabilitado\r\n")
+	roundTrip(t, conn, "AUTH admin\r\n", "+OK\r\n")
+	roundTrip(t, conn, "ACL WHOAMI\r\n", "$7\r\ndefault\r\n")
+	roundTrip(t, conn, "ACL SETUSER app on >pw ~app:* +@read +@write -flushdb\r\n", "+OK\r\n")
+	roundTrip(t, conn, "ACL SETUSER app +@nope\r\n", "-ERR error en la regla '+@nope': categoría desconocida 'nope'\r\n")
+
+	other := dial(t, conn)
+	roundTrip(t, other, "AUTH app pw\r\n", "+OK\r\n")
+	roundTrip(t, other, "SET app:1 v\r\n", "+OK\r\n")
+	roundTrip(t, other, "MGET app:1 other\r\n", "-NOPERM permiso denegado: el usuario 'app' no puede acceder a la clave 'other'\r\n")
+	roundTrip(t, other, "FLUSHDB\r\n", "-NOPERM permiso denegado: el usuario 'app' no puede ejecutar 'flushdb'\r\n")
+
+	// Al eliminar el usuario sus conexiones deben autenticarse de nuevo
+	roundTrip(t, conn, "ACL DELUSER app\r\n", ":1\r\n")
+	roundTrip(t, other, "GET app:1\r\n", "-NOAUTH autenticación requerida\r\n")
+	roundTrip(t, other, "HELLO 3\r\n", "-NOAUTH HELLO requiere una conexión autenticada o la opción AUTH\r\n")
+}
@@ -0,0 +1,332 @@
+package resp
+
+import (
+	"cache-engine/internal/cache"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// maxTrackedKeys limita las claves recordadas en modo por defecto. Al
+	// superarlo se invalida todo y se empieza de cero.
+	maxTrackedKeys = 1 << 20
+
+	// trackingBuffer es el tamaño del buffer de eventos del motor
+	trackingBuffer = 4096
+
+	// pushTimeout limita la escritura de una invalidación a un cliente lento
+	pushTimeout = 5 * time.Second
+)
+
+// bcastTracking es el registro de un cliente en modo BCAST
+type bcastTracking struct {
+	target    int64    // Conexión que recibe las invalidaciones
+	namespace string   // Namespace seleccionado al activar el seguimiento
+	prefixes  []string // Vacío = todas las claves
+}
+
+// tracking implementa CLIENT TRACKING: recuerda las claves leídas por cada
+// conexión (o los prefijos en modo BCAST) y envía un push "invalidate"
+// cuando cambian, expiran o se expulsan.
+type tracking struct {
+	once   sync.Once
+	cancel func() // Cancela la suscripción a eventos del motor
+
+	mu      sync.Mutex
+	keys    map[string]map[string]map[int64]struct{} // namespace → clave → destinatarios
+	count   int                                      // Claves registradas en keys
+	bcast   map[int64]bcastTracking                  // Por id de cliente
+	dropped int64                                    // Último valor de DroppedEvents
+}
+
+// startTracking se suscribe a los eventos del motor la primera vez que un
+// cliente activa el seguimiento
+func (s *Server) startTracking() {
+	t := &s.tracking
+	t.once.Do(func() {
+		t.mu.Lock()
+		t.dropped = s.engine.DroppedEvents()
+		t.mu.Unlock()
+
+		events, cancel := s.engine.Subscribe(trackingBuffer)
+		t.cancel = cancel
+		go s.runTracking(events)
+	})
+}
+
+// stopTracking cancela la suscripción a eventos, si existe
+func (s *Server) stopTracking() {
+	t := &s.tracking
+	// Consumir el once impide suscribirse después de cerrar
+	t.once.Do(func() {})
+	if t.cancel != nil {
+		t.cancel()
+	}
+}
+
+// trackKey registra que el cliente leyó una clave (modo por defecto)
+func (s *Server) trackKey(c *client, key string) {
+	if !c.tracking || c.bcast {
+		return
+	}
+
+	t := &s.tracking
+	ns := c.db.Name()
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.count >= maxTrackedKeys {
+		targets := t.allTargets()
+		t.keys = make(map[string]map[string]map[int64]struct{})
+		t.count = 0
+		go s.invalidate(targets, nil)
+	}
+
+	byKey, exists := t.keys[ns]
+	if !exists {
+		byKey = make(map[string]map[int64]struct{})
+		t.keys[ns] = byKey
+	}
+	targets, exists := byKey[key]
+	if !exists {
+		targets = make(map[int64]struct{})
+		byKey[key] = targets
+		t.count++
+	}
+	targets[c.trackTarget] = struct{}{}
+}
+
+// allTargets retorna todos los destinatarios registrados. Requiere t.mu tomado.
+func (t *tracking) allTargets() map[int64]struct{} {
+	all := make(map[int64]struct{})
+	for _, byKey := range t.keys {
+		for _, targets := range byKey {
+			for id := range targets {
+				all[id] = struct{}{}
+			}
+		}
+	}
+	for _, b := range t.bcast {
+		all[b.target] = struct{}{}
+	}
+	return all
+}
+
+// untrack elimina el registro BCAST de un cliente. Las claves leídas en
+// modo por defecto se descartan al invalidarse.
+func (s *Server) untrack(c *client) {
+	t := &s.tracking
+	t.mu.Lock()
+	delete(t.bcast, c.id)
+	t.mu.Unlock()
+}
+
+// runTracking traduce los eventos del motor en invalidaciones
+func (s *Server) runTracking(events <-chan cache.Event) {
+	t := &s.tracking
+	for event := range events {
+		t.mu.Lock()
+
+		// Si se perdieron eventos no se sabe qué claves cambiaron:
+		// invalidar todo para mantener la coherencia de los clientes
+		if dropped := s.engine.DroppedEvents(); dropped != t.dropped {
+			t.dropped = dropped
+			targets := t.allTargets()
+			t.keys = make(map[string]map[string]map[int64]struct{})
+			t.count = 0
+			t.mu.Unlock()
+			s.invalidate(targets, nil)
+			continue
+		}
+
+		targets := make(map[int64]struct{})
+		byKey := t.keys[event.Namespace]
+
+		if event.Type == cache.EventFlush {
+			for _, ids := range byKey {
+				for id := range ids {
+					targets[id] = struct{}{}
+				}
+			}
+			t.count -= len(byKey)
+			delete(t.keys, event.Namespace)
+		} else if ids, exists := byKey[event.Key]; exists {
+			// Como en Redis, una clave se invalida una sola vez: el cliente
+			// vuelve a registrarla al leerla de nuevo
+			targets = ids
+			delete(byKey, event.Key)
+			t.count--
+		}
+
+		for _, b := range t.bcast {
+			if b.namespace == event.Namespace && (event.Type == cache.EventFlush || matchesPrefix(event.Key, b.prefixes)) {
+				targets[b.target] = struct{}{}
+			}
+		}
+		t.mu.Unlock()
+
+		if event.Type == cache.EventFlush {
+			s.invalidate(targets, nil)
+		} else {
+			s.invalidate(targets, []string{event.Key})
+		}
+	}
+}
+
+// matchesPrefix indica si la clave empieza por alguno de los prefijos
+func matchesPrefix(key string, prefixes []string) bool {
+	if len(prefixes) == 0 {
+		return true
+	}
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// invalidate envía un push "invalidate" a cada destinatario. keys nil
+// indica que se invalidan todas las claves.
+func (s *Server) invalidate(targets map[int64]struct{}, keys []string) {
+	for id := range targets {
+		s.mu.Lock()
+		c, exists := s.clients[id]
+		s.mu.Unlock()
+		if !exists {
+			continue
+		}
+
+		c.wmu.Lock()
+		if c.writer.Protocol() < 3 {
+			c.wmu.Unlock()
+			continue
+		}
+		c.writer.WritePush(2)
+		c.writer.WriteBulkString("invalidate")
+		if keys == nil {
+			c.writer.WriteNull()
+		} else {
+			c.writer.WriteArray(len(keys))
+			for _, key := range keys {
+				c.writer.WriteBulkString(key)
+			}
+		}
+		timeout := pushTimeout
+		if s.config.OutputTimeout > 0 {
+			timeout = s.config.OutputTimeout
+		}
+		if err := s.flush(c, timeout); err != nil {
+			c.conn.Close()
+		}
+		c.wmu.Unlock()
+	}
+}
+
+// cmdClientTracking implementa
+// CLIENT TRACKING ON|OFF [REDIRECT id] [BCAST] [PREFIX prefijo]...
+func cmdClientTracking(s *Server, c *client, args [][]byte) {
+	if len(args) < 3 {
+		c.writer.WriteError("ERR número de argumentos incorrecto para 'client tracking'")
+		return
+	}
+
+	switch strings.ToUpper(string(args[2])) {
+	case "OFF":
+		c.tracking, c.bcast = false, false
+		s.untrack(c)
+		c.writer.WriteSimple("OK")
+		return
+	case "ON":
+	default:
+		c.writer.WriteError("ERR error de sintaxis")
+		return
+	}
+
+	target := c.id
+	bcast := false
+	var prefixes []string
+	for i := 3; i < len(args); i++ {
+		switch strings.ToUpper(string(args[i])) {
+		case "BCAST":
+			bcast = true
+		case "PREFIX":
+			if i+1 >= len(args) {
+				c.writer.WriteError("ERR error de sintaxis")
+				return
+			}
+			prefixes = append(prefixes, string(args[i+1]))
+			i++
+		case "REDIRECT":
+			if i+1 >= len(args) {
+				c.writer.WriteError("ERR error de sintaxis")
+				return
+			}
+			id, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil {
+				c.writer.WriteError("ERR id de cliente inválido")
+				return
+			}
+			target = id
+			i++
+		default:
+			c.writer.WriteError(fmt.Sprintf("ERR opción desconocida '%s' en CLIENT TRACKING", args[i]))
+			return
+		}
+	}
+
+	if len(prefixes) > 0 && !bcast {
+		c.writer.WriteError("ERR PREFIX solo puede usarse en modo BCAST")
+		return
+	}
+
+	// Las invalidaciones son mensajes push: el destinatario debe usar RESP3
+	if target == c.id {
+		if c.writer.Protocol() < 3 {
+			c.writer.WriteError("ERR CLIENT TRACKING requiere RESP3 (HELLO 3) o REDIRECT")
+			return
+		}
+	} else {
+		s.mu.Lock()
+		receiver, exists := s.clients[target]
+		s.mu.Unlock()
+		if !exists {
+			c.writer.WriteError("ERR el cliente de REDIRECT no existe")
+			return
+		}
+		// Sin pub/sub, un destinatario RESP2 no recibiría nada
+		if !receiver.resp3.Load() {
+			c.writer.WriteError("ERR el cliente de REDIRECT debe usar RESP3 (HELLO 3)")
+			return
+		}
+		keepAlive(receiver)
+	}
+
+	s.startTracking()
+
+	c.tracking, c.bcast, c.trackTarget = true, bcast, target
+	if target == c.id {
+		keepAlive(c)
+	}
+	t := &s.tracking
+	t.mu.Lock()
+	if bcast {
+		t.bcast[c.id] = bcastTracking{target: target, namespace: c.db.Name(), prefixes: prefixes}
+	} else {
+		delete(t.bcast, c.id)
+	}
+	t.mu.Unlock()
+
+	c.writer.WriteSimple("OK")
+}
+
+// keepAlive exime de IdleTimeout a una conexión que recibe invalidaciones:
+// puede pasar mucho tiempo sin enviar comandos
+func keepAlive(c *client) {
+	c.keepAlive.Store(true)
+	c.conn.SetReadDeadline(time.Time{})
+}
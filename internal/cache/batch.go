@@ -0,0 +1,158 @@
+package cache
+
+import "sort"
+
+// Operaciones sobre varias claves que toman el lock una sola vez
+
+// MGet obtiene varios valores del namespace. found[i] indica si keys[i] existe.
+func (n *Namespace) MGet(keys ...string) (values []interface{}, found []bool) {
+	return n.engine.mget(n.name, keys)
+}
+
+// MSet almacena varios valores en el namespace. Si el lote no cabe en la
+// cuota no se escribe ninguna clave y se retorna ErrQuotaExceeded.
+func (n *Namespace) MSet(entries map[string]interface{}) error {
+	_, err := n.engine.mset(n.name, entries, SetAlways, 0)
+	return err
+}
+
+// MSetEx almacena varios valores que expiran en seconds segundos, con los
+// mismos límites que MSet. Valores y expiraciones se aplican en una sola
+// operación.
+func (n *Namespace) MSetEx(entries map[string]interface{}, seconds int) error {
+	_, err := n.engine.mset(n.name, entries, SetAlways, deadline(seconds))
+	return err
+}
+
+// MSetNX almacena varios valores solo si ninguna de las claves existe.
+// Retorna false sin escribir nada si alguna ya existía.
+func (n *Namespace) MSetNX(entries map[string]interface{}) (bool, error) {
+	return n.engine.mset(n.name, entries, SetIfAbsent, 0)
+}
+
+// MDelete elimina varias claves del namespace y retorna cuántas existían
+func (n *Namespace) MDelete(keys ...string) int {
+	return n.engine.mdelete(n.name, keys)
+}
+
+// MGet obtiene varios valores del namespace por defecto
+func (c *CacheEngine) MGet(keys ...string) ([]interface{}, []bool) {
+	return c.mget(DefaultNamespace, keys)
+}
+
+// MSet almacena varios valores en el namespace por defecto
+func (c *CacheEngine) MSet(entries map[string]interface{}) error {
+	_, err := c.mset(DefaultNamespace, entries, SetAlways, 0)
+	return err
+}
+
+// MSetEx almacena varios valores con expiración en el namespace por defecto
+func (c *CacheEngine) MSetEx(entries map[string]interface{}, seconds int) error {
+	_, err := c.mset(DefaultNamespace, entries, SetAlways, deadline(seconds))
+	return err
+}
+
+// MSetNX almacena varios valores en el namespace por defecto si ninguno existe
+func (c *CacheEngine) MSetNX(entries map[string]interface{}) (bool, error) {
+	return c.mset(DefaultNamespace, entries, SetIfAbsent, 0)
+}
+
+// MDelete elimina varias claves del namespace por defecto
+func (c *CacheEngine) MDelete(keys ...string) int {
+	return c.mdelete(DefaultNamespace, keys)
+}
+
+// mget obtiene varios valores de un namespace
+func (c *CacheEngine) mget(ns string, keys []string) ([]interface{}, []bool) {
+	values := make([]interface{}, len(keys))
+	found := make([]bool, len(keys))
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.find(ns)
+	for i, key := range keys {
+		values[i], found[i] = c.lookup(ks, key)
+	}
+	return values, found
+}
+
+// mset escribe un lote completo o nada, que expira en expiresAt (0 =
+// nunca). Con SetIfAbsent falla (false) si alguna clave existe.
+func (c *CacheEngine) mset(ns string, entries map[string]interface{}, cond SetCondition, expiresAt int64) (bool, error) {
+	// Orden fijo para que la expulsión sea determinista
+	keys := make([]string, 0, len(entries))
+	for key := range entries {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.space(ns)
+	if cond == SetIfAbsent {
+		for _, key := range keys {
+			if _, live := c.liveEntry(ks, key); live {
+				return false, nil
+			}
+		}
+	}
+
+	if err := c.checkBatch(ks, keys, entries); err != nil {
+		return false, err
+	}
+
+	for _, key := range keys {
+		if _, err := c.store(ks, key, entries[key], SetAlways, expiresAt); err != nil {
+			// checkBatch garantiza que el lote cabe: no debería ocurrir
+			return false, err
+		}
+	}
+	return true, nil
+}
+
+// checkBatch comprueba que un lote cabe entero en el namespace antes de
+// escribirlo, para que mset sea todo o nada. Requiere c.mu tomado.
+func (c *CacheEngine) checkBatch(ks *keyspace, keys []string, entries map[string]interface{}) error {
+	added := 0
+	var delta, total int64
+	for _, key := range keys {
+		size := entrySize(key, entries[key])
+		total += size
+		delta += size
+		if old, exists := ks.data[key]; exists {
+			delta -= old.size
+		} else {
+			added++
+		}
+	}
+
+	// Con expulsión, las claves antiguas dejan sitio; las del propio lote
+	// son las más recientes y no se expulsan si el lote entero cabe
+	rejected := (ks.quota.MaxEntries > 0 && len(keys) > ks.quota.MaxEntries) ||
+		(ks.quota.MaxBytes > 0 && total > ks.quota.MaxBytes) ||
+		(ks.quota.Reject && ks.overQuota(added, delta)) ||
+		(c.maxEntries > 0 && len(keys) > c.maxEntries)
+
+	if rejected {
+		ks.rejections++
+		return ErrQuotaExceeded
+	}
+	return nil
+}
+
+// mdelete elimina varias claves de un namespace
+func (c *CacheEngine) mdelete(ns string, keys []string) int {
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.find(ns)
+	deleted := 0
+	for _, key := range keys {
+		if c.remove(ks, key) {
+			deleted++
+		}
+	}
+	return deleted
+}
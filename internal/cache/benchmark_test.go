@@ -1,6 +1,7 @@
 package cache
 
 import (
+	"context"
 	"fmt"
 	"testing"
 )
@@ -8,7 +9,7 @@ import (
 // BenchmarkSet mide el rendimiento de escrituras
 func BenchmarkSet(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -20,7 +21,7 @@ func BenchmarkSet(b *testing.B) {
 // BenchmarkGet mide el rendimiento de lecturas
 func BenchmarkGet(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Pre-poblar el cache
 	for i := 0; i < 1000; i++ {
@@ -38,7 +39,7 @@ func BenchmarkGet(b *testing.B) {
 // BenchmarkSetGet mide lecturas y escrituras mixtas
 func BenchmarkSetGet(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
@@ -55,7 +56,7 @@ func BenchmarkSetGet(b *testing.B) {
 // BenchmarkConcurrentSet mide escrituras concurrentes
 func BenchmarkConcurrentSet(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
@@ -71,7 +72,7 @@ func BenchmarkConcurrentSet(b *testing.B) {
 // BenchmarkConcurrentGet mide lecturas concurrentes
 func BenchmarkConcurrentGet(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Pre-poblar el cache
 	for i := 0; i < 1000; i++ {
@@ -93,7 +94,7 @@ func BenchmarkConcurrentGet(b *testing.B) {
 // BenchmarkConcurrentSetGet mide lecturas y escrituras concurrentes
 func BenchmarkConcurrentSetGet(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	b.ResetTimer()
 	b.RunParallel(func(pb *testing.PB) {
@@ -114,7 +115,7 @@ func BenchmarkConcurrentSetGet(b *testing.B) {
 // BenchmarkDelete mide el rendimiento de eliminaciones
 func BenchmarkDelete(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Pre-poblar el cache
 	for i := 0; i < b.N; i++ {
@@ -132,7 +133,7 @@ func BenchmarkDelete(b *testing.B) {
 // BenchmarkExpire mide el rendimiento de establecer expiraciones
 func BenchmarkExpire(b *testing.B) {
 	cache := NewCacheEngine(10000)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Pre-poblar el cache
 	for i := 0; i < 1000; i++ {
@@ -1,24 +1,133 @@
 package cache
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"sort"
 	"sync"
 	"time"
 )
 
+// DefaultNamespace es el namespace usado cuando no se selecciona ninguno
+const DefaultNamespace = "0"
+
+// ErrQuotaExceeded indica que una escritura superaría la cuota del namespace
+var ErrQuotaExceeded = errors.New("cuota del namespace excedida")
+
+// EvictionPolicy define cómo se elige la víctima al alcanzar el límite global
+type EvictionPolicy int
+
+const (
+	// EvictionLRU expulsa la entrada menos usada de todo el motor
+	EvictionLRU EvictionPolicy = iota
+	// EvictionFair expulsa del namespace que más excede su parte del límite global
+	EvictionFair
+)
+
+// String retorna el nombre de la política
+func (p EvictionPolicy) String() string {
+	switch p {
+	case EvictionFair:
+		return "fair"
+	default:
+		return "lru"
+	}
+}
+
+// ParseEvictionPolicy convierte un nombre ("lru", "fair") en una política
+func ParseEvictionPolicy(name string) (EvictionPolicy, error) {
+	switch name {
+	case "lru", "LRU":
+		return EvictionLRU, nil
+	case "fair", "FAIR":
+		return EvictionFair, nil
+	}
+	return EvictionLRU, fmt.Errorf("política de expulsión desconocida: %s", name)
+}
+
+// SetCondition restringe cuándo se aplica una escritura
+type SetCondition int
+
+const (
+	// SetAlways escribe siempre
+	SetAlways SetCondition = iota
+	// SetIfAbsent escribe solo si la clave no existe (NX)
+	SetIfAbsent
+	// SetIfPresent escribe solo si la clave ya existe (XX)
+	SetIfPresent
+)
+
+// Quota define los límites propios de un namespace
+type Quota struct {
+	MaxEntries int   // Máximo de entradas (0 = sin límite propio)
+	MaxBytes   int64 // Máximo de bytes estimados (0 = sin límite propio)
+	Reject     bool  // Retornar ErrQuotaExceeded en lugar de expulsar
+}
+
 // CacheEntry representa un valor almacenado en el cache
 type CacheEntry struct {
 	Value      interface{} // Valor almacenado
 	ExpiresAt  int64       // Timestamp de expiración (0 = sin expiración)
 	LastAccess int64       // Timestamp del último acceso (para LRU)
+	size       int64       // Tamaño estimado en bytes (clave + valor)
+	cas        uint64      // Versión de la entrada (cambia con cada escritura)
+}
+
+// keyspace agrupa las entradas y estadísticas de un namespace
+type keyspace struct {
+	name        string                 // Nombre del namespace
+	data        map[string]*CacheEntry // Almacenamiento clave-valor del namespace
+	quota       Quota                  // Límites propios (cero = solo aplica el límite global)
+	bytes       int64                  // Bytes estimados ocupados
+	hits        int64                  // Lecturas con resultado
+	misses      int64                  // Lecturas sin resultado
+	sets        int64                  // Escrituras realizadas
+	deletes     int64                  // Claves eliminadas con DEL
+	evictions   int64                  // Entradas expulsadas por LRU
+	expirations int64                  // Entradas eliminadas por expiración
+	rejections  int64                  // Escrituras rechazadas por cuota
+}
+
+// newKeyspace crea un namespace vacío
+func newKeyspace(name string) *keyspace {
+	return &keyspace{name: name, data: make(map[string]*CacheEntry)}
+}
+
+// NamespaceStats resume el estado de un namespace
+type NamespaceStats struct {
+	Name        string // Nombre del namespace
+	Keys        int    // Número de entradas
+	Bytes       int64  // Bytes estimados ocupados
+	MaxEntries  int    // Límite propio (0 = sin límite propio)
+	MaxBytes    int64  // Cuota de bytes (0 = sin cuota)
+	Hits        int64  // Lecturas con resultado
+	Misses      int64  // Lecturas sin resultado
+	Sets        int64  // Escrituras realizadas
+	Deletes     int64  // Claves eliminadas con DEL
+	Evictions   int64  // Entradas expulsadas por LRU
+	Expirations int64  // Entradas eliminadas por expiración
+	Rejections  int64  // Escrituras rechazadas por cuota
 }
 
 // CacheEngine es el motor principal del cache
 type CacheEngine struct {
-	data       map[string]*CacheEntry // Almacenamiento clave-valor
-	mu         sync.RWMutex           // Mutex para concurrencia segura
-	maxEntries int                    // Límite máximo de entradas (para LRU)
-	stopClean  chan bool              // Canal para detener el barrido periódico
-	logFile    string                 // Archivo de log para persistencia (opcional)
+	namespaces map[string]*keyspace // Namespaces lógicos (bases de datos)
+	mu         sync.RWMutex         // Mutex para concurrencia segura
+	maxEntries int                  // Límite máximo de entradas entre todos los namespaces (para LRU)
+	policy     EvictionPolicy       // Política de expulsión al alcanzar maxEntries
+	stopClean  chan bool            // Canal para detener el barrido periódico
+	cleanDone  chan struct{}        // Se cierra cuando termina el barrido periódico
+	cleanEvery chan time.Duration   // Canal para cambiar la frecuencia del barrido
+	closeOnce  sync.Once            // Close solo detiene el barrido una vez
+	journal    Journal              // Destino de las modificaciones (nil = ninguno)
+	pending    []Mutation           // Modificaciones de la operación en curso
+	startTime  time.Time            // Instante de creación (para uptime)
+	casCounter uint64               // Última versión asignada a una entrada
+	misses     int64                // Lecturas en namespaces que no existen
+	commands   commandStats         // Histogramas de latencia por comando
+	watchers   watchers             // Suscriptores a eventos del espacio de claves
 }
 
 // NewCacheEngine crea una nueva instancia del motor de cache
@@ -28,9 +137,12 @@ func NewCacheEngine(maxEntries int) *CacheEngine {
 	}
 
 	cache := &CacheEngine{
-		data:       make(map[string]*CacheEntry),
+		namespaces: map[string]*keyspace{DefaultNamespace: newKeyspace(DefaultNamespace)},
 		maxEntries: maxEntries,
 		stopClean:  make(chan bool),
+		cleanDone:  make(chan struct{}),
+		cleanEvery: make(chan time.Duration),
+		startTime:  time.Now(),
 	}
 
 	// Iniciar barrido periódico de claves expiradas
@@ -39,126 +151,681 @@ func NewCacheEngine(maxEntries int) *CacheEngine {
 	return cache
 }
 
-// Set almacena un valor en el cache
-func (c *CacheEngine) Set(key string, value interface{}) {
+// Namespace representa un espacio de claves lógico dentro del motor.
+// Todas las operaciones comparten el mutex del CacheEngine.
+type Namespace struct {
+	engine *CacheEngine
+	name   string
+}
+
+// Namespace retorna el namespace con el nombre indicado. No se crea hasta
+// la primera escritura, así que leer de uno inexistente no lo añade.
+func (c *CacheEngine) Namespace(name string) *Namespace {
+	if name == "" {
+		name = DefaultNamespace
+	}
+	return &Namespace{engine: c, name: name}
+}
+
+// space obtiene (o crea) el keyspace de un namespace. Requiere c.mu tomado en escritura.
+func (c *CacheEngine) space(name string) *keyspace {
+	ks, exists := c.namespaces[name]
+	if !exists {
+		ks = newKeyspace(name)
+		c.namespaces[name] = ks
+	}
+	return ks
+}
+
+// find obtiene el keyspace de un namespace sin crearlo (nil = no existe).
+// Las lecturas y los borrados lo usan para no crear namespaces vacíos.
+// Requiere c.mu tomado.
+func (c *CacheEngine) find(name string) *keyspace {
+	return c.namespaces[name]
+}
+
+// totalEntries cuenta las entradas de todos los namespaces. Requiere c.mu tomado.
+func (c *CacheEngine) totalEntries() int {
+	total := 0
+	for _, ks := range c.namespaces {
+		total += len(ks.data)
+	}
+	return total
+}
+
+// Name retorna el nombre del namespace
+func (n *Namespace) Name() string {
+	return n.name
+}
+
+// Set almacena un valor en el namespace
+func (n *Namespace) Set(key string, value interface{}) error {
+	return n.engine.set(n.name, key, value)
+}
+
+// SetIf almacena un valor si se cumple la condición. Retorna false si no se escribió.
+func (n *Namespace) SetIf(key string, value interface{}, cond SetCondition) (bool, error) {
+	return n.engine.setIf(n.name, key, value, cond, 0)
+}
+
+// SetEx almacena un valor que expira en seconds segundos (0 = sin
+// expiración) si se cumple la condición. El valor y su expiración se
+// aplican en una sola operación; con seconds negativo la clave expira al
+// momento. Retorna false si no se escribió.
+func (n *Namespace) SetEx(key string, value interface{}, seconds int, cond SetCondition) (bool, error) {
+	return n.engine.setIf(n.name, key, value, cond, deadline(seconds))
+}
+
+// Upsert almacena un valor que expira en seconds segundos (0 = sin
+// expiración) y retorna si la clave no existía, todo en una sola operación
+func (n *Namespace) Upsert(key string, value interface{}, seconds int) (bool, error) {
+	c := n.engine
 	c.mu.Lock()
+	defer c.unlock()
 
-	// Si alcanzamos el límite, ejecutar eviction (LRU)
-	if len(c.data) >= c.maxEntries {
-		c.evictLRU()
+	ks := c.space(n.name)
+	_, existed := c.liveEntry(ks, key)
+	if _, err := c.store(ks, key, value, SetAlways, deadline(seconds)); err != nil {
+		return false, err
 	}
+	return !existed, nil
+}
 
-	now := time.Now().UnixNano() // Usar nanosegundos para mejor precisión
-	c.data[key] = &CacheEntry{
-		Value:      value,
-		ExpiresAt:  0, // Sin expiración por defecto
-		LastAccess: now,
+// Keys retorna las claves vigentes del namespace que coinciden con el patrón
+// (sintaxis de path.Match; vacío o "*" = todas), ordenadas
+func (n *Namespace) Keys(pattern string) []string {
+	return n.engine.keys(n.name, pattern)
+}
+
+// Get obtiene un valor del namespace
+func (n *Namespace) Get(key string) (interface{}, bool) {
+	return n.engine.get(n.name, key)
+}
+
+// GetTTL obtiene un valor junto con sus segundos restantes (-1 = sin
+// expiración), leídos en una sola operación
+func (n *Namespace) GetTTL(key string) (interface{}, int64, bool) {
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.find(n.name)
+	value, exists := c.lookup(ks, key)
+	if !exists {
+		return nil, 0, false
+	}
+	return value, remaining(ks.data[key], time.Now().Unix()), true
+}
+
+// Delete elimina una clave del namespace
+func (n *Namespace) Delete(key string) bool {
+	return n.engine.delete(n.name, key)
+}
+
+// Expire establece un tiempo de expiración para una clave del namespace
+func (n *Namespace) Expire(key string, seconds int) bool {
+	return n.engine.expire(n.name, key, seconds)
+}
+
+// TTL retorna los segundos restantes de una clave del namespace
+func (n *Namespace) TTL(key string) (int64, bool) {
+	return n.engine.ttl(n.name, key)
+}
+
+// Size retorna el número de entradas del namespace
+func (n *Namespace) Size() int {
+	n.engine.mu.RLock()
+	defer n.engine.mu.RUnlock()
+
+	if ks, exists := n.engine.namespaces[n.name]; exists {
+		return len(ks.data)
+	}
+	return 0
+}
+
+// SetMaxEntries establece el límite propio del namespace (0 = sin límite propio)
+func (n *Namespace) SetMaxEntries(maxEntries int) {
+	n.engine.mu.RLock()
+	quota := n.engine.namespaceQuota(n.name)
+	n.engine.mu.RUnlock()
+
+	quota.MaxEntries = maxEntries
+	n.SetQuota(quota)
+}
+
+// Quota retorna la cuota actual del namespace
+func (n *Namespace) Quota() Quota {
+	n.engine.mu.RLock()
+	defer n.engine.mu.RUnlock()
+	return n.engine.namespaceQuota(n.name)
+}
+
+// SetQuota establece la cuota del namespace. Si el contenido actual la
+// supera, se expulsan entradas LRU hasta cumplirla.
+func (n *Namespace) SetQuota(quota Quota) {
+	if quota.MaxEntries < 0 {
+		quota.MaxEntries = 0
+	}
+	if quota.MaxBytes < 0 {
+		quota.MaxBytes = 0
+	}
+
+	n.engine.mu.Lock()
+	defer n.engine.unlock()
+
+	ks := n.engine.space(n.name)
+	ks.quota = quota
+	for ks.overQuota(0, 0) {
+		if !n.engine.evictFrom(ks, "") {
+			break
+		}
+	}
+}
+
+// namespaceQuota retorna la cuota de un namespace. Requiere c.mu tomado.
+func (c *CacheEngine) namespaceQuota(name string) Quota {
+	if ks, exists := c.namespaces[name]; exists {
+		return ks.quota
+	}
+	return Quota{}
+}
+
+// overQuota indica si el namespace superaría su cuota al añadir entries y bytes
+func (ks *keyspace) overQuota(entries int, bytes int64) bool {
+	if ks.quota.MaxEntries > 0 && len(ks.data)+entries > ks.quota.MaxEntries {
+		return true
+	}
+	return ks.quota.MaxBytes > 0 && ks.bytes+bytes > ks.quota.MaxBytes
+}
+
+// Flush elimina todas las claves del namespace
+func (n *Namespace) Flush() {
+	n.engine.mu.Lock()
+	defer n.engine.unlock()
+
+	if ks := n.engine.find(n.name); ks != nil {
+		ks.data = make(map[string]*CacheEntry)
+		ks.bytes = 0
+	}
+	n.engine.logMutation(Mutation{Operation: OpFlushDB, Namespace: n.name})
+	n.engine.notify(EventFlush, n.name, "")
+}
+
+// Move mueve una clave a otro namespace. Falla si la clave no existe
+// en el origen o ya existe en el destino.
+func (n *Namespace) Move(key, target string) bool {
+	if target == "" {
+		target = DefaultNamespace
+	}
+	if target == n.name {
+		return false
+	}
+
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	src := c.find(n.name)
+	if src == nil {
+		return false
+	}
+	entry, exists := src.data[key]
+	if !exists || isExpired(entry, time.Now().Unix()) {
+		return false
+	}
+
+	dst := c.space(target)
+	current, exists := dst.data[key]
+	if exists && !isExpired(current, time.Now().Unix()) {
+		return false
 	}
 
-	// Registrar operación en log si está habilitado
-	logFile := c.logFile
-	c.mu.Unlock()
+	// El total no cambia, pero el destino puede tener una cuota propia
+	var delta int64 = entry.size
+	if exists {
+		delta -= current.size
+	}
+	if err := c.reserve(dst, key, !exists, delta); err != nil {
+		return false
+	}
+
+	delete(src.data, key)
+	src.bytes -= entry.size
+	dst.data[key] = entry
+	dst.bytes += delta
+	c.notify(EventDelete, n.name, key)
+	c.notify(EventSet, target, key)
+	return true
+}
 
-	if logFile != "" {
-		// Importar persistence causaría dependencia circular, así que el logging
-		// se maneja desde el CLI
+// Stats retorna las estadísticas del namespace
+func (n *Namespace) Stats() NamespaceStats {
+	n.engine.mu.RLock()
+	defer n.engine.mu.RUnlock()
+
+	stats := NamespaceStats{Name: n.name}
+	if ks, exists := n.engine.namespaces[n.name]; exists {
+		stats.Keys = len(ks.data)
+		stats.Bytes = ks.bytes
+		stats.MaxEntries = ks.quota.MaxEntries
+		stats.MaxBytes = ks.quota.MaxBytes
+		stats.Hits = ks.hits
+		stats.Misses = ks.misses
+		stats.Sets = ks.sets
+		stats.Deletes = ks.deletes
+		stats.Evictions = ks.evictions
+		stats.Expirations = ks.expirations
+		stats.Rejections = ks.rejections
 	}
+	return stats
 }
 
-// Get obtiene un valor del cache
+// Set almacena un valor en el namespace por defecto
+func (c *CacheEngine) Set(key string, value interface{}) error {
+	return c.set(DefaultNamespace, key, value)
+}
+
+// Get obtiene un valor del namespace por defecto
 func (c *CacheEngine) Get(key string) (interface{}, bool) {
+	return c.get(DefaultNamespace, key)
+}
+
+// Delete elimina una clave del namespace por defecto
+func (c *CacheEngine) Delete(key string) bool {
+	return c.delete(DefaultNamespace, key)
+}
+
+// Expire establece un tiempo de expiración para una clave del namespace por defecto
+func (c *CacheEngine) Expire(key string, seconds int) bool {
+	return c.expire(DefaultNamespace, key, seconds)
+}
+
+// TTL retorna los segundos restantes de una clave del namespace por defecto
+func (c *CacheEngine) TTL(key string) (int64, bool) {
+	return c.ttl(DefaultNamespace, key)
+}
+
+// SetIf almacena un valor en el namespace por defecto si se cumple la condición
+func (c *CacheEngine) SetIf(key string, value interface{}, cond SetCondition) (bool, error) {
+	return c.setIf(DefaultNamespace, key, value, cond, 0)
+}
+
+// SetEx almacena un valor con expiración en el namespace por defecto
+func (c *CacheEngine) SetEx(key string, value interface{}, seconds int, cond SetCondition) (bool, error) {
+	return c.setIf(DefaultNamespace, key, value, cond, deadline(seconds))
+}
+
+// Keys retorna las claves del namespace por defecto que coinciden con el patrón
+func (c *CacheEngine) Keys(pattern string) []string {
+	return c.keys(DefaultNamespace, pattern)
+}
+
+// set almacena un valor en un namespace
+func (c *CacheEngine) set(ns, key string, value interface{}) error {
+	_, err := c.setIf(ns, key, value, SetAlways, 0)
+	return err
+}
+
+// setIf almacena un valor en un namespace si se cumple la condición
+func (c *CacheEngine) setIf(ns, key string, value interface{}, cond SetCondition, expiresAt int64) (bool, error) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
 
-	entry, exists := c.data[key]
+	defer c.unlock()
+
+	return c.store(c.space(ns), key, value, cond, expiresAt)
+}
+
+// deadline convierte una expiración relativa en segundos en un instante
+// absoluto (0 = sin expiración)
+func deadline(seconds int) int64 {
+	if seconds == 0 {
+		return 0
+	}
+	return time.Now().Unix() + int64(seconds)
+}
+
+// store escribe una entrada que expira en expiresAt (0 = nunca) si se
+// cumple la condición. Requiere c.mu tomado.
+func (c *CacheEngine) store(ks *keyspace, key string, value interface{}, cond SetCondition, expiresAt int64) (bool, error) {
+	size := entrySize(key, value)
+
+	now := time.Now().Unix()
+	old, exists := ks.data[key]
+	live := exists && !isExpired(old, now)
+	if (cond == SetIfAbsent && live) || (cond == SetIfPresent && !live) {
+		return false, nil
+	}
+
+	// Escribir con una expiración vencida deja la clave sin valor
+	if expiresAt != 0 && expiresAt <= now {
+		c.remove(ks, key)
+		return true, nil
+	}
+
+	delta := size
+	if exists {
+		delta -= old.size
+	}
+
+	if err := c.reserve(ks, key, !exists, delta); err != nil {
+		return false, err
+	}
+
+	// Solo una clave nueva puede superar el límite global
+	if !exists && c.totalEntries() >= c.maxEntries {
+		c.evictGlobal()
+	}
+
+	ks.data[key] = &CacheEntry{
+		Value:      value,
+		ExpiresAt:  expiresAt,
+		LastAccess: time.Now().UnixNano(), // Usar nanosegundos para mejor precisión
+		size:       size,
+		cas:        c.nextCAS(),
+	}
+	ks.bytes += delta
+	ks.sets++
+	c.notify(EventSet, ks.name, key)
+	return true, nil
+}
+
+// keys lista las claves vigentes de un namespace que coinciden con el patrón
+func (c *CacheEngine) keys(ns, pattern string) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ks, exists := c.namespaces[ns]
 	if !exists {
-		return nil, false
+		return nil
 	}
 
-	// Verificar si la clave ha expirado
 	now := time.Now().Unix()
-	if entry.ExpiresAt > 0 && entry.ExpiresAt <= now {
-		delete(c.data, key)
+	keys := make([]string, 0, len(ks.data))
+	for key, entry := range ks.data {
+		if isExpired(entry, now) {
+			continue
+		}
+		if pattern != "" && pattern != "*" {
+			if matched, _ := path.Match(pattern, key); !matched {
+				continue
+			}
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// reserve hace espacio en el namespace para una escritura de la clave key,
+// que añade una entrada si isNew y delta bytes. Según la cuota expulsa
+// entradas del propio namespace o retorna ErrQuotaExceeded. Requiere c.mu tomado.
+func (c *CacheEngine) reserve(ks *keyspace, key string, isNew bool, delta int64) error {
+	entries := 0
+	if isNew {
+		entries = 1
+	}
+
+	// Un valor mayor que toda la cuota nunca cabe
+	if ks.quota.MaxBytes > 0 && delta > ks.quota.MaxBytes {
+		ks.rejections++
+		return ErrQuotaExceeded
+	}
+
+	for ks.overQuota(entries, delta) {
+		if ks.quota.Reject {
+			ks.rejections++
+			return ErrQuotaExceeded
+		}
+		if !c.evictFrom(ks, key) {
+			ks.rejections++
+			return ErrQuotaExceeded
+		}
+	}
+
+	return nil
+}
+
+// entrySize estima el tamaño en bytes de una entrada
+func entrySize(key string, value interface{}) int64 {
+	size := int64(len(key))
+	switch v := value.(type) {
+	case nil:
+	case string:
+		size += int64(len(v))
+	case []byte:
+		size += int64(len(v))
+	case bool, int8, uint8:
+		size++
+	case int16, uint16:
+		size += 2
+	case int32, uint32, float32:
+		size += 4
+	case int, int64, uint, uint64, float64:
+		size += 8
+	default:
+		size += int64(len(fmt.Sprint(v)))
+	}
+	return size
+}
+
+// get obtiene un valor de un namespace
+func (c *CacheEngine) get(ns, key string) (interface{}, bool) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	return c.lookup(c.find(ns), key)
+}
+
+// lookup obtiene un valor vigente y actualiza su último acceso. ks puede
+// ser nil si el namespace no existe. Requiere c.mu tomado.
+func (c *CacheEngine) lookup(ks *keyspace, key string) (interface{}, bool) {
+	entry, exists := c.liveEntry(ks, key)
+	if !exists {
+		c.miss(ks)
 		return nil, false
 	}
 
 	// Actualizar último acceso (para LRU) usando nanosegundos
 	entry.LastAccess = time.Now().UnixNano()
+	ks.hits++
 	return entry.Value, true
 }
 
-// Delete elimina una clave del cache
-func (c *CacheEngine) Delete(key string) bool {
+// delete elimina una clave de un namespace
+func (c *CacheEngine) delete(ns, key string) bool {
 	c.mu.Lock()
 
-	_, exists := c.data[key]
-	if exists {
-		delete(c.data, key)
-	}
+	defer c.unlock()
 
-	// Registrar operación en log si está habilitado
-	logFile := c.logFile
-	c.mu.Unlock()
+	return c.remove(c.find(ns), key)
+}
 
-	if exists && logFile != "" {
-		// El logging se maneja desde el CLI
+// remove elimina una clave de un namespace. ks puede ser nil si el
+// namespace no existe. Requiere c.mu tomado.
+func (c *CacheEngine) remove(ks *keyspace, key string) bool {
+	if ks == nil {
+		return false
+	}
+	entry, exists := ks.data[key]
+	if !exists {
+		return false
 	}
 
-	return exists
+	delete(ks.data, key)
+	ks.bytes -= entry.size
+	ks.deletes++
+	c.notify(EventDelete, ks.name, key)
+	return true
 }
 
-// Expire establece un tiempo de expiración para una clave
-func (c *CacheEngine) Expire(key string, seconds int) bool {
+// expire establece un tiempo de expiración para una clave de un namespace
+func (c *CacheEngine) expire(ns, key string, seconds int) bool {
 	c.mu.Lock()
+	defer c.unlock()
 
-	entry, exists := c.data[key]
+	entry, exists := c.liveEntry(c.find(ns), key)
 	if !exists {
-		c.mu.Unlock()
 		return false
 	}
 
-	expiresAt := time.Now().Unix() + int64(seconds)
-	entry.ExpiresAt = expiresAt
+	entry.ExpiresAt = time.Now().Unix() + int64(seconds)
+	c.notify(EventExpire, ns, key)
+	return true
+}
+
+// ttl retorna los segundos restantes de una clave (-1 = sin expiración).
+// No actualiza el último acceso ni las estadísticas.
+func (c *CacheEngine) ttl(ns, key string) (int64, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 
-	// Registrar operación en log si está habilitado
-	logFile := c.logFile
-	c.mu.Unlock()
+	ks, exists := c.namespaces[ns]
+	if !exists {
+		return 0, false
+	}
 
-	if logFile != "" {
-		// El logging se maneja desde el CLI
+	entry, exists := ks.data[key]
+	now := time.Now().Unix()
+	if !exists || isExpired(entry, now) {
+		return 0, false
 	}
 
-	return true
+	return remaining(entry, now), true
 }
 
-// evictLRU elimina la entrada menos recientemente usada
+// remaining retorna los segundos que le quedan a una entrada vigente en el
+// instante now (-1 = sin expiración)
+func remaining(entry *CacheEntry, now int64) int64 {
+	if entry.ExpiresAt == 0 {
+		return -1
+	}
+	return entry.ExpiresAt - now
+}
+
+// isExpired indica si una entrada ha expirado en el instante now (segundos)
+func isExpired(entry *CacheEntry, now int64) bool {
+	return entry.ExpiresAt > 0 && entry.ExpiresAt <= now
+}
+
+// evictGlobal expulsa una entrada según la política configurada
+func (c *CacheEngine) evictGlobal() {
+	if c.policy == EvictionFair {
+		c.evictFair()
+		return
+	}
+	c.evictLRU()
+}
+
+// evictFair expulsa la entrada LRU del namespace que más excede su parte
+// equitativa del límite global
+func (c *CacheEngine) evictFair() {
+	active := 0
+	for _, ks := range c.namespaces {
+		if len(ks.data) > 0 {
+			active++
+		}
+	}
+	if active == 0 {
+		return
+	}
+
+	share := c.maxEntries / active
+	var victim *keyspace
+	excess := 0
+	for _, ks := range c.namespaces {
+		if len(ks.data) == 0 {
+			continue
+		}
+		over := len(ks.data) - share
+		if victim == nil || over > excess {
+			victim = ks
+			excess = over
+		}
+	}
+
+	c.evictFrom(victim, "")
+}
+
+// evictLRU elimina la entrada menos recientemente usada de todos los namespaces
 func (c *CacheEngine) evictLRU() {
+	var oldestSpace *keyspace
 	var oldestKey string
 	var oldestTime int64 = time.Now().UnixNano()
 
 	// Buscar la clave con el acceso más antiguo
-	for key, entry := range c.data {
-		if entry.LastAccess < oldestTime {
+	for _, ks := range c.namespaces {
+		for key, entry := range ks.data {
+			if entry.LastAccess < oldestTime {
+				oldestTime = entry.LastAccess
+				oldestKey = key
+				oldestSpace = ks
+			}
+		}
+	}
+
+	// Eliminar la entrada más antigua
+	if oldestSpace != nil {
+		oldestSpace.bytes -= oldestSpace.data[oldestKey].size
+		delete(oldestSpace.data, oldestKey)
+		oldestSpace.evictions++
+		c.notify(EventEvicted, oldestSpace.name, oldestKey)
+	}
+}
+
+// evictFrom elimina la entrada menos recientemente usada de un namespace,
+// sin tocar la clave skip. Retorna false si no había nada que expulsar.
+func (c *CacheEngine) evictFrom(ks *keyspace, skip string) bool {
+	var oldestKey string
+	var oldestTime int64
+	found := false
+
+	for key, entry := range ks.data {
+		if key == skip {
+			continue
+		}
+		if !found || entry.LastAccess < oldestTime {
 			oldestTime = entry.LastAccess
 			oldestKey = key
+			found = true
 		}
 	}
 
-	// Eliminar la entrada más antigua
-	if oldestKey != "" {
-		delete(c.data, oldestKey)
+	if found {
+		ks.bytes -= ks.data[oldestKey].size
+		delete(ks.data, oldestKey)
+		ks.evictions++
+		c.notify(EventEvicted, ks.name, oldestKey)
+	}
+	return found
+}
+
+// DefaultCleanupInterval es la frecuencia por defecto del barrido de
+// claves expiradas
+const DefaultCleanupInterval = time.Second
+
+// SetCleanupInterval cambia la frecuencia del barrido de claves expiradas
+func (c *CacheEngine) SetCleanupInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultCleanupInterval
+	}
+	select {
+	case c.cleanEvery <- interval:
+	case <-c.stopClean:
 	}
 }
 
 // periodicCleanup ejecuta un barrido periódico para eliminar claves expiradas
 func (c *CacheEngine) periodicCleanup() {
-	ticker := time.NewTicker(1 * time.Second)
+	defer close(c.cleanDone)
+	ticker := time.NewTicker(DefaultCleanupInterval)
 	defer ticker.Stop()
 
 	for {
 		select {
 		case <-ticker.C:
 			c.cleanExpired()
+		case interval := <-c.cleanEvery:
+			ticker.Reset(interval)
 		case <-c.stopClean:
 			return
 		}
@@ -168,61 +835,146 @@ func (c *CacheEngine) periodicCleanup() {
 // cleanExpired elimina todas las claves expiradas
 func (c *CacheEngine) cleanExpired() {
 	c.mu.Lock()
-	defer c.mu.Unlock()
+	defer c.unlock()
 
 	now := time.Now().Unix()
-	for key, entry := range c.data {
-		if entry.ExpiresAt > 0 && entry.ExpiresAt <= now {
-			delete(c.data, key)
+	for _, ks := range c.namespaces {
+		for key, entry := range ks.data {
+			if isExpired(entry, now) {
+				delete(ks.data, key)
+				ks.bytes -= entry.size
+				ks.expirations++
+				c.notify(EventExpired, ks.name, key)
+			}
 		}
 	}
 }
 
-// Size retorna el número de entradas en el cache
+// Size retorna el número de entradas en el cache (todos los namespaces)
 func (c *CacheEngine) Size() int {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return len(c.data)
+	return c.totalEntries()
 }
 
 // MaxEntries retorna el límite máximo de entradas
 func (c *CacheEngine) MaxEntries() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	return c.maxEntries
 }
 
-// Close detiene los procesos en segundo plano
-func (c *CacheEngine) Close() {
-	close(c.stopClean)
-}
+// SetMaxEntries cambia el límite global de entradas. Si el contenido actual
+// lo supera, se expulsan entradas según la política hasta cumplirlo.
+func (c *CacheEngine) SetMaxEntries(maxEntries int) {
+	if maxEntries <= 0 {
+		return
+	}
 
-// EnableLogging habilita el logging de operaciones en tiempo real
-func (c *CacheEngine) EnableLogging(filename string) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.logFile = filename
+	defer c.unlock()
+
+	c.maxEntries = maxEntries
+	for total := c.totalEntries(); total > c.maxEntries; {
+		c.evictGlobal()
+		if remaining := c.totalEntries(); remaining < total {
+			total = remaining
+		} else {
+			break
+		}
+	}
+}
+
+// EvictionPolicy retorna la política de expulsión actual
+func (c *CacheEngine) EvictionPolicy() EvictionPolicy {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.policy
 }
 
-// DisableLogging deshabilita el logging de operaciones
-func (c *CacheEngine) DisableLogging() {
+// SetEvictionPolicy cambia la política de expulsión
+func (c *CacheEngine) SetEvictionPolicy(policy EvictionPolicy) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-	c.logFile = ""
+	defer c.unlock()
+	c.policy = policy
 }
 
-// GetLogFile retorna el archivo de log actual
-func (c *CacheEngine) GetLogFile() string {
+// Namespaces retorna los nombres de los namespaces existentes ordenados
+func (c *CacheEngine) Namespaces() []string {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
-	return c.logFile
+
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// SwapNamespaces intercambia el contenido de dos namespaces
+func (c *CacheEngine) SwapNamespaces(a, b string) {
+	if a == b {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.unlock()
+
+	ksA, ksB := c.space(a), c.space(b)
+	ksA.data, ksB.data = ksB.data, ksA.data
+	ksA.bytes, ksB.bytes = ksB.bytes, ksA.bytes
+	c.logMutation(Mutation{Operation: OpSwapDB, Namespace: a, Value: b})
+	c.notify(EventFlush, a, "")
+	c.notify(EventFlush, b, "")
+}
+
+// FlushAll elimina todas las claves de todos los namespaces
+func (c *CacheEngine) FlushAll() {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.logMutation(Mutation{Operation: OpFlushAll})
+	for _, ks := range c.namespaces {
+		ks.data = make(map[string]*CacheEntry)
+		ks.bytes = 0
+		c.notify(EventFlush, ks.name, "")
+	}
+}
+
+// Close detiene el barrido periódico, espera a que termine y cierra las
+// suscripciones a eventos. Se puede llamar varias veces; si ctx vence
+// antes de que el barrido termine retorna ctx.Err().
+func (c *CacheEngine) Close(ctx context.Context) error {
+	c.closeOnce.Do(func() {
+		close(c.stopClean)
+		c.watchers.closeAll()
+	})
+
+	select {
+	case <-c.cleanDone:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
 }
 
-// ExportData retorna una copia segura de los datos para persistencia
+// ExportData retorna una copia segura de los datos del namespace por defecto para persistencia
 func (c *CacheEngine) ExportData() map[string]*CacheEntry {
+	return c.ExportNamespace(DefaultNamespace)
+}
+
+// ExportNamespace retorna una copia segura de los datos de un namespace
+func (c *CacheEngine) ExportNamespace(name string) map[string]*CacheEntry {
 	c.mu.RLock()
 	defer c.mu.RUnlock()
 
 	copy := make(map[string]*CacheEntry)
-	for k, v := range c.data {
+	ks, exists := c.namespaces[name]
+	if !exists {
+		return copy
+	}
+	for k, v := range ks.data {
 		// Hacemos una copia del puntero para evitar condiciones de carrera si se modifica el entry
 		entryCopy := *v
 		copy[k] = &entryCopy
@@ -230,10 +982,19 @@ func (c *CacheEngine) ExportData() map[string]*CacheEntry {
 	return copy
 }
 
-// ImportData restaura datos masivamente (útil para snapshots)
+// ImportData restaura datos masivamente en el namespace por defecto (útil para snapshots)
 func (c *CacheEngine) ImportData(data map[string]*CacheEntry) {
 	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.data = data
+	defer c.unlock()
+
+	ks := c.space(DefaultNamespace)
+	ks.data = data
+	ks.bytes = 0
+	c.logMutation(Mutation{Operation: OpFlushDB, Namespace: DefaultNamespace})
+	c.notify(EventFlush, DefaultNamespace, "")
+	for key, entry := range data {
+		entry.size = entrySize(key, entry.Value)
+		ks.bytes += entry.size
+		c.logMutation(Mutation{Operation: OpSet, Namespace: DefaultNamespace, Key: key, Value: entry.Value, ExpiresAt: entry.ExpiresAt})
+	}
 }
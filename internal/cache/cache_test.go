@@ -1,6 +1,8 @@
 package cache
 
 import (
+	"context"
+	"fmt"
 	"testing"
 	"time"
 )
@@ -8,7 +10,7 @@ import (
 // TestSetAndGet prueba las operaciones básicas SET y GET
 func TestSetAndGet(t *testing.T) {
 	cache := NewCacheEngine(10)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Test SET y GET
 	cache.Set("key1", "value1")
@@ -26,7 +28,7 @@ func TestSetAndGet(t *testing.T) {
 // TestGetNonExistent prueba obtener una clave inexistente
 func TestGetNonExistent(t *testing.T) {
 	cache := NewCacheEngine(10)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	_, exists := cache.Get("nonexistent")
 	if exists {
@@ -37,7 +39,7 @@ func TestGetNonExistent(t *testing.T) {
 // TestDelete prueba la operación DELETE
 func TestDelete(t *testing.T) {
 	cache := NewCacheEngine(10)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	cache.Set("key1", "value1")
 	deleted := cache.Delete("key1")
@@ -55,7 +57,7 @@ func TestDelete(t *testing.T) {
 // TestExpire prueba la expiración de claves
 func TestExpire(t *testing.T) {
 	cache := NewCacheEngine(10)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	cache.Set("key1", "value1")
 	cache.Expire("key1", 1) // Expira en 1 segundo
@@ -79,7 +81,7 @@ func TestExpire(t *testing.T) {
 // TestLRUEviction prueba la expulsión LRU
 func TestLRUEviction(t *testing.T) {
 	cache := NewCacheEngine(3) // Solo 3 entradas
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Agregar 3 elementos
 	cache.Set("key1", "value1")
@@ -124,7 +126,7 @@ func TestLRUEviction(t *testing.T) {
 // TestConcurrency prueba operaciones concurrentes
 func TestConcurrency(t *testing.T) {
 	cache := NewCacheEngine(100)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	done := make(chan bool)
 
@@ -161,7 +163,7 @@ func TestConcurrency(t *testing.T) {
 // TestSize prueba el método Size
 func TestSize(t *testing.T) {
 	cache := NewCacheEngine(10)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	if cache.Size() != 0 {
 		t.Error("El cache debería estar vacío inicialmente")
@@ -184,7 +186,7 @@ func TestSize(t *testing.T) {
 // TestPeriodicCleanup prueba el barrido periódico
 func TestPeriodicCleanup(t *testing.T) {
 	cache := NewCacheEngine(10)
-	defer cache.Close()
+	defer cache.Close(context.Background())
 
 	// Agregar claves con expiración
 	cache.Set("key1", "value1")
@@ -200,3 +202,450 @@ func TestPeriodicCleanup(t *testing.T) {
 		t.Errorf("El cache debería estar vacío después del cleanup, tiene %d entradas", cache.Size())
 	}
 }
+
+// TestClose prueba que Close se puede llamar varias veces y termina las
+// suscripciones
+func TestClose(t *testing.T) {
+	cache := NewCacheEngine(10)
+	events, cancel := cache.Subscribe(1)
+
+	if err := cache.Close(context.Background()); err != nil {
+		t.Fatalf("No se pudo cerrar: %v", err)
+	}
+	if err := cache.Close(context.Background()); err != nil {
+		t.Errorf("Cerrar dos veces no debería fallar: %v", err)
+	}
+	if _, ok := <-events; ok {
+		t.Error("El canal de eventos debería estar cerrado")
+	}
+	cancel()
+}
+
+// TestNamespacesIsolation prueba que los namespaces no comparten claves
+func TestNamespacesIsolation(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	db1 := cache.Namespace("1")
+	cache.Set("key1", "default")
+	db1.Set("key1", "uno")
+
+	value, _ := cache.Get("key1")
+	if value != "default" {
+		t.Errorf("Esperaba 'default', obtuve '%v'", value)
+	}
+
+	value, _ = db1.Get("key1")
+	if value != "uno" {
+		t.Errorf("Esperaba 'uno', obtuve '%v'", value)
+	}
+
+	if cache.Size() != 2 {
+		t.Errorf("Esperaba tamaño total 2, obtuve %d", cache.Size())
+	}
+
+	db1.Flush()
+	if db1.Size() != 0 || cache.Namespace(DefaultNamespace).Size() != 1 {
+		t.Error("FLUSHDB solo debería vaciar el namespace actual")
+	}
+}
+
+// TestNamespaceReadDoesNotCreate prueba que las lecturas y los borrados en
+// un namespace inexistente no lo crean, pero sí cuentan como fallos
+func TestNamespaceReadDoesNotCreate(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	ghost := cache.Namespace("fantasma")
+	ghost.Get("key")
+	ghost.GetCAS("key")
+	ghost.MGet("a", "b")
+	ghost.Delete("key")
+	ghost.MDelete("a")
+	ghost.Expire("key", 10)
+	ghost.Persist("key")
+	ghost.Move("key", DefaultNamespace)
+	ghost.Flush()
+
+	if names := cache.Namespaces(); len(names) != 1 || names[0] != DefaultNamespace {
+		t.Errorf("No debería crearse el namespace, obtuve %v", names)
+	}
+	if misses := cache.Stats().Misses; misses != 4 {
+		t.Errorf("Esperaba 4 fallos de lectura, obtuve %d", misses)
+	}
+
+	ghost.Set("key", "valor")
+	if names := cache.Namespaces(); len(names) != 2 {
+		t.Errorf("La primera escritura debería crear el namespace, obtuve %v", names)
+	}
+}
+
+// TestNamespaceSwapAndMove prueba SWAPDB y MOVE
+func TestNamespaceSwapAndMove(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	cache.Set("key1", "value1")
+	cache.SwapNamespaces(DefaultNamespace, "users")
+
+	if _, exists := cache.Get("key1"); exists {
+		t.Error("key1 debería haberse movido con SWAPDB")
+	}
+
+	users := cache.Namespace("users")
+	if !users.Move("key1", DefaultNamespace) {
+		t.Fatal("Esperaba que MOVE tuviera éxito")
+	}
+
+	if _, exists := cache.Get("key1"); !exists {
+		t.Error("key1 debería existir en el namespace por defecto")
+	}
+
+	users.Set("key1", "otro")
+	if users.Move("key1", DefaultNamespace) {
+		t.Error("MOVE no debería sobrescribir una clave existente en el destino")
+	}
+
+	cache.FlushAll()
+	if cache.Size() != 0 {
+		t.Errorf("FLUSHALL debería vaciar todo, quedan %d entradas", cache.Size())
+	}
+}
+
+// TestNamespaceMaxEntries prueba el límite propio de un namespace
+func TestNamespaceMaxEntries(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	cache.Set("global", "value")
+
+	db := cache.Namespace("limited")
+	db.SetMaxEntries(2)
+	db.Set("key1", "value1")
+	time.Sleep(10 * time.Millisecond)
+	db.Set("key2", "value2")
+	time.Sleep(10 * time.Millisecond)
+	db.Set("key3", "value3")
+
+	if db.Size() != 2 {
+		t.Errorf("Esperaba 2 entradas en el namespace, obtuve %d", db.Size())
+	}
+
+	if _, exists := db.Get("key1"); exists {
+		t.Error("key1 debería haber sido expulsada del namespace")
+	}
+
+	if _, exists := cache.Get("global"); !exists {
+		t.Error("El límite del namespace no debería afectar a otros namespaces")
+	}
+
+	if stats := db.Stats(); stats.Evictions != 1 || stats.Misses != 1 {
+		t.Errorf("Estadísticas inesperadas: %+v", stats)
+	}
+}
+
+// TestSetMaxEntries prueba que reducir el límite global expulsa al momento
+func TestSetMaxEntries(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	for _, key := range []string{"key1", "key2", "key3", "key4"} {
+		cache.Set(key, "value")
+		time.Sleep(time.Millisecond)
+	}
+
+	cache.SetMaxEntries(2)
+	if cache.Size() != 2 || cache.MaxEntries() != 2 {
+		t.Errorf("Esperaba 2 entradas con límite 2, obtuve %d con límite %d", cache.Size(), cache.MaxEntries())
+	}
+	if _, exists := cache.Get("key1"); exists {
+		t.Error("key1 debería haber sido expulsada")
+	}
+	if _, exists := cache.Get("key4"); !exists {
+		t.Error("key4 debería seguir en el cache")
+	}
+}
+
+// TestNamespaceQuotaReject prueba que una cuota en modo REJECT retorna error
+func TestNamespaceQuotaReject(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	db := cache.Namespace("tenant")
+	db.SetQuota(Quota{MaxEntries: 1, Reject: true})
+
+	if err := db.Set("key1", "value1"); err != nil {
+		t.Fatalf("No esperaba error: %v", err)
+	}
+
+	if err := db.Set("key2", "value2"); err != ErrQuotaExceeded {
+		t.Errorf("Esperaba ErrQuotaExceeded, obtuve %v", err)
+	}
+
+	// Sobrescribir una clave existente no añade entradas
+	if err := db.Set("key1", "otro"); err != nil {
+		t.Errorf("No esperaba error al sobrescribir: %v", err)
+	}
+
+	if stats := db.Stats(); stats.Rejections != 1 {
+		t.Errorf("Esperaba 1 rechazo, obtuve %d", stats.Rejections)
+	}
+}
+
+// TestNamespaceByteQuota prueba la cuota de bytes con expulsión
+func TestNamespaceByteQuota(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	db := cache.Namespace("tenant")
+	db.SetQuota(Quota{MaxBytes: 20})
+
+	db.Set("a", "123456789") // 10 bytes
+	time.Sleep(10 * time.Millisecond)
+	db.Set("b", "123456789") // 10 bytes
+	time.Sleep(10 * time.Millisecond)
+	db.Set("c", "123456789") // Debería expulsar "a"
+
+	if _, exists := db.Get("a"); exists {
+		t.Error("a debería haber sido expulsada por la cuota de bytes")
+	}
+
+	if stats := db.Stats(); stats.Bytes != 20 {
+		t.Errorf("Esperaba 20 bytes, obtuve %d", stats.Bytes)
+	}
+
+	if err := db.Set("big", "un valor demasiado grande para la cuota"); err != ErrQuotaExceeded {
+		t.Errorf("Esperaba ErrQuotaExceeded, obtuve %v", err)
+	}
+}
+
+// TestFairEviction prueba que la expulsión justa protege a otros namespaces
+func TestFairEviction(t *testing.T) {
+	cache := NewCacheEngine(4)
+	defer cache.Close(context.Background())
+	cache.SetEvictionPolicy(EvictionFair)
+
+	quiet := cache.Namespace("quiet")
+	quiet.Set("hot", "value")
+	time.Sleep(10 * time.Millisecond)
+
+	noisy := cache.Namespace("noisy")
+	for i := 0; i < 10; i++ {
+		noisy.Set(fmt.Sprintf("key%d", i), i)
+		time.Sleep(time.Millisecond)
+	}
+
+	if _, exists := quiet.Get("hot"); !exists {
+		t.Error("La clave del namespace tranquilo no debería haber sido expulsada")
+	}
+
+	if cache.Size() != 4 {
+		t.Errorf("Esperaba 4 entradas, obtuve %d", cache.Size())
+	}
+}
+
+// TestSetIfAndKeys prueba las escrituras condicionales y el listado de claves
+func TestSetIfAndKeys(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	if ok, _ := cache.SetIf("user:1", "a", SetIfPresent); ok {
+		t.Error("XX no debería escribir una clave inexistente")
+	}
+
+	if ok, _ := cache.SetIf("user:1", "a", SetIfAbsent); !ok {
+		t.Error("NX debería escribir una clave inexistente")
+	}
+
+	if ok, _ := cache.SetIf("user:1", "b", SetIfAbsent); ok {
+		t.Error("NX no debería sobrescribir una clave existente")
+	}
+
+	cache.Set("user:2", "c")
+	cache.Set("session:1", "d")
+
+	keys := cache.Keys("user:*")
+	if len(keys) != 2 || keys[0] != "user:1" || keys[1] != "user:2" {
+		t.Errorf("Claves inesperadas: %v", keys)
+	}
+
+	if len(cache.Keys("")) != 3 {
+		t.Errorf("Esperaba 3 claves, obtuve %v", cache.Keys(""))
+	}
+}
+
+// TestCompareAndSwap prueba los tokens CAS y las actualizaciones atómicas
+func TestCompareAndSwap(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	db := cache.Namespace(DefaultNamespace)
+	db.Set("key1", "a")
+	_, cas, _ := db.GetCAS("key1")
+
+	if err := db.CompareAndSwap("key1", "b", cas); err != nil {
+		t.Fatalf("No esperaba error: %v", err)
+	}
+
+	if err := db.CompareAndSwap("key1", "c", cas); err != ErrCASMismatch {
+		t.Errorf("Esperaba ErrCASMismatch, obtuve %v", err)
+	}
+
+	if err := db.CompareAndSwap("missing", "c", cas); err != ErrKeyNotFound {
+		t.Errorf("Esperaba ErrKeyNotFound, obtuve %v", err)
+	}
+
+	db.Expire("key1", 60)
+	value, err := db.Update("key1", func(v interface{}) (interface{}, error) {
+		return v.(string) + "!", nil
+	})
+	if err != nil || value != "b!" {
+		t.Errorf("Update inesperado: %v %v", value, err)
+	}
+
+	if ttl, _ := db.TTL("key1"); ttl <= 0 {
+		t.Error("Update debería conservar la expiración")
+	}
+
+	newCAS, _ := db.CASToken("key1")
+	if err := db.CompareAndDelete("key1", cas); err != ErrCASMismatch {
+		t.Errorf("Esperaba ErrCASMismatch, obtuve %v", err)
+	}
+	if err := db.CompareAndDelete("key1", newCAS); err != nil {
+		t.Errorf("No esperaba error: %v", err)
+	}
+}
+
+// TestBatchOperations prueba MGet, MSet y MDelete
+func TestBatchOperations(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	if err := cache.MSet(map[string]interface{}{"a": "1", "b": "2", "c": "3"}); err != nil {
+		t.Fatalf("No se pudo ejecutar MSet: %v", err)
+	}
+
+	values, found := cache.MGet("a", "missing", "c")
+	if !found[0] || found[1] || !found[2] {
+		t.Errorf("Claves encontradas inesperadas: %v", found)
+	}
+	if values[0] != "1" || values[1] != nil || values[2] != "3" {
+		t.Errorf("Valores inesperados: %v", values)
+	}
+
+	if deleted := cache.MDelete("a", "b", "missing"); deleted != 2 {
+		t.Errorf("Esperaba 2 claves eliminadas, obtuve %d", deleted)
+	}
+	if cache.Size() != 1 {
+		t.Errorf("Esperaba 1 clave, obtuve %d", cache.Size())
+	}
+}
+
+// TestBatchAllOrNothing prueba que MSetNX y MSet no escriben lotes parciales
+func TestBatchAllOrNothing(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	db := cache.Namespace("tenant")
+	db.Set("b", "old")
+
+	written, err := db.MSetNX(map[string]interface{}{"a": "1", "b": "2"})
+	if err != nil || written {
+		t.Errorf("MSetNX debería fallar si alguna clave existe: %v %v", written, err)
+	}
+	if _, exists := db.Get("a"); exists {
+		t.Error("MSetNX no debería escribir ninguna clave")
+	}
+
+	written, err = db.MSetNX(map[string]interface{}{"a": "1", "c": "3"})
+	if err != nil || !written {
+		t.Errorf("MSetNX debería escribir el lote: %v %v", written, err)
+	}
+
+	db.SetQuota(Quota{MaxEntries: 4, Reject: true})
+	err = db.MSet(map[string]interface{}{"d": "4", "e": "5"})
+	if err != ErrQuotaExceeded {
+		t.Errorf("Esperaba ErrQuotaExceeded, obtuve %v", err)
+	}
+	if db.Size() != 3 {
+		t.Errorf("El lote rechazado no debería escribir claves, hay %d", db.Size())
+	}
+
+	// Sobrescribir claves existentes cabe en la cuota
+	if err := db.MSet(map[string]interface{}{"a": "x", "d": "4"}); err != nil {
+		t.Errorf("No esperaba error: %v", err)
+	}
+}
+
+// TestSetEx prueba que el valor y la expiración se escriben en una sola
+// operación, también en el journal
+func TestSetEx(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+	journal := &recorder{}
+	cache.SetJournal(journal)
+
+	if written, err := cache.SetEx("k", "v", 60, SetIfAbsent); err != nil || !written {
+		t.Fatalf("No se pudo escribir: %v %v", written, err)
+	}
+	if ttl, _ := cache.TTL("k"); ttl < 59 || ttl > 60 {
+		t.Errorf("Esperaba TTL 60, obtuve %d", ttl)
+	}
+	if len(journal.batches) != 1 || len(journal.batches[0]) != 1 || journal.batches[0][0].ExpiresAt == 0 {
+		t.Errorf("Esperaba un único SET con expiración, obtuve %+v", journal.batches)
+	}
+
+	if written, _ := cache.SetEx("k", "otro", 60, SetIfAbsent); written {
+		t.Error("NX no debería escribir sobre una clave existente")
+	}
+	cache.SetEx("k", "v2", 0, SetAlways)
+	if ttl, _ := cache.TTL("k"); ttl != -1 {
+		t.Errorf("seconds 0 debería quitar la expiración, obtuve %d", ttl)
+	}
+	if written, _ := cache.SetEx("k", "v3", -1, SetIfPresent); !written {
+		t.Error("Esperaba escritura con expiración vencida")
+	}
+	if _, found := cache.Get("k"); found {
+		t.Error("Una expiración vencida no debería dejar la clave")
+	}
+
+	if err := cache.MSetEx(map[string]interface{}{"a": 1, "b": 2}, 30); err != nil {
+		t.Fatalf("No se pudo escribir el lote: %v", err)
+	}
+	for _, key := range []string{"a", "b"} {
+		if ttl, _ := cache.TTL(key); ttl < 29 || ttl > 30 {
+			t.Errorf("%s: esperaba TTL 30, obtuve %d", key, ttl)
+		}
+	}
+}
+
+// TestUpsertGetTTL prueba las escrituras que informan si la clave era nueva
+// y las lecturas del valor con su TTL
+func TestUpsertGetTTL(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+	ns := cache.Namespace("db")
+
+	if _, _, found := ns.GetTTL("k"); found {
+		t.Error("La clave no debería existir")
+	}
+	if created, err := ns.Upsert("k", "v1", 60); err != nil || !created {
+		t.Errorf("Esperaba clave nueva, obtuve %v %v", created, err)
+	}
+	if value, ttl, found := ns.GetTTL("k"); !found || value != "v1" || ttl < 59 || ttl > 60 {
+		t.Errorf("GetTTL inesperado: %v %d %v", value, ttl, found)
+	}
+
+	if created, err := ns.Upsert("k", "v2", 0); err != nil || created {
+		t.Errorf("Esperaba sobrescritura, obtuve %v %v", created, err)
+	}
+	if value, ttl, _ := ns.GetTTL("k"); value != "v2" || ttl != -1 {
+		t.Errorf("Esperaba v2 sin expiración, obtuve %v %d", value, ttl)
+	}
+
+	// Una clave expirada cuenta como nueva
+	ns.ExpireAt("k", time.Now().Unix()-1)
+	if created, _ := ns.Upsert("k", "v3", 0); !created {
+		t.Error("Una clave expirada debería contar como nueva")
+	}
+}
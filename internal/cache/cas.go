@@ -0,0 +1,220 @@
+package cache
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	// ErrKeyNotFound indica que la clave no existe o ha expirado
+	ErrKeyNotFound = errors.New("clave no encontrada")
+
+	// ErrCASMismatch indica que la versión de la entrada cambió desde la lectura
+	ErrCASMismatch = errors.New("la versión de la clave no coincide")
+)
+
+// nextCAS asigna una nueva versión. Requiere c.mu tomado en escritura.
+func (c *CacheEngine) nextCAS() uint64 {
+	c.casCounter++
+	return c.casCounter
+}
+
+// liveEntry retorna la entrada vigente de una clave, eliminándola si expiró.
+// ks puede ser nil si el namespace no existe. Requiere c.mu tomado en
+// escritura.
+func (c *CacheEngine) liveEntry(ks *keyspace, key string) (*CacheEntry, bool) {
+	if ks == nil {
+		return nil, false
+	}
+	entry, exists := ks.data[key]
+	if !exists {
+		return nil, false
+	}
+	if isExpired(entry, time.Now().Unix()) {
+		delete(ks.data, key)
+		ks.bytes -= entry.size
+		ks.expirations++
+		c.notify(EventExpired, ks.name, key)
+		return nil, false
+	}
+	return entry, true
+}
+
+// miss cuenta una lectura sin resultado en ks, o en el motor si el
+// namespace no existe. Requiere c.mu tomado en escritura.
+func (c *CacheEngine) miss(ks *keyspace) {
+	if ks == nil {
+		c.misses++
+		return
+	}
+	ks.misses++
+}
+
+// GetCAS obtiene un valor junto con su versión (token CAS)
+func (n *Namespace) GetCAS(key string) (interface{}, uint64, bool) {
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.find(n.name)
+	entry, exists := c.liveEntry(ks, key)
+	if !exists {
+		c.miss(ks)
+		return nil, 0, false
+	}
+
+	entry.LastAccess = time.Now().UnixNano()
+	ks.hits++
+	return entry.Value, entry.cas, true
+}
+
+// CompareAndSwap sustituye el valor solo si la versión actual es cas.
+// Retorna ErrKeyNotFound, ErrCASMismatch o ErrQuotaExceeded si no se escribió.
+// La expiración de la entrada se conserva.
+func (n *Namespace) CompareAndSwap(key string, value interface{}, cas uint64) error {
+	return n.compareAndSwap(key, value, cas, keepTTL)
+}
+
+// CompareAndSwapEx es CompareAndSwap pero fija también la expiración en
+// seconds segundos (0 = sin expiración) en la misma operación
+func (n *Namespace) CompareAndSwapEx(key string, value interface{}, cas uint64, seconds int) error {
+	return n.compareAndSwap(key, value, cas, deadline(seconds))
+}
+
+// compareAndSwap sustituye el valor si la versión es cas
+func (n *Namespace) compareAndSwap(key string, value interface{}, cas uint64, expiresAt int64) error {
+	_, err := n.update(key, expiresAt, func(current interface{}, version uint64) (interface{}, error) {
+		if version != cas {
+			return nil, ErrCASMismatch
+		}
+		return value, nil
+	})
+	return err
+}
+
+// Update reemplaza atómicamente el valor de una clave existente con el
+// resultado de fn, conservando su expiración. Si fn retorna error no se
+// modifica nada. Retorna el nuevo valor.
+func (n *Namespace) Update(key string, fn func(value interface{}) (interface{}, error)) (interface{}, error) {
+	return n.update(key, keepTTL, func(current interface{}, _ uint64) (interface{}, error) {
+		return fn(current)
+	})
+}
+
+// keepTTL indica a update que conserve la expiración de la entrada
+const keepTTL = -1
+
+// update aplica fn sobre la entrada vigente de una clave y fija su
+// expiración en expiresAt (0 = sin expiración, keepTTL = la actual)
+func (n *Namespace) update(key string, expiresAt int64, fn func(value interface{}, cas uint64) (interface{}, error)) (interface{}, error) {
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.find(n.name)
+	entry, exists := c.liveEntry(ks, key)
+	if !exists {
+		return nil, ErrKeyNotFound
+	}
+
+	value, err := fn(entry.Value, entry.cas)
+	if err != nil {
+		return nil, err
+	}
+
+	// Escribir con una expiración vencida deja la clave sin valor
+	if expiresAt > 0 && expiresAt <= time.Now().Unix() {
+		c.remove(ks, key)
+		return value, nil
+	}
+
+	size := entrySize(key, value)
+	delta := size - entry.size
+	if err := c.reserve(ks, key, false, delta); err != nil {
+		return nil, err
+	}
+
+	entry.Value = value
+	if expiresAt != keepTTL {
+		entry.ExpiresAt = expiresAt
+	}
+	entry.size = size
+	entry.cas = c.nextCAS()
+	entry.LastAccess = time.Now().UnixNano()
+	ks.bytes += delta
+	ks.sets++
+	c.notify(EventSet, n.name, key)
+
+	return value, nil
+}
+
+// Persist elimina la expiración de una clave. Retorna false si no existe.
+func (n *Namespace) Persist(key string) bool {
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	entry, exists := c.liveEntry(c.find(n.name), key)
+	if !exists {
+		return false
+	}
+	entry.ExpiresAt = 0
+	c.notify(EventExpire, n.name, key)
+	return true
+}
+
+// ExpireAt fija la expiración de una clave en el instante deadline
+// (timestamp Unix en segundos). Retorna false si no existe.
+func (n *Namespace) ExpireAt(key string, deadline int64) bool {
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	entry, exists := c.liveEntry(c.find(n.name), key)
+	if !exists {
+		return false
+	}
+	entry.ExpiresAt = deadline
+	c.notify(EventExpire, n.name, key)
+	return true
+}
+
+// CASToken retorna la versión actual de una clave sin contarla como lectura
+func (n *Namespace) CASToken(key string) (uint64, bool) {
+	c := n.engine
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	ks, exists := c.namespaces[n.name]
+	if !exists {
+		return 0, false
+	}
+	entry, exists := ks.data[key]
+	if !exists || isExpired(entry, time.Now().Unix()) {
+		return 0, false
+	}
+	return entry.cas, true
+}
+
+// CompareAndDelete elimina la clave solo si su versión actual es cas.
+// Retorna ErrKeyNotFound o ErrCASMismatch si no se eliminó.
+func (n *Namespace) CompareAndDelete(key string, cas uint64) error {
+	c := n.engine
+	c.mu.Lock()
+	defer c.unlock()
+
+	ks := c.find(n.name)
+	entry, exists := c.liveEntry(ks, key)
+	if !exists {
+		return ErrKeyNotFound
+	}
+	if entry.cas != cas {
+		return ErrCASMismatch
+	}
+
+	delete(ks.data, key)
+	ks.bytes -= entry.size
+	ks.deletes++
+	c.notify(EventDelete, n.name, key)
+	return nil
+}
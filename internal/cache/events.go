@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EventType identifica el tipo de cambio en el espacio de claves
+type EventType int
+
+const (
+	// EventSet indica que una clave se escribió
+	EventSet EventType = iota
+	// EventDelete indica que una clave se eliminó explícitamente
+	EventDelete
+	// EventExpire indica que se cambió la expiración de una clave
+	EventExpire
+	// EventExpired indica que una clave expiró
+	EventExpired
+	// EventEvicted indica que una clave fue expulsada por límite o cuota
+	EventEvicted
+	// EventFlush indica que se vació un namespace completo (Key vacío)
+	EventFlush
+)
+
+// String retorna el nombre del tipo de evento
+func (t EventType) String() string {
+	switch t {
+	case EventSet:
+		return "set"
+	case EventDelete:
+		return "del"
+	case EventExpire:
+		return "expire"
+	case EventExpired:
+		return "expired"
+	case EventEvicted:
+		return "evicted"
+	case EventFlush:
+		return "flush"
+	}
+	return "unknown"
+}
+
+// Event describe un cambio en el espacio de claves
+type Event struct {
+	Type      EventType
+	Namespace string
+	Key       string
+	Time      time.Time
+}
+
+// watchers mantiene los suscriptores a eventos del motor
+type watchers struct {
+	mu      sync.RWMutex
+	subs    map[int64]chan Event
+	nextID  int64
+	active  atomic.Int32 // Número de suscriptores (evita trabajo si no hay ninguno)
+	dropped atomic.Int64 // Eventos descartados por suscriptores lentos
+}
+
+// Subscribe registra un suscriptor a los cambios del espacio de claves.
+// Los eventos se entregan sin bloquear al motor: si el canal (de tamaño
+// buffer) está lleno, el evento se descarta. La función retornada cancela
+// la suscripción y cierra el canal.
+func (c *CacheEngine) Subscribe(buffer int) (<-chan Event, func()) {
+	if buffer <= 0 {
+		buffer = 128
+	}
+
+	w := &c.watchers
+	ch := make(chan Event, buffer)
+
+	w.mu.Lock()
+	if w.subs == nil {
+		w.subs = make(map[int64]chan Event)
+	}
+	w.nextID++
+	id := w.nextID
+	w.subs[id] = ch
+	w.active.Add(1)
+	w.mu.Unlock()
+
+	var once sync.Once
+	cancel := func() {
+		once.Do(func() {
+			w.mu.Lock()
+			if _, exists := w.subs[id]; exists {
+				delete(w.subs, id)
+				w.active.Add(-1)
+				close(ch)
+			}
+			w.mu.Unlock()
+		})
+	}
+	return ch, cancel
+}
+
+// closeAll cierra los canales de todos los suscriptores
+func (w *watchers) closeAll() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	for id, ch := range w.subs {
+		delete(w.subs, id)
+		w.active.Add(-1)
+		close(ch)
+	}
+}
+
+// DroppedEvents retorna los eventos descartados por suscriptores lentos
+func (c *CacheEngine) DroppedEvents() int64 {
+	return c.watchers.dropped.Load()
+}
+
+// notify registra el cambio en el journal y entrega un evento a los
+// suscriptores sin bloquear. Requiere c.mu tomado.
+func (c *CacheEngine) notify(eventType EventType, namespace, key string) {
+	c.logEvent(eventType, namespace, key)
+
+	w := &c.watchers
+	if w.active.Load() == 0 {
+		return
+	}
+
+	event := Event{Type: eventType, Namespace: namespace, Key: key, Time: time.Now()}
+
+	w.mu.RLock()
+	defer w.mu.RUnlock()
+	for _, ch := range w.subs {
+		select {
+		case ch <- event:
+		default:
+			w.dropped.Add(1)
+		}
+	}
+}
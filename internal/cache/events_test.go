@@ -0,0 +1,154 @@
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// nextEvent espera el siguiente evento del canal
+func nextEvent(t *testing.T, events <-chan Event) Event {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(2 * time.Second):
+		t.Fatal("No llegó ningún evento")
+	}
+	return Event{}
+}
+
+// TestSubscribe prueba la notificación de cambios del espacio de claves
+func TestSubscribe(t *testing.T) {
+	cache := NewCacheEngine(1)
+	defer cache.Close(context.Background())
+
+	events, cancel := cache.Subscribe(16)
+	defer cancel()
+
+	cache.Set("key1", "value1")
+	if event := nextEvent(t, events); event.Type != EventSet || event.Key != "key1" || event.Namespace != DefaultNamespace {
+		t.Errorf("Evento inesperado: %+v", event)
+	}
+
+	cache.Expire("key1", 60)
+	if event := nextEvent(t, events); event.Type != EventExpire {
+		t.Errorf("Esperaba expire, obtuve %s", event.Type)
+	}
+
+	// Límite de 1 entrada: key1 se expulsa antes de escribir key2
+	cache.Set("key2", "value2")
+	if event := nextEvent(t, events); event.Type != EventEvicted || event.Key != "key1" {
+		t.Errorf("Esperaba la expulsión de key1, obtuve %+v", event)
+	}
+	nextEvent(t, events)
+
+	cache.Delete("key2")
+	if event := nextEvent(t, events); event.Type != EventDelete {
+		t.Errorf("Esperaba del, obtuve %s", event.Type)
+	}
+
+	cache.Namespace("other").Flush()
+	if event := nextEvent(t, events); event.Type != EventFlush || event.Namespace != "other" {
+		t.Errorf("Esperaba flush de other, obtuve %+v", event)
+	}
+}
+
+// TestSubscribeCancelAndDrop prueba la cancelación y el descarte sin bloqueo
+func TestSubscribeCancelAndDrop(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	events, cancel := cache.Subscribe(1)
+	cache.Set("key1", "a")
+	cache.Set("key2", "b") // El buffer está lleno: se descarta
+
+	if cache.DroppedEvents() != 1 {
+		t.Errorf("Esperaba 1 evento descartado, obtuve %d", cache.DroppedEvents())
+	}
+
+	cancel()
+	cancel() // Idempotente
+
+	<-events
+	if _, open := <-events; open {
+		t.Error("El canal debería cerrarse al cancelar")
+	}
+}
+
+// recorder es un journal que guarda las operaciones que recibe
+type recorder struct {
+	batches [][]Mutation
+}
+
+func (r *recorder) Append(mutations []Mutation) {
+	r.batches = append(r.batches, append([]Mutation(nil), mutations...))
+}
+
+// TestJournal prueba que el motor envía al journal cada modificación, en
+// orden y agrupada por operación, incluidas las expulsiones
+func TestJournal(t *testing.T) {
+	cache := NewCacheEngine(2)
+	defer cache.Close(context.Background())
+
+	journal := &recorder{}
+	if old := cache.SetJournal(journal); old != nil {
+		t.Fatalf("No esperaba journal previo: %v", old)
+	}
+
+	cache.Set("a", "1")
+	cache.Expire("a", 60)
+	cache.Set("b", "2")
+	cache.Set("c", "3") // Expulsa a
+	cache.Delete("b")
+	cache.Delete("ausente")
+	cache.Namespace("other").Move("c", DefaultNamespace)
+	cache.Namespace(DefaultNamespace).Move("c", "other")
+	cache.SwapNamespaces(DefaultNamespace, "other")
+	cache.Namespace("other").Flush()
+	cache.FlushAll()
+
+	want := [][]Mutation{
+		{{Operation: OpSet, Namespace: DefaultNamespace, Key: "a", Value: "1"}},
+		{{Operation: OpExpire, Namespace: DefaultNamespace, Key: "a"}},
+		{{Operation: OpSet, Namespace: DefaultNamespace, Key: "b", Value: "2"}},
+		{
+			{Operation: OpDelete, Namespace: DefaultNamespace, Key: "a"},
+			{Operation: OpSet, Namespace: DefaultNamespace, Key: "c", Value: "3"},
+		},
+		{{Operation: OpDelete, Namespace: DefaultNamespace, Key: "b"}},
+		{
+			{Operation: OpDelete, Namespace: DefaultNamespace, Key: "c"},
+			{Operation: OpSet, Namespace: "other", Key: "c", Value: "3"},
+		},
+		{{Operation: OpSwapDB, Namespace: DefaultNamespace, Value: "other"}},
+		{{Operation: OpFlushDB, Namespace: "other"}},
+		{{Operation: OpFlushAll}},
+	}
+	if len(journal.batches) != len(want) {
+		t.Fatalf("Esperaba %d operaciones, obtuve %d: %+v", len(want), len(journal.batches), journal.batches)
+	}
+	for i, batch := range journal.batches {
+		if len(batch) != len(want[i]) {
+			t.Errorf("Operación %d: esperaba %+v, obtuve %+v", i, want[i], batch)
+			continue
+		}
+		for j, m := range batch {
+			if m.Operation == OpExpire && m.ExpiresAt == 0 {
+				t.Errorf("Operación %d: EXPIRE sin instante absoluto", i)
+			}
+			m.ExpiresAt = 0
+			if m != want[i][j] {
+				t.Errorf("Operación %d: esperaba %+v, obtuve %+v", i, want[i][j], m)
+			}
+		}
+	}
+
+	if cache.SetJournal(nil) != journal {
+		t.Error("SetJournal debería retornar el journal anterior")
+	}
+	cache.Set("d", "4")
+	if len(journal.batches) != len(want) {
+		t.Error("El journal anterior no debería recibir más operaciones")
+	}
+}
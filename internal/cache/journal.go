@@ -0,0 +1,121 @@
+package cache
+
+// Operaciones que el motor envía al journal
+const (
+	OpSet      = "SET"
+	OpDelete   = "DEL"
+	OpExpire   = "EXPIRE"
+	OpFlushDB  = "FLUSHDB"
+	OpFlushAll = "FLUSHALL"
+	OpSwapDB   = "SWAPDB"
+)
+
+// Mutation describe una modificación del motor
+type Mutation struct {
+	Operation string      // Op*
+	Namespace string      // Namespace afectado
+	Key       string      // Vacío en FLUSHDB, FLUSHALL y SWAPDB
+	Value     interface{} // Valor de SET o namespace destino de SWAPDB
+	ExpiresAt int64       // Expiración absoluta de SET y EXPIRE en segundos (0 = sin expiración)
+}
+
+// Journal recibe las modificaciones del motor en el orden en que se
+// aplican, sea cual sea el front-end o la llamada que las produce. Append
+// se llama una vez por operación con todas sus modificaciones y con el
+// motor bloqueado, así que no debe usar el motor ni conservar el slice. No
+// retorna error: la operación ya está aplicada y el journal informa de sus
+// fallos por su cuenta.
+type Journal interface {
+	Append(mutations []Mutation)
+}
+
+// SetJournal cambia el journal del motor (nil = ninguno) y retorna el
+// anterior, que ya no recibe modificaciones
+func (c *CacheEngine) SetJournal(j Journal) Journal {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.flushJournal()
+	old := c.journal
+	c.journal = j
+	return old
+}
+
+// Journal retorna el journal actual (nil = ninguno)
+func (c *CacheEngine) Journal() Journal {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return c.journal
+}
+
+// Checkpoint cambia el journal como SetJournal y retorna también una copia
+// de todos los namespaces tomada en el mismo instante, de modo que j
+// recibe exactamente las modificaciones posteriores a la copia
+func (c *CacheEngine) Checkpoint(j Journal) (map[string]map[string]*CacheEntry, Journal) {
+	c.mu.Lock()
+	defer c.unlock()
+
+	c.flushJournal()
+	data := make(map[string]map[string]*CacheEntry, len(c.namespaces))
+	for name, ks := range c.namespaces {
+		entries := make(map[string]*CacheEntry, len(ks.data))
+		for key, entry := range ks.data {
+			entryCopy := *entry
+			entries[key] = &entryCopy
+		}
+		data[name] = entries
+	}
+	old := c.journal
+	c.journal = j
+	return data, old
+}
+
+// unlock envía al journal las modificaciones de la operación en curso y
+// libera c.mu. Hacerlo con el mutex tomado mantiene el orden del journal
+// igual al de las operaciones.
+func (c *CacheEngine) unlock() {
+	c.flushJournal()
+	c.mu.Unlock()
+}
+
+// flushJournal envía las modificaciones pendientes. Requiere c.mu tomado.
+func (c *CacheEngine) flushJournal() {
+	if len(c.pending) > 0 {
+		c.journal.Append(c.pending)
+		c.pending = nil
+	}
+}
+
+// logMutation añade una modificación a la operación en curso. Requiere
+// c.mu tomado.
+func (c *CacheEngine) logMutation(m Mutation) {
+	if c.journal != nil {
+		c.pending = append(c.pending, m)
+	}
+}
+
+// logEvent traduce un evento del espacio de claves en su modificación. Las
+// claves expiradas no se registran: su expiración ya está en el journal
+// como instante absoluto. Los vaciados se registran donde se producen,
+// porque el evento no distingue FLUSHDB, FLUSHALL y SWAPDB. Requiere c.mu
+// tomado.
+func (c *CacheEngine) logEvent(eventType EventType, namespace, key string) {
+	if c.journal == nil {
+		return
+	}
+
+	switch eventType {
+	case EventSet, EventExpire:
+		entry, exists := c.space(namespace).data[key]
+		if !exists {
+			return
+		}
+		m := Mutation{Operation: OpSet, Namespace: namespace, Key: key, Value: entry.Value, ExpiresAt: entry.ExpiresAt}
+		if eventType == EventExpire {
+			m.Operation, m.Value = OpExpire, nil
+		}
+		c.logMutation(m)
+	case EventDelete, EventEvicted:
+		c.logMutation(Mutation{Operation: OpDelete, Namespace: namespace, Key: key})
+	}
+}
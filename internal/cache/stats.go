@@ -0,0 +1,272 @@
+package cache
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LatencyBuckets son los límites superiores de los histogramas de latencia
+var LatencyBuckets = []time.Duration{
+	10 * time.Microsecond,
+	50 * time.Microsecond,
+	100 * time.Microsecond,
+	500 * time.Microsecond,
+	1 * time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	500 * time.Millisecond,
+}
+
+// InfoSections son las secciones disponibles para FormatInfo
+var InfoSections = []string{"server", "stats", "memory", "keyspace", "commandstats"}
+
+// CommandStats resume las llamadas y latencias de un comando
+type CommandStats struct {
+	Calls     int64         // Número de llamadas
+	TotalTime time.Duration // Tiempo acumulado
+	Buckets   []int64       // Llamadas por bucket de LatencyBuckets (el último es +Inf)
+}
+
+// Average retorna la latencia media del comando
+func (s CommandStats) Average() time.Duration {
+	if s.Calls == 0 {
+		return 0
+	}
+	return s.TotalTime / time.Duration(s.Calls)
+}
+
+// Stats es una foto de las estadísticas del motor
+type Stats struct {
+	Uptime      time.Duration           // Tiempo desde la creación del motor
+	Keys        int                     // Entradas en todos los namespaces
+	Bytes       int64                   // Bytes estimados ocupados
+	MaxEntries  int                     // Límite global de entradas
+	Policy      EvictionPolicy          // Política de expulsión
+	Hits        int64                   // Lecturas con resultado
+	Misses      int64                   // Lecturas sin resultado
+	HitRatio    float64                 // Hits / (Hits + Misses)
+	Sets        int64                   // Escrituras realizadas
+	Deletes     int64                   // Claves eliminadas con DEL
+	Evictions   int64                   // Entradas expulsadas
+	Expirations int64                   // Entradas eliminadas por expiración
+	Rejections  int64                   // Escrituras rechazadas por cuota
+	Namespaces  []NamespaceStats        // Estadísticas por namespace
+	Commands    map[string]CommandStats // Latencias por comando
+}
+
+// commandStats guarda los histogramas de latencia por comando
+type commandStats struct {
+	mu    sync.Mutex
+	stats map[string]*CommandStats
+}
+
+// record registra una llamada a un comando
+func (cs *commandStats) record(name string, elapsed time.Duration) {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	if cs.stats == nil {
+		cs.stats = make(map[string]*CommandStats)
+	}
+
+	stats, exists := cs.stats[name]
+	if !exists {
+		stats = &CommandStats{Buckets: make([]int64, len(LatencyBuckets)+1)}
+		cs.stats[name] = stats
+	}
+
+	stats.Calls++
+	stats.TotalTime += elapsed
+
+	bucket := len(LatencyBuckets)
+	for i, bound := range LatencyBuckets {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+	stats.Buckets[bucket]++
+}
+
+// snapshot retorna una copia de los histogramas
+func (cs *commandStats) snapshot() map[string]CommandStats {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+
+	result := make(map[string]CommandStats, len(cs.stats))
+	for name, stats := range cs.stats {
+		copyStats := *stats
+		copyStats.Buckets = append([]int64(nil), stats.Buckets...)
+		result[name] = copyStats
+	}
+	return result
+}
+
+// reset borra todos los histogramas
+func (cs *commandStats) reset() {
+	cs.mu.Lock()
+	defer cs.mu.Unlock()
+	cs.stats = nil
+}
+
+// RecordCommand registra la latencia de un comando ejecutado por un front-end
+func (c *CacheEngine) RecordCommand(name string, elapsed time.Duration) {
+	c.commands.record(strings.ToUpper(name), elapsed)
+}
+
+// Stats retorna las estadísticas actuales del motor
+func (c *CacheEngine) Stats() Stats {
+	c.mu.RLock()
+
+	stats := Stats{
+		Uptime:     time.Since(c.startTime),
+		MaxEntries: c.maxEntries,
+		Policy:     c.policy,
+	}
+
+	names := make([]string, 0, len(c.namespaces))
+	for name := range c.namespaces {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		ks := c.namespaces[name]
+		stats.Namespaces = append(stats.Namespaces, NamespaceStats{
+			Name:        name,
+			Keys:        len(ks.data),
+			Bytes:       ks.bytes,
+			MaxEntries:  ks.quota.MaxEntries,
+			MaxBytes:    ks.quota.MaxBytes,
+			Hits:        ks.hits,
+			Misses:      ks.misses,
+			Sets:        ks.sets,
+			Deletes:     ks.deletes,
+			Evictions:   ks.evictions,
+			Expirations: ks.expirations,
+			Rejections:  ks.rejections,
+		})
+
+		stats.Keys += len(ks.data)
+		stats.Bytes += ks.bytes
+		stats.Hits += ks.hits
+		stats.Misses += ks.misses
+		stats.Sets += ks.sets
+		stats.Deletes += ks.deletes
+		stats.Evictions += ks.evictions
+		stats.Expirations += ks.expirations
+		stats.Rejections += ks.rejections
+	}
+	stats.Misses += c.misses
+	c.mu.RUnlock()
+
+	if lookups := stats.Hits + stats.Misses; lookups > 0 {
+		stats.HitRatio = float64(stats.Hits) / float64(lookups)
+	}
+	stats.Commands = c.commands.snapshot()
+
+	return stats
+}
+
+// ResetStats pone a cero los contadores y histogramas (no afecta al uptime)
+func (c *CacheEngine) ResetStats() {
+	c.mu.Lock()
+	for _, ks := range c.namespaces {
+		ks.hits, ks.misses = 0, 0
+		ks.sets, ks.deletes = 0, 0
+		ks.evictions, ks.expirations, ks.rejections = 0, 0, 0
+	}
+	c.misses = 0
+	c.mu.Unlock()
+
+	c.commands.reset()
+}
+
+// FormatInfo genera el texto del comando INFO. Con section vacío o "all"
+// incluye todas las secciones.
+func FormatInfo(stats Stats, section string) (string, error) {
+	section = strings.ToLower(section)
+	if section != "" && section != "all" {
+		known := false
+		for _, name := range InfoSections {
+			if name == section {
+				known = true
+				break
+			}
+		}
+		if !known {
+			return "", fmt.Errorf("sección desconocida: %s", section)
+		}
+	}
+
+	var b strings.Builder
+	include := func(name string) bool {
+		if section == "" || section == "all" || section == name {
+			if b.Len() > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(&b, "# %s\n", strings.ToUpper(name[:1])+name[1:])
+			return true
+		}
+		return false
+	}
+
+	if include("server") {
+		fmt.Fprintf(&b, "uptime_in_seconds:%d\n", int64(stats.Uptime.Seconds()))
+		fmt.Fprintf(&b, "max_entries:%d\n", stats.MaxEntries)
+		fmt.Fprintf(&b, "eviction_policy:%s\n", stats.Policy)
+	}
+
+	if include("stats") {
+		fmt.Fprintf(&b, "keyspace_hits:%d\n", stats.Hits)
+		fmt.Fprintf(&b, "keyspace_misses:%d\n", stats.Misses)
+		fmt.Fprintf(&b, "hit_ratio:%.4f\n", stats.HitRatio)
+		fmt.Fprintf(&b, "total_sets:%d\n", stats.Sets)
+		fmt.Fprintf(&b, "total_deletes:%d\n", stats.Deletes)
+		fmt.Fprintf(&b, "evicted_keys:%d\n", stats.Evictions)
+		fmt.Fprintf(&b, "expired_keys:%d\n", stats.Expirations)
+		fmt.Fprintf(&b, "rejected_writes:%d\n", stats.Rejections)
+	}
+
+	if include("memory") {
+		fmt.Fprintf(&b, "used_bytes:%d\n", stats.Bytes)
+		fmt.Fprintf(&b, "keys:%d\n", stats.Keys)
+	}
+
+	if include("keyspace") {
+		for _, ns := range stats.Namespaces {
+			fmt.Fprintf(&b, "ns_%s:keys=%d,bytes=%d,max_entries=%d,max_bytes=%d,hits=%d,misses=%d,evictions=%d,expirations=%d\n",
+				ns.Name, ns.Keys, ns.Bytes, ns.MaxEntries, ns.MaxBytes, ns.Hits, ns.Misses, ns.Evictions, ns.Expirations)
+		}
+	}
+
+	if include("commandstats") {
+		names := make([]string, 0, len(stats.Commands))
+		for name := range stats.Commands {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			cmd := stats.Commands[name]
+			fmt.Fprintf(&b, "cmdstat_%s:calls=%d,usec=%d,usec_per_call=%.2f",
+				strings.ToLower(name), cmd.Calls, cmd.TotalTime.Microseconds(),
+				float64(cmd.Average().Nanoseconds())/1000)
+			for i, count := range cmd.Buckets {
+				bound := "inf"
+				if i < len(LatencyBuckets) {
+					bound = fmt.Sprintf("%d", LatencyBuckets[i].Microseconds())
+				}
+				fmt.Fprintf(&b, ",hist_%s=%d", bound, count)
+			}
+			b.WriteString("\n")
+		}
+	}
+
+	return b.String(), nil
+}
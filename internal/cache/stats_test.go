@@ -0,0 +1,90 @@
+package cache
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestStatsCounters prueba los contadores de aciertos, fallos y escrituras
+func TestStatsCounters(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	cache.Set("key1", "value1")
+	cache.Get("key1")
+	cache.Get("key1")
+	cache.Get("missing")
+	cache.Delete("key1")
+
+	stats := cache.Stats()
+	if stats.Hits != 2 || stats.Misses != 1 {
+		t.Errorf("Esperaba 2 aciertos y 1 fallo, obtuve %d y %d", stats.Hits, stats.Misses)
+	}
+
+	if stats.Sets != 1 || stats.Deletes != 1 {
+		t.Errorf("Esperaba 1 escritura y 1 eliminación, obtuve %d y %d", stats.Sets, stats.Deletes)
+	}
+
+	if stats.HitRatio < 0.66 || stats.HitRatio > 0.67 {
+		t.Errorf("Ratio de aciertos inesperado: %f", stats.HitRatio)
+	}
+
+	cache.ResetStats()
+	if stats := cache.Stats(); stats.Hits != 0 || stats.Sets != 0 {
+		t.Error("RESETSTATS debería poner los contadores a cero")
+	}
+}
+
+// TestStatsEvictionsAndExpirations prueba los contadores de expulsión y expiración
+func TestStatsEvictionsAndExpirations(t *testing.T) {
+	cache := NewCacheEngine(1)
+	defer cache.Close(context.Background())
+
+	cache.Set("key1", "value1")
+	cache.Set("key2", "value2")
+	cache.Expire("key2", 1)
+	time.Sleep(1100 * time.Millisecond)
+	cache.Get("key2")
+
+	stats := cache.Stats()
+	if stats.Evictions != 1 {
+		t.Errorf("Esperaba 1 expulsión, obtuve %d", stats.Evictions)
+	}
+
+	if stats.Expirations != 1 {
+		t.Errorf("Esperaba 1 expiración, obtuve %d", stats.Expirations)
+	}
+}
+
+// TestRecordCommand prueba los histogramas de latencia por comando
+func TestRecordCommand(t *testing.T) {
+	cache := NewCacheEngine(10)
+	defer cache.Close(context.Background())
+
+	cache.RecordCommand("get", 5*time.Microsecond)
+	cache.RecordCommand("GET", 2*time.Second)
+
+	cmd := cache.Stats().Commands["GET"]
+	if cmd.Calls != 2 {
+		t.Fatalf("Esperaba 2 llamadas, obtuve %d", cmd.Calls)
+	}
+
+	if cmd.Buckets[0] != 1 || cmd.Buckets[len(cmd.Buckets)-1] != 1 {
+		t.Errorf("Buckets inesperados: %v", cmd.Buckets)
+	}
+
+	info, err := FormatInfo(cache.Stats(), "commandstats")
+	if err != nil {
+		t.Fatalf("No esperaba error: %v", err)
+	}
+
+	if !strings.Contains(info, "cmdstat_get:calls=2") {
+		t.Errorf("INFO no contiene las estadísticas de GET:\n%s", info)
+	}
+
+	if _, err := FormatInfo(cache.Stats(), "desconocida"); err == nil {
+		t.Error("Esperaba error para una sección desconocida")
+	}
+}
@@ -0,0 +1,41 @@
+package cache
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// Payload es un valor que acompaña unos datos con metadatos de un
+// front-end, como los flags de memcached. Los demás protocolos muestran
+// solo los datos.
+type Payload interface {
+	Bytes() []byte
+}
+
+// ValueBytes convierte un valor almacenado en bytes para los protocolos de red.
+// Cadenas, []byte y Payload se devuelven tal cual, los números en decimal y
+// el resto en JSON.
+func ValueBytes(value interface{}) []byte {
+	switch v := value.(type) {
+	case []byte:
+		return v
+	case Payload:
+		return v.Bytes()
+	case string:
+		return []byte(v)
+	case int:
+		return []byte(strconv.Itoa(v))
+	case int64:
+		return []byte(strconv.FormatInt(v, 10))
+	case float64:
+		return []byte(strconv.FormatFloat(v, 'f', -1, 64))
+	case bool:
+		return []byte(strconv.FormatBool(v))
+	}
+
+	if data, err := json.Marshal(value); err == nil {
+		return data
+	}
+	return []byte(fmt.Sprint(value))
+}
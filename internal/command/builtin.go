@@ -0,0 +1,444 @@
+package command
+
+import (
+	"cache-engine/internal/cache"
+	"cache-engine/internal/persistence"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+func init() {
+	builtins := []Command{
+		{Name: "PING", Arity: -1, Flags: FlagConnection, Handler: cmdPing,
+			Usage: "[message]", Summary: "Comprueba la conexión"},
+		{Name: "ECHO", Arity: 2, Flags: FlagConnection, Handler: cmdEcho,
+			Usage: "message", Summary: "Retorna el mensaje"},
+		{Name: "SELECT", Arity: 2, Flags: FlagConnection, Handler: cmdSelect,
+			Usage: "namespace", Summary: "Cambia de namespace"},
+		{Name: "HELP", Arity: -1, Flags: FlagConnection, Handler: cmdHelp,
+			Usage: "[command]", Summary: "Lista los comandos o muestra la ayuda de uno"},
+
+		{Name: "GET", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: cmdGet,
+			Usage: "key", Summary: "Obtiene el valor de una clave"},
+		{Name: "MGET", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: cmdMGet,
+			Usage: "key [key ...]", Summary: "Obtiene varios valores"},
+		{Name: "EXISTS", Arity: -2, Flags: FlagReadOnly, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: cmdExists,
+			Usage: "key [key ...]", Summary: "Cuenta las claves que existen"},
+		{Name: "TTL", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: cmdTTL,
+			Usage: "key", Summary: "Segundos hasta la expiración (-1 sin expiración, -2 si no existe)"},
+		{Name: "TYPE", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: cmdType,
+			Usage: "key", Summary: "Tipo de una clave (string o none)"},
+		{Name: "KEYS", Arity: 2, Flags: FlagReadOnly | FlagKeyspace, Handler: cmdKeys,
+			Usage: "pattern", Summary: "Lista las claves que cumplen un patrón glob"},
+		{Name: "DBSIZE", Arity: 1, Flags: FlagReadOnly | FlagKeyspace, Handler: cmdDBSize,
+			Summary: "Número de claves del namespace"},
+
+		{Name: "SET", Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: cmdSet,
+			Usage: "key value [EX seconds|PX milliseconds] [NX|XX]", Summary: "Establece el valor de una clave"},
+		{Name: "MSET", Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: -1, KeyStep: 2, Handler: cmdMSet,
+			Usage: "key value [key value ...]", Summary: "Establece varias claves"},
+		{Name: "MSETNX", Arity: -3, Flags: FlagWrite, FirstKey: 1, LastKey: -1, KeyStep: 2, Handler: cmdMSetNX,
+			Usage: "key value [key value ...]", Summary: "Establece varias claves si ninguna existe"},
+		{Name: "DEL", Arity: -2, Flags: FlagWrite, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: cmdDel,
+			Usage: "key [key ...]", Summary: "Elimina claves y retorna cuántas existían"},
+		{Name: "MDEL", Arity: -2, Flags: FlagWrite, FirstKey: 1, LastKey: -1, KeyStep: 1, Handler: cmdDel,
+			Usage: "key [key ...]", Summary: "Alias de DEL"},
+		{Name: "EXPIRE", Arity: 3, Flags: FlagWrite, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: cmdExpire,
+			Usage: "key seconds", Summary: "Establece la expiración de una clave"},
+		{Name: "MOVE", Arity: 3, Flags: FlagWrite | FlagKeyspace, FirstKey: 1, LastKey: 1, KeyStep: 1, Handler: cmdMove,
+			Usage: "key namespace", Summary: "Mueve una clave a otro namespace"},
+		{Name: "FLUSHDB", Arity: -1, Flags: FlagWrite | FlagKeyspace | FlagDangerous, Handler: cmdFlushDB,
+			Summary: "Vacía el namespace actual"},
+		{Name: "FLUSHALL", Arity: -1, Flags: FlagWrite | FlagKeyspace | FlagDangerous, Handler: cmdFlushAll,
+			Summary: "Vacía todos los namespaces"},
+		{Name: "SWAPDB", Arity: 3, Flags: FlagWrite | FlagKeyspace | FlagDangerous, Handler: cmdSwapDB,
+			Usage: "namespace1 namespace2", Summary: "Intercambia dos namespaces"},
+
+		{Name: "INFO", Arity: -1, Flags: FlagAdmin, Handler: cmdInfo,
+			Usage: "[section]", Summary: "Estadísticas detalladas (server, stats, memory, keyspace, commandstats)"},
+		{Name: "STATS", Arity: 1, Flags: FlagAdmin, Handler: cmdStats,
+			Summary: "Resumen de las estadísticas"},
+		{Name: "RESETSTATS", Arity: 1, Flags: FlagAdmin, Handler: cmdResetStats,
+			Summary: "Reinicia las estadísticas"},
+		{Name: "DBLIMIT", Arity: 2, Flags: FlagAdmin, Handler: cmdDBLimit,
+			Usage: "max", Summary: "Límite propio de entradas del namespace (0 = sin límite)"},
+		{Name: "QUOTA", Arity: -3, Flags: FlagAdmin, Handler: cmdQuota,
+			Usage: "max-entries max-bytes [EVICT|REJECT]", Summary: "Cuota del namespace actual"},
+		{Name: "EVICTION", Arity: -1, Flags: FlagAdmin, Handler: cmdEviction,
+			Usage: "[LRU|FAIR]", Summary: "Consulta o cambia la política de expulsión global"},
+
+		{Name: "ENABLELOG", Arity: -1, Flags: FlagAdmin | FlagDangerous | FlagLocal, Handler: cmdEnableLog,
+			Usage: "[file]", Summary: "Habilita el logging automático; continúa un log existente o empieza con el estado actual"},
+		{Name: "DISABLELOG", Arity: 1, Flags: FlagAdmin | FlagLocal, Handler: cmdDisableLog,
+			Summary: "Deshabilita el logging automático"},
+		{Name: "SAVE", Arity: -1, Flags: FlagAdmin | FlagLocal, Handler: cmdSave,
+			Usage: "[file]", Summary: "Guarda el estado actual en un log"},
+		{Name: "LOAD", Arity: -1, Flags: FlagWrite | FlagKeyspace | FlagAdmin | FlagDangerous | FlagLocal, Handler: cmdLoad,
+			Usage: "[file]", Summary: "Carga el estado desde un log"},
+	}
+	for _, cmd := range builtins {
+		if err := Register(cmd); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// storedValue convierte un argumento en el valor que se guarda: texto si
+// es UTF-8 válido y bytes si no, para que la persistencia lo codifique en
+// base64
+func storedValue(arg []byte) interface{} {
+	if utf8.Valid(arg) {
+		return string(arg)
+	}
+	return append([]byte(nil), arg...)
+}
+
+// stringArgs convierte los argumentos en texto
+func stringArgs(args [][]byte) []string {
+	parts := make([]string, len(args))
+	for i, arg := range args {
+		parts[i] = string(arg)
+	}
+	return parts
+}
+
+// optionalArg retorna el argumento i o def si no existe
+func optionalArg(args [][]byte, i int, def string) string {
+	if i < len(args) {
+		return string(args[i])
+	}
+	return def
+}
+
+func cmdPing(ctx *Context, args [][]byte) (interface{}, error) {
+	switch len(args) {
+	case 1:
+		return Status("PONG"), nil
+	case 2:
+		return args[1], nil
+	}
+	return nil, &ArityError{Command: "PING"}
+}
+
+func cmdEcho(ctx *Context, args [][]byte) (interface{}, error) {
+	return args[1], nil
+}
+
+func cmdSelect(ctx *Context, args [][]byte) (interface{}, error) {
+	ctx.DB = ctx.Engine.Namespace(string(args[1]))
+	return OK, nil
+}
+
+// cmdHelp lista los comandos con su sinopsis o muestra la ayuda de uno
+func cmdHelp(ctx *Context, args [][]byte) (interface{}, error) {
+	if len(args) > 2 {
+		return nil, &ArityError{Command: "HELP"}
+	}
+	if len(args) == 2 {
+		cmd, exists := ctx.Lookup(string(args[1]))
+		if !exists {
+			return nil, fmt.Errorf("%w '%s'", ErrUnknown, args[1])
+		}
+		return Text(cmd.Help()), nil
+	}
+
+	return Text(Index(ctx.Commands())), nil
+}
+
+func cmdGet(ctx *Context, args [][]byte) (interface{}, error) {
+	value, exists := ctx.DB.Get(string(args[1]))
+	if !exists {
+		return nil, nil
+	}
+	return cache.ValueBytes(value), nil
+}
+
+func cmdMGet(ctx *Context, args [][]byte) (interface{}, error) {
+	values, found := ctx.DB.MGet(stringArgs(args[1:])...)
+	results := make([]interface{}, len(values))
+	for i, value := range values {
+		if found[i] {
+			results[i] = cache.ValueBytes(value)
+		}
+	}
+	return results, nil
+}
+
+func cmdExists(ctx *Context, args [][]byte) (interface{}, error) {
+	var count int64
+	for _, key := range args[1:] {
+		if _, exists := ctx.DB.TTL(string(key)); exists {
+			count++
+		}
+	}
+	return count, nil
+}
+
+func cmdTTL(ctx *Context, args [][]byte) (interface{}, error) {
+	ttl, exists := ctx.DB.TTL(string(args[1]))
+	if !exists {
+		return int64(-2), nil
+	}
+	return ttl, nil
+}
+
+func cmdType(ctx *Context, args [][]byte) (interface{}, error) {
+	if _, exists := ctx.DB.TTL(string(args[1])); !exists {
+		return Status("none"), nil
+	}
+	return Status("string"), nil
+}
+
+func cmdKeys(ctx *Context, args [][]byte) (interface{}, error) {
+	keys := ctx.DB.Keys(string(args[1]))
+	results := make([]interface{}, len(keys))
+	for i, key := range keys {
+		results[i] = key
+	}
+	return results, nil
+}
+
+func cmdDBSize(ctx *Context, args [][]byte) (interface{}, error) {
+	return int64(ctx.DB.Size()), nil
+}
+
+// cmdSet implementa SET key value [EX seconds | PX milliseconds] [NX | XX]
+func cmdSet(ctx *Context, args [][]byte) (interface{}, error) {
+	key, value := string(args[1]), storedValue(args[2])
+	cond := cache.SetAlways
+	ttl := 0
+
+	for i := 3; i < len(args); i++ {
+		option := strings.ToUpper(string(args[i]))
+		switch option {
+		case "NX", "XX":
+			if cond != cache.SetAlways {
+				return nil, ErrSyntax
+			}
+			cond = cache.SetIfAbsent
+			if option == "XX" {
+				cond = cache.SetIfPresent
+			}
+		case "EX", "PX":
+			if ttl != 0 || i+1 >= len(args) {
+				return nil, ErrSyntax
+			}
+			n, err := strconv.ParseInt(string(args[i+1]), 10, 64)
+			if err != nil || n <= 0 {
+				return nil, errors.New("tiempo de expiración inválido en 'set'")
+			}
+			if option == "PX" {
+				// El motor trabaja en segundos: redondear hacia arriba
+				n = int64(math.Ceil(float64(n) / 1000))
+			}
+			ttl = int(n)
+			i++
+		default:
+			return nil, ErrSyntax
+		}
+	}
+
+	written, err := ctx.DB.SetEx(key, value, ttl, cond)
+	if err != nil {
+		return nil, err
+	}
+	if !written {
+		return nil, nil
+	}
+	return OK, nil
+}
+
+// parsePairs convierte los argumentos clave-valor de MSET y MSETNX
+func parsePairs(args [][]byte) (map[string]interface{}, error) {
+	if len(args)%2 != 1 {
+		return nil, &ArityError{Command: string(args[0])}
+	}
+
+	entries := make(map[string]interface{}, len(args)/2)
+	for i := 1; i < len(args); i += 2 {
+		entries[string(args[i])] = storedValue(args[i+1])
+	}
+	return entries, nil
+}
+
+func cmdMSet(ctx *Context, args [][]byte) (interface{}, error) {
+	entries, err := parsePairs(args)
+	if err != nil {
+		return nil, err
+	}
+	if err := ctx.DB.MSet(entries); err != nil {
+		return nil, err
+	}
+	return OK, nil
+}
+
+func cmdMSetNX(ctx *Context, args [][]byte) (interface{}, error) {
+	entries, err := parsePairs(args)
+	if err != nil {
+		return nil, err
+	}
+	written, err := ctx.DB.MSetNX(entries)
+	if err != nil {
+		return nil, err
+	}
+	if !written {
+		return int64(0), nil
+	}
+	return int64(1), nil
+}
+
+func cmdDel(ctx *Context, args [][]byte) (interface{}, error) {
+	keys := stringArgs(args[1:])
+	return int64(ctx.DB.MDelete(keys...)), nil
+}
+
+func cmdExpire(ctx *Context, args [][]byte) (interface{}, error) {
+	key := string(args[1])
+	seconds, err := strconv.Atoi(string(args[2]))
+	if err != nil {
+		return nil, ErrNotInteger
+	}
+	if !ctx.DB.Expire(key, seconds) {
+		return int64(0), nil
+	}
+	return int64(1), nil
+}
+
+func cmdMove(ctx *Context, args [][]byte) (interface{}, error) {
+	key, target := string(args[1]), string(args[2])
+	if !ctx.DB.Move(key, target) {
+		return int64(0), nil
+	}
+	return int64(1), nil
+}
+
+func cmdFlushDB(ctx *Context, args [][]byte) (interface{}, error) {
+	ctx.DB.Flush()
+	return OK, nil
+}
+
+func cmdFlushAll(ctx *Context, args [][]byte) (interface{}, error) {
+	ctx.Engine.FlushAll()
+	return OK, nil
+}
+
+func cmdSwapDB(ctx *Context, args [][]byte) (interface{}, error) {
+	ctx.Engine.SwapNamespaces(string(args[1]), string(args[2]))
+	return OK, nil
+}
+
+func cmdInfo(ctx *Context, args [][]byte) (interface{}, error) {
+	info, err := cache.FormatInfo(ctx.Engine.Stats(), optionalArg(args, 1, ""))
+	if err != nil {
+		return nil, err
+	}
+	return Text(info), nil
+}
+
+// cmdStats resume las estadísticas del motor y de cada namespace
+func cmdStats(ctx *Context, args [][]byte) (interface{}, error) {
+	engine := ctx.Engine
+	stats := engine.Stats()
+	lines := []string{
+		fmt.Sprintf("Entradas en cache: %d", engine.Size()),
+		fmt.Sprintf("Límite máximo: %d", engine.MaxEntries()),
+		fmt.Sprintf("Política de expulsión: %s", engine.EvictionPolicy()),
+		fmt.Sprintf("Aciertos: %d  Fallos: %d  Ratio: %.2f%%", stats.Hits, stats.Misses, stats.HitRatio*100),
+		fmt.Sprintf("Escrituras: %d  Eliminaciones: %d  Expulsiones: %d  Expiradas: %d",
+			stats.Sets, stats.Deletes, stats.Evictions, stats.Expirations),
+		fmt.Sprintf("Uptime: %s", stats.Uptime.Truncate(time.Second)),
+	}
+	for _, ns := range stats.Namespaces {
+		lines = append(lines, fmt.Sprintf("Namespace %s: claves=%d/%d bytes=%d/%d aciertos=%d fallos=%d expulsiones=%d rechazos=%d",
+			ns.Name, ns.Keys, ns.MaxEntries, ns.Bytes, ns.MaxBytes,
+			ns.Hits, ns.Misses, ns.Evictions, ns.Rejections))
+	}
+	return Text(strings.Join(lines, "\n")), nil
+}
+
+func cmdResetStats(ctx *Context, args [][]byte) (interface{}, error) {
+	ctx.Engine.ResetStats()
+	return OK, nil
+}
+
+func cmdDBLimit(ctx *Context, args [][]byte) (interface{}, error) {
+	limit, err := strconv.Atoi(string(args[1]))
+	if err != nil || limit < 0 {
+		return nil, errors.New("el límite debe ser un número no negativo")
+	}
+	ctx.DB.SetMaxEntries(limit)
+	return OK, nil
+}
+
+func cmdQuota(ctx *Context, args [][]byte) (interface{}, error) {
+	if len(args) > 4 {
+		return nil, ErrSyntax
+	}
+	maxEntries, errEntries := strconv.Atoi(string(args[1]))
+	maxBytes, errBytes := strconv.ParseInt(string(args[2]), 10, 64)
+	if errEntries != nil || errBytes != nil || maxEntries < 0 || maxBytes < 0 {
+		return nil, errors.New("los límites deben ser números no negativos")
+	}
+
+	quota := cache.Quota{MaxEntries: maxEntries, MaxBytes: maxBytes}
+	switch mode := strings.ToUpper(optionalArg(args, 3, "EVICT")); mode {
+	case "EVICT":
+	case "REJECT":
+		quota.Reject = true
+	default:
+		return nil, errors.New("el modo debe ser EVICT o REJECT")
+	}
+	ctx.DB.SetQuota(quota)
+	return OK, nil
+}
+
+func cmdEviction(ctx *Context, args [][]byte) (interface{}, error) {
+	switch len(args) {
+	case 1:
+		return []byte(ctx.Engine.EvictionPolicy().String()), nil
+	case 2:
+		policy, err := cache.ParseEvictionPolicy(string(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		ctx.Engine.SetEvictionPolicy(policy)
+		return OK, nil
+	}
+	return nil, &ArityError{Command: "EVICTION"}
+}
+
+func cmdEnableLog(ctx *Context, args [][]byte) (interface{}, error) {
+	filename := optionalArg(args, 1, persistence.DefaultLogFile)
+	if err := persistence.EnableLogging(ctx.Engine, filename); err != nil {
+		return nil, err
+	}
+	return Status("Logging automático habilitado en: " + filename), nil
+}
+
+func cmdDisableLog(ctx *Context, args [][]byte) (interface{}, error) {
+	if err := persistence.DisableLogging(ctx.Engine); err != nil {
+		return nil, err
+	}
+	return Status("Logging automático deshabilitado"), nil
+}
+
+func cmdSave(ctx *Context, args [][]byte) (interface{}, error) {
+	filename := optionalArg(args, 1, persistence.DefaultLogFile)
+	if err := persistence.SaveToLog(ctx.Engine, filename); err != nil {
+		return nil, err
+	}
+	return Status("Guardado en " + filename), nil
+}
+
+func cmdLoad(ctx *Context, args [][]byte) (interface{}, error) {
+	filename := optionalArg(args, 1, persistence.DefaultLogFile)
+	if err := persistence.LoadFromLog(ctx.Engine, filename); err != nil {
+		return nil, err
+	}
+	return Status("Cargado desde " + filename), nil
+}
@@ -0,0 +1,370 @@
+// Package command contiene la tabla de comandos compartida por la CLI y los
+// front-ends de red. Cada comando declara su aridad, sus flags, la posición
+// de sus claves y su ayuda; los front-ends solo traducen los argumentos y
+// las respuestas a su protocolo.
+package command
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// Flag describe el comportamiento de un comando
+type Flag uint16
+
+const (
+	FlagReadOnly   Flag = 1 << iota // Solo lee claves
+	FlagWrite                       // Modifica claves
+	FlagKeyspace                    // Opera sobre el namespace completo
+	FlagAdmin                       // Administración del servidor
+	FlagDangerous                   // Puede afectar a todos los datos
+	FlagConnection                  // Estado de la sesión o de la conexión
+	FlagLocal                       // Solo en la CLI local: accede a archivos del servidor
+)
+
+// flagNames asocia cada flag con el nombre que muestran HELP y COMMAND INFO
+var flagNames = []struct {
+	flag Flag
+	name string
+}{
+	{FlagReadOnly, "readonly"},
+	{FlagWrite, "write"},
+	{FlagKeyspace, "keyspace"},
+	{FlagAdmin, "admin"},
+	{FlagDangerous, "dangerous"},
+	{FlagConnection, "connection"},
+	{FlagLocal, "local"},
+}
+
+// Names retorna los nombres de los flags activos
+func (f Flag) Names() []string {
+	names := []string{}
+	for _, fn := range flagNames {
+		if f&fn.flag != 0 {
+			names = append(names, fn.name)
+		}
+	}
+	return names
+}
+
+// Categories retorna las categorías de ACL que corresponden a los flags
+func (f Flag) Categories() acl.Category {
+	var categories acl.Category
+	if f&FlagReadOnly != 0 {
+		categories |= acl.CategoryRead
+	}
+	if f&FlagWrite != 0 {
+		categories |= acl.CategoryWrite
+	}
+	if f&FlagKeyspace != 0 {
+		categories |= acl.CategoryKeyspace
+	}
+	if f&FlagAdmin != 0 {
+		categories |= acl.CategoryAdmin
+	}
+	if f&FlagDangerous != 0 {
+		categories |= acl.CategoryDangerous
+	}
+	if f&FlagConnection != 0 {
+		categories |= acl.CategoryConnection
+	}
+	return categories
+}
+
+// access retorna cómo usa las claves un comando con estos flags
+func (f Flag) access() acl.Access {
+	var access acl.Access
+	if f&FlagReadOnly != 0 {
+		access |= acl.AccessRead
+	}
+	if f&FlagWrite != 0 {
+		access |= acl.AccessWrite
+	}
+	return access
+}
+
+// Handler ejecuta un comando; args[0] es el nombre del comando. El
+// resultado es uno de los tipos de respuesta: nil, Status, Text, []byte,
+// string, int64, []interface{} o map[string]interface{}.
+type Handler func(ctx *Context, args [][]byte) (interface{}, error)
+
+// Command describe un comando. Las posiciones de las claves siguen la
+// convención de Redis y se usan para comprobar permisos, autocompletar y
+// seguir las claves leídas.
+type Command struct {
+	Name     string // En mayúsculas
+	Arity    int    // Positivo: número exacto de argumentos; negativo: mínimo
+	Flags    Flag
+	FirstKey int     // Posición de la primera clave (0 = sin claves)
+	LastKey  int     // Posición de la última clave (negativo: desde el final)
+	KeyStep  int     // Distancia entre claves consecutivas
+	Usage    string  // Argumentos, p. ej. "key value [EX seconds]"
+	Summary  string  // Descripción de una línea
+	Handler  Handler // nil solo en las tablas de front-end (NewFrontendRegistry)
+}
+
+// Keys extrae las claves de los argumentos de un comando
+func (cmd *Command) Keys(args [][]byte) []string {
+	if cmd.FirstKey == 0 || cmd.FirstKey >= len(args) {
+		return nil
+	}
+	last := cmd.LastKey
+	if last < 0 {
+		last += len(args)
+	}
+
+	var keys []string
+	for i := cmd.FirstKey; i <= last && i < len(args); i += cmd.KeyStep {
+		keys = append(keys, string(args[i]))
+	}
+	return keys
+}
+
+// CheckArity comprueba el número de argumentos
+func (cmd *Command) CheckArity(args [][]byte) error {
+	if (cmd.Arity > 0 && len(args) != cmd.Arity) || (cmd.Arity < 0 && len(args) < -cmd.Arity) {
+		return &ArityError{Command: cmd.Name}
+	}
+	return nil
+}
+
+// Call valida la aridad, ejecuta el comando y registra su latencia en las
+// estadísticas del motor, también cuando falla
+func (cmd *Command) Call(ctx *Context, args [][]byte) (interface{}, error) {
+	if err := cmd.CheckArity(args); err != nil {
+		return nil, err
+	}
+	start := time.Now()
+	result, err := cmd.Handler(ctx, args)
+	ctx.Engine.RecordCommand(cmd.Name, time.Since(start))
+	return result, err
+}
+
+// Synopsis retorna el nombre seguido de los argumentos
+func (cmd *Command) Synopsis() string {
+	if cmd.Usage == "" {
+		return cmd.Name
+	}
+	return cmd.Name + " " + cmd.Usage
+}
+
+// Help retorna la ayuda de un comando: sinopsis, descripción y flags
+func (cmd *Command) Help() string {
+	lines := []string{cmd.Synopsis()}
+	if cmd.Summary != "" {
+		lines = append(lines, "  "+cmd.Summary)
+	}
+	if flags := cmd.Flags.Names(); len(flags) > 0 {
+		lines = append(lines, "  flags: "+strings.Join(flags, ", "))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Index retorna una línea por comando con su sinopsis y su descripción
+func Index(commands []*Command) string {
+	lines := make([]string, len(commands))
+	for i, cmd := range commands {
+		lines[i] = fmt.Sprintf("%-40s %s", cmd.Synopsis(), cmd.Summary)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Respuestas de los comandos que no son datos de usuario
+type (
+	// Status es una respuesta breve de estado (OK, PONG)
+	Status string
+
+	// Text es un informe de varias líneas para leerlo tal cual (INFO)
+	Text string
+)
+
+// OK es la respuesta de los comandos sin resultado
+const OK = Status("OK")
+
+// JSONValue convierte una respuesta en un valor codificable en JSON. Los
+// bytes que no son UTF-8 válido se codifican como {"base64": "..."}.
+func JSONValue(result interface{}) interface{} {
+	switch v := result.(type) {
+	case Status:
+		return string(v)
+	case Text:
+		return string(v)
+	case []byte:
+		if utf8.Valid(v) {
+			return string(v)
+		}
+		return map[string]string{"base64": base64.StdEncoding.EncodeToString(v)}
+	case []interface{}:
+		items := make([]interface{}, len(v))
+		for i, item := range v {
+			items[i] = JSONValue(item)
+		}
+		return items
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			m[key] = JSONValue(item)
+		}
+		return m
+	}
+	return result
+}
+
+// Errores de los comandos. Los front-ends añaden el prefijo de su
+// protocolo (ERR, OOM...).
+var (
+	ErrSyntax     = errors.New("error de sintaxis")
+	ErrNotInteger = errors.New("el valor no es un entero o está fuera de rango")
+	ErrUnknown    = errors.New("comando desconocido")
+)
+
+// ArityError indica un número de argumentos incorrecto
+type ArityError struct {
+	Command string
+}
+
+func (e *ArityError) Error() string {
+	return fmt.Sprintf("número de argumentos incorrecto para '%s'", strings.ToLower(e.Command))
+}
+
+// Context es el estado de la sesión o la conexión que ejecuta un comando
+type Context struct {
+	Engine   *cache.CacheEngine
+	DB       *cache.Namespace // Namespace seleccionado; SELECT lo cambia
+	Registry *Registry        // Tabla de comandos del front-end
+	Local    bool             // CLI local: admite los comandos FlagLocal
+}
+
+// NewContext crea un contexto sobre el namespace por defecto
+func NewContext(engine *cache.CacheEngine, registry *Registry, local bool) *Context {
+	return &Context{Engine: engine, DB: engine.Namespace(cache.DefaultNamespace), Registry: registry, Local: local}
+}
+
+// available indica si el comando se puede ejecutar en este contexto
+func (ctx *Context) available(cmd *Command) bool {
+	return ctx.Local || cmd.Flags&FlagLocal == 0
+}
+
+// Lookup busca un comando disponible en este contexto
+func (ctx *Context) Lookup(name string) (*Command, bool) {
+	cmd, exists := ctx.Registry.Lookup(name)
+	if !exists || !ctx.available(cmd) {
+		return nil, false
+	}
+	return cmd, true
+}
+
+// Commands retorna los comandos disponibles en este contexto, ordenados
+func (ctx *Context) Commands() []*Command {
+	var commands []*Command
+	for _, cmd := range ctx.Registry.Commands() {
+		if ctx.available(cmd) {
+			commands = append(commands, cmd)
+		}
+	}
+	return commands
+}
+
+// Registry es una tabla de comandos. Un registro puede heredar los
+// comandos de otro (parent) para añadir los propios de un front-end sin
+// modificar la tabla compartida.
+type Registry struct {
+	parent   *Registry
+	frontend bool // Admite comandos sin Handler, que ejecuta el front-end
+
+	mu       sync.RWMutex
+	commands map[string]*Command
+}
+
+// Default es la tabla compartida por la CLI y los front-ends de red
+var Default = NewRegistry(nil)
+
+// NewRegistry crea una tabla vacía que hereda los comandos de parent
+// (nil = ninguno)
+func NewRegistry(parent *Registry) *Registry {
+	return &Registry{parent: parent, commands: make(map[string]*Command)}
+}
+
+// NewFrontendRegistry crea la tabla propia de un front-end. A diferencia
+// de NewRegistry admite comandos sin Handler, que el front-end ejecuta por
+// su cuenta; no se deben usar con Command.Call.
+func NewFrontendRegistry(parent *Registry) *Registry {
+	r := NewRegistry(parent)
+	r.frontend = true
+	return r
+}
+
+// Register añade un comando, o reemplaza uno con el mismo nombre, y
+// declara sus categorías de ACL. Los comandos se deben registrar antes de
+// atender conexiones.
+func (r *Registry) Register(cmd Command) error {
+	cmd.Name = strings.ToUpper(cmd.Name)
+	switch {
+	case cmd.Name == "" || strings.ContainsAny(cmd.Name, " |"):
+		return fmt.Errorf("nombre de comando inválido: %q", cmd.Name)
+	case cmd.Arity == 0:
+		return fmt.Errorf("el comando %s necesita una aridad", cmd.Name)
+	case cmd.FirstKey < 0 || (cmd.FirstKey > 0 && cmd.KeyStep <= 0):
+		return fmt.Errorf("posiciones de clave inválidas en %s", cmd.Name)
+	case cmd.Handler == nil && !r.frontend:
+		return fmt.Errorf("el comando %s necesita un handler", cmd.Name)
+	}
+
+	r.mu.Lock()
+	r.commands[cmd.Name] = &cmd
+	r.mu.Unlock()
+	acl.RegisterCommand(cmd.Name, cmd.Flags.Categories(), cmd.Flags.access())
+	return nil
+}
+
+// Lookup busca un comando por nombre, sin distinguir mayúsculas
+func (r *Registry) Lookup(name string) (*Command, bool) {
+	name = strings.ToUpper(name)
+	r.mu.RLock()
+	cmd, exists := r.commands[name]
+	r.mu.RUnlock()
+	if !exists && r.parent != nil {
+		return r.parent.Lookup(name)
+	}
+	return cmd, exists
+}
+
+// Commands retorna los comandos, propios y heredados, ordenados por nombre
+func (r *Registry) Commands() []*Command {
+	byName := make(map[string]*Command)
+	for reg := r; reg != nil; reg = reg.parent {
+		reg.mu.RLock()
+		for name, cmd := range reg.commands {
+			if _, shadowed := byName[name]; !shadowed {
+				byName[name] = cmd
+			}
+		}
+		reg.mu.RUnlock()
+	}
+
+	commands := make([]*Command, 0, len(byName))
+	for _, cmd := range byName {
+		commands = append(commands, cmd)
+	}
+	sort.Slice(commands, func(i, j int) bool { return commands[i].Name < commands[j].Name })
+	return commands
+}
+
+// Register añade un comando a la tabla compartida. Así un programa que
+// embebe el motor puede ofrecer comandos propios en todos los front-ends.
+func Register(cmd Command) error {
+	return Default.Register(cmd)
+}
+
+// Lookup busca un comando en la tabla compartida
+func Lookup(name string) (*Command, bool) {
+	return Default.Lookup(name)
+}
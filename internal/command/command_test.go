@@ -0,0 +1,177 @@
+package command
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/cache"
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// call ejecuta un comando por nombre en el contexto
+func call(t *testing.T, ctx *Context, parts ...string) (interface{}, error) {
+	t.Helper()
+	cmd, exists := ctx.Lookup(parts[0])
+	if !exists {
+		return nil, ErrUnknown
+	}
+	args := make([][]byte, len(parts))
+	for i, part := range parts {
+		args[i] = []byte(part)
+	}
+	return cmd.Call(ctx, args)
+}
+
+// TestBuiltins prueba los comandos de la tabla compartida
+func TestBuiltins(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+	ctx := NewContext(engine, Default, false)
+
+	tests := []struct {
+		args []string
+		want interface{}
+	}{
+		{[]string{"SET", "a", "1", "NX"}, OK},
+		{[]string{"SET", "a", "2", "NX"}, nil},
+		{[]string{"get", "a"}, []byte("1")},
+		{[]string{"MSET", "b", "\xff", "c", "3"}, OK},
+		{[]string{"MGET", "a", "b", "x"}, []interface{}{[]byte("1"), []byte("\xff"), nil}},
+		{[]string{"EXISTS", "a", "x"}, int64(1)},
+		{[]string{"MDEL", "c", "x"}, int64(1)},
+		{[]string{"TYPE", "x"}, Status("none")},
+		{[]string{"SELECT", "otro"}, OK},
+		{[]string{"DBSIZE"}, int64(0)},
+	}
+	for _, tt := range tests {
+		got, err := call(t, ctx, tt.args...)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: esperaba %v, obtuve %v %v", tt.args, tt.want, got, err)
+		}
+	}
+	if ctx.DB.Name() != "otro" {
+		t.Errorf("SELECT debería cambiar el namespace, obtuve %s", ctx.DB.Name())
+	}
+
+	// Los valores binarios se guardan como bytes para la persistencia
+	if value, _ := engine.Get("b"); !reflect.DeepEqual(value, []byte("\xff")) {
+		t.Errorf("Esperaba []byte, obtuve %#v", value)
+	}
+
+	var arity *ArityError
+	if _, err := call(t, ctx, "MSET", "a", "1", "b"); !errors.As(err, &arity) {
+		t.Errorf("Esperaba ArityError, obtuve %v", err)
+	}
+	if _, err := call(t, ctx, "EXPIRE", "a", "x"); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("Esperaba ErrNotInteger, obtuve %v", err)
+	}
+	if _, err := call(t, ctx, "SAVE"); !errors.Is(err, ErrUnknown) {
+		t.Error("SAVE solo debería estar disponible en la CLI local")
+	}
+}
+
+// journal guarda las modificaciones que el motor registra
+type journal struct {
+	mutations []cache.Mutation
+}
+
+func (j *journal) Append(mutations []cache.Mutation) {
+	j.mutations = append(j.mutations, mutations...)
+}
+
+// TestSetExpiration prueba que SET con EX o PX escribe el valor y su
+// expiración en una sola modificación
+func TestSetExpiration(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+	ctx := NewContext(engine, Default, false)
+	j := &journal{}
+	engine.SetJournal(j)
+
+	if got, err := call(t, ctx, "SET", "k", "v", "EX", "10", "NX"); err != nil || got != OK {
+		t.Fatalf("SET: esperaba OK, obtuve %v %v", got, err)
+	}
+	if got, err := call(t, ctx, "SET", "p", "v", "PX", "1500"); err != nil || got != OK {
+		t.Fatalf("SET: esperaba OK, obtuve %v %v", got, err)
+	}
+	if len(j.mutations) != 2 {
+		t.Fatalf("Esperaba 2 modificaciones, obtuve %+v", j.mutations)
+	}
+	for _, m := range j.mutations {
+		if m.Operation != cache.OpSet || m.ExpiresAt == 0 {
+			t.Errorf("Esperaba un SET con expiración, obtuve %+v", m)
+		}
+	}
+	if ttl, _ := engine.TTL("p"); ttl < 1 || ttl > 2 {
+		t.Errorf("PX debería redondear a 2 segundos, obtuve %d", ttl)
+	}
+}
+
+// TestRegister prueba los comandos registrados por el programa
+func TestRegister(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	registry := NewRegistry(Default)
+	err := registry.Register(Command{
+		Name: "getlen", Arity: 2, Flags: FlagReadOnly, FirstKey: 1, LastKey: 1, KeyStep: 1,
+		Usage: "key", Summary: "Longitud del valor",
+		Handler: func(ctx *Context, args [][]byte) (interface{}, error) {
+			value, _ := ctx.DB.Get(string(args[1]))
+			return int64(len(cache.ValueBytes(value))), nil
+		},
+	})
+	if err != nil {
+		t.Fatalf("No se pudo registrar: %v", err)
+	}
+	if err := registry.Register(Command{Name: "MAL NOMBRE", Arity: 1, Handler: cmdPing}); err == nil {
+		t.Error("Esperaba error con un nombre con espacios")
+	}
+	if err := Register(Command{Name: "SINHANDLER", Arity: 1}); err == nil {
+		t.Error("Esperaba error con un comando sin handler en la tabla compartida")
+	}
+	if _, exists := Lookup("SINHANDLER"); exists {
+		t.Error("El comando sin handler no debería registrarse")
+	}
+	if err := NewFrontendRegistry(Default).Register(Command{Name: "LOCAL", Arity: 1}); err != nil {
+		t.Errorf("Una tabla de front-end debería admitir comandos sin handler: %v", err)
+	}
+	if _, exists := Lookup("GETLEN"); exists {
+		t.Error("El comando no debería añadirse a la tabla padre")
+	}
+
+	ctx := NewContext(engine, registry, false)
+	call(t, ctx, "SET", "k", "hola")
+	if got, err := call(t, ctx, "GETLEN", "k"); err != nil || got != int64(4) {
+		t.Errorf("GETLEN: esperaba 4, obtuve %v %v", got, err)
+	}
+
+	// Las categorías de ACL vienen de los flags
+	if acl.CommandCategories("GETLEN") != acl.CategoryRead {
+		t.Errorf("Categorías inesperadas: %v", acl.CommandCategories("GETLEN"))
+	}
+
+	help, _ := call(t, ctx, "HELP", "getlen")
+	if want := Text("GETLEN key\n  Longitud del valor\n  flags: readonly"); help != want {
+		t.Errorf("Esperaba %q, obtuve %q", want, help)
+	}
+	index, _ := call(t, ctx, "HELP")
+	if text := string(index.(Text)); !strings.Contains(text, "GETLEN key") || strings.Contains(text, "SAVE") {
+		t.Errorf("HELP debería listar los comandos disponibles: %q", text)
+	}
+}
+
+// TestKeys prueba la extracción de claves de los argumentos
+func TestKeys(t *testing.T) {
+	mset, _ := Lookup("MSET")
+	args := [][]byte{[]byte("MSET"), []byte("a"), []byte("1"), []byte("b"), []byte("2")}
+	if got := mset.Keys(args); !reflect.DeepEqual(got, []string{"a", "b"}) {
+		t.Errorf("Esperaba [a b], obtuve %v", got)
+	}
+	flush, _ := Lookup("FLUSHDB")
+	if got := flush.Keys([][]byte{[]byte("FLUSHDB")}); got != nil {
+		t.Errorf("FLUSHDB no tiene claves: %v", got)
+	}
+}
@@ -0,0 +1,66 @@
+package config
+
+import (
+	"cache-engine/internal/command"
+	"fmt"
+	"strings"
+)
+
+// Command retorna el comando CONFIG sobre la configuración del gestor,
+// para registrarlo en la tabla de comandos
+func (m *Manager) Command() command.Command {
+	return command.Command{
+		Name: "CONFIG", Arity: -2, Flags: command.FlagAdmin | command.FlagDangerous, Handler: m.cmdConfig,
+		Usage:   "GET pattern [pattern ...]|SET key value [key value ...]|REWRITE",
+		Summary: "Consulta y cambia la configuración sin reiniciar",
+	}
+}
+
+// cmdConfig implementa CONFIG GET, CONFIG SET y CONFIG REWRITE. GET
+// oculta los secretos y, fuera de la CLI local, SET no cambia las opciones
+// que escriben en ficheros del servidor.
+func (m *Manager) cmdConfig(ctx *command.Context, args [][]byte) (interface{}, error) {
+	params := make([]string, len(args)-2)
+	for i, arg := range args[2:] {
+		params[i] = string(arg)
+	}
+
+	switch strings.ToUpper(string(args[1])) {
+	case "GET":
+		if len(params) == 0 {
+			return nil, command.ErrSyntax
+		}
+		result := make(map[string]interface{})
+		for key, value := range m.Get(params...) {
+			if secretKeys[key] {
+				value = mask(value)
+			}
+			result[key] = value
+		}
+		return result, nil
+
+	case "SET":
+		if len(params) == 0 || len(params)%2 != 0 {
+			return nil, command.ErrSyntax
+		}
+		for i := 0; i < len(params) && !ctx.Local; i += 2 {
+			if Local(params[i]) {
+				return nil, fmt.Errorf("%s solo se puede cambiar desde la CLI local", strings.ToLower(params[i]))
+			}
+		}
+		if err := m.Set(params...); err != nil {
+			return nil, err
+		}
+		return command.OK, nil
+
+	case "REWRITE":
+		if len(params) != 0 {
+			return nil, command.ErrSyntax
+		}
+		if err := m.Rewrite(); err != nil {
+			return nil, err
+		}
+		return command.OK, nil
+	}
+	return nil, fmt.Errorf("subcomando desconocido '%s' en CONFIG", args[1])
+}
@@ -0,0 +1,442 @@
+// Package config carga la configuración del servidor desde un fichero
+// JSON, YAML o TOML y la completa con variables de entorno y flags.
+//
+// Cada opción tiene una clave con puntos que sigue la estructura del
+// fichero (p. ej. "http.port"). La misma clave sirve para las variables
+// de entorno, en mayúsculas, con guiones bajos y el prefijo EnvPrefix
+// (CACHE_ENGINE_HTTP_PORT).
+package config
+
+import (
+	"cache-engine/internal/cache"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// EnvPrefix es el prefijo de las variables de entorno de configuración
+const EnvPrefix = "CACHE_ENGINE_"
+
+// DefaultShutdownTimeout es la espera máxima por defecto a las peticiones
+// en curso al apagar
+const DefaultShutdownTimeout = 10 * time.Second
+
+// Modes son los modos de ejecución admitidos
+var Modes = []string{"cli", "http", "resp", "memcache", "grpc"}
+
+// Config es la configuración completa del servidor
+type Config struct {
+	MaxEntries      int           `json:"max_entries"`      // Límite global de entradas
+	CleanupInterval time.Duration `json:"cleanup_interval"` // Frecuencia del barrido de claves expiradas
+	Eviction        string        `json:"eviction"`         // Política de expulsión: lru o fair
+	Mode            string        `json:"mode"`             // Modo de ejecución
+	Metrics         string        `json:"metrics"`          // Dirección de /metrics (vacío = desactivado)
+	ShutdownTimeout time.Duration `json:"shutdown_timeout"` // Espera máxima a las peticiones en curso al apagar
+
+	HTTP     HTTPConfig     `json:"http"`
+	RESP     ListenerConfig `json:"resp"`
+	Memcache ListenerConfig `json:"memcache"`
+	GRPC     ListenerConfig `json:"grpc"`
+
+	UnixSocket     string `json:"unix_socket"`      // Socket Unix adicional (vacío = ninguno)
+	UnixSocketPerm string `json:"unix_socket_perm"` // Permisos del socket en octal
+	NoTCP          bool   `json:"no_tcp"`           // Escuchar solo en el socket Unix
+
+	TLS         TLSConfig         `json:"tls"`
+	ACL         ACLConfig         `json:"acl"`
+	Clients     ClientsConfig     `json:"clients"`
+	Persistence PersistenceConfig `json:"persistence"`
+}
+
+// ListenerConfig configura el listener TCP de un front-end
+type ListenerConfig struct {
+	Port int `json:"port"`
+}
+
+// HTTPConfig configura el servidor HTTP
+type HTTPConfig struct {
+	Port       int  `json:"port"`
+	EnableCORS bool `json:"enable_cors"`
+}
+
+// TLSConfig configura TLS en todos los listeners
+type TLSConfig struct {
+	Cert       string `json:"cert"`        // Certificado del servidor (PEM)
+	Key        string `json:"key"`         // Clave privada (PEM)
+	CA         string `json:"ca"`          // CA de los certificados de cliente (PEM)
+	MinVersion string `json:"min_version"` // 1.2 o 1.3
+	ClientAuth bool   `json:"client_auth"` // Exigir certificado de cliente
+}
+
+// ACLConfig configura los usuarios de los servidores de red
+type ACLConfig struct {
+	RequirePass string `json:"requirepass"` // Contraseña del usuario default
+	File        string `json:"file"`        // Fichero de usuarios
+}
+
+// ClientsConfig limita las conexiones de RESP y memcached
+type ClientsConfig struct {
+	Max           int           `json:"max"`            // Conexiones simultáneas (0 = sin límite)
+	IdleTimeout   time.Duration `json:"idle_timeout"`   // Cierre por inactividad (0 = nunca)
+	OutputLimit   int           `json:"output_limit"`   // Bytes de respuesta pendientes (0 = sin límite)
+	OutputTimeout time.Duration `json:"output_timeout"` // Espera a un cliente lento (0 = sin límite)
+}
+
+// PersistenceConfig configura el log de operaciones
+type PersistenceConfig struct {
+	Enabled          bool          `json:"enabled"`           // Cargar el log al arrancar y escribir en él
+	LogFile          string        `json:"log_file"`          // Fichero del log
+	SnapshotInterval time.Duration `json:"snapshot_interval"` // Guardar el estado completo cada intervalo en lugar de cada operación (0 = cada operación)
+	SnapshotOnExit   bool          `json:"snapshot_on_exit"`  // Compactar el log con una instantánea al apagar
+}
+
+// Default retorna la configuración por defecto, la misma que sin fichero
+// ni flags
+func Default() *Config {
+	return &Config{
+		MaxEntries:      1000,
+		CleanupInterval: time.Second,
+		Eviction:        "lru",
+		Mode:            "cli",
+		ShutdownTimeout: DefaultShutdownTimeout,
+		HTTP:            HTTPConfig{Port: 8080},
+		RESP:            ListenerConfig{Port: 6379},
+		Memcache:        ListenerConfig{Port: 11211},
+		GRPC:            ListenerConfig{Port: 50051},
+		UnixSocketPerm:  "700",
+		TLS:             TLSConfig{MinVersion: "1.2"},
+		Persistence:     PersistenceConfig{LogFile: "cache.log"},
+	}
+}
+
+// Load retorna la configuración por defecto completada con el fichero
+// indicado (si lo hay) y las variables de entorno. No la valida: faltan
+// los flags.
+func Load(path string) (*Config, error) {
+	cfg := Default()
+	if path != "" {
+		if err := cfg.LoadFile(path); err != nil {
+			return nil, err
+		}
+	}
+	if err := cfg.LoadEnv(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// LoadFile aplica un fichero de configuración. El formato se deduce de la
+// extensión: .json, .yaml, .yml o .toml.
+func (c *Config) LoadFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("error al leer la configuración: %v", err)
+	}
+
+	var values map[string]interface{}
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		values, err = parseJSON(data)
+	case ".yaml", ".yml":
+		values, err = parseYAML(data)
+	case ".toml":
+		values, err = parseTOML(data)
+	default:
+		return fmt.Errorf("%s: formato de configuración desconocido (usa .json, .yaml o .toml)", path)
+	}
+	if err != nil {
+		return fmt.Errorf("%s: %v", path, err)
+	}
+
+	flat := make(map[string]interface{})
+	flatten("", values, flat)
+	keys := make([]string, 0, len(flat))
+	for key := range flat {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		value := flat[key]
+		if legacy, exists := legacyKeys[key]; exists {
+			if _, current := flat[legacy.key]; current {
+				continue // La clave nueva prevalece
+			}
+			key, value = legacy.key, fmt.Sprint(value)+legacy.unit
+		}
+		if err := c.Set(key, value); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+	}
+	return nil
+}
+
+// legacyKeys son las claves de los ficheros de configuración anteriores,
+// con la opción que las sustituye y la unidad en que expresaban el valor
+var legacyKeys = map[string]struct{ key, unit string }{
+	"cleanup_interval_seconds":              {"cleanup_interval", "s"},
+	"default_mode":                          {"mode", ""},
+	"persistence.snapshot_interval_minutes": {"persistence.snapshot_interval", "m"},
+}
+
+// LoadEnv aplica las variables de entorno CACHE_ENGINE_<CLAVE>
+func (c *Config) LoadEnv() error {
+	for _, key := range Keys() {
+		name := EnvName(key)
+		if value, exists := os.LookupEnv(name); exists {
+			if err := c.Set(key, value); err != nil {
+				return fmt.Errorf("%s: %v", name, err)
+			}
+		}
+	}
+	return nil
+}
+
+// EnvName retorna la variable de entorno de una clave
+func EnvName(key string) string {
+	return EnvPrefix + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// Keys retorna las claves de todas las opciones en orden
+func Keys() []string {
+	var keys []string
+	for _, f := range fields(reflect.ValueOf(Default()).Elem(), "") {
+		keys = append(keys, f.key)
+	}
+	return keys
+}
+
+// Set cambia una opción. value puede ser el texto de una variable de
+// entorno o un flag, o un valor ya tipado leído de un fichero.
+func (c *Config) Set(key string, value interface{}) error {
+	field, exists := c.field(key)
+	if !exists {
+		if isSection(key) {
+			return fmt.Errorf("%s es una sección, no un valor", key)
+		}
+		return fmt.Errorf("clave desconocida: %s", key)
+	}
+	if err := setValue(field, value); err != nil {
+		return fmt.Errorf("%s: %v", key, err)
+	}
+	return nil
+}
+
+// Port retorna el puerto del modo configurado (0 en modo cli)
+func (c *Config) Port() int {
+	switch c.Mode {
+	case "http":
+		return c.HTTP.Port
+	case "resp":
+		return c.RESP.Port
+	case "memcache":
+		return c.Memcache.Port
+	case "grpc":
+		return c.GRPC.Port
+	}
+	return 0
+}
+
+// SetPort cambia el puerto del front-end del modo configurado. En modo cli
+// o con un modo desconocido no hace nada; Validate informa del modo.
+func (c *Config) SetPort(port string) error {
+	mode := strings.ToLower(c.Mode)
+	if mode == "cli" || !validMode(mode) {
+		return nil
+	}
+	return c.Set(mode+".port", port)
+}
+
+// SocketPerm retorna los permisos del socket Unix. Solo es válido tras
+// Validate.
+func (c *Config) SocketPerm() os.FileMode {
+	perm, _ := strconv.ParseUint(c.UnixSocketPerm, 8, 32)
+	return os.FileMode(perm)
+}
+
+// Validate normaliza el modo y la política de expulsión, comprueba que los
+// valores son coherentes y retorna todos los problemas encontrados
+func (c *Config) Validate() error {
+	c.Mode = strings.ToLower(c.Mode)
+	c.Eviction = strings.ToLower(c.Eviction)
+
+	var errs []error
+	fail := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.MaxEntries <= 0 {
+		fail("max_entries debe ser mayor que 0")
+	}
+	if c.CleanupInterval <= 0 {
+		fail("cleanup_interval debe ser mayor que 0")
+	}
+	if _, err := cache.ParseEvictionPolicy(c.Eviction); err != nil {
+		fail("eviction: %v (usa lru o fair)", err)
+	}
+	if !validMode(c.Mode) {
+		fail("mode: modo desconocido %q (usa %s)", c.Mode, strings.Join(Modes, ", "))
+	}
+
+	ports := []struct {
+		key  string
+		port int
+	}{{"http.port", c.HTTP.Port}, {"resp.port", c.RESP.Port}, {"memcache.port", c.Memcache.Port}, {"grpc.port", c.GRPC.Port}}
+	for _, p := range ports {
+		if p.port < 1 || p.port > 65535 {
+			fail("%s: puerto fuera de rango (1-65535): %d", p.key, p.port)
+		}
+	}
+
+	if perm, err := strconv.ParseUint(c.UnixSocketPerm, 8, 32); err != nil || perm > 0777 {
+		fail("unix_socket_perm: permisos inválidos %q (octal, p. ej. 700)", c.UnixSocketPerm)
+	}
+	if c.NoTCP && c.UnixSocket == "" {
+		fail("no_tcp requiere unix_socket")
+	}
+
+	if (c.TLS.Cert == "") != (c.TLS.Key == "") {
+		fail("tls: hacen falta tls.cert y tls.key")
+	}
+	if c.TLS.MinVersion != "1.2" && c.TLS.MinVersion != "1.3" {
+		fail("tls.min_version: versión no soportada %q (usa 1.2 o 1.3)", c.TLS.MinVersion)
+	}
+	if c.TLS.ClientAuth && c.TLS.CA == "" {
+		fail("tls.client_auth requiere tls.ca")
+	}
+
+	if c.ShutdownTimeout < 0 {
+		fail("shutdown_timeout no puede ser negativo")
+	}
+
+	if c.Clients.Max < 0 {
+		fail("clients.max no puede ser negativo")
+	}
+	if c.Clients.IdleTimeout < 0 {
+		fail("clients.idle_timeout no puede ser negativo")
+	}
+	if c.Clients.OutputLimit < 0 {
+		fail("clients.output_limit no puede ser negativo")
+	}
+	if c.Clients.OutputTimeout < 0 {
+		fail("clients.output_timeout no puede ser negativo")
+	}
+
+	if c.Persistence.Enabled && c.Persistence.LogFile == "" {
+		fail("persistence.enabled requiere persistence.log_file")
+	}
+	if c.Persistence.SnapshotInterval < 0 {
+		fail("persistence.snapshot_interval no puede ser negativo")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("configuración inválida: %w", errors.Join(errs...))
+	}
+	return nil
+}
+
+// validMode indica si mode es uno de los modos admitidos
+func validMode(mode string) bool {
+	for _, m := range Modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+// isSection indica si key es el nombre de una sección
+func isSection(key string) bool {
+	for _, k := range Keys() {
+		if strings.HasPrefix(k, strings.ToLower(key)+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// field es una opción de la configuración con su clave
+type field struct {
+	key   string
+	value reflect.Value
+}
+
+// fields recorre la estructura y retorna sus opciones; las estructuras
+// anidadas son secciones cuyas claves llevan su nombre como prefijo
+func fields(v reflect.Value, prefix string) []field {
+	var result []field
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		key := prefix + strings.Split(t.Field(i).Tag.Get("json"), ",")[0]
+		if v.Field(i).Kind() == reflect.Struct {
+			result = append(result, fields(v.Field(i), key+".")...)
+			continue
+		}
+		result = append(result, field{key: key, value: v.Field(i)})
+	}
+	return result
+}
+
+// field busca una opción por su clave
+func (c *Config) field(key string) (reflect.Value, bool) {
+	key = strings.ToLower(key)
+	for _, f := range fields(reflect.ValueOf(c).Elem(), "") {
+		if f.key == key {
+			return f.value, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setValue convierte value al tipo de la opción y lo asigna
+func setValue(field reflect.Value, value interface{}) error {
+	text, isText := value.(string)
+	if !isText {
+		text = fmt.Sprint(value)
+	}
+
+	switch {
+	case field.Type() == durationType:
+		d, err := time.ParseDuration(text)
+		if err != nil {
+			return fmt.Errorf("duración inválida %q (usa unidades como 500ms, 10s o 5m)", text)
+		}
+		field.SetInt(int64(d))
+	case field.Kind() == reflect.Int:
+		n, err := strconv.Atoi(text)
+		if err != nil {
+			return fmt.Errorf("se esperaba un entero: %q", text)
+		}
+		field.SetInt(int64(n))
+	case field.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return fmt.Errorf("se esperaba true o false: %q", text)
+		}
+		field.SetBool(b)
+	case field.Kind() == reflect.String:
+		if value == nil {
+			text = ""
+		}
+		field.SetString(text)
+	}
+	return nil
+}
+
+// flatten convierte las secciones anidadas en claves con puntos
+func flatten(prefix string, values map[string]interface{}, out map[string]interface{}) {
+	for key, value := range values {
+		if section, ok := value.(map[string]interface{}); ok {
+			flatten(prefix+key+".", section, out)
+			continue
+		}
+		out[prefix+key] = value
+	}
+}
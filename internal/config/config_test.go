@@ -0,0 +1,355 @@
+package config
+
+import (
+	"cache-engine/internal/cache"
+	"cache-engine/internal/command"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// writeFile crea un fichero de configuración temporal
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("No se pudo escribir el fichero: %v", err)
+	}
+	return path
+}
+
+// TestExamples prueba que los ficheros de ejemplo se cargan y son válidos
+func TestExamples(t *testing.T) {
+	for _, name := range []string{"cache.example.json", "cache.example.yaml", "cache.example.toml"} {
+		cfg, err := Load(filepath.Join("..", "..", "configs", name))
+		if err != nil {
+			t.Fatalf("No se pudo cargar %s: %v", name, err)
+		}
+		if err := cfg.Validate(); err != nil {
+			t.Errorf("%s no es válido: %v", name, err)
+		}
+	}
+
+	cfg, _ := Load(filepath.Join("..", "..", "configs", "cache.example.toml"))
+	if cfg.Mode != "http" || !cfg.HTTP.EnableCORS || cfg.ACL.RequirePass != "secreto" ||
+		cfg.Persistence.SnapshotInterval != 5*time.Minute || cfg.RESP.Port != 6379 {
+		t.Errorf("Configuración TOML inesperada: %+v", cfg)
+	}
+}
+
+// TestLegacyKeys prueba que se sigue cargando el fichero de ejemplo
+// original, con las claves anteriores, y que las nuevas prevalecen
+func TestLegacyKeys(t *testing.T) {
+	original := `{
+  "max_entries": 1000,
+  "cleanup_interval_seconds": 1,
+  "default_mode": "cli",
+  "http": {
+    "port": 8080,
+    "enable_cors": false
+  },
+  "persistence": {
+    "enabled": false,
+    "log_file": "cache.log",
+    "snapshot_interval_minutes": 5
+  }
+}`
+	cfg, err := Load(writeFile(t, "cache.json", original))
+	if err != nil {
+		t.Fatalf("No se pudo cargar el ejemplo original: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Errorf("El ejemplo original no es válido: %v", err)
+	}
+	if cfg.CleanupInterval != time.Second || cfg.Mode != "cli" || cfg.Persistence.SnapshotInterval != 5*time.Minute {
+		t.Errorf("Claves anteriores mal traducidas: %+v", cfg)
+	}
+
+	cfg, err = Load(writeFile(t, "cache.yaml", "cleanup_interval_seconds: 3\ncleanup_interval: 250ms\ndefault_mode: http\n"))
+	if err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if cfg.CleanupInterval != 250*time.Millisecond || cfg.Mode != "http" {
+		t.Errorf("La clave nueva debería prevalecer: %+v", cfg)
+	}
+}
+
+// TestFormats prueba que los tres formatos producen la misma configuración
+func TestFormats(t *testing.T) {
+	files := map[string]string{
+		"c.json": `{"max_entries": 50, "cleanup_interval": "250ms", "resp": {"port": 7000}, "tls": {"min_version": "1.3"}}`,
+		"c.yaml": "max_entries: 50  # entradas\ncleanup_interval: 250ms\nresp:\n  port: 7000\ntls:\n  min_version: \"1.3\"\n",
+		"c.toml": "max_entries = 5_0\ncleanup_interval = \"250ms\"\nresp.port = 7000\n[tls]\nmin_version = '1.3' # mínima\n",
+	}
+	for name, content := range files {
+		cfg, err := Load(writeFile(t, name, content))
+		if err != nil {
+			t.Fatalf("No se pudo cargar %s: %v", name, err)
+		}
+		if cfg.MaxEntries != 50 || cfg.CleanupInterval != 250*time.Millisecond ||
+			cfg.RESP.Port != 7000 || cfg.TLS.MinVersion != "1.3" || cfg.HTTP.Port != 8080 {
+			t.Errorf("%s: configuración inesperada: %+v", name, cfg)
+		}
+	}
+}
+
+// TestEnv prueba que las variables de entorno prevalecen sobre el fichero
+func TestEnv(t *testing.T) {
+	path := writeFile(t, "c.yaml", "max_entries: 50\nhttp:\n  port: 9000\n")
+	t.Setenv("CACHE_ENGINE_MAX_ENTRIES", "75")
+	t.Setenv("CACHE_ENGINE_HTTP_ENABLE_CORS", "true")
+	t.Setenv("CACHE_ENGINE_CLIENTS_IDLE_TIMEOUT", "30s")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if cfg.MaxEntries != 75 || cfg.HTTP.Port != 9000 || !cfg.HTTP.EnableCORS || cfg.Clients.IdleTimeout != 30*time.Second {
+		t.Errorf("Configuración inesperada: %+v", cfg)
+	}
+
+	t.Setenv("CACHE_ENGINE_HTTP_PORT", "ochenta")
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), "CACHE_ENGINE_HTTP_PORT") {
+		t.Errorf("Esperaba un error con el nombre de la variable, obtuve %v", err)
+	}
+}
+
+// TestErrors prueba los mensajes de los ficheros y valores incorrectos
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name, content, want string
+	}{
+		{"c.json", `{"max_entries": "mil"}`, "max_entries: se esperaba un entero"},
+		{"c.json", `{"http": {"prot": 80}}`, "clave desconocida: http.prot"},
+		{"c.json", `{"http": 80}`, "http es una sección"},
+		{"c.json", `{"cleanup_interval": 5}`, "duración inválida"},
+		{"c.yaml", "http:\n  port: 80\n   cors: true\n", "línea 3: sangría inconsistente"},
+		{"c.yaml", "mode: resp\nmode: http\n", "línea 2: clave repetida"},
+		{"c.yaml", "modes:\n  - resp\n", "línea 2: las listas no están soportadas"},
+		{"c.toml", "mode = resp\n", "línea 1: valor inválido"},
+		{"c.toml", "[http\nport = 80\n", "línea 1: tabla mal cerrada"},
+		{"c.ini", "max_entries=1\n", "formato de configuración desconocido"},
+	}
+	for _, tt := range tests {
+		_, err := Load(writeFile(t, tt.name, tt.content))
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s %q: esperaba %q, obtuve %v", tt.name, tt.content, tt.want, err)
+		}
+	}
+}
+
+// TestValidate prueba la validación de los valores
+func TestValidate(t *testing.T) {
+	if err := Default().Validate(); err != nil {
+		t.Fatalf("La configuración por defecto debería ser válida: %v", err)
+	}
+
+	cfg := Default()
+	cfg.MaxEntries = 0
+	cfg.Mode = "ftp"
+	cfg.RESP.Port = 70000
+	cfg.UnixSocketPerm = "999"
+	cfg.NoTCP = true
+	cfg.TLS.Cert = "server.pem"
+	cfg.Persistence.Enabled = true
+	cfg.Persistence.LogFile = ""
+	cfg.ShutdownTimeout = -time.Second
+	err := cfg.Validate()
+	if err == nil {
+		t.Fatal("Esperaba errores de validación")
+	}
+	for _, want := range []string{"max_entries", "modo desconocido \"ftp\"", "resp.port", "unix_socket_perm", "no_tcp", "tls.key", "persistence.log_file", "shutdown_timeout"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("El error debería mencionar %q: %v", want, err)
+		}
+	}
+
+	cfg = Default()
+	cfg.Set("mode", "RESP")
+	cfg.Set("resp.port", "7001")
+	if err := cfg.Validate(); err != nil || cfg.Mode != "resp" || cfg.Port() != 7001 {
+		t.Errorf("Esperaba modo resp en el puerto 7001, obtuve %s %d %v", cfg.Mode, cfg.Port(), err)
+	}
+}
+
+// TestManager prueba los cambios en caliente y la recarga del fichero
+func TestManager(t *testing.T) {
+	path := writeFile(t, "c.toml", "max_entries = 10\n")
+	cfg, _ := Load(path)
+	var applied []string
+	manager := NewManager(cfg, path, func() (*Config, error) {
+		cfg, err := Load(path)
+		if err == nil {
+			err = cfg.Validate()
+		}
+		return cfg, err
+	}, func(old, cfg *Config) error {
+		applied = append(applied, fmt.Sprintf("%d->%d", old.MaxEntries, cfg.MaxEntries))
+		return nil
+	})
+
+	if err := manager.Set("max_entries", "5", "eviction", "fair"); err != nil {
+		t.Fatalf("No se pudo cambiar la configuración: %v", err)
+	}
+	if got := manager.Get("max_*", "evic*"); got["max_entries"] != "5" || got["eviction"] != "fair" || len(got) != 2 {
+		t.Errorf("Valores inesperados: %v", got)
+	}
+
+	// Un cambio inválido o que requiere reiniciar no aplica ninguno
+	if err := manager.Set("max_entries", "7", "http.port", "9000"); err == nil || !strings.Contains(err.Error(), "reiniciando") {
+		t.Errorf("Esperaba error al cambiar http.port, obtuve %v", err)
+	}
+	if err := manager.Set("max_entries", "-1"); err == nil {
+		t.Error("Esperaba error de validación")
+	}
+	if manager.Config().MaxEntries != 5 || len(applied) != 1 {
+		t.Errorf("Los cambios fallidos no deberían aplicarse: %d %v", manager.Config().MaxEntries, applied)
+	}
+
+	os.WriteFile(path, []byte("max_entries = 20\n[http]\nport = 9000\n"), 0600)
+	changes, err := manager.Reload()
+	if err != nil {
+		t.Fatalf("No se pudo recargar: %v", err)
+	}
+	var got []string
+	for _, change := range changes {
+		got = append(got, change.String())
+	}
+	want := []string{`max_entries: "5" -> "20"`, `eviction: "fair" -> "lru"`, `http.port: "8080" -> "9000" (requiere reiniciar)`}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Cambios inesperados:\n%s", strings.Join(got, "\n"))
+	}
+	if cfg := manager.Config(); cfg.MaxEntries != 20 || cfg.HTTP.Port != 8080 {
+		t.Errorf("La recarga debería conservar http.port: %+v", cfg)
+	}
+
+	password := Change{Key: "acl.requirepass", Old: "", New: "secreto"}
+	if strings.Contains(password.String(), "secreto") {
+		t.Errorf("La contraseña no debería mostrarse: %s", password)
+	}
+}
+
+// TestRewrite prueba que CONFIG REWRITE produce un fichero equivalente en
+// cada formato
+func TestRewrite(t *testing.T) {
+	for name, content := range map[string]string{"c.json": "{}", "c.yaml": "", "c.toml": ""} {
+		path := writeFile(t, name, content)
+		cfg, err := Load(path)
+		if err != nil {
+			t.Fatalf("No se pudo cargar %s: %v", name, err)
+		}
+		manager := NewManager(cfg, path, nil, nil)
+		if err := manager.Set("max_entries", "42", "acl.requirepass", `con "comillas" # y almohadilla`, "persistence.snapshot_interval", "90s"); err != nil {
+			t.Fatalf("No se pudo cambiar la configuración: %v", err)
+		}
+		if err := manager.Rewrite(); err != nil {
+			t.Fatalf("No se pudo reescribir %s: %v", name, err)
+		}
+
+		reloaded, err := Load(path)
+		if err != nil {
+			t.Fatalf("No se pudo leer %s reescrito: %v", name, err)
+		}
+		if changes := Diff(manager.Config(), reloaded); len(changes) != 0 {
+			t.Errorf("%s: el fichero reescrito difiere: %v", name, changes)
+		}
+	}
+
+	// Los valores del entorno y de los flags no se escriben en el fichero
+	path := writeFile(t, "c.yaml", "max_entries: 10\n")
+	t.Setenv(EnvName("acl.requirepass"), "del-entorno")
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	cfg.Set("http.port", "9999") // Como un flag
+	manager := NewManager(cfg, path, nil, nil)
+	if err := manager.Set("eviction", "fair"); err != nil {
+		t.Fatalf("No se pudo cambiar la configuración: %v", err)
+	}
+	if err := manager.Rewrite(); err != nil {
+		t.Fatalf("No se pudo reescribir: %v", err)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "del-entorno") || strings.Contains(string(data), "9999") {
+		t.Errorf("El fichero no debería recoger el entorno ni los flags:\n%s", data)
+	}
+	file := Default()
+	if err := file.LoadFile(path); err != nil || file.MaxEntries != 10 || file.Eviction != "fair" {
+		t.Errorf("El fichero debería conservar su contenido y el cambio: %+v %v", file, err)
+	}
+
+	manager = NewManager(Default(), "", nil, nil)
+	if err := manager.Rewrite(); err != ErrNoFile {
+		t.Errorf("Esperaba ErrNoFile, obtuve %v", err)
+	}
+}
+
+// TestCommand prueba el comando CONFIG
+func TestCommand(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	manager := NewManager(Default(), "", nil, func(old, cfg *Config) error {
+		engine.SetMaxEntries(cfg.MaxEntries)
+		return nil
+	})
+	registry := command.NewRegistry(command.Default)
+	if err := registry.Register(manager.Command()); err != nil {
+		t.Fatalf("No se pudo registrar: %v", err)
+	}
+	ctx := command.NewContext(engine, registry, false)
+	cmd, _ := ctx.Lookup("config")
+
+	run := func(parts ...string) (interface{}, error) {
+		args := make([][]byte, len(parts))
+		for i, part := range parts {
+			args[i] = []byte(part)
+		}
+		return cmd.Call(ctx, args)
+	}
+
+	if result, err := run("CONFIG", "SET", "max_entries", "50"); err != nil || result != command.OK {
+		t.Fatalf("CONFIG SET: %v %v", result, err)
+	}
+	if engine.MaxEntries() != 50 {
+		t.Errorf("El motor debería tener límite 50, tiene %d", engine.MaxEntries())
+	}
+	result, _ := run("CONFIG", "GET", "max_entries")
+	if !reflect.DeepEqual(result, map[string]interface{}{"max_entries": "50"}) {
+		t.Errorf("CONFIG GET: %v", result)
+	}
+	if _, err := run("CONFIG", "SET", "max_entries"); err != command.ErrSyntax {
+		t.Errorf("Esperaba ErrSyntax, obtuve %v", err)
+	}
+	if _, err := run("CONFIG", "REWRITE"); err != ErrNoFile {
+		t.Errorf("Esperaba ErrNoFile, obtuve %v", err)
+	}
+
+	// Los clientes de red no cambian ficheros ni leen la contraseña
+	for _, key := range []string{"persistence.log_file", "PERSISTENCE.ENABLED"} {
+		if _, err := run("CONFIG", "SET", "max_entries", "60", key, "x"); err == nil {
+			t.Errorf("%s no debería poder cambiarse por red", key)
+		}
+	}
+	if engine.MaxEntries() != 50 {
+		t.Errorf("No debería aplicarse ningún cambio, el límite es %d", engine.MaxEntries())
+	}
+	if _, err := run("CONFIG", "SET", "acl.requirepass", "secreto"); err != nil {
+		t.Fatalf("CONFIG SET acl.requirepass: %v", err)
+	}
+	result, _ = run("CONFIG", "GET", "acl.*")
+	if !reflect.DeepEqual(result, map[string]interface{}{"acl.requirepass": "********", "acl.file": ""}) {
+		t.Errorf("CONFIG GET debería ocultar la contraseña: %v", result)
+	}
+
+	ctx.Local = true
+	if _, err := run("CONFIG", "SET", "persistence.log_file", filepath.Join(t.TempDir(), "cache.log")); err != nil {
+		t.Errorf("La CLI local debería poder cambiar el log: %v", err)
+	}
+}
@@ -0,0 +1,365 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// parseJSON interpreta un fichero JSON
+func parseJSON(data []byte) (map[string]interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var values map[string]interface{}
+	if err := decoder.Decode(&values); err != nil {
+		return nil, fmt.Errorf("JSON inválido: %v", err)
+	}
+	return values, nil
+}
+
+// parseYAML interpreta el subconjunto de YAML que necesita la
+// configuración: mapas anidados por sangría con espacios, escalares y
+// comentarios. Las listas y los bloques de texto no se admiten.
+func parseYAML(data []byte) (map[string]interface{}, error) {
+	// frame es un mapa abierto: indent es la sangría de su clave y child
+	// la de sus entradas (-1 hasta ver la primera)
+	type frame struct {
+		indent int
+		child  int
+		values map[string]interface{}
+	}
+	root := make(map[string]interface{})
+	stack := []*frame{{indent: -1, child: -1, values: root}}
+
+	for n, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimRight(stripComment(raw, false), " \t\r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		if trimmed[0] == '\t' {
+			return nil, fmt.Errorf("línea %d: la sangría debe usar espacios", n+1)
+		}
+		if trimmed == "-" || strings.HasPrefix(trimmed, "- ") {
+			return nil, fmt.Errorf("línea %d: las listas no están soportadas", n+1)
+		}
+
+		indent := len(line) - len(trimmed)
+		for len(stack) > 1 && indent <= stack[len(stack)-1].indent {
+			stack = stack[:len(stack)-1]
+		}
+		top := stack[len(stack)-1]
+		if top.child == -1 {
+			top.child = indent
+		} else if indent != top.child {
+			return nil, fmt.Errorf("línea %d: sangría inconsistente", n+1)
+		}
+
+		key, value, found := strings.Cut(trimmed, ":")
+		if !found || (value != "" && value[0] != ' ') {
+			return nil, fmt.Errorf("línea %d: se esperaba 'clave: valor'", n+1)
+		}
+		key = unquoteKey(strings.TrimSpace(key))
+		if _, exists := top.values[key]; exists {
+			return nil, fmt.Errorf("línea %d: clave repetida %q", n+1, key)
+		}
+
+		value = strings.TrimSpace(value)
+		if value == "" {
+			section := make(map[string]interface{})
+			top.values[key] = section
+			stack = append(stack, &frame{indent: indent, child: -1, values: section})
+			continue
+		}
+		scalar, err := yamlScalar(value)
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %v", n+1, err)
+		}
+		top.values[key] = scalar
+	}
+	return root, nil
+}
+
+// yamlScalar convierte un escalar YAML en cadena, entero, real, booleano o nil
+func yamlScalar(value string) (interface{}, error) {
+	switch value[0] {
+	case '"':
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("cadena mal cerrada: %s", value)
+		}
+		return s, nil
+	case '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return nil, fmt.Errorf("cadena mal cerrada: %s", value)
+		}
+		return strings.ReplaceAll(value[1:len(value)-1], "''", "'"), nil
+	case '[', '{', '|', '>', '&', '*', '!':
+		return nil, fmt.Errorf("sintaxis no soportada: %s", value)
+	}
+
+	switch strings.ToLower(value) {
+	case "null", "~":
+		return nil, nil
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(value, 64); err == nil {
+		return f, nil
+	}
+	return value, nil
+}
+
+// parseTOML interpreta el subconjunto de TOML que necesita la
+// configuración: tablas, claves con puntos, cadenas, enteros, reales y
+// booleanos. Los arrays, las fechas y las cadenas multilínea no se admiten.
+func parseTOML(data []byte) (map[string]interface{}, error) {
+	root := make(map[string]interface{})
+	current := root
+
+	for n, raw := range strings.Split(string(data), "\n") {
+		line := strings.TrimSpace(stripComment(raw, true))
+		if line == "" {
+			continue
+		}
+
+		if strings.HasPrefix(line, "[[") {
+			return nil, fmt.Errorf("línea %d: los arrays de tablas no están soportados", n+1)
+		}
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' {
+				return nil, fmt.Errorf("línea %d: tabla mal cerrada", n+1)
+			}
+			path, err := tomlKey(line[1 : len(line)-1])
+			if err == nil {
+				current, err = table(root, path)
+			}
+			if err != nil {
+				return nil, fmt.Errorf("línea %d: %v", n+1, err)
+			}
+			continue
+		}
+
+		keyText, valueText, found := strings.Cut(line, "=")
+		if !found {
+			return nil, fmt.Errorf("línea %d: se esperaba 'clave = valor'", n+1)
+		}
+		path, err := tomlKey(keyText)
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %v", n+1, err)
+		}
+		target, err := table(current, path[:len(path)-1])
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %v", n+1, err)
+		}
+		key := path[len(path)-1]
+		if _, exists := target[key]; exists {
+			return nil, fmt.Errorf("línea %d: clave repetida %q", n+1, key)
+		}
+		value, err := tomlValue(strings.TrimSpace(valueText))
+		if err != nil {
+			return nil, fmt.Errorf("línea %d: %v", n+1, err)
+		}
+		target[key] = value
+	}
+	return root, nil
+}
+
+// bareKey son los caracteres de una clave TOML sin comillas
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// tomlKey divide una clave TOML con puntos en sus partes
+func tomlKey(text string) ([]string, error) {
+	parts := strings.Split(text, ".")
+	for i, part := range parts {
+		part = strings.TrimSpace(part)
+		if len(part) >= 2 && (part[0] == '"' || part[0] == '\'') && part[len(part)-1] == part[0] {
+			part = part[1 : len(part)-1]
+		} else if !bareKey.MatchString(part) {
+			return nil, fmt.Errorf("clave inválida: %q", strings.TrimSpace(text))
+		}
+		parts[i] = part
+	}
+	return parts, nil
+}
+
+// tomlValue convierte un valor TOML en cadena, entero, real o booleano
+func tomlValue(value string) (interface{}, error) {
+	switch {
+	case value == "":
+		return nil, errors.New("falta el valor")
+	case strings.HasPrefix(value, `"""`), strings.HasPrefix(value, "'''"):
+		return nil, errors.New("las cadenas multilínea no están soportadas")
+	case value[0] == '"':
+		s, err := strconv.Unquote(value)
+		if err != nil {
+			return nil, fmt.Errorf("cadena mal cerrada: %s", value)
+		}
+		return s, nil
+	case value[0] == '\'':
+		if len(value) < 2 || value[len(value)-1] != '\'' {
+			return nil, fmt.Errorf("cadena mal cerrada: %s", value)
+		}
+		return value[1 : len(value)-1], nil
+	case value[0] == '[', value[0] == '{':
+		return nil, errors.New("los arrays y las tablas en línea no están soportados")
+	case value == "true":
+		return true, nil
+	case value == "false":
+		return false, nil
+	}
+	if n, err := strconv.ParseInt(value, 0, 64); err == nil {
+		return n, nil
+	}
+	if f, err := strconv.ParseFloat(strings.ReplaceAll(value, "_", ""), 64); err == nil {
+		return f, nil
+	}
+	return nil, fmt.Errorf("valor inválido: %s (las cadenas van entre comillas)", value)
+}
+
+// table retorna la tabla anidada de path dentro de values, creándola si no
+// existe
+func table(values map[string]interface{}, path []string) (map[string]interface{}, error) {
+	for _, part := range path {
+		next, exists := values[part]
+		if !exists {
+			section := make(map[string]interface{})
+			values[part] = section
+			values = section
+			continue
+		}
+		section, ok := next.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s no es una tabla", part)
+		}
+		values = section
+	}
+	return values, nil
+}
+
+// unquoteKey quita las comillas de una clave YAML
+func unquoteKey(key string) string {
+	if len(key) >= 2 && (key[0] == '"' || key[0] == '\'') && key[len(key)-1] == key[0] {
+		return key[1 : len(key)-1]
+	}
+	return key
+}
+
+// stripComment elimina el comentario de una línea. Fuera de las cadenas,
+// un # empieza un comentario en TOML siempre y en YAML solo al principio o
+// tras un espacio.
+func stripComment(line string, anyHash bool) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		ch := line[i]
+		switch {
+		case quote != 0:
+			if ch == '\\' && quote == '"' {
+				i++
+			} else if ch == quote {
+				quote = 0
+			}
+		case ch == '"' || ch == '\'':
+			if anyHash || i == 0 || line[i-1] == ' ' {
+				quote = ch
+			}
+		case ch == '#':
+			if anyHash || i == 0 || line[i-1] == ' ' || line[i-1] == '\t' {
+				return line[:i]
+			}
+		}
+	}
+	return line
+}
+
+// section agrupa las opciones que se escriben bajo un mismo nombre; la
+// sección sin nombre son las opciones de primer nivel
+type section struct {
+	name   string
+	fields []field
+}
+
+// sections agrupa las opciones de cfg por sección en el orden de la
+// estructura, con las de primer nivel delante
+func sections(cfg *Config) []section {
+	result := []section{{}}
+	index := map[string]int{"": 0}
+	for _, f := range fields(reflect.ValueOf(cfg).Elem(), "") {
+		name, key := "", f.key
+		if dot := strings.LastIndex(f.key, "."); dot >= 0 {
+			name, key = f.key[:dot], f.key[dot+1:]
+		}
+		i, exists := index[name]
+		if !exists {
+			i = len(result)
+			index[name] = i
+			result = append(result, section{name: name})
+		}
+		result[i].fields = append(result[i].fields, field{key: key, value: f.value})
+	}
+	return result
+}
+
+// literal escribe el valor de una opción; las cadenas y duraciones van
+// entre comillas dobles, válidas en los tres formatos
+func literal(v reflect.Value) string {
+	if v.Type() == durationType || v.Kind() == reflect.String {
+		quoted, _ := json.Marshal(format(v))
+		return string(quoted)
+	}
+	return format(v)
+}
+
+// encode escribe cfg en el formato de la extensión ext
+func encode(cfg *Config, ext string) ([]byte, error) {
+	var buf bytes.Buffer
+	switch strings.ToLower(ext) {
+	case ".json":
+		var items []string
+		for _, s := range sections(cfg) {
+			var lines []string
+			for _, f := range s.fields {
+				lines = append(lines, fmt.Sprintf("%q: %s", f.key, literal(f.value)))
+			}
+			if s.name == "" {
+				items = append(items, lines...)
+				continue
+			}
+			items = append(items, fmt.Sprintf("%q: {\n    %s\n  }", s.name, strings.Join(lines, ",\n    ")))
+		}
+		fmt.Fprintf(&buf, "{\n  %s\n}\n", strings.Join(items, ",\n  "))
+	case ".yaml", ".yml":
+		for _, s := range sections(cfg) {
+			indent := ""
+			if s.name != "" {
+				fmt.Fprintf(&buf, "\n%s:\n", s.name)
+				indent = "  "
+			}
+			for _, f := range s.fields {
+				fmt.Fprintf(&buf, "%s%s: %s\n", indent, f.key, literal(f.value))
+			}
+		}
+	case ".toml":
+		for _, s := range sections(cfg) {
+			if s.name != "" {
+				fmt.Fprintf(&buf, "\n[%s]\n", s.name)
+			}
+			for _, f := range s.fields {
+				fmt.Fprintf(&buf, "%s = %s\n", f.key, literal(f.value))
+			}
+		}
+	default:
+		return nil, fmt.Errorf("formato de configuración desconocido: %s", ext)
+	}
+	return buf.Bytes(), nil
+}
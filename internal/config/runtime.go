@@ -0,0 +1,268 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ErrNoFile indica que la configuración no se cargó de un fichero
+var ErrNoFile = errors.New("no se indicó fichero de configuración")
+
+// runtimeKeys son las opciones que se pueden cambiar sin reiniciar
+var runtimeKeys = map[string]bool{
+	"max_entries":                   true,
+	"cleanup_interval":              true,
+	"eviction":                      true,
+	"shutdown_timeout":              true,
+	"acl.requirepass":               true,
+	"persistence.enabled":           true,
+	"persistence.log_file":          true,
+	"persistence.snapshot_interval": true,
+	"persistence.snapshot_on_exit":  true,
+}
+
+// secretKeys son las opciones cuyo valor no se muestra en los cambios
+var secretKeys = map[string]bool{"acl.requirepass": true}
+
+// localKeys son las opciones que escriben en ficheros del servidor. Solo
+// se cambian desde la CLI local o el fichero de configuración.
+var localKeys = map[string]bool{
+	"persistence.enabled":  true,
+	"persistence.log_file": true,
+}
+
+// Runtime indica si una opción se puede cambiar sin reiniciar
+func Runtime(key string) bool {
+	return runtimeKeys[strings.ToLower(key)]
+}
+
+// Local indica si una opción solo se puede cambiar desde la CLI local
+func Local(key string) bool {
+	return localKeys[strings.ToLower(key)]
+}
+
+// Get retorna el valor de una opción como texto
+func (c *Config) Get(key string) (string, bool) {
+	field, exists := c.field(key)
+	if !exists {
+		return "", false
+	}
+	return format(field), true
+}
+
+// format representa el valor de una opción como en las variables de entorno
+func format(v reflect.Value) string {
+	if v.Type() == durationType {
+		return time.Duration(v.Int()).String()
+	}
+	return fmt.Sprint(v.Interface())
+}
+
+// Change es una opción con valores distintos entre dos configuraciones
+type Change struct {
+	Key     string
+	Old     string
+	New     string
+	Restart bool // La opción no se aplica hasta reiniciar
+}
+
+// String describe el cambio sin mostrar los secretos
+func (c Change) String() string {
+	before, after := c.Old, c.New
+	if secretKeys[c.Key] {
+		before, after = mask(before), mask(after)
+	}
+	text := fmt.Sprintf("%s: %q -> %q", c.Key, before, after)
+	if c.Restart {
+		text += " (requiere reiniciar)"
+	}
+	return text
+}
+
+// mask oculta un secreto no vacío
+func mask(secret string) string {
+	if secret == "" {
+		return ""
+	}
+	return "********"
+}
+
+// Diff retorna las opciones que cambian de a a b
+func Diff(a, b *Config) []Change {
+	var changes []Change
+	for _, key := range Keys() {
+		before, _ := a.Get(key)
+		after, _ := b.Get(key)
+		if before != after {
+			changes = append(changes, Change{Key: key, Old: before, New: after, Restart: !Runtime(key)})
+		}
+	}
+	return changes
+}
+
+// ApplyFunc aplica una configuración a los componentes en marcha. old es
+// la configuración anterior, o nil al arrancar.
+type ApplyFunc func(old, cfg *Config) error
+
+// Manager mantiene la configuración en uso y la cambia en caliente con
+// CONFIG SET o recargando el fichero
+type Manager struct {
+	mu      sync.Mutex
+	current *Config
+	path    string                  // Fichero de configuración (vacío = ninguno)
+	load    func() (*Config, error) // Vuelve a leer fichero, entorno y flags
+	apply   ApplyFunc
+	changes map[string]string // Opciones cambiadas con CONFIG SET desde la última carga
+}
+
+// NewManager crea un gestor para la configuración cfg, ya aplicada y
+// cargada de path con load
+func NewManager(cfg *Config, path string, load func() (*Config, error), apply ApplyFunc) *Manager {
+	return &Manager{current: cfg, path: path, load: load, apply: apply}
+}
+
+// Config retorna una copia de la configuración en uso
+func (m *Manager) Config() *Config {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	cfg := *m.current
+	return &cfg
+}
+
+// Get retorna las opciones cuyas claves coinciden con alguno de los
+// patrones glob
+func (m *Manager) Get(patterns ...string) map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	values := make(map[string]string)
+	for _, key := range Keys() {
+		for _, pattern := range patterns {
+			if matched, _ := path.Match(strings.ToLower(pattern), key); matched {
+				values[key], _ = m.current.Get(key)
+				break
+			}
+		}
+	}
+	return values
+}
+
+// Set cambia una o varias opciones (clave, valor, clave, valor...). Los
+// cambios se validan y se aplican juntos: si alguno falla no se aplica
+// ninguno.
+func (m *Manager) Set(pairs ...string) error {
+	if len(pairs) == 0 || len(pairs)%2 != 0 {
+		return errors.New("se esperaban pares clave valor")
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := *m.current
+	for i := 0; i < len(pairs); i += 2 {
+		if err := cfg.Set(pairs[i], pairs[i+1]); err != nil {
+			return err
+		}
+		if !Runtime(pairs[i]) {
+			return fmt.Errorf("%s solo se puede cambiar reiniciando", strings.ToLower(pairs[i]))
+		}
+	}
+	if err := m.replace(&cfg); err != nil {
+		return err
+	}
+
+	if m.changes == nil {
+		m.changes = make(map[string]string)
+	}
+	for i := 0; i < len(pairs); i += 2 {
+		m.changes[strings.ToLower(pairs[i])] = pairs[i+1]
+	}
+	return nil
+}
+
+// Reload vuelve a leer la configuración y aplica los cambios. Las opciones
+// que requieren reiniciar conservan su valor actual y se marcan en los
+// cambios retornados.
+func (m *Manager) Reload() ([]Change, error) {
+	cfg, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	changes := Diff(m.current, cfg)
+	for _, change := range changes {
+		if change.Restart {
+			cfg.Set(change.Key, change.Old)
+		}
+	}
+	if err := m.replace(cfg); err != nil {
+		return nil, err
+	}
+	m.changes = nil
+	return changes, nil
+}
+
+// replace valida y aplica cfg. Requiere m.mu tomado.
+func (m *Manager) replace(cfg *Config) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	if m.apply != nil {
+		if err := m.apply(m.current, cfg); err != nil {
+			return err
+		}
+	}
+	m.current = cfg
+	return nil
+}
+
+// Rewrite guarda en el fichero del que se cargó la configuración, en su
+// mismo formato, su contenido más los cambios hechos con CONFIG SET. Los
+// valores de las variables de entorno y los flags no se escriben: entre
+// ellos puede haber secretos que no deben acabar en el fichero.
+func (m *Manager) Rewrite() error {
+	if m.path == "" {
+		return ErrNoFile
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cfg := Default()
+	if err := cfg.LoadFile(m.path); err != nil {
+		return err
+	}
+	for key, value := range m.changes {
+		if err := cfg.Set(key, value); err != nil {
+			return err
+		}
+	}
+	data, err := encode(cfg, filepath.Ext(m.path))
+	if err != nil {
+		return err
+	}
+
+	perm := os.FileMode(0644)
+	if info, err := os.Stat(m.path); err == nil {
+		perm = info.Mode().Perm()
+	}
+	tmp := m.path + ".tmp"
+	if err := os.WriteFile(tmp, data, perm); err != nil {
+		return fmt.Errorf("error al escribir la configuración: %v", err)
+	}
+	if err := os.Rename(tmp, m.path); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error al escribir la configuración: %v", err)
+	}
+	return nil
+}
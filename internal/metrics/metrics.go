@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"bufio"
+	"cache-engine/internal/cache"
+	"cache-engine/internal/persistence"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ContentType es el tipo MIME del formato de texto de Prometheus
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// writer genera el formato de exposición de texto de Prometheus
+type writer struct {
+	w *bufio.Writer
+}
+
+// header escribe las líneas HELP y TYPE de una métrica
+func (mw *writer) header(name, help, kind string) {
+	fmt.Fprintf(mw.w, "# HELP %s %s\n", name, help)
+	fmt.Fprintf(mw.w, "# TYPE %s %s\n", name, kind)
+}
+
+// sample escribe una muestra con etiquetas opcionales (pares nombre, valor)
+func (mw *writer) sample(name string, value float64, labels ...string) {
+	mw.w.WriteString(name)
+	if len(labels) > 0 {
+		mw.w.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				mw.w.WriteByte(',')
+			}
+			fmt.Fprintf(mw.w, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		mw.w.WriteByte('}')
+	}
+	mw.w.WriteByte(' ')
+	mw.w.WriteString(formatValue(value))
+	mw.w.WriteByte('\n')
+}
+
+// single escribe una métrica con una sola muestra sin etiquetas
+func (mw *writer) single(name, help, kind string, value float64) {
+	mw.header(name, help, kind)
+	mw.sample(name, value)
+}
+
+// histogram escribe los buckets acumulados, la suma y el total de un histograma
+func (mw *writer) histogram(name string, buckets []int64, sum time.Duration, labels ...string) {
+	var cumulative int64
+	for i, count := range buckets {
+		cumulative += count
+		le := "+Inf"
+		if i < len(cache.LatencyBuckets) {
+			le = formatValue(cache.LatencyBuckets[i].Seconds())
+		}
+		mw.sample(name+"_bucket", float64(cumulative), append(labels, "le", le)...)
+	}
+	mw.sample(name+"_sum", sum.Seconds(), labels...)
+	mw.sample(name+"_count", float64(cumulative), labels...)
+}
+
+// escapeLabel escapa un valor de etiqueta según el formato de texto
+func escapeLabel(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	value = strings.ReplaceAll(value, "\n", `\n`)
+	return strings.ReplaceAll(value, `"`, `\"`)
+}
+
+// formatValue formatea un valor numérico como lo espera Prometheus
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// Write escribe todas las métricas del motor en formato de texto de Prometheus
+func Write(out io.Writer, c *cache.CacheEngine) error {
+	stats := c.Stats()
+	mw := &writer{w: bufio.NewWriter(out)}
+
+	mw.single("cache_uptime_seconds", "Tiempo desde el arranque del motor.", "gauge", stats.Uptime.Seconds())
+	mw.single("cache_keys", "Entradas almacenadas en todos los namespaces.", "gauge", float64(stats.Keys))
+	mw.single("cache_bytes", "Bytes estimados ocupados por las entradas.", "gauge", float64(stats.Bytes))
+	mw.single("cache_max_entries", "Límite global de entradas.", "gauge", float64(stats.MaxEntries))
+	mw.single("cache_hits_total", "Lecturas con resultado.", "counter", float64(stats.Hits))
+	mw.single("cache_misses_total", "Lecturas sin resultado.", "counter", float64(stats.Misses))
+	mw.single("cache_sets_total", "Escrituras realizadas.", "counter", float64(stats.Sets))
+	mw.single("cache_deletes_total", "Claves eliminadas con DEL.", "counter", float64(stats.Deletes))
+	mw.single("cache_evictions_total", "Entradas expulsadas por límite o cuota.", "counter", float64(stats.Evictions))
+	mw.single("cache_expirations_total", "Entradas eliminadas por expiración.", "counter", float64(stats.Expirations))
+	mw.single("cache_rejections_total", "Escrituras rechazadas por cuota.", "counter", float64(stats.Rejections))
+
+	// Métricas por namespace
+	perNamespace := []struct {
+		name, help, kind string
+		value            func(cache.NamespaceStats) float64
+	}{
+		{"cache_namespace_keys", "Entradas por namespace.", "gauge", func(ns cache.NamespaceStats) float64 { return float64(ns.Keys) }},
+		{"cache_namespace_bytes", "Bytes estimados por namespace.", "gauge", func(ns cache.NamespaceStats) float64 { return float64(ns.Bytes) }},
+		{"cache_namespace_hits_total", "Lecturas con resultado por namespace.", "counter", func(ns cache.NamespaceStats) float64 { return float64(ns.Hits) }},
+		{"cache_namespace_misses_total", "Lecturas sin resultado por namespace.", "counter", func(ns cache.NamespaceStats) float64 { return float64(ns.Misses) }},
+		{"cache_namespace_evictions_total", "Entradas expulsadas por namespace.", "counter", func(ns cache.NamespaceStats) float64 { return float64(ns.Evictions) }},
+		{"cache_namespace_expirations_total", "Entradas expiradas por namespace.", "counter", func(ns cache.NamespaceStats) float64 { return float64(ns.Expirations) }},
+	}
+	for _, metric := range perNamespace {
+		mw.header(metric.name, metric.help, metric.kind)
+		for _, ns := range stats.Namespaces {
+			mw.sample(metric.name, metric.value(ns), "namespace", ns.Name)
+		}
+	}
+
+	// Latencia por comando
+	names := make([]string, 0, len(stats.Commands))
+	for name := range stats.Commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	mw.header("cache_command_duration_seconds", "Latencia de los comandos ejecutados.", "histogram")
+	for _, name := range names {
+		cmd := stats.Commands[name]
+		mw.histogram("cache_command_duration_seconds", cmd.Buckets, cmd.TotalTime, "command", name)
+	}
+
+	// Persistencia
+	writes := persistence.GetWriteStats()
+	mw.header("cache_persistence_write_duration_seconds", "Latencia de escritura en el log.", "histogram")
+	mw.histogram("cache_persistence_write_duration_seconds", writes.Buckets, writes.TotalTime)
+	mw.single("cache_persistence_write_errors_total", "Escrituras fallidas en el log.", "counter", float64(writes.Errors))
+	mw.single("cache_persistence_log_bytes", "Tamaño del archivo de log activo.", "gauge", float64(persistence.LogSize(persistence.LogFile(c))))
+
+	return mw.w.Flush()
+}
+
+// Handler retorna un http.Handler que sirve las métricas del motor
+func Handler(c *cache.CacheEngine) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := Write(w, c); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
+
+// ListenAndServe expone /metrics en la dirección indicada, con TLS si
+// tlsConfig no es nil
+func ListenAndServe(addr string, c *cache.CacheEngine, tlsConfig *tls.Config) error {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", Handler(c))
+
+	server := &http.Server{Addr: addr, Handler: mux, TLSConfig: tlsConfig}
+	if tlsConfig != nil {
+		return server.ListenAndServeTLS("", "")
+	}
+	return server.ListenAndServe()
+}
@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"cache-engine/internal/cache"
+	"context"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestWriteExposition prueba el formato de texto generado
+func TestWriteExposition(t *testing.T) {
+	c := cache.NewCacheEngine(10)
+	defer c.Close(context.Background())
+
+	c.Set("key1", "value1")
+	c.Get("key1")
+	c.Get("missing")
+	c.Namespace("users").Set("key2", "value2")
+	c.RecordCommand("GET", 20*time.Microsecond)
+
+	var out strings.Builder
+	if err := Write(&out, c); err != nil {
+		t.Fatalf("No esperaba error: %v", err)
+	}
+	text := out.String()
+
+	expected := []string{
+		"# TYPE cache_hits_total counter",
+		"cache_hits_total 1",
+		"cache_misses_total 1",
+		"cache_keys 2",
+		"cache_max_entries 10",
+		`cache_namespace_keys{namespace="users"} 1`,
+		`cache_command_duration_seconds_bucket{command="GET",le="1e-05"} 0`,
+		`cache_command_duration_seconds_bucket{command="GET",le="5e-05"} 1`,
+		`cache_command_duration_seconds_bucket{command="GET",le="+Inf"} 1`,
+		`cache_command_duration_seconds_count{command="GET"} 1`,
+		"# TYPE cache_persistence_write_duration_seconds histogram",
+	}
+	for _, line := range expected {
+		if !strings.Contains(text, line+"\n") {
+			t.Errorf("Falta la línea %q en:\n%s", line, text)
+		}
+	}
+}
+
+// TestHandler prueba el endpoint HTTP
+func TestHandler(t *testing.T) {
+	c := cache.NewCacheEngine(10)
+	defer c.Close(context.Background())
+
+	rec := httptest.NewRecorder()
+	Handler(c).ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+
+	if rec.Code != 200 {
+		t.Errorf("Esperaba 200, obtuve %d", rec.Code)
+	}
+
+	if rec.Header().Get("Content-Type") != ContentType {
+		t.Errorf("Content-Type inesperado: %s", rec.Header().Get("Content-Type"))
+	}
+}
+
+// TestEscapeLabel prueba el escapado de etiquetas
+func TestEscapeLabel(t *testing.T) {
+	if got := escapeLabel("a\"b\\c\nd"); got != `a\"b\\c\nd` {
+		t.Errorf("Escapado inesperado: %s", got)
+	}
+}
@@ -0,0 +1,112 @@
+package netutil
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+)
+
+// DefaultSocketPerm son los permisos por defecto de los sockets Unix: solo
+// el usuario del proceso puede conectarse
+const DefaultSocketPerm os.FileMode = 0700
+
+// ListenConfig describe dónde escucha un front-end
+type ListenConfig struct {
+	Addr       string      // Dirección TCP (p. ej. ":6379")
+	NoTCP      bool        // No escuchar en TCP; requiere UnixSocket
+	UnixSocket string      // Ruta del socket Unix (vacío = ninguno)
+	SocketPerm os.FileMode // Permisos del socket (0 = DefaultSocketPerm)
+}
+
+// Listen abre el listener TCP y el socket Unix configurados
+func Listen(config ListenConfig) ([]net.Listener, error) {
+	if config.NoTCP && config.UnixSocket == "" {
+		return nil, errors.New("sin TCP hace falta un socket Unix")
+	}
+
+	var listeners []net.Listener
+	if !config.NoTCP {
+		listener, err := net.Listen("tcp", config.Addr)
+		if err != nil {
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+
+	if config.UnixSocket != "" {
+		listener, err := ListenUnix(config.UnixSocket, config.SocketPerm)
+		if err != nil {
+			for _, l := range listeners {
+				l.Close()
+			}
+			return nil, err
+		}
+		listeners = append(listeners, listener)
+	}
+	return listeners, nil
+}
+
+// ListenUnix escucha en un socket Unix con los permisos indicados. Un
+// socket abandonado por un proceso anterior se elimina; uno en uso no.
+// El fichero se borra al cerrar el listener.
+func ListenUnix(path string, perm os.FileMode) (net.Listener, error) {
+	if perm == 0 {
+		perm = DefaultSocketPerm
+	}
+
+	if info, err := os.Lstat(path); err == nil {
+		if info.Mode()&os.ModeSocket == 0 {
+			return nil, fmt.Errorf("%s existe y no es un socket", path)
+		}
+		if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+			conn.Close()
+			return nil, fmt.Errorf("el socket %s está en uso", path)
+		}
+		if err := os.Remove(path); err != nil {
+			return nil, err
+		}
+	}
+
+	listener, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.Chmod(path, perm); err != nil {
+		listener.Close()
+		return nil, err
+	}
+	return listener, nil
+}
+
+// ServeAll atiende cada listener en su propia goroutine y espera a que
+// terminen todos. Si uno falla se cierran los demás y se retorna su error.
+func ServeAll(listeners []net.Listener, serve func(net.Listener) error) error {
+	errs := make(chan error, len(listeners))
+	for _, listener := range listeners {
+		go func() { errs <- serve(listener) }()
+	}
+
+	var first error
+	for range listeners {
+		if err := <-errs; err != nil && first == nil {
+			first = err
+			for _, listener := range listeners {
+				listener.Close()
+			}
+		}
+	}
+	return first
+}
+
+// Describe retorna las direcciones de escucha para mostrarlas al usuario
+func (c ListenConfig) Describe() string {
+	switch {
+	case c.UnixSocket == "":
+		return c.Addr
+	case c.NoTCP:
+		return "unix:" + c.UnixSocket
+	}
+	return c.Addr + " y unix:" + c.UnixSocket
+}
@@ -0,0 +1,119 @@
+package netutil
+
+import (
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// socketPath retorna una ruta corta para un socket Unix; t.TempDir puede
+// superar el límite de longitud de las rutas de sockets
+func socketPath(t *testing.T) string {
+	t.Helper()
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatalf("No se pudo crear el directorio: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	return filepath.Join(dir, "test.sock")
+}
+
+// TestListenUnix prueba los permisos y la limpieza de sockets abandonados
+func TestListenUnix(t *testing.T) {
+	path := socketPath(t)
+
+	listener, err := ListenUnix(path, 0760)
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatalf("No existe el socket: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0760 {
+		t.Errorf("Permisos: esperaba 0760, obtuve %o", perm)
+	}
+
+	// Un socket en uso no se sustituye
+	if _, err := ListenUnix(path, 0); err == nil {
+		t.Error("Esperaba error: socket en uso")
+	}
+
+	// Un socket abandonado se elimina
+	listener.(*net.UnixListener).SetUnlinkOnClose(false)
+	listener.Close()
+	listener, err = ListenUnix(path, 0)
+	if err != nil {
+		t.Fatalf("No se pudo reutilizar el socket abandonado: %v", err)
+	}
+	info, _ = os.Stat(path)
+	if perm := info.Mode().Perm(); perm != DefaultSocketPerm {
+		t.Errorf("Permisos por defecto: esperaba %o, obtuve %o", DefaultSocketPerm, perm)
+	}
+
+	// Al cerrar se borra el fichero
+	listener.Close()
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("El socket debería borrarse al cerrar: %v", err)
+	}
+
+	// Un fichero normal no se borra
+	os.WriteFile(path, []byte("datos"), 0600)
+	if _, err := ListenUnix(path, 0); err == nil {
+		t.Error("Esperaba error: la ruta no es un socket")
+	}
+}
+
+// TestListenAndServeAll prueba TCP y socket Unix a la vez
+func TestListenAndServeAll(t *testing.T) {
+	if _, err := Listen(ListenConfig{NoTCP: true}); err == nil {
+		t.Error("Esperaba error: sin TCP ni socket")
+	}
+
+	path := socketPath(t)
+	config := ListenConfig{Addr: "127.0.0.1:0", UnixSocket: path}
+	listeners, err := Listen(config)
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	if len(listeners) != 2 {
+		t.Fatalf("Esperaba 2 listeners, obtuve %d", len(listeners))
+	}
+
+	failure := errors.New("fallo")
+	done := make(chan error)
+	go func() {
+		done <- ServeAll(listeners, func(listener net.Listener) error {
+			conn, err := listener.Accept()
+			if err != nil {
+				return nil
+			}
+			conn.Write([]byte("x"))
+			conn.Close()
+			if listener.Addr().Network() == "unix" {
+				return failure
+			}
+			return nil
+		})
+	}()
+
+	// Cada listener atiende su conexión; el error del Unix cierra el resto
+	for _, addr := range []net.Addr{listeners[0].Addr(), listeners[1].Addr()} {
+		conn, err := net.Dial(addr.Network(), addr.String())
+		if err != nil {
+			t.Fatalf("No se pudo conectar a %s: %v", addr, err)
+		}
+		buf := make([]byte, 1)
+		conn.Read(buf)
+		conn.Close()
+	}
+	if err := <-done; err != failure {
+		t.Errorf("Esperaba el error del listener, obtuve %v", err)
+	}
+
+	if got := config.Describe(); got != "127.0.0.1:0 y unix:"+path {
+		t.Errorf("Describe: obtuve %q", got)
+	}
+}
@@ -0,0 +1,253 @@
+// Package netutil contiene utilidades compartidas por los listeners de red
+// de los distintos front-ends.
+package netutil
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultReloadInterval es cada cuánto se comprueba si los ficheros de
+// certificados cambiaron
+const DefaultReloadInterval = 10 * time.Second
+
+// TLSConfig describe la configuración TLS común a todos los listeners
+type TLSConfig struct {
+	CertFile   string // Certificado del servidor (PEM)
+	KeyFile    string // Clave privada del servidor (PEM)
+	CAFile     string // CAs con las que se verifican los certificados de cliente (PEM)
+	MinVersion string // Versión mínima: "1.2" (por defecto) o "1.3"
+	ClientAuth bool   // Exigir certificado de cliente (mTLS); requiere CAFile
+
+	// ReloadInterval es cada cuánto se comprueban los ficheros
+	// (0 = DefaultReloadInterval, negativo = nunca)
+	ReloadInterval time.Duration
+
+	// OnReloadError recibe los errores de las recargas automáticas; el
+	// certificado anterior sigue en uso
+	OnReloadError func(error)
+}
+
+// Enabled indica si hay certificado configurado
+func (c TLSConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// TLSReloader sirve los certificados de una TLSConfig y los recarga cuando
+// cambian los ficheros, sin reiniciar los listeners
+type TLSReloader struct {
+	config     TLSConfig
+	minVersion uint16
+
+	cert atomic.Pointer[tls.Certificate]
+	pool atomic.Pointer[x509.CertPool]
+
+	mu       sync.Mutex // Serializa las recargas
+	modTimes map[string]time.Time
+
+	stop     chan struct{}
+	stopOnce sync.Once
+}
+
+// NewTLSReloader carga los certificados y, salvo que ReloadInterval sea
+// negativo, empieza a vigilar los ficheros
+func NewTLSReloader(config TLSConfig) (*TLSReloader, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, errors.New("TLS requiere certificado y clave")
+	}
+	if config.ClientAuth && config.CAFile == "" {
+		return nil, errors.New("el certificado de cliente obligatorio requiere una CA")
+	}
+
+	minVersion, err := parseVersion(config.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	r := &TLSReloader{
+		config:     config,
+		minVersion: minVersion,
+		modTimes:   make(map[string]time.Time),
+		stop:       make(chan struct{}),
+	}
+	if err := r.Reload(); err != nil {
+		return nil, err
+	}
+
+	interval := config.ReloadInterval
+	if interval == 0 {
+		interval = DefaultReloadInterval
+	}
+	if interval > 0 {
+		go r.watch(interval)
+	}
+	return r, nil
+}
+
+// parseVersion traduce la versión mínima configurada
+func parseVersion(version string) (uint16, error) {
+	switch version {
+	case "", "1.2":
+		return tls.VersionTLS12, nil
+	case "1.3":
+		return tls.VersionTLS13, nil
+	}
+	return 0, fmt.Errorf("versión de TLS no soportada: %s", version)
+}
+
+// Reload vuelve a leer los certificados. Si fallan, se mantienen los
+// anteriores y se retorna el error.
+func (r *TLSReloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cert, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+	if err != nil {
+		return fmt.Errorf("error al cargar el certificado: %v", err)
+	}
+
+	var pool *x509.CertPool
+	if r.config.CAFile != "" {
+		pem, err := os.ReadFile(r.config.CAFile)
+		if err != nil {
+			return fmt.Errorf("error al leer la CA: %v", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("la CA %s no contiene certificados válidos", r.config.CAFile)
+		}
+	}
+
+	r.cert.Store(&cert)
+	r.pool.Store(pool)
+	for _, name := range r.files() {
+		if info, err := os.Stat(name); err == nil {
+			r.modTimes[name] = info.ModTime()
+		}
+	}
+	return nil
+}
+
+// files retorna los ficheros vigilados
+func (r *TLSReloader) files() []string {
+	files := []string{r.config.CertFile, r.config.KeyFile}
+	if r.config.CAFile != "" {
+		files = append(files, r.config.CAFile)
+	}
+	return files
+}
+
+// changed indica si algún fichero cambió desde la última recarga
+func (r *TLSReloader) changed() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, name := range r.files() {
+		info, err := os.Stat(name)
+		if err == nil && !info.ModTime().Equal(r.modTimes[name]) {
+			return true
+		}
+	}
+	return false
+}
+
+// watch recarga los certificados cuando cambian los ficheros
+func (r *TLSReloader) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if !r.changed() {
+				continue
+			}
+			if err := r.Reload(); err != nil && r.config.OnReloadError != nil {
+				r.config.OnReloadError(err)
+			}
+		case <-r.stop:
+			return
+		}
+	}
+}
+
+// Close deja de vigilar los ficheros
+func (r *TLSReloader) Close() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// ServerConfig retorna la configuración para un servidor. Certificado y CA
+// se leen en cada handshake, de modo que las recargas se aplican a las
+// conexiones nuevas sin tocar el listener.
+func (r *TLSReloader) ServerConfig() *tls.Config {
+	config := &tls.Config{
+		MinVersion: r.minVersion,
+		GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+			return r.cert.Load(), nil
+		},
+	}
+
+	if r.config.CAFile != "" {
+		// La verificación se hace a mano para usar siempre la CA vigente
+		config.ClientAuth = tls.RequestClientCert
+		if r.config.ClientAuth {
+			config.ClientAuth = tls.RequireAnyClientCert
+		}
+		config.VerifyConnection = r.verifyClient
+	}
+	return config
+}
+
+// verifyClient verifica el certificado de cliente contra la CA vigente
+func (r *TLSReloader) verifyClient(state tls.ConnectionState) error {
+	if len(state.PeerCertificates) == 0 {
+		if r.config.ClientAuth {
+			return errors.New("se requiere certificado de cliente")
+		}
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range state.PeerCertificates[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := state.PeerCertificates[0].Verify(x509.VerifyOptions{
+		Roots:         r.pool.Load(),
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	})
+	return err
+}
+
+// ClientTLSConfig crea la configuración de un cliente. Sin caFile se usan
+// las CAs del sistema; certFile y keyFile presentan un certificado de
+// cliente para mTLS.
+func ClientTLSConfig(serverName, caFile, certFile, keyFile string, insecure bool) (*tls.Config, error) {
+	config := &tls.Config{ServerName: serverName, InsecureSkipVerify: insecure, MinVersion: tls.VersionTLS12}
+
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("error al leer la CA: %v", err)
+		}
+		config.RootCAs = x509.NewCertPool()
+		if !config.RootCAs.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("la CA %s no contiene certificados válidos", caFile)
+		}
+	}
+
+	if certFile != "" || keyFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("error al cargar el certificado de cliente: %v", err)
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+	return config, nil
+}
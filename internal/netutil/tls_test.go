@@ -0,0 +1,235 @@
+package netutil
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// authority es una CA de prueba
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+// newAuthority crea una CA autofirmada
+func newAuthority(t *testing.T) *authority {
+	t.Helper()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("No se pudo crear la CA: %v", err)
+	}
+	cert, _ := x509.ParseCertificate(der)
+	return &authority{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue firma un certificado de servidor o de cliente y retorna cert y clave en PEM
+func (a *authority) issue(t *testing.T, serial int64, usage x509.ExtKeyUsage) ([]byte, []byte) {
+	t.Helper()
+
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		DNSNames:     []string{"localhost"},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, a.cert, &key.PublicKey, a.key)
+	if err != nil {
+		t.Fatalf("No se pudo firmar el certificado: %v", err)
+	}
+	keyDER, _ := x509.MarshalECPrivateKey(key)
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+}
+
+// writeFile escribe un fichero y adelanta su fecha de modificación para
+// que el cambio se detecte aunque el sistema de ficheros tenga poca resolución
+func writeFile(t *testing.T, name string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(name, data, 0600); err != nil {
+		t.Fatalf("No se pudo escribir %s: %v", name, err)
+	}
+	os.Chtimes(name, modTime, modTime)
+}
+
+// serve acepta conexiones TLS y completa el handshake
+func serve(t *testing.T, config *tls.Config) string {
+	t.Helper()
+
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", config)
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				conn.(*tls.Conn).Handshake()
+				conn.Write([]byte("ok"))
+				conn.Close()
+			}()
+		}
+	}()
+	return listener.Addr().String()
+}
+
+// dialSerial conecta y retorna el número de serie del certificado del servidor
+func dialSerial(addr string, config *tls.Config) (int64, error) {
+	conn, err := tls.Dial("tcp", addr, config)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	// Con TLS 1.3 el rechazo del certificado de cliente llega al leer
+	buf := make([]byte, 2)
+	if _, err := conn.Read(buf); err != nil {
+		return 0, err
+	}
+	return conn.ConnectionState().PeerCertificates[0].SerialNumber.Int64(), nil
+}
+
+// TestTLSReload prueba la recarga automática del certificado del servidor
+func TestTLSReload(t *testing.T) {
+	ca := newAuthority(t)
+	dir := t.TempDir()
+	certFile, keyFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem")
+
+	now := time.Now()
+	cert, key := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, cert, now)
+	writeFile(t, keyFile, key, now)
+
+	reloader, err := NewTLSReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile, ReloadInterval: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("No se pudo crear el reloader: %v", err)
+	}
+	defer reloader.Close()
+	addr := serve(t, reloader.ServerConfig())
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	client := &tls.Config{RootCAs: roots, ServerName: "localhost"}
+	if serial, err := dialSerial(addr, client); err != nil || serial != 2 {
+		t.Fatalf("Esperaba el certificado 2, obtuve %d %v", serial, err)
+	}
+
+	// Un certificado nuevo se sirve sin reiniciar el listener
+	cert, key = ca.issue(t, 3, x509.ExtKeyUsageServerAuth)
+	writeFile(t, keyFile, key, now.Add(time.Minute))
+	writeFile(t, certFile, cert, now.Add(time.Minute))
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		serial, err := dialSerial(addr, client)
+		if err == nil && serial == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("No se recargó el certificado: %d %v", serial, err)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	// Un fichero inválido no sustituye al certificado vigente
+	writeFile(t, certFile, []byte("basura"), now.Add(2*time.Minute))
+	if err := reloader.Reload(); err == nil {
+		t.Error("Esperaba error al recargar un certificado inválido")
+	}
+	if serial, err := dialSerial(addr, client); err != nil || serial != 3 {
+		t.Errorf("Debería seguir sirviéndose el certificado 3: %d %v", serial, err)
+	}
+}
+
+// TestMutualTLS prueba la verificación obligatoria de certificados de cliente
+func TestMutualTLS(t *testing.T) {
+	ca, other := newAuthority(t), newAuthority(t)
+	dir := t.TempDir()
+	certFile, keyFile, caFile := filepath.Join(dir, "cert.pem"), filepath.Join(dir, "key.pem"), filepath.Join(dir, "ca.pem")
+
+	cert, key := ca.issue(t, 2, x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, cert, time.Now())
+	writeFile(t, keyFile, key, time.Now())
+	writeFile(t, caFile, ca.pem, time.Now())
+
+	if _, err := NewTLSReloader(TLSConfig{CertFile: certFile, KeyFile: keyFile, ClientAuth: true}); err == nil {
+		t.Error("Esperaba error: mTLS sin CA")
+	}
+
+	reloader, err := NewTLSReloader(TLSConfig{
+		CertFile: certFile, KeyFile: keyFile, CAFile: caFile,
+		ClientAuth: true, MinVersion: "1.3", ReloadInterval: -1,
+	})
+	if err != nil {
+		t.Fatalf("No se pudo crear el reloader: %v", err)
+	}
+	addr := serve(t, reloader.ServerConfig())
+
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(ca.pem)
+	clientCert := func(a *authority) []tls.Certificate {
+		certPEM, keyPEM := a.issue(t, 10, x509.ExtKeyUsageClientAuth)
+		pair, _ := tls.X509KeyPair(certPEM, keyPEM)
+		return []tls.Certificate{pair}
+	}
+
+	if _, err := dialSerial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost"}); err == nil {
+		t.Error("Una conexión sin certificado de cliente debería rechazarse")
+	}
+	if _, err := dialSerial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: clientCert(other)}); err == nil {
+		t.Error("Un certificado de otra CA debería rechazarse")
+	}
+	if _, err := dialSerial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost", Certificates: clientCert(ca)}); err != nil {
+		t.Errorf("El certificado de la CA debería aceptarse: %v", err)
+	}
+	if _, err := dialSerial(addr, &tls.Config{RootCAs: roots, ServerName: "localhost", MaxVersion: tls.VersionTLS12, Certificates: clientCert(ca)}); err == nil {
+		t.Error("TLS 1.2 debería rechazarse con versión mínima 1.3")
+	}
+
+	// Configuración de cliente a partir de ficheros
+	clientCertFile, clientKeyFile := filepath.Join(dir, "client.pem"), filepath.Join(dir, "client.key")
+	certPEM, keyPEM := ca.issue(t, 11, x509.ExtKeyUsageClientAuth)
+	writeFile(t, clientCertFile, certPEM, time.Now())
+	writeFile(t, clientKeyFile, keyPEM, time.Now())
+	config, err := ClientTLSConfig("localhost", caFile, clientCertFile, clientKeyFile, false)
+	if err != nil {
+		t.Fatalf("No se pudo crear la configuración de cliente: %v", err)
+	}
+	if _, err := dialSerial(addr, config); err != nil {
+		t.Errorf("ClientTLSConfig debería conectar con mTLS: %v", err)
+	}
+	if _, err := ClientTLSConfig("localhost", keyFile, "", "", false); err == nil {
+		t.Error("Esperaba error: CA sin certificados")
+	}
+}
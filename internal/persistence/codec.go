@@ -0,0 +1,260 @@
+package persistence
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Codec convierte los valores de un tipo en el contenido JSON que se guarda
+// en el log y de vuelta
+type Codec interface {
+	Encode(value interface{}) ([]byte, error)
+	Decode(data []byte) (interface{}, error)
+}
+
+// jsonCodec guarda los valores como JSON y los recupera con su tipo exacto
+type jsonCodec struct {
+	typ reflect.Type
+}
+
+func (c jsonCodec) Encode(value interface{}) ([]byte, error) {
+	return json.Marshal(value)
+}
+
+func (c jsonCodec) Decode(data []byte) (interface{}, error) {
+	ptr := reflect.New(c.typ)
+	if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+		return nil, err
+	}
+	return ptr.Elem().Interface(), nil
+}
+
+// codecs es el registro de tipos que se guardan con su etiqueta en el log
+var codecs = struct {
+	sync.RWMutex
+	byName map[string]Codec
+	byType map[reflect.Type]string
+}{byName: make(map[string]Codec), byType: make(map[reflect.Type]string)}
+
+func init() {
+	builtin := map[string]interface{}{
+		"string": "", "bytes": []byte(nil), "bool": false,
+		"int": int(0), "int8": int8(0), "int16": int16(0), "int32": int32(0), "int64": int64(0),
+		"uint": uint(0), "uint8": uint8(0), "uint16": uint16(0), "uint32": uint32(0), "uint64": uint64(0),
+		"float32": float32(0), "float64": float64(0),
+	}
+	for name, sample := range builtin {
+		if err := RegisterType(name, sample); err != nil {
+			panic(err)
+		}
+	}
+	if err := RegisterCodec("map", map[string]interface{}(nil), mapCodec{}); err != nil {
+		panic(err)
+	}
+	if err := RegisterCodec("list", []interface{}(nil), listCodec{}); err != nil {
+		panic(err)
+	}
+}
+
+// typed es un valor anidado en un map o una lista, con su etiqueta
+type typed struct {
+	Type  string          `json:"type"`
+	Value json.RawMessage `json:"value"`
+}
+
+// encodeNested etiqueta un valor anidado. Los nil se guardan como null.
+func encodeNested(value interface{}) (json.RawMessage, error) {
+	if value == nil {
+		return json.RawMessage("null"), nil
+	}
+	name, data, err := encodeTyped(value)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(typed{Type: name, Value: data})
+}
+
+// decodeNested reconstruye un valor anidado con su tipo
+func decodeNested(data json.RawMessage) (interface{}, error) {
+	if bytes.Equal(data, []byte("null")) {
+		return nil, nil
+	}
+	var t typed
+	if err := json.Unmarshal(data, &t); err != nil {
+		return nil, err
+	}
+	return decodeTyped(t.Type, t.Value)
+}
+
+// mapCodec guarda los map[string]interface{} con la etiqueta de cada
+// valor, de modo que los anidados conservan también su tipo
+type mapCodec struct{}
+
+func (mapCodec) Encode(value interface{}) ([]byte, error) {
+	m := value.(map[string]interface{})
+	if m == nil {
+		return []byte("null"), nil
+	}
+	fields := make(map[string]json.RawMessage, len(m))
+	for key, v := range m {
+		data, err := encodeNested(v)
+		if err != nil {
+			return nil, fmt.Errorf("campo %s: %v", key, err)
+		}
+		fields[key] = data
+	}
+	return json.Marshal(fields)
+}
+
+func (mapCodec) Decode(data []byte) (interface{}, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	if fields == nil {
+		return map[string]interface{}(nil), nil
+	}
+	m := make(map[string]interface{}, len(fields))
+	for key, raw := range fields {
+		v, err := decodeNested(raw)
+		if err != nil {
+			return nil, fmt.Errorf("campo %s: %v", key, err)
+		}
+		m[key] = v
+	}
+	return m, nil
+}
+
+// listCodec guarda los []interface{} con la etiqueta de cada elemento
+type listCodec struct{}
+
+func (listCodec) Encode(value interface{}) ([]byte, error) {
+	list := value.([]interface{})
+	if list == nil {
+		return []byte("null"), nil
+	}
+	items := make([]json.RawMessage, len(list))
+	for i, v := range list {
+		data, err := encodeNested(v)
+		if err != nil {
+			return nil, fmt.Errorf("elemento %d: %v", i, err)
+		}
+		items[i] = data
+	}
+	return json.Marshal(items)
+}
+
+func (listCodec) Decode(data []byte) (interface{}, error) {
+	var items []json.RawMessage
+	if err := json.Unmarshal(data, &items); err != nil {
+		return nil, err
+	}
+	if items == nil {
+		return []interface{}(nil), nil
+	}
+	list := make([]interface{}, len(items))
+	for i, raw := range items {
+		v, err := decodeNested(raw)
+		if err != nil {
+			return nil, fmt.Errorf("elemento %d: %v", i, err)
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+// RegisterCodec asocia la etiqueta name al tipo de sample, de modo que sus
+// valores se guardan en el log con codec y se recuperan con el mismo tipo.
+// Los tipos se deben registrar antes de escribir o cargar el log.
+func RegisterCodec(name string, sample interface{}, codec Codec) error {
+	typ := reflect.TypeOf(sample)
+	switch {
+	case name == "" || strings.ContainsAny(name, " \t\""):
+		return fmt.Errorf("nombre de tipo inválido: %q", name)
+	case typ == nil:
+		return fmt.Errorf("el tipo %s necesita un valor de ejemplo", name)
+	case codec == nil:
+		return fmt.Errorf("el tipo %s necesita un codec", name)
+	}
+
+	codecs.Lock()
+	defer codecs.Unlock()
+	if _, exists := codecs.byName[name]; exists {
+		return fmt.Errorf("el tipo %s ya está registrado", name)
+	}
+	if other, exists := codecs.byType[typ]; exists {
+		return fmt.Errorf("%s ya está registrado como %s", typ, other)
+	}
+	codecs.byName[name] = codec
+	codecs.byType[typ] = name
+	return nil
+}
+
+// RegisterType registra el tipo de sample para guardarlo como JSON. Sirve
+// para las estructuras y demás tipos que encoding/json sabe reconstruir.
+func RegisterType(name string, sample interface{}) error {
+	return RegisterCodec(name, sample, jsonCodec{typ: reflect.TypeOf(sample)})
+}
+
+// encodeTyped retorna la etiqueta y el contenido de value. Los tipos sin
+// registrar son un error: como JSON genérico no se recuperarían con su tipo.
+func encodeTyped(value interface{}) (string, json.RawMessage, error) {
+	if value == nil {
+		return "", nil, nil
+	}
+
+	codecs.RLock()
+	name, exists := codecs.byType[reflect.TypeOf(value)]
+	codec := codecs.byName[name]
+	codecs.RUnlock()
+
+	if !exists {
+		return "", nil, fmt.Errorf("tipo %T sin registrar (¿falta RegisterType?)", value)
+	}
+	data, err := codec.Encode(value)
+	if err == nil && !json.Valid(data) {
+		err = fmt.Errorf("el codec no produjo JSON válido")
+	}
+	if err != nil {
+		return "", nil, fmt.Errorf("tipo %s: %v", name, err)
+	}
+	return name, data, nil
+}
+
+// decodeTyped reconstruye un valor a partir de su etiqueta y su contenido
+func decodeTyped(name string, data json.RawMessage) (interface{}, error) {
+	codecs.RLock()
+	codec, exists := codecs.byName[name]
+	codecs.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("tipo desconocido %q (¿falta registrarlo?)", name)
+	}
+
+	value, err := codec.Decode(data)
+	if err != nil {
+		return nil, fmt.Errorf("tipo %s: %v", name, err)
+	}
+	return value, nil
+}
+
+// decodeLegacy recupera los valores de los logs sin etiqueta de tipo, en los
+// que los []byte se marcaban con encoding "base64"
+func decodeLegacy(data json.RawMessage, encoding string) (interface{}, error) {
+	var value interface{}
+	if err := json.Unmarshal(data, &value); err != nil {
+		return nil, err
+	}
+	if encoding != "base64" {
+		return value, nil
+	}
+	encoded, ok := value.(string)
+	if !ok {
+		return nil, fmt.Errorf("valor base64 inválido")
+	}
+	return base64.StdEncoding.DecodeString(encoded)
+}
@@ -0,0 +1,241 @@
+package persistence
+
+import (
+	"bytes"
+	"cache-engine/internal/cache"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// FileJournal es el journal del motor sobre un fichero de log: cada
+// modificación se añade al fichero en el orden en que se aplica
+type FileJournal struct {
+	filename string
+
+	mu       sync.Mutex
+	file     *os.File     // nil mientras se escribe la instantánea inicial
+	buffered bytes.Buffer // Modificaciones recibidas durante la instantánea
+	err      error        // Primer error de escritura o de codificación
+	closed   bool
+}
+
+// Append implementa cache.Journal. Una modificación cuyo valor no se puede
+// codificar se descarta, se informa en el momento y el resto se registra.
+func (j *FileJournal) Append(mutations []cache.Mutation) {
+	start := time.Now()
+	var buf bytes.Buffer
+	var encodeErr error
+	for _, m := range mutations {
+		entry := LogEntry{Operation: m.Operation, Namespace: m.Namespace, Key: m.Key, Value: m.Value, ExpiresAt: m.ExpiresAt}
+		data, err := encodeEntries([]LogEntry{entry}, start.Unix())
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Operación descartada del log %s: %v\n", j.filename, err)
+			recordWrite(start, err)
+			if encodeErr == nil {
+				encodeErr = err
+			}
+			continue
+		}
+		buf.Write(data)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if encodeErr != nil && j.err == nil {
+		j.err = encodeErr
+	}
+	switch {
+	case j.closed || buf.Len() == 0:
+		return
+	case j.file == nil:
+		j.buffered.Write(buf.Bytes())
+		return
+	}
+	_, err := j.file.Write(buf.Bytes())
+	if err != nil {
+		err = fmt.Errorf("error al escribir en log: %v", err)
+		if j.err == nil {
+			j.err = err
+		}
+	}
+	recordWrite(start, err)
+}
+
+// Filename retorna el fichero del journal
+func (j *FileJournal) Filename() string {
+	return j.filename
+}
+
+// Close asegura en disco lo registrado y cierra el fichero. Retorna el
+// primer error de escritura o de codificación, si lo hubo.
+func (j *FileJournal) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.closed {
+		return nil
+	}
+	j.closed = true
+
+	err := j.err
+	if j.file != nil {
+		if syncErr := j.file.Sync(); syncErr != nil && err == nil {
+			err = fmt.Errorf("error al sincronizar el log: %v", syncErr)
+		}
+		if closeErr := j.file.Close(); closeErr != nil && err == nil {
+			err = closeErr
+		}
+	}
+	return err
+}
+
+// start escribe la instantánea data seguida de las modificaciones recibidas
+// mientras tanto en un fichero temporal, que reemplaza al log, y sigue
+// añadiendo en él
+func (j *FileJournal) start(data map[string]map[string]*cache.CacheEntry) (err error) {
+	begin := time.Now()
+	defer func() { recordWrite(begin, err) }()
+
+	tmp := j.filename + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_APPEND|os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error al preparar la instantánea: %v", err)
+	}
+	fail := func(err error) error {
+		file.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := writeState(file, data, begin.Unix()); err != nil {
+		return fail(err)
+	}
+
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if _, err := file.Write(j.buffered.Bytes()); err != nil {
+		return fail(fmt.Errorf("error al escribir en log: %v", err))
+	}
+	j.buffered.Reset()
+	// El contenido debe estar en disco antes de reemplazar el log
+	if err := file.Sync(); err != nil {
+		return fail(fmt.Errorf("error al sincronizar el log: %v", err))
+	}
+	if err := os.Rename(tmp, j.filename); err != nil {
+		return fail(fmt.Errorf("error al reemplazar el log: %v", err))
+	}
+	j.file = file
+	return nil
+}
+
+// EnableLogging registra en filename todas las modificaciones del motor,
+// vengan del front-end que vengan. Si el fichero ya tiene contenido se
+// siguen añadiendo a él sin tocar lo anterior, salvo una última línea a
+// medio escribir, que se descarta; si no, el log empieza con una
+// instantánea del estado actual. Si ya había un journal, se cierra.
+func EnableLogging(c *cache.CacheEngine, filename string) error {
+	if filename == "" {
+		filename = DefaultLogFile
+	}
+
+	info, err := os.Stat(filename)
+	switch {
+	case os.IsNotExist(err) || err == nil && info.Size() == 0:
+		return compact(c, filename)
+	case err != nil:
+		return fmt.Errorf("error al abrir archivo de log: %v", err)
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_RDWR, 0644)
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de log: %v", err)
+	}
+	if err := repairTail(file, info.Size()); err != nil {
+		file.Close()
+		return fmt.Errorf("error al preparar el log: %v", err)
+	}
+	return closeJournal(c.SetJournal(&FileJournal{filename: filename, file: file}))
+}
+
+// repairTail deja el log terminado en un salto de línea antes de añadir
+// entradas: si la última línea es una entrada completa le falta solo el
+// salto; si quedó a medio escribir se descarta, como al cargar el log
+func repairTail(file *os.File, size int64) error {
+	for window := int64(4096); ; window *= 2 {
+		if window > size {
+			window = size
+		}
+		tail := make([]byte, window)
+		if _, err := file.ReadAt(tail, size-window); err != nil {
+			return err
+		}
+		content := bytes.TrimRight(tail, " \t\r\n")
+		i := bytes.LastIndexByte(content, '\n')
+		if i < 0 && window < size {
+			continue // La última línea empieza antes de la ventana
+		}
+
+		line := bytes.TrimSpace(content[i+1:])
+		switch {
+		case len(line) == 0 || !json.Valid(line):
+			return file.Truncate(size - window + int64(i+1))
+		case tail[len(tail)-1] == '\n':
+			return nil
+		}
+		_, err := file.Write([]byte("\n"))
+		return err
+	}
+}
+
+// compact reemplaza filename por una instantánea del estado actual y sigue
+// registrando en él las modificaciones del motor
+func compact(c *cache.CacheEngine, filename string) error {
+	j := &FileJournal{filename: filename}
+	data, old := c.Checkpoint(j)
+	closeErr := closeJournal(old)
+
+	if err := j.start(data); err != nil {
+		if c.Journal() == j {
+			c.SetJournal(nil)
+		}
+		j.Close()
+		return err
+	}
+	return closeErr
+}
+
+// DisableLogging deja de registrar las modificaciones del motor y cierra
+// el journal, asegurando en disco lo registrado
+func DisableLogging(c *cache.CacheEngine) error {
+	return closeJournal(c.SetJournal(nil))
+}
+
+// LogFile retorna el fichero en el que se registran las modificaciones del
+// motor (vacío = ninguno)
+func LogFile(c *cache.CacheEngine) string {
+	if j, ok := c.Journal().(*FileJournal); ok {
+		return j.Filename()
+	}
+	return ""
+}
+
+// closeJournal cierra un journal que lo admita
+func closeJournal(j cache.Journal) error {
+	if closer, ok := j.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
+
+// sameFile indica si dos rutas se refieren al mismo fichero
+func sameFile(a, b string) bool {
+	if a == "" || b == "" {
+		return false
+	}
+	absA, errA := filepath.Abs(a)
+	absB, errB := filepath.Abs(b)
+	return errA == nil && errB == nil && absA == absB
+}
@@ -1,59 +1,182 @@
 package persistence
 
 import (
+	"bufio"
+	"bytes"
 	"cache-engine/internal/cache"
 	"encoding/json"
 	"fmt"
+	"io"
 	"os"
 	"time"
 )
 
 // LogEntry representa una operación en el log
 type LogEntry struct {
-	Operation string      `json:"operation"` // SET, DEL, EXPIRE
-	Key       string      `json:"key"`
-	Value     interface{} `json:"value,omitempty"`
-	ExpiresAt int64       `json:"expires_at,omitempty"`
-	Timestamp int64       `json:"timestamp"`
+	Operation string // SET, DEL, EXPIRE, MOVE, SWAPDB, FLUSHDB, FLUSHALL
+	Namespace string // Vacío = namespace por defecto
+	Key       string
+	Value     interface{} // Se guarda con la etiqueta de su tipo registrado
+	ExpiresAt int64       // Instante de expiración, Unix en segundos (0 = sin expiración)
+	Timestamp int64       // Momento de la escritura, Unix en segundos
+}
+
+// record es la forma de LogEntry en el fichero: el valor va como etiqueta
+// de tipo más contenido. Las entradas sin tipo vienen de logs anteriores,
+// que marcaban los []byte con encoding "base64".
+type record struct {
+	Operation string          `json:"operation"`
+	Namespace string          `json:"namespace,omitempty"`
+	Key       string          `json:"key"`
+	Type      string          `json:"type,omitempty"`
+	Value     json.RawMessage `json:"value,omitempty"`
+	Encoding  string          `json:"encoding,omitempty"`
+	ExpiresAt int64           `json:"expires_at,omitempty"`
+	Timestamp int64           `json:"timestamp"`
 }
 
 const (
 	DefaultLogFile = "cache.log"
 )
 
-// LogOperation registra una operación individual en el log (append-only)
-func LogOperation(filename, operation, key string, value interface{}, expiresAt int64) error {
-	if filename == "" {
-		return nil // Logging deshabilitado
+// MarshalJSON implementa json.Marshaler
+func (e LogEntry) MarshalJSON() ([]byte, error) {
+	typ, value, err := encodeTyped(e.Value)
+	if err != nil {
+		return nil, fmt.Errorf("clave %s: %v", e.Key, err)
+	}
+	return json.Marshal(record{
+		Operation: e.Operation,
+		Namespace: e.Namespace,
+		Key:       e.Key,
+		Type:      typ,
+		Value:     value,
+		ExpiresAt: e.ExpiresAt,
+		Timestamp: e.Timestamp,
+	})
+}
+
+// UnmarshalJSON implementa json.Unmarshaler
+func (e *LogEntry) UnmarshalJSON(data []byte) error {
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return err
 	}
 
-	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	var value interface{}
+	var err error
+	switch {
+	case len(r.Value) == 0:
+	case r.Type != "":
+		value, err = decodeTyped(r.Type, r.Value)
+	default:
+		value, err = decodeLegacy(r.Value, r.Encoding)
+	}
 	if err != nil {
-		return fmt.Errorf("error al abrir archivo de log: %v", err)
+		return fmt.Errorf("valor inválido para la clave %s: %v", r.Key, err)
 	}
-	defer file.Close()
 
-	logEntry := LogEntry{
+	*e = LogEntry{
+		Operation: r.Operation,
+		Namespace: r.Namespace,
+		Key:       r.Key,
+		Value:     value,
+		ExpiresAt: r.ExpiresAt,
+		Timestamp: r.Timestamp,
+	}
+	return nil
+}
+
+// LogOperation registra una operación individual en el log (append-only).
+// Para MOVE y SWAPDB, value contiene el namespace destino.
+func LogOperation(filename, namespace, operation, key string, value interface{}, expiresAt int64) error {
+	return LogOperations(filename, []LogEntry{{
 		Operation: operation,
+		Namespace: namespace,
 		Key:       key,
 		Value:     value,
 		ExpiresAt: expiresAt,
-		Timestamp: time.Now().Unix(),
+	}})
+}
+
+// LogOperations registra varias operaciones con una sola escritura en el
+// log. Los timestamps vacíos se completan con la hora actual.
+func LogOperations(filename string, entries []LogEntry) (err error) {
+	if filename == "" || len(entries) == 0 {
+		return nil // Logging deshabilitado
 	}
 
-	encoder := json.NewEncoder(file)
-	if err := encoder.Encode(logEntry); err != nil {
+	start := time.Now()
+	defer func() { recordWrite(start, err) }()
+
+	data, err := encodeEntries(entries, start.Unix())
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de log: %v", err)
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
 		return fmt.Errorf("error al escribir en log: %v", err)
 	}
 
 	return nil
 }
 
-// SaveToLog guarda el estado actual del cache en formato JSON append-only
-func SaveToLog(c *cache.CacheEngine, filename string) error {
+// encodeEntries codifica entradas como líneas JSON. Las entradas del
+// namespace por defecto se guardan sin namespace y los timestamps vacíos
+// toman el valor now.
+func encodeEntries(entries []LogEntry, now int64) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	for _, logEntry := range entries {
+		if logEntry.Namespace == cache.DefaultNamespace {
+			logEntry.Namespace = ""
+		}
+		if logEntry.Timestamp == 0 {
+			logEntry.Timestamp = now
+		}
+		if err := encoder.Encode(logEntry); err != nil {
+			return nil, fmt.Errorf("error al escribir en log: %v", err)
+		}
+	}
+	return buf.Bytes(), nil
+}
+
+// Sync fuerza la escritura en disco del log. Las operaciones se escriben
+// sin buffer, pero pueden quedar en la caché del sistema operativo.
+func Sync(filename string) error {
+	file, err := os.OpenFile(filename, os.O_WRONLY, 0)
+	if os.IsNotExist(err) {
+		return nil // Aún no se ha registrado nada
+	}
+	if err != nil {
+		return fmt.Errorf("error al abrir archivo de log: %v", err)
+	}
+	defer file.Close()
+
+	if err := file.Sync(); err != nil {
+		return fmt.Errorf("error al sincronizar el log: %v", err)
+	}
+	return nil
+}
+
+// SaveToLog guarda el estado actual del cache en formato JSON append-only.
+// Sobre el log activo, en cambio, lo compacta como Snapshot.
+func SaveToLog(c *cache.CacheEngine, filename string) (err error) {
 	if filename == "" {
 		filename = DefaultLogFile
 	}
+	if sameFile(filename, LogFile(c)) {
+		return compact(c, filename)
+	}
+
+	start := time.Now()
+	defer func() { recordWrite(start, err) }()
 
 	// Abrir archivo en modo append
 	file, err := os.OpenFile(filename, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
@@ -62,30 +185,80 @@ func SaveToLog(c *cache.CacheEngine, filename string) error {
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-
 	// Obtener datos de forma segura
-	data := c.ExportData()
+	data := make(map[string]map[string]*cache.CacheEntry)
+	for _, name := range c.Namespaces() {
+		data[name] = c.ExportNamespace(name)
+	}
+	return writeState(file, data, start.Unix())
+}
 
-	// Escribir todas las entradas actuales
-	for key, entry := range data {
-		logEntry := LogEntry{
-			Operation: "SET",
-			Key:       key,
-			Value:     entry.Value,
-			ExpiresAt: entry.ExpiresAt,
-			Timestamp: entry.LastAccess,
+// writeState escribe como SET cada entrada vigente de data. Timestamp es,
+// como en las operaciones, el momento de la escritura en segundos.
+func writeState(w io.Writer, data map[string]map[string]*cache.CacheEntry, now int64) error {
+	encoder := json.NewEncoder(w)
+	for name, entries := range data {
+		namespace := name
+		if namespace == cache.DefaultNamespace {
+			namespace = ""
 		}
 
-		if err := encoder.Encode(logEntry); err != nil {
-			return fmt.Errorf("error al escribir entrada: %v", err)
+		for key, entry := range entries {
+			if entry.ExpiresAt > 0 && entry.ExpiresAt <= now {
+				continue
+			}
+			logEntry := LogEntry{
+				Operation: "SET",
+				Namespace: namespace,
+				Key:       key,
+				Value:     entry.Value,
+				ExpiresAt: entry.ExpiresAt,
+				Timestamp: now,
+			}
+			if err := encoder.Encode(logEntry); err != nil {
+				return fmt.Errorf("error al escribir entrada: %v", err)
+			}
 		}
 	}
+	return nil
+}
 
+// Snapshot reemplaza el log por el estado actual del cache. Se escribe en
+// un fichero temporal que se renombra al terminar, de modo que un fallo a
+// mitad conserva el log anterior. Si es el log activo, se compacta sin
+// dejar de registrar las modificaciones.
+func Snapshot(c *cache.CacheEngine, filename string) error {
+	if filename == "" {
+		filename = DefaultLogFile
+	}
+	if sameFile(filename, LogFile(c)) {
+		return compact(c, filename)
+	}
+
+	tmp := filename + ".tmp"
+	if err := os.Remove(tmp); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error al preparar la instantánea: %v", err)
+	}
+	// El contenido debe estar en disco antes de reemplazar el log
+	err := SaveToLog(c, tmp)
+	if err == nil {
+		err = Sync(tmp)
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, filename); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("error al reemplazar el log: %v", err)
+	}
 	return nil
 }
 
-// LoadFromLog carga el estado del cache desde el archivo de log
+// LoadFromLog carga el estado del cache desde el archivo de log. Una última
+// línea incompleta, la que deja una caída a mitad de escritura, se toma
+// como el final del log. El fichero no se modifica: EnableLogging la
+// descarta al continuar el log.
 func LoadFromLog(c *cache.CacheEngine, filename string) error {
 	if filename == "" {
 		filename = DefaultLogFile
@@ -95,6 +268,10 @@ func LoadFromLog(c *cache.CacheEngine, filename string) error {
 	if _, err := os.Stat(filename); os.IsNotExist(err) {
 		return fmt.Errorf("archivo de log no existe: %s", filename)
 	}
+	// Las operaciones cargadas se añadirían al mismo fichero que se lee
+	if sameFile(filename, LogFile(c)) {
+		return fmt.Errorf("no se puede cargar el log activo: %s", filename)
+	}
 
 	file, err := os.Open(filename)
 	if err != nil {
@@ -102,38 +279,92 @@ func LoadFromLog(c *cache.CacheEngine, filename string) error {
 	}
 	defer file.Close()
 
-	decoder := json.NewDecoder(file)
+	reader := bufio.NewReader(file)
+	now := time.Now().Unix()
+	var offset int64 // Inicio de la línea en curso
 
-	// Leer y aplicar cada operación del log
+	// Leer y aplicar cada operación del log, una por línea
 	for {
-		var logEntry LogEntry
-		if err := decoder.Decode(&logEntry); err != nil {
-			if err.Error() == "EOF" {
+		line, readErr := reader.ReadBytes('\n')
+		if readErr != nil && readErr != io.EOF {
+			return fmt.Errorf("error al leer el log: %v", readErr)
+		}
+		start := offset
+		offset += int64(len(line))
+
+		trimmed := bytes.TrimSpace(line)
+		if len(trimmed) == 0 {
+			if readErr == io.EOF {
 				break
 			}
-			return fmt.Errorf("error al leer entrada del log: %v", err)
+			continue
 		}
 
-		// Aplicar operación según el tipo
-		switch logEntry.Operation {
-		case "SET":
-			c.Set(logEntry.Key, logEntry.Value)
-			if logEntry.ExpiresAt > 0 {
-				// Calcular segundos restantes
-				seconds := int(logEntry.ExpiresAt - logEntry.Timestamp)
-				if seconds > 0 {
-					c.Expire(logEntry.Key, seconds)
-				}
-			}
-		case "DEL":
-			c.Delete(logEntry.Key)
-		case "EXPIRE":
-			seconds := int(logEntry.ExpiresAt - logEntry.Timestamp)
-			if seconds > 0 {
-				c.Expire(logEntry.Key, seconds)
+		if !json.Valid(trimmed) {
+			rest, _ := io.ReadAll(reader)
+			if len(bytes.TrimSpace(rest)) > 0 {
+				return fmt.Errorf("entrada corrupta en el byte %d del log", start)
 			}
+			// Escritura interrumpida: el log termina en la línea anterior
+			break
+		}
+
+		var logEntry LogEntry
+		if err := json.Unmarshal(trimmed, &logEntry); err != nil {
+			return fmt.Errorf("error al leer entrada del log: %v", err)
+		}
+		applyEntry(c, logEntry, now)
+		if readErr == io.EOF {
+			break
 		}
 	}
 
 	return nil
 }
+
+// applyEntry aplica una entrada del log al motor. now es el instante de la
+// carga, con el que se descartan las claves ya expiradas.
+func applyEntry(c *cache.CacheEngine, logEntry LogEntry, now int64) {
+	// Las entradas sin namespace pertenecen al namespace por defecto
+	ns := c.Namespace(logEntry.Namespace)
+
+	// Aplicar operación según el tipo
+	switch logEntry.Operation {
+	case "SET":
+		// ExpiresAt es un instante absoluto: las claves que expiraron
+		// mientras el servidor estaba parado no se restauran
+		if logEntry.ExpiresAt > 0 && logEntry.ExpiresAt <= now {
+			ns.Delete(logEntry.Key)
+			return
+		}
+		if err := ns.Set(logEntry.Key, logEntry.Value); err != nil {
+			// La cuota actual del namespace rechaza la entrada
+			return
+		}
+		if logEntry.ExpiresAt > 0 {
+			ns.ExpireAt(logEntry.Key, logEntry.ExpiresAt)
+		}
+	case "DEL":
+		ns.Delete(logEntry.Key)
+	case "EXPIRE":
+		if logEntry.ExpiresAt == 0 {
+			ns.Persist(logEntry.Key)
+		} else if logEntry.ExpiresAt <= now {
+			ns.Delete(logEntry.Key)
+		} else {
+			ns.ExpireAt(logEntry.Key, logEntry.ExpiresAt)
+		}
+	case "MOVE":
+		if target, ok := logEntry.Value.(string); ok {
+			ns.Move(logEntry.Key, target)
+		}
+	case "SWAPDB":
+		if target, ok := logEntry.Value.(string); ok {
+			c.SwapNamespaces(ns.Name(), target)
+		}
+	case "FLUSHDB":
+		ns.Flush()
+	case "FLUSHALL":
+		c.FlushAll()
+	}
+}
@@ -0,0 +1,454 @@
+package persistence
+
+import (
+	"bytes"
+	"cache-engine/internal/cache"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// point es un tipo de usuario registrado para el log
+type point struct {
+	X, Y int
+}
+
+func init() {
+	if err := RegisterType("test.point", point{}); err != nil {
+		panic(err)
+	}
+}
+
+// newEngine crea un motor que se cierra al terminar la prueba
+func newEngine(t *testing.T) *cache.CacheEngine {
+	t.Helper()
+	engine := cache.NewCacheEngine(100)
+	t.Cleanup(func() { engine.Close(context.Background()) })
+	return engine
+}
+
+// TestTypedValues prueba que los valores conservan su tipo exacto al
+// recargar el log, tanto desde operaciones como desde una instantánea
+func TestTypedValues(t *testing.T) {
+	values := map[string]interface{}{
+		"string":  "hola",
+		"bytes":   []byte{0, 1, 0xff},
+		"int":     42,
+		"int64":   int64(1) << 60,
+		"uint8":   uint8(7),
+		"float32": float32(1.5),
+		"bool":    true,
+		"map":     map[string]interface{}{"a": 1.0, "b": "c", "n": 3, "p": point{X: 1}, "l": []interface{}{int64(4), nil}},
+		"list":    []interface{}{"x", 2.0, 5, []byte{1}, map[string]interface{}{"u": uint8(6)}},
+		"point":   point{X: 1, Y: -2},
+	}
+
+	dir := t.TempDir()
+	logFile := filepath.Join(dir, "ops.log")
+	source := newEngine(t)
+	for key, value := range values {
+		if err := LogOperation(logFile, "", "SET", key, value, 0); err != nil {
+			t.Fatalf("No se pudo registrar %s: %v", key, err)
+		}
+		source.Set(key, value)
+	}
+	snapshotFile := filepath.Join(dir, "snapshot.log")
+	if err := Snapshot(source, snapshotFile); err != nil {
+		t.Fatalf("No se pudo guardar la instantánea: %v", err)
+	}
+
+	for _, file := range []string{logFile, snapshotFile} {
+		engine := newEngine(t)
+		if err := LoadFromLog(engine, file); err != nil {
+			t.Fatalf("No se pudo cargar %s: %v", filepath.Base(file), err)
+		}
+		for key, want := range values {
+			got, _ := engine.Get(key)
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("%s: %s: esperaba %#v, obtuve %#v", filepath.Base(file), key, want, got)
+			}
+		}
+	}
+}
+
+// TestLegacyLog prueba que se siguen cargando los logs sin etiqueta de tipo
+func TestLegacyLog(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	content := `{"operation":"SET","key":"n","value":3,"timestamp":1}
+{"operation":"SET","key":"b","value":"AAE=","encoding":"base64","timestamp":1}
+`
+	if err := os.WriteFile(logFile, []byte(content), 0644); err != nil {
+		t.Fatalf("No se pudo escribir el log: %v", err)
+	}
+
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if got, _ := engine.Get("n"); got != 3.0 {
+		t.Errorf("n: esperaba 3.0, obtuve %#v", got)
+	}
+	if got, _ := engine.Get("b"); !reflect.DeepEqual(got, []byte{0, 1}) {
+		t.Errorf("b: esperaba []byte{0, 1}, obtuve %#v", got)
+	}
+}
+
+// TestRegisterCodec prueba los errores del registro y los tipos desconocidos
+func TestRegisterCodec(t *testing.T) {
+	if err := RegisterType("int", int(0)); err == nil {
+		t.Error("Esperaba error al repetir un nombre")
+	}
+	if err := RegisterType("entero", int(0)); err == nil {
+		t.Error("Esperaba error al repetir un tipo")
+	}
+	if err := RegisterType("vacío", nil); err == nil {
+		t.Error("Esperaba error sin valor de ejemplo")
+	}
+
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	os.WriteFile(logFile, []byte(`{"operation":"SET","key":"k","type":"otro","value":1,"timestamp":1}`+"\n"), 0644)
+	if err := LoadFromLog(newEngine(t), logFile); err == nil {
+		t.Error("Esperaba error con un tipo sin registrar")
+	}
+	// Sin registrar no se recuperaría el tipo, así que no se escribe
+	type unknown struct{ A int }
+	for _, value := range []interface{}{unknown{1}, map[string]interface{}{"a": unknown{1}}, []interface{}{map[string]int{}}} {
+		if err := LogOperation(logFile, "", "SET", "k", value, 0); err == nil {
+			t.Errorf("Esperaba error al registrar %#v", value)
+		}
+	}
+}
+
+// TestJournalEncodeError prueba que el journal descarta solo la
+// modificación que no se puede codificar y conserva el error para Close
+func TestJournalEncodeError(t *testing.T) {
+	type unknown struct{ A int }
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	source := newEngine(t)
+	if err := EnableLogging(source, logFile); err != nil {
+		t.Fatalf("No se pudo activar el log: %v", err)
+	}
+	source.MSet(map[string]interface{}{"a": 1, "b": unknown{2}, "c": "3"})
+	if err := DisableLogging(source); err == nil {
+		t.Error("Esperaba el error de codificación al cerrar")
+	}
+
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if a, _ := engine.Get("a"); a != 1 {
+		t.Errorf("a: esperaba 1, obtuve %#v", a)
+	}
+	if c, _ := engine.Get("c"); c != "3" {
+		t.Errorf("c: esperaba \"3\", obtuve %#v", c)
+	}
+	if _, found := engine.Get("b"); found {
+		t.Error("b no debería estar en el log")
+	}
+}
+
+// replay escribe entries en un log nuevo y lo carga en un motor vacío,
+// como al reiniciar el servidor
+func replay(t *testing.T, entries ...LogEntry) *cache.CacheEngine {
+	t.Helper()
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	if err := LogOperations(logFile, entries); err != nil {
+		t.Fatalf("No se pudo escribir el log: %v", err)
+	}
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar el log: %v", err)
+	}
+	return engine
+}
+
+// TestReplayTTL prueba que ExpiresAt se trata como un instante absoluto al
+// reproducir el log, sin importar cuándo se escribió la entrada
+func TestReplayTTL(t *testing.T) {
+	now := time.Now().Unix()
+	hourAgo := now - 3600
+
+	tests := []struct {
+		name    string
+		entries []LogEntry
+		exists  bool
+		ttl     int64 // Segundos restantes esperados (-1 = sin expiración)
+	}{
+		{"sin expiración", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "v", Timestamp: hourAgo},
+		}, true, -1},
+		{"SET escrito hace una hora que aún no expira", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "v", ExpiresAt: now + 600, Timestamp: hourAgo},
+		}, true, 600},
+		{"SET que expiró con el servidor parado", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "v", ExpiresAt: now - 10, Timestamp: hourAgo},
+		}, false, 0},
+		{"SET expirado reemplaza al valor anterior", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "viejo"},
+			{Operation: "SET", Key: "k", Value: "v", ExpiresAt: now - 10, Timestamp: hourAgo},
+		}, false, 0},
+		{"EXPIRE escrito hace una hora que aún no expira", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "v", Timestamp: hourAgo},
+			{Operation: "EXPIRE", Key: "k", ExpiresAt: now + 600, Timestamp: hourAgo},
+		}, true, 600},
+		{"EXPIRE que expiró con el servidor parado", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "v", Timestamp: hourAgo},
+			{Operation: "EXPIRE", Key: "k", ExpiresAt: now - 10, Timestamp: hourAgo},
+		}, false, 0},
+		{"SET posterior quita la expiración", []LogEntry{
+			{Operation: "SET", Key: "k", Value: "v", ExpiresAt: now - 10},
+			{Operation: "SET", Key: "k", Value: "v"},
+		}, true, -1},
+	}
+
+	for _, tt := range tests {
+		engine := replay(t, tt.entries...)
+		ttl, exists := engine.TTL("k")
+		if exists != tt.exists {
+			t.Errorf("%s: esperaba existe=%v, obtuve %v", tt.name, tt.exists, exists)
+			continue
+		}
+		// Se admite un segundo de margen por el reloj
+		if exists && (ttl > tt.ttl || ttl < tt.ttl-1) {
+			t.Errorf("%s: esperaba TTL %d, obtuve %d", tt.name, tt.ttl, ttl)
+		}
+		if _, found := engine.Get("k"); found != tt.exists {
+			t.Errorf("%s: Get debería coincidir con TTL", tt.name)
+		}
+	}
+}
+
+// TestSnapshotRestart prueba que una instantánea conserva las expiraciones,
+// omite las claves expiradas y escribe el timestamp en segundos
+func TestSnapshotRestart(t *testing.T) {
+	source := newEngine(t)
+	source.Set("permanente", "a")
+	source.Set("temporal", "b")
+	source.Expire("temporal", 600)
+	source.Set("expirada", "c")
+	source.Expire("expirada", -1)
+
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	if err := Snapshot(source, logFile); err != nil {
+		t.Fatalf("No se pudo guardar la instantánea: %v", err)
+	}
+
+	file, err := os.Open(logFile)
+	if err != nil {
+		t.Fatalf("No se pudo abrir el log: %v", err)
+	}
+	defer file.Close()
+	decoder := json.NewDecoder(file)
+	now := time.Now().Unix()
+	for decoder.More() {
+		var entry LogEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatalf("No se pudo leer el log: %v", err)
+		}
+		if entry.Key == "expirada" {
+			t.Error("La instantánea no debería incluir claves expiradas")
+		}
+		if entry.Timestamp < now-5 || entry.Timestamp > now+5 {
+			t.Errorf("%s: timestamp %d no está en segundos", entry.Key, entry.Timestamp)
+		}
+	}
+
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if ttl, exists := engine.TTL("permanente"); !exists || ttl != -1 {
+		t.Errorf("permanente: esperaba TTL -1, obtuve %d %v", ttl, exists)
+	}
+	if ttl, exists := engine.TTL("temporal"); !exists || ttl < 599 || ttl > 600 {
+		t.Errorf("temporal: esperaba TTL 600, obtuve %d %v", ttl, exists)
+	}
+	if engine.Size() != 2 {
+		t.Errorf("Esperaba 2 claves tras reiniciar, obtuve %d", engine.Size())
+	}
+}
+
+// TestEnableLogging prueba que el log recoge las operaciones hechas con la
+// API del motor, incluidas las expulsiones, y que reproduce su estado
+func TestEnableLogging(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	source := cache.NewCacheEngine(3)
+	defer source.Close(context.Background())
+	source.Set("previa", "a")
+
+	if err := EnableLogging(source, logFile); err != nil {
+		t.Fatalf("No se pudo activar el log: %v", err)
+	}
+	if LogFile(source) != logFile {
+		t.Errorf("Esperaba el log %s, obtuve %q", logFile, LogFile(source))
+	}
+	if err := LoadFromLog(source, logFile); err == nil {
+		t.Error("Esperaba error al cargar el log activo")
+	}
+
+	source.Set("b", 2)
+	source.Expire("b", 600)
+	source.Set("c", point{X: 3})
+	source.Set("d", "expulsa a previa")
+	source.Namespace("other").Set("e", []byte("e"))
+	source.Delete("c")
+	source.SwapNamespaces(cache.DefaultNamespace, "other")
+
+	// La instantánea del log activo lo compacta sin dejar de registrar
+	if err := Snapshot(source, logFile); err != nil {
+		t.Fatalf("No se pudo compactar el log: %v", err)
+	}
+	source.Namespace("other").Set("f", 1.5)
+	if err := DisableLogging(source); err != nil {
+		t.Fatalf("No se pudo desactivar el log: %v", err)
+	}
+	want := map[string]map[string]*cache.CacheEntry{
+		cache.DefaultNamespace: source.ExportNamespace(cache.DefaultNamespace),
+		"other":                source.ExportNamespace("other"),
+	}
+	source.Set("g", "sin registrar")
+
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	for name, entries := range want {
+		got := engine.ExportNamespace(name)
+		if len(got) != len(entries) {
+			t.Errorf("%s: esperaba %d claves, obtuve %d", name, len(entries), len(got))
+		}
+		for key, entry := range entries {
+			if !reflect.DeepEqual(got[key].Value, entry.Value) || got[key].ExpiresAt != entry.ExpiresAt {
+				t.Errorf("%s: %s: esperaba %#v, obtuve %#v", name, key, entry, got[key])
+			}
+		}
+	}
+	if _, found := engine.Namespace("other").Get("previa"); found {
+		t.Error("La clave expulsada no debería reaparecer")
+	}
+}
+
+// TestTornLog prueba que una última línea a medio escribir se toma como el
+// final del log sin modificarlo, que al continuarlo se descarta, y que una
+// línea corrupta en medio es un error
+func TestTornLog(t *testing.T) {
+	complete := `{"operation":"SET","key":"a","type":"string","value":"1","timestamp":1}` + "\n"
+	tests := []struct {
+		name string
+		tail string
+	}{
+		{"línea cortada", `{"operation":"SET","key":"b","ty`},
+		{"línea cortada con salto", `{"operation":"SET","key":"b"` + "\n"},
+		{"ceros tras la caída", "\x00\x00\x00\x00"},
+	}
+	for _, tt := range tests {
+		logFile := filepath.Join(t.TempDir(), "cache.log")
+		os.WriteFile(logFile, []byte(complete+tt.tail), 0644)
+
+		engine := newEngine(t)
+		if err := LoadFromLog(engine, logFile); err != nil {
+			t.Errorf("%s: no debería fallar: %v", tt.name, err)
+			continue
+		}
+		if got, _ := engine.Get("a"); got != "1" || engine.Size() != 1 {
+			t.Errorf("%s: esperaba solo la entrada completa, obtuve %v (%d claves)", tt.name, got, engine.Size())
+		}
+		if data, _ := os.ReadFile(logFile); string(data) != complete+tt.tail {
+			t.Errorf("%s: cargar no debería modificar el log, quedó %q", tt.name, data)
+		}
+
+		// Al continuar el log se descarta la línea cortada
+		if err := EnableLogging(engine, logFile); err != nil {
+			t.Fatalf("%s: no se pudo activar el log: %v", tt.name, err)
+		}
+		engine.Set("c", "3")
+		DisableLogging(engine)
+		reloaded := newEngine(t)
+		if err := LoadFromLog(reloaded, logFile); err != nil || reloaded.Size() != 2 {
+			t.Errorf("%s: esperaba 2 claves tras continuar el log, obtuve %d %v", tt.name, reloaded.Size(), err)
+		}
+	}
+
+	corrupt := filepath.Join(t.TempDir(), "cache.log")
+	os.WriteFile(corrupt, []byte(`{"oper`+"\n"+complete), 0644)
+	if err := LoadFromLog(newEngine(t), corrupt); err == nil {
+		t.Error("Esperaba error con una línea corrupta en medio del log")
+	}
+}
+
+// TestLoadThenEnable prueba que un log cuya última entrada está completa
+// pero sin salto de línea se carga y se continúa
+func TestLoadThenEnable(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	os.WriteFile(logFile, []byte(`{"operation":"SET","key":"a","type":"string","value":"1","timestamp":1}`), 0644)
+
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if err := EnableLogging(engine, logFile); err != nil {
+		t.Fatalf("No se pudo activar el log tras cargarlo: %v", err)
+	}
+	engine.Set("b", "2")
+	if err := DisableLogging(engine); err != nil {
+		t.Fatalf("No se pudo desactivar el log: %v", err)
+	}
+
+	reloaded := newEngine(t)
+	if err := LoadFromLog(reloaded, logFile); err != nil {
+		t.Fatalf("No se pudo recargar: %v", err)
+	}
+	if a, _ := reloaded.Get("a"); a != "1" || reloaded.Size() != 2 {
+		t.Errorf("Esperaba a y b, obtuve %v (%d claves)", a, reloaded.Size())
+	}
+}
+
+// TestEnableLoggingExisting prueba que activar el log sobre un fichero con
+// contenido lo continúa sin reemplazarlo, y que solo SAVE lo compacta
+func TestEnableLoggingExisting(t *testing.T) {
+	logFile := filepath.Join(t.TempDir(), "cache.log")
+	if err := LogOperation(logFile, "", "SET", "anterior", "a", 0); err != nil {
+		t.Fatalf("No se pudo registrar: %v", err)
+	}
+
+	source := newEngine(t)
+	source.Set("memoria", "m")
+	if err := EnableLogging(source, logFile); err != nil {
+		t.Fatalf("No se pudo activar el log: %v", err)
+	}
+	source.Set("nueva", "n")
+	source.Delete("memoria")
+	if err := DisableLogging(source); err != nil {
+		t.Fatalf("No se pudo desactivar el log: %v", err)
+	}
+
+	engine := newEngine(t)
+	if err := LoadFromLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo cargar: %v", err)
+	}
+	if got, _ := engine.Get("anterior"); got != "a" {
+		t.Errorf("El contenido anterior del log debería conservarse, obtuve %#v", got)
+	}
+	if got, _ := engine.Get("nueva"); got != "n" || engine.Size() != 2 {
+		t.Errorf("Esperaba anterior y nueva, obtuve %v (%d claves)", got, engine.Size())
+	}
+
+	// SAVE sobre el log activo lo compacta con el estado del motor
+	if err := EnableLogging(engine, logFile); err != nil {
+		t.Fatalf("No se pudo activar el log: %v", err)
+	}
+	engine.Delete("anterior")
+	if err := SaveToLog(engine, logFile); err != nil {
+		t.Fatalf("No se pudo compactar: %v", err)
+	}
+	DisableLogging(engine)
+	if data, _ := os.ReadFile(logFile); bytes.Count(data, []byte("\n")) != 1 {
+		t.Errorf("El log compactado debería tener una línea:\n%s", data)
+	}
+
+}
@@ -0,0 +1,70 @@
+package persistence
+
+import (
+	"cache-engine/internal/cache"
+	"os"
+	"sync"
+	"time"
+)
+
+// WriteStats resume las escrituras realizadas en el log
+type WriteStats struct {
+	Writes    int64         // Escrituras completadas
+	Errors    int64         // Escrituras fallidas
+	TotalTime time.Duration // Tiempo acumulado de escritura
+	Buckets   []int64       // Escrituras por bucket de cache.LatencyBuckets (el último es +Inf)
+}
+
+// writeStats acumula la latencia de escritura del log
+var writeStats = struct {
+	mu    sync.Mutex
+	stats WriteStats
+}{stats: WriteStats{Buckets: make([]int64, len(cache.LatencyBuckets)+1)}}
+
+// recordWrite registra la duración de una escritura en el log
+func recordWrite(start time.Time, err error) {
+	elapsed := time.Since(start)
+
+	writeStats.mu.Lock()
+	defer writeStats.mu.Unlock()
+
+	if err != nil {
+		writeStats.stats.Errors++
+		return
+	}
+
+	writeStats.stats.Writes++
+	writeStats.stats.TotalTime += elapsed
+
+	bucket := len(cache.LatencyBuckets)
+	for i, bound := range cache.LatencyBuckets {
+		if elapsed <= bound {
+			bucket = i
+			break
+		}
+	}
+	writeStats.stats.Buckets[bucket]++
+}
+
+// GetWriteStats retorna una copia de las estadísticas de escritura del log
+func GetWriteStats() WriteStats {
+	writeStats.mu.Lock()
+	defer writeStats.mu.Unlock()
+
+	stats := writeStats.stats
+	stats.Buckets = append([]int64(nil), writeStats.stats.Buckets...)
+	return stats
+}
+
+// LogSize retorna el tamaño en bytes del archivo de log (0 si no existe)
+func LogSize(filename string) int64 {
+	if filename == "" {
+		return 0
+	}
+
+	info, err := os.Stat(filename)
+	if err != nil {
+		return 0
+	}
+	return info.Size()
+}
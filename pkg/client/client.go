@@ -0,0 +1,450 @@
+// Package client es el cliente Go para servidores cache-engine remotos.
+// Habla RESP (el modo -mode=resp del servidor) con un pool de conexiones,
+// pipelining y reconexión automática con backoff exponencial.
+//
+// Client implementa la interfaz Cache, que también cumple *cache.CacheEngine,
+// de modo que el mismo código puede usar el motor embebido o uno remoto.
+//
+// Con Config.NearCacheSize el cliente guarda localmente los valores leídos
+// y el servidor le avisa (CLIENT TRACKING) cuando cambian, expiran o se
+// expulsan, de modo que las lecturas repetidas no salen a la red.
+package client
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// DefaultAddr es la dirección por defecto del servidor
+	DefaultAddr = "localhost:6379"
+
+	defaultPoolSize    = 10
+	defaultDialTimeout = 5 * time.Second
+	defaultTimeout     = 5 * time.Second
+	defaultMaxRetries  = 3
+	defaultMinBackoff  = 50 * time.Millisecond
+	defaultMaxBackoff  = 2 * time.Second
+)
+
+// Cache es el conjunto de operaciones común al motor embebido
+// (*cache.CacheEngine) y al cliente remoto (*Client)
+type Cache interface {
+	Set(key string, value interface{}) error
+	Get(key string) (interface{}, bool)
+	Delete(key string) bool
+	Expire(key string, seconds int) bool
+	TTL(key string) (int64, bool)
+	Keys(pattern string) []string
+	Size() int
+}
+
+// Config contiene las opciones del cliente. Los valores cero usan los
+// valores por defecto.
+type Config struct {
+	Network     string        // "tcp" (por defecto) o "unix"
+	Addr        string        // host:puerto del servidor o ruta del socket Unix (por defecto localhost:6379)
+	Namespace   string        // Namespace usado por todas las conexiones (vacío = por defecto)
+	Username    string        // Usuario ACL (vacío = default)
+	Password    string        // Contraseña (vacío = sin AUTH)
+	TLS         *tls.Config   // Conexión cifrada (nil = texto plano)
+	PoolSize    int           // Conexiones simultáneas máximas (por defecto 10)
+	DialTimeout time.Duration // Timeout de conexión (por defecto 5s)
+	Timeout     time.Duration // Timeout por comando si el contexto no tiene deadline (por defecto 5s)
+	MaxRetries  int           // Reintentos ante errores de red (por defecto 3, -1 = ninguno)
+	MinBackoff  time.Duration // Espera antes del primer reintento (por defecto 50ms)
+	MaxBackoff  time.Duration // Espera máxima entre reintentos (por defecto 2s)
+
+	NearCacheSize int // Entradas del near cache local (0 = deshabilitado)
+}
+
+// Client es un cliente seguro para uso concurrente
+type Client struct {
+	config Config
+	pool   *pool
+
+	// Near cache (nil si está deshabilitado)
+	near         *nearCache
+	trackingID   atomic.Int64 // Id de la conexión de invalidaciones (0 = no disponible)
+	stop         chan struct{}
+	trackingDone chan struct{}
+
+	mu           sync.Mutex
+	closed       bool
+	trackingConn net.Conn
+}
+
+// New crea un cliente. Las conexiones se abren bajo demanda.
+func New(config Config) *Client {
+	if config.Network == "" {
+		config.Network = "tcp"
+	}
+	if config.Addr == "" {
+		config.Addr = DefaultAddr
+	}
+	if config.PoolSize <= 0 {
+		config.PoolSize = defaultPoolSize
+	}
+	if config.DialTimeout <= 0 {
+		config.DialTimeout = defaultDialTimeout
+	}
+	if config.Timeout <= 0 {
+		config.Timeout = defaultTimeout
+	}
+	if config.MaxRetries == 0 {
+		config.MaxRetries = defaultMaxRetries
+	} else if config.MaxRetries < 0 {
+		config.MaxRetries = 0
+	}
+	if config.MinBackoff <= 0 {
+		config.MinBackoff = defaultMinBackoff
+	}
+	if config.MaxBackoff < config.MinBackoff {
+		config.MaxBackoff = max(defaultMaxBackoff, config.MinBackoff)
+	}
+
+	c := &Client{config: config}
+	c.pool = newPool(config.PoolSize, c.dial)
+
+	if config.NearCacheSize > 0 {
+		c.near = newNearCache(config.NearCacheSize)
+		c.stop = make(chan struct{})
+		c.trackingDone = make(chan struct{})
+		go c.runTracking()
+	}
+	return c
+}
+
+// Close cierra las conexiones del cliente
+func (c *Client) Close() error {
+	c.mu.Lock()
+	alreadyClosed := c.closed
+	c.closed = true
+	if c.trackingConn != nil {
+		c.trackingConn.Close()
+	}
+	c.mu.Unlock()
+
+	if c.near != nil && !alreadyClosed {
+		close(c.stop)
+		<-c.trackingDone
+	}
+	c.pool.close()
+	return nil
+}
+
+// dial abre una conexión y selecciona el namespace configurado
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	nc, err := c.dialNet(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	cn := &conn{nc: nc, reader: bufio.NewReader(nc), writer: bufio.NewWriter(nc)}
+
+	// Autenticación y namespace en una sola ida y vuelta
+	var setup [][]interface{}
+	if c.config.Password != "" {
+		setup = append(setup, []interface{}{"AUTH", c.username(), c.config.Password})
+	}
+	if c.config.Namespace != "" {
+		setup = append(setup, []interface{}{"SELECT", c.config.Namespace})
+	}
+	if len(setup) > 0 {
+		replies, err := cn.roundTrip(ctx, c.config.Timeout, setup)
+		for i := 0; err == nil && i < len(replies); i++ {
+			err = replyError(replies[i])
+		}
+		if err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	if c.near != nil {
+		if err := c.enableTracking(ctx, cn); err != nil {
+			nc.Close()
+			return nil, err
+		}
+	}
+	return cn, nil
+}
+
+// dialNet abre una conexión de red con el servidor, cifrada si hay TLS
+func (c *Client) dialNet(ctx context.Context) (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: c.config.DialTimeout}
+	if c.config.TLS != nil {
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: c.config.TLS}
+		return tlsDialer.DialContext(ctx, c.config.Network, c.config.Addr)
+	}
+	return dialer.DialContext(ctx, c.config.Network, c.config.Addr)
+}
+
+// username retorna el usuario con el que se autentican las conexiones
+func (c *Client) username() string {
+	if c.config.Username == "" {
+		return "default"
+	}
+	return c.config.Username
+}
+
+// do ejecuta comandos en una conexión del pool, reintentando con backoff
+// si la conexión falla. Los errores del servidor no se reintentan.
+func (c *Client) do(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error) {
+	for attempt := 0; ; attempt++ {
+		cn, err := c.pool.get(ctx)
+		for err == nil && c.stale(cn) {
+			// Configurada para otra conexión de invalidaciones: reemplazarla
+			c.pool.put(cn, false)
+			cn, err = c.pool.get(ctx)
+		}
+		if err == nil {
+			var replies []interface{}
+			replies, err = cn.roundTrip(ctx, c.config.Timeout, cmds)
+			c.pool.put(cn, err == nil)
+			if err == nil {
+				return replies, nil
+			}
+		}
+
+		var serverErr Error
+		if ctx.Err() != nil || errors.Is(err, ErrClosed) || errors.As(err, &serverErr) ||
+			attempt >= c.config.MaxRetries {
+			return nil, err
+		}
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// backoff calcula la espera antes del reintento attempt: exponencial con
+// jitter para no sincronizar a los clientes tras una caída del servidor
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.config.MinBackoff << min(attempt, 16)
+	if d > c.config.MaxBackoff || d <= 0 {
+		d = c.config.MaxBackoff
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// Do ejecuta un comando arbitrario y retorna su respuesta. Un error del
+// servidor se retorna como Error.
+func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, error) {
+	replies, err := c.do(ctx, args)
+	if err != nil {
+		return nil, err
+	}
+	if err := replyError(replies[0]); err != nil {
+		return nil, err
+	}
+	return replies[0], nil
+}
+
+// replyError retorna la respuesta como error si el servidor devolvió uno
+func replyError(reply interface{}) error {
+	if err, ok := reply.(Error); ok {
+		return err
+	}
+	return nil
+}
+
+// replyInt interpreta una respuesta entera
+func replyInt(reply interface{}) (int64, error) {
+	switch v := reply.(type) {
+	case int64:
+		return v, nil
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	}
+	return 0, fmt.Errorf("%w: se esperaba un entero, se obtuvo %T", ErrProtocol, reply)
+}
+
+// Ping comprueba la conexión con el servidor
+func (c *Client) Ping(ctx context.Context) error {
+	_, err := c.Do(ctx, "PING")
+	return err
+}
+
+// SetContext guarda un valor con una expiración opcional (0 = sin expiración)
+func (c *Client) SetContext(ctx context.Context, key string, value interface{}, ttl time.Duration) error {
+	args := []interface{}{"SET", key, value}
+	if ttl > 0 {
+		args = append(args, "PX", ttl.Milliseconds())
+	}
+	_, err := c.Do(ctx, args...)
+	c.invalidateLocal(key)
+	return err
+}
+
+// invalidateLocal descarta del near cache las claves escritas por este
+// cliente sin esperar al push del servidor (lectura de las propias escrituras)
+func (c *Client) invalidateLocal(keys ...string) {
+	if c.near == nil {
+		return
+	}
+	for _, key := range keys {
+		c.near.invalidate(key)
+	}
+}
+
+// GetContext obtiene un valor; found es false si la clave no existe. Con
+// near cache, las claves ya leídas se sirven localmente.
+func (c *Client) GetContext(ctx context.Context, key string) (value []byte, found bool, err error) {
+	if c.near == nil {
+		return c.get(ctx, key)
+	}
+
+	if cached, ok := c.near.get(key); ok {
+		return []byte(cached), true, nil
+	}
+
+	tracked := c.trackingID.Load() != 0
+	epoch := c.near.begin(key)
+	value, found, err = c.get(ctx, key)
+	c.near.finish(key, epoch, string(value), tracked && found && err == nil)
+	return value, found, err
+}
+
+// get lee un valor del servidor
+func (c *Client) get(ctx context.Context, key string) ([]byte, bool, error) {
+	reply, err := c.Do(ctx, "GET", key)
+	if err != nil || reply == nil {
+		return nil, false, err
+	}
+	data, ok := reply.([]byte)
+	if !ok {
+		return nil, false, fmt.Errorf("%w: se esperaba un bulk string, se obtuvo %T", ErrProtocol, reply)
+	}
+	return data, true, nil
+}
+
+// DeleteContext elimina claves y retorna cuántas existían
+func (c *Client) DeleteContext(ctx context.Context, keys ...string) (int64, error) {
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	reply, err := c.Do(ctx, args...)
+	c.invalidateLocal(keys...)
+	if err != nil {
+		return 0, err
+	}
+	return replyInt(reply)
+}
+
+// ExpireContext establece la expiración de una clave existente
+func (c *Client) ExpireContext(ctx context.Context, key string, seconds int) (bool, error) {
+	reply, err := c.Do(ctx, "EXPIRE", key, seconds)
+	if err != nil {
+		return false, err
+	}
+	n, err := replyInt(reply)
+	return n == 1, err
+}
+
+// TTLContext retorna los segundos restantes (-1 = sin expiración);
+// found es false si la clave no existe
+func (c *Client) TTLContext(ctx context.Context, key string) (ttl int64, found bool, err error) {
+	reply, err := c.Do(ctx, "TTL", key)
+	if err != nil {
+		return 0, false, err
+	}
+	ttl, err = replyInt(reply)
+	if err != nil || ttl == -2 {
+		return 0, false, err
+	}
+	return ttl, true, nil
+}
+
+// KeysContext lista las claves que coinciden con un patrón glob
+func (c *Client) KeysContext(ctx context.Context, pattern string) ([]string, error) {
+	reply, err := c.Do(ctx, "KEYS", pattern)
+	if err != nil {
+		return nil, err
+	}
+	items, ok := reply.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("%w: se esperaba un array, se obtuvo %T", ErrProtocol, reply)
+	}
+	keys := make([]string, len(items))
+	for i, item := range items {
+		keys[i] = replyString(item)
+	}
+	return keys, nil
+}
+
+// SizeContext retorna el número de claves del namespace
+func (c *Client) SizeContext(ctx context.Context) (int, error) {
+	reply, err := c.Do(ctx, "DBSIZE")
+	if err != nil {
+		return 0, err
+	}
+	n, err := replyInt(reply)
+	return int(n), err
+}
+
+// Los métodos siguientes implementan Cache con el timeout configurado.
+// Como las firmas del motor no retornan error, un fallo de red se
+// interpreta como clave inexistente; usar las variantes *Context para
+// distinguirlo.
+
+// Set guarda un valor sin expiración
+func (c *Client) Set(key string, value interface{}) error {
+	return c.SetContext(context.Background(), key, value, 0)
+}
+
+// Get obtiene un valor como string (los servidores remotos guardan bytes)
+func (c *Client) Get(key string) (interface{}, bool) {
+	value, found, err := c.GetContext(context.Background(), key)
+	if err != nil || !found {
+		return nil, false
+	}
+	return string(value), true
+}
+
+// Delete elimina una clave
+func (c *Client) Delete(key string) bool {
+	n, err := c.DeleteContext(context.Background(), key)
+	return err == nil && n > 0
+}
+
+// Expire establece la expiración de una clave
+func (c *Client) Expire(key string, seconds int) bool {
+	updated, err := c.ExpireContext(context.Background(), key, seconds)
+	return err == nil && updated
+}
+
+// TTL retorna los segundos restantes de una clave (-1 = sin expiración)
+func (c *Client) TTL(key string) (int64, bool) {
+	ttl, found, err := c.TTLContext(context.Background(), key)
+	return ttl, err == nil && found
+}
+
+// Keys lista las claves que coinciden con un patrón glob
+func (c *Client) Keys(pattern string) []string {
+	keys, _ := c.KeysContext(context.Background(), pattern)
+	return keys
+}
+
+// Size retorna el número de claves del namespace
+func (c *Client) Size() int {
+	n, _ := c.SizeContext(context.Background())
+	return n
+}
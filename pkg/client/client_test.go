@@ -0,0 +1,315 @@
+package client
+
+import (
+	"cache-engine/internal/acl"
+	"cache-engine/internal/api/resp"
+	"cache-engine/internal/cache"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// El motor embebido y el cliente remoto son intercambiables
+var (
+	_ Cache = (*cache.CacheEngine)(nil)
+	_ Cache = (*Client)(nil)
+)
+
+// startServer arranca un servidor RESP en la dirección indicada (o en un
+// puerto libre si está vacía) y retorna el servidor y la dirección
+func startServer(t *testing.T, engine *cache.CacheEngine, addr string) (*resp.Server, string) {
+	t.Helper()
+	return startServerWith(t, engine, addr, resp.Config{})
+}
+
+// startServerWith arranca un servidor RESP con la configuración indicada
+func startServerWith(t *testing.T, engine *cache.CacheEngine, addr string, config resp.Config) (*resp.Server, string) {
+	t.Helper()
+
+	if addr == "" {
+		addr = "127.0.0.1:0"
+	}
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+
+	server := resp.NewServer(engine, config)
+	go server.Serve(listener)
+	t.Cleanup(func() { server.Close() })
+	return server, listener.Addr().String()
+}
+
+// newTestClient crea un motor, un servidor y un cliente conectado
+func newTestClient(t *testing.T, config Config) (*cache.CacheEngine, *Client) {
+	t.Helper()
+
+	engine := cache.NewCacheEngine(100)
+	t.Cleanup(func() { engine.Close(context.Background()) })
+	_, config.Addr = startServer(t, engine, "")
+
+	client := New(config)
+	t.Cleanup(func() { client.Close() })
+	return engine, client
+}
+
+// useCache ejecuta las mismas operaciones sobre cualquier implementación
+func useCache(t *testing.T, c Cache) {
+	t.Helper()
+
+	if err := c.Set("user:1", "Juan"); err != nil {
+		t.Fatalf("Set falló: %v", err)
+	}
+	if value, found := c.Get("user:1"); !found || value != "Juan" {
+		t.Errorf("Get: esperaba Juan, obtuve %v %v", value, found)
+	}
+	if !c.Expire("user:1", 60) {
+		t.Error("Expire debería actualizar una clave existente")
+	}
+	if ttl, found := c.TTL("user:1"); !found || ttl <= 0 || ttl > 60 {
+		t.Errorf("TTL inesperado: %d %v", ttl, found)
+	}
+	c.Set("user:2", "Ana")
+	if keys := c.Keys("user:*"); len(keys) != 2 {
+		t.Errorf("Keys: esperaba 2 claves, obtuve %v", keys)
+	}
+	if c.Size() != 2 {
+		t.Errorf("Size: esperaba 2, obtuve %d", c.Size())
+	}
+	if !c.Delete("user:1") || c.Delete("user:1") {
+		t.Error("Delete debería eliminar la clave una sola vez")
+	}
+	if _, found := c.Get("user:1"); found {
+		t.Error("La clave eliminada no debería existir")
+	}
+}
+
+// TestEmbeddedAndRemote prueba que el mismo código funciona con ambos modos
+func TestEmbeddedAndRemote(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+	useCache(t, engine)
+
+	_, client := newTestClient(t, Config{})
+	useCache(t, client)
+}
+
+// TestNamespace prueba que el cliente trabaja en el namespace configurado
+func TestNamespace(t *testing.T) {
+	engine, client := newTestClient(t, Config{Namespace: "sessions"})
+
+	client.Set("token", "abc")
+	if _, found := engine.Get("token"); found {
+		t.Error("La clave no debería estar en el namespace por defecto")
+	}
+	if value, found := engine.Namespace("sessions").Get("token"); !found || value != "abc" {
+		t.Errorf("Esperaba abc en sessions, obtuve %v", value)
+	}
+}
+
+// TestPipeline prueba el envío de varios comandos en una ida y vuelta
+func TestPipeline(t *testing.T) {
+	_, client := newTestClient(t, Config{})
+	ctx := context.Background()
+
+	replies, err := client.Pipeline().
+		Set("a", "1", 0).
+		Set("b", 2, time.Minute).
+		Get("a").
+		Do("NOPE").
+		Delete("a", "b").
+		Exec(ctx)
+	if err != nil {
+		t.Fatalf("Exec falló: %v", err)
+	}
+	if len(replies) != 5 {
+		t.Fatalf("Esperaba 5 respuestas, obtuve %d", len(replies))
+	}
+	if replies[0] != "OK" || string(replies[2].([]byte)) != "1" {
+		t.Errorf("Respuestas inesperadas: %v", replies)
+	}
+	if _, ok := replies[3].(Error); !ok {
+		t.Errorf("Un comando desconocido debería responder Error, obtuve %v", replies[3])
+	}
+	if replies[4] != int64(2) {
+		t.Errorf("DEL: esperaba 2, obtuve %v", replies[4])
+	}
+}
+
+// TestServerError prueba que los errores del servidor se retornan sin reintentar
+func TestServerError(t *testing.T) {
+	_, client := newTestClient(t, Config{})
+
+	_, err := client.Do(context.Background(), "EXPIRE", "k", "abc")
+	var serverErr Error
+	if !errors.As(err, &serverErr) {
+		t.Errorf("Esperaba un Error del servidor, obtuve %v", err)
+	}
+	// La conexión sigue siendo válida
+	if err := client.Ping(context.Background()); err != nil {
+		t.Errorf("Ping falló tras un error del servidor: %v", err)
+	}
+}
+
+// TestReconnect prueba la reconexión tras reiniciar el servidor
+func TestReconnect(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	server, addr := startServer(t, engine, "")
+	client := New(Config{Addr: addr, MinBackoff: 10 * time.Millisecond})
+	defer client.Close()
+
+	if err := client.Set("k", "v"); err != nil {
+		t.Fatalf("Set falló: %v", err)
+	}
+
+	// Reiniciar el servidor: la conexión del pool queda rota
+	server.Close()
+	startServer(t, engine, addr)
+
+	if value, found := client.Get("k"); !found || value != "v" {
+		t.Errorf("Tras reconectar esperaba v, obtuve %v %v", value, found)
+	}
+}
+
+// TestContextTimeout prueba que el contexto limita la espera de la respuesta
+func TestContextTimeout(t *testing.T) {
+	// Servidor que acepta conexiones pero nunca responde
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("No se pudo escuchar: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			nc, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer nc.Close()
+		}
+	}()
+
+	client := New(Config{Addr: listener.Addr().String()})
+	defer client.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, _, err = client.GetContext(ctx, "k")
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Esperaba DeadlineExceeded, obtuve %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("El timeout tardó demasiado: %v", elapsed)
+	}
+}
+
+// TestPoolConcurrency prueba el uso concurrente con un pool pequeño
+func TestPoolConcurrency(t *testing.T) {
+	engine, client := newTestClient(t, Config{PoolSize: 2})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			key := fmt.Sprintf("key:%d", i)
+			if err := client.Set(key, i); err != nil {
+				t.Errorf("Set falló: %v", err)
+			}
+			if value, found := client.Get(key); !found || value != fmt.Sprint(i) {
+				t.Errorf("Get %s: obtuve %v %v", key, value, found)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if engine.Size() != 20 {
+		t.Errorf("Esperaba 20 claves, obtuve %d", engine.Size())
+	}
+}
+
+// TestClosed prueba que un cliente cerrado no abre conexiones
+func TestClosed(t *testing.T) {
+	_, client := newTestClient(t, Config{})
+	client.Close()
+
+	if err := client.Ping(context.Background()); !errors.Is(err, ErrClosed) {
+		t.Errorf("Esperaba ErrClosed, obtuve %v", err)
+	}
+}
+
+// TestAuthentication prueba que las conexiones del pool y la de
+// invalidaciones se autentican
+func TestAuthentication(t *testing.T) {
+	users := acl.NewStore()
+	users.SetUser(acl.DefaultUser, "off")
+	users.SetUser("app", "on", ">pw", "allkeys", "+@all")
+
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+	_, addr := startServerWith(t, engine, "", resp.Config{ACL: users})
+
+	anonymous := New(Config{Addr: addr, MaxRetries: -1})
+	defer anonymous.Close()
+	if err := anonymous.SetContext(context.Background(), "k", "v", 0); err == nil || !strings.HasPrefix(err.Error(), "NOAUTH") {
+		t.Errorf("Esperaba NOAUTH, obtuve %v", err)
+	}
+
+	client := New(Config{Addr: addr, Username: "app", Password: "pw", NearCacheSize: 10})
+	defer client.Close()
+	useCache(t, client)
+
+	// Las invalidaciones llegan por la conexión autenticada con HELLO
+	waitFor(t, "conexión de invalidaciones", func() bool { return client.trackingID.Load() != 0 })
+	engine.Set("k", "v")
+	client.Get("k")
+	engine.Set("k", "otro")
+	waitFor(t, "invalidación de k", func() bool { return client.near.len() == 0 })
+	if value, _ := client.Get("k"); value != "otro" {
+		t.Errorf("Esperaba otro tras la invalidación, obtuve %v", value)
+	}
+}
+
+// TestUnixSocket prueba la conexión por socket Unix sin listener TCP
+func TestUnixSocket(t *testing.T) {
+	dir, err := os.MkdirTemp("", "sock")
+	if err != nil {
+		t.Fatalf("No se pudo crear el directorio: %v", err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "cache.sock")
+
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+	server := resp.NewServer(engine, resp.Config{UnixSocket: path, NoTCP: true})
+	go server.ListenAndServe()
+	defer server.Close()
+
+	client := New(Config{Network: "unix", Addr: path})
+	defer client.Close()
+
+	ctx := context.Background()
+	deadline := time.Now().Add(2 * time.Second)
+	for client.SetContext(ctx, "clave", "valor", 0) != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("No se pudo conectar por el socket Unix")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	value, found, err := client.GetContext(ctx, "clave")
+	if err != nil || !found || string(value) != "valor" {
+		t.Errorf("Esperaba 'valor', obtuve %q %v %v", value, found, err)
+	}
+}
@@ -0,0 +1,129 @@
+package client
+
+import (
+	"container/list"
+	"sync"
+)
+
+// nearCache es la cache local del cliente, acotada por número de entradas
+// con expulsión LRU. Se mantiene coherente con las invalidaciones que el
+// servidor envía por CLIENT TRACKING.
+type nearCache struct {
+	mu       sync.Mutex
+	size     int
+	entries  map[string]*list.Element
+	lru      *list.List // Frente = uso más reciente
+	inflight map[string]*fetch
+	epoch    uint64 // Cambia con cada clear: descarta lecturas en curso
+}
+
+// nearEntry es una entrada de la lista LRU
+type nearEntry struct {
+	key   string
+	value string
+}
+
+// fetch son las lecturas en curso de una clave. Si llega una invalidación
+// mientras tanto, el valor leído puede estar obsoleto y no se guarda.
+type fetch struct {
+	count int
+	dirty bool
+}
+
+// newNearCache crea una cache local de hasta size entradas
+func newNearCache(size int) *nearCache {
+	return &nearCache{
+		size:     size,
+		entries:  make(map[string]*list.Element),
+		lru:      list.New(),
+		inflight: make(map[string]*fetch),
+	}
+}
+
+// get retorna el valor local de una clave
+func (n *nearCache) get(key string) (string, bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	elem, exists := n.entries[key]
+	if !exists {
+		return "", false
+	}
+	n.lru.MoveToFront(elem)
+	return elem.Value.(*nearEntry).value, true
+}
+
+// begin registra una lectura remota de la clave y retorna la época actual
+func (n *nearCache) begin(key string) uint64 {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f, exists := n.inflight[key]
+	if !exists {
+		f = &fetch{}
+		n.inflight[key] = f
+	}
+	f.count++
+	return n.epoch
+}
+
+// finish termina una lectura remota y guarda el valor si store es true y
+// no hubo invalidaciones desde begin
+func (n *nearCache) finish(key string, epoch uint64, value string, store bool) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	f := n.inflight[key]
+	dirty := f.dirty
+	if f.count--; f.count == 0 {
+		delete(n.inflight, key)
+	}
+
+	if !store || dirty || epoch != n.epoch {
+		return
+	}
+
+	if elem, exists := n.entries[key]; exists {
+		elem.Value.(*nearEntry).value = value
+		n.lru.MoveToFront(elem)
+		return
+	}
+
+	n.entries[key] = n.lru.PushFront(&nearEntry{key: key, value: value})
+	if n.lru.Len() > n.size {
+		oldest := n.lru.Back()
+		n.lru.Remove(oldest)
+		delete(n.entries, oldest.Value.(*nearEntry).key)
+	}
+}
+
+// invalidate elimina una clave y marca como obsoletas sus lecturas en curso
+func (n *nearCache) invalidate(key string) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if elem, exists := n.entries[key]; exists {
+		n.lru.Remove(elem)
+		delete(n.entries, key)
+	}
+	if f, exists := n.inflight[key]; exists {
+		f.dirty = true
+	}
+}
+
+// clear vacía la cache y descarta todas las lecturas en curso
+func (n *nearCache) clear() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.entries = make(map[string]*list.Element)
+	n.lru.Init()
+	n.epoch++
+}
+
+// len retorna el número de entradas locales
+func (n *nearCache) len() int {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	return n.lru.Len()
+}
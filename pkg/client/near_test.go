@@ -0,0 +1,139 @@
+package client
+
+import (
+	"cache-engine/internal/cache"
+	"context"
+	"testing"
+	"time"
+)
+
+// TestNearCacheLRU prueba el límite de entradas y la expulsión LRU
+func TestNearCacheLRU(t *testing.T) {
+	near := newNearCache(2)
+
+	for _, key := range []string{"a", "b"} {
+		near.finish(key, near.begin(key), "v-"+key, true)
+	}
+	near.get("a") // "b" pasa a ser la menos usada
+	near.finish("c", near.begin("c"), "v-c", true)
+
+	if near.len() != 2 {
+		t.Errorf("Esperaba 2 entradas, obtuve %d", near.len())
+	}
+	if _, ok := near.get("b"); ok {
+		t.Error("La clave menos usada debería haberse expulsado")
+	}
+	if value, ok := near.get("a"); !ok || value != "v-a" {
+		t.Errorf("Esperaba v-a, obtuve %q", value)
+	}
+}
+
+// TestNearCacheInflightInvalidation prueba que una invalidación durante una
+// lectura remota impide guardar el valor obsoleto
+func TestNearCacheInflightInvalidation(t *testing.T) {
+	near := newNearCache(10)
+
+	epoch := near.begin("k")
+	near.invalidate("k")
+	near.finish("k", epoch, "viejo", true)
+	if _, ok := near.get("k"); ok {
+		t.Error("No debería guardarse un valor invalidado durante la lectura")
+	}
+
+	epoch = near.begin("k")
+	near.clear()
+	near.finish("k", epoch, "viejo", true)
+	if _, ok := near.get("k"); ok {
+		t.Error("No debería guardarse un valor leído antes de vaciar la cache")
+	}
+
+	near.finish("k", near.begin("k"), "nuevo", true)
+	if value, ok := near.get("k"); !ok || value != "nuevo" {
+		t.Errorf("Esperaba nuevo, obtuve %q", value)
+	}
+}
+
+// waitFor espera hasta que cond se cumpla o falla el test
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("Tiempo agotado esperando: %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// TestNearCacheInvalidation prueba la coherencia con cambios hechos por
+// otros clientes del servidor
+func TestNearCacheInvalidation(t *testing.T) {
+	engine, client := newTestClient(t, Config{NearCacheSize: 100})
+	ctx := context.Background()
+	engine.Set("user", "Juan")
+
+	waitFor(t, "conexión de invalidaciones", func() bool { return client.trackingID.Load() != 0 })
+
+	if value, found := client.Get("user"); !found || value != "Juan" {
+		t.Fatalf("Esperaba Juan, obtuve %v", value)
+	}
+	if client.near.len() != 1 {
+		t.Fatalf("La clave leída debería estar en el near cache")
+	}
+
+	// Un cambio en el servidor invalida la copia local
+	engine.Set("user", "Ana")
+	waitFor(t, "invalidación de user", func() bool { return client.near.len() == 0 })
+	if value, _ := client.Get("user"); value != "Ana" {
+		t.Errorf("Esperaba Ana tras la invalidación, obtuve %v", value)
+	}
+
+	// Las escrituras propias se ven de inmediato
+	if err := client.SetContext(ctx, "user", "Eva", 0); err != nil {
+		t.Fatalf("Set falló: %v", err)
+	}
+	if value, _ := client.Get("user"); value != "Eva" {
+		t.Errorf("Esperaba Eva, obtuve %v", value)
+	}
+
+	// La expiración y el vaciado también invalidan
+	client.Get("user")
+	engine.FlushAll()
+	waitFor(t, "invalidación por FLUSHALL", func() bool { return client.near.len() == 0 })
+	if _, found := client.Get("user"); found {
+		t.Error("La clave no debería existir tras FLUSHALL")
+	}
+}
+
+// TestNearCacheReconnect prueba que el near cache se vacía y se recupera al
+// perder la conexión de invalidaciones
+func TestNearCacheReconnect(t *testing.T) {
+	engine := cache.NewCacheEngine(100)
+	defer engine.Close(context.Background())
+
+	server, addr := startServer(t, engine, "")
+	client := New(Config{Addr: addr, NearCacheSize: 10, MinBackoff: 10 * time.Millisecond})
+	defer client.Close()
+
+	engine.Set("k", "v1")
+	waitFor(t, "conexión de invalidaciones", func() bool { return client.trackingID.Load() != 0 })
+	client.Get("k")
+
+	server.Close()
+	waitFor(t, "vaciado del near cache", func() bool {
+		return client.trackingID.Load() == 0 && client.near.len() == 0
+	})
+
+	// Un cambio mientras el cliente está desconectado no debe leerse obsoleto
+	engine.Set("k", "v2")
+	startServer(t, engine, addr)
+	if value, found := client.Get("k"); !found || value != "v2" {
+		t.Errorf("Esperaba v2, obtuve %v", value)
+	}
+
+	waitFor(t, "reconexión de invalidaciones", func() bool { return client.trackingID.Load() != 0 })
+	client.Get("k")
+	engine.Set("k", "v3")
+	waitFor(t, "invalidación tras reconectar", func() bool { return client.near.len() == 0 })
+}
@@ -0,0 +1,76 @@
+package client
+
+import (
+	"context"
+	"time"
+)
+
+// Pipeline acumula comandos y los envía juntos en una sola ida y vuelta.
+// No es seguro para uso concurrente.
+type Pipeline struct {
+	client  *Client
+	cmds    [][]interface{}
+	written []string // Claves escritas, a invalidar en el near cache
+}
+
+// Pipeline crea un pipeline vacío
+func (c *Client) Pipeline() *Pipeline {
+	return &Pipeline{client: c}
+}
+
+// Do encola un comando arbitrario
+func (p *Pipeline) Do(args ...interface{}) *Pipeline {
+	p.cmds = append(p.cmds, args)
+	return p
+}
+
+// Set encola un SET con expiración opcional (0 = sin expiración)
+func (p *Pipeline) Set(key string, value interface{}, ttl time.Duration) *Pipeline {
+	p.written = append(p.written, key)
+	if ttl > 0 {
+		return p.Do("SET", key, value, "PX", ttl.Milliseconds())
+	}
+	return p.Do("SET", key, value)
+}
+
+// Get encola un GET
+func (p *Pipeline) Get(key string) *Pipeline {
+	return p.Do("GET", key)
+}
+
+// Delete encola un DEL
+func (p *Pipeline) Delete(keys ...string) *Pipeline {
+	args := make([]interface{}, 0, len(keys)+1)
+	args = append(args, "DEL")
+	for _, key := range keys {
+		args = append(args, key)
+	}
+	p.written = append(p.written, keys...)
+	return p.Do(args...)
+}
+
+// Expire encola un EXPIRE
+func (p *Pipeline) Expire(key string, seconds int) *Pipeline {
+	return p.Do("EXPIRE", key, seconds)
+}
+
+// Len retorna el número de comandos encolados
+func (p *Pipeline) Len() int {
+	return len(p.cmds)
+}
+
+// Exec envía los comandos encolados y retorna una respuesta por comando,
+// en el mismo orden. Los errores del servidor aparecen como valores Error
+// en su posición; el error retornado es de red o de contexto. El pipeline
+// queda vacío para reutilizarse.
+func (p *Pipeline) Exec(ctx context.Context) ([]interface{}, error) {
+	if len(p.cmds) == 0 {
+		return nil, nil
+	}
+	cmds, written := p.cmds, p.written
+	p.cmds, p.written = nil, nil
+
+	replies, err := p.client.do(ctx, cmds...)
+	p.client.invalidateLocal(written...)
+	return replies, err
+}
@@ -0,0 +1,146 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"sync"
+	"time"
+)
+
+// ErrClosed indica que el cliente ya fue cerrado
+var ErrClosed = errors.New("client: cliente cerrado")
+
+// conn es una conexión del pool
+type conn struct {
+	nc         net.Conn
+	reader     *bufio.Reader
+	writer     *bufio.Writer
+	trackingID int64 // Conexión a la que redirige sus invalidaciones (0 = ninguna)
+}
+
+// roundTrip envía los comandos en un solo bloque (pipelining) y lee una
+// respuesta por comando. El error retornado es siempre de red o de
+// protocolo: los errores del servidor vienen como respuestas de tipo Error.
+func (cn *conn) roundTrip(ctx context.Context, timeout time.Duration, cmds [][]interface{}) ([]interface{}, error) {
+	deadline, ok := ctx.Deadline()
+	if !ok && timeout > 0 {
+		deadline = time.Now().Add(timeout)
+	}
+	cn.nc.SetDeadline(deadline)
+
+	// Cancelar el contexto desbloquea la lectura en curso
+	stop := context.AfterFunc(ctx, func() {
+		cn.nc.SetDeadline(time.Unix(1, 0))
+	})
+	defer stop()
+
+	for _, cmd := range cmds {
+		writeCommand(cn.writer, cmd)
+	}
+	if err := cn.writer.Flush(); err != nil {
+		return nil, contextError(ctx, err)
+	}
+
+	replies := make([]interface{}, 0, len(cmds))
+	for len(replies) < len(cmds) {
+		reply, err := readReply(cn.reader)
+		if err != nil {
+			return nil, contextError(ctx, err)
+		}
+		if _, isPush := reply.(Push); isPush {
+			// Los push no responden a ningún comando
+			continue
+		}
+		replies = append(replies, reply)
+	}
+	return replies, nil
+}
+
+// contextError prioriza el error del contexto sobre el de red que provocó
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+	return err
+}
+
+// pool mantiene un número acotado de conexiones reutilizables
+type pool struct {
+	dial  func(ctx context.Context) (*conn, error)
+	slots chan struct{} // Una ficha por conexión abierta o en uso
+	idle  chan *conn    // Conexiones libres
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// newPool crea un pool de hasta size conexiones
+func newPool(size int, dial func(ctx context.Context) (*conn, error)) *pool {
+	return &pool{
+		dial:  dial,
+		slots: make(chan struct{}, size),
+		idle:  make(chan *conn, size),
+	}
+}
+
+// get obtiene una conexión libre o abre una nueva, esperando si el pool
+// está completo
+func (p *pool) get(ctx context.Context) (*conn, error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	p.mu.Lock()
+	closed := p.closed
+	p.mu.Unlock()
+	if closed {
+		<-p.slots
+		return nil, ErrClosed
+	}
+
+	select {
+	case cn := <-p.idle:
+		return cn, nil
+	default:
+	}
+
+	cn, err := p.dial(ctx)
+	if err != nil {
+		<-p.slots
+		return nil, err
+	}
+	return cn, nil
+}
+
+// put devuelve una conexión al pool; si no es reutilizable se cierra
+func (p *pool) put(cn *conn, reuse bool) {
+	p.mu.Lock()
+	if reuse && !p.closed {
+		p.idle <- cn
+	} else {
+		cn.nc.Close()
+	}
+	p.mu.Unlock()
+	<-p.slots
+}
+
+// close cierra las conexiones libres; las que están en uso se cierran al
+// devolverse
+func (p *pool) close() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.closed = true
+	for {
+		select {
+		case cn := <-p.idle:
+			cn.nc.Close()
+		default:
+			return
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package client
+
+import (
+	"bufio"
+	"cache-engine/internal/cache"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// ErrProtocol indica una respuesta mal formada del servidor
+var ErrProtocol = errors.New("client: respuesta RESP inválida")
+
+// Error es un error devuelto por el servidor (-ERR ...). No invalida la
+// conexión ni provoca reintentos.
+type Error string
+
+// Error implementa la interfaz error
+func (e Error) Error() string {
+	return string(e)
+}
+
+// Push es un mensaje push de RESP3 (>), enviado por el servidor sin petición
+type Push []interface{}
+
+// writeCommand escribe un comando como array de bulk strings
+func writeCommand(w *bufio.Writer, args []interface{}) {
+	w.WriteString("*" + strconv.Itoa(len(args)) + "\r\n")
+	for _, arg := range args {
+		data := cache.ValueBytes(arg)
+		w.WriteString("$" + strconv.Itoa(len(data)) + "\r\n")
+		w.Write(data)
+		w.WriteString("\r\n")
+	}
+}
+
+// readLine lee una línea sin el terminador \r\n
+func readLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	if len(line) < 2 || line[len(line)-2] != '\r' {
+		return "", ErrProtocol
+	}
+	return line[:len(line)-2], nil
+}
+
+// readReply lee una respuesta RESP2 o RESP3. Los tipos resultantes son:
+// string (simple), []byte (bulk), int64, float64, bool, nil, Error,
+// []interface{} (array y set), map[string]interface{} y Push.
+func readReply(r *bufio.Reader) (interface{}, error) {
+	line, err := readLine(r)
+	if err != nil {
+		return nil, err
+	}
+	if line == "" {
+		return nil, ErrProtocol
+	}
+
+	prefix, payload := line[0], line[1:]
+	switch prefix {
+	case '+':
+		return payload, nil
+	case '-':
+		return Error(payload), nil
+	case ':':
+		n, err := strconv.ParseInt(payload, 10, 64)
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		return n, nil
+	case '(':
+		// Big number: se conserva como texto
+		return payload, nil
+	case ',':
+		f, err := strconv.ParseFloat(payload, 64)
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		return f, nil
+	case '#':
+		return payload == "t", nil
+	case '_':
+		return nil, nil
+	case '$', '=', '!':
+		data, err := readBlob(r, payload)
+		if err != nil || data == nil {
+			return nil, err
+		}
+		switch prefix {
+		case '!':
+			return Error(data), nil
+		case '=':
+			// Verbatim: se descarta el formato ("txt:")
+			if len(data) >= 4 {
+				data = data[4:]
+			}
+		}
+		return data, nil
+	case '*', '~', '>':
+		n, err := strconv.Atoi(payload)
+		if err != nil {
+			return nil, ErrProtocol
+		}
+		if n < 0 {
+			return nil, nil
+		}
+		items := make([]interface{}, n)
+		for i := range items {
+			if items[i], err = readReply(r); err != nil {
+				return nil, err
+			}
+		}
+		if prefix == '>' {
+			return Push(items), nil
+		}
+		return items, nil
+	case '%':
+		n, err := strconv.Atoi(payload)
+		if err != nil || n < 0 {
+			return nil, ErrProtocol
+		}
+		m := make(map[string]interface{}, n)
+		for i := 0; i < n; i++ {
+			key, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			value, err := readReply(r)
+			if err != nil {
+				return nil, err
+			}
+			m[replyString(key)] = value
+		}
+		return m, nil
+	}
+	return nil, fmt.Errorf("%w: tipo '%c' desconocido", ErrProtocol, prefix)
+}
+
+// readBlob lee el contenido de un bulk string de longitud payload
+func readBlob(r *bufio.Reader, payload string) ([]byte, error) {
+	n, err := strconv.Atoi(payload)
+	if err != nil {
+		return nil, ErrProtocol
+	}
+	if n < 0 {
+		return nil, nil
+	}
+
+	buf := make([]byte, n+2)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		return nil, err
+	}
+	if buf[n] != '\r' || buf[n+1] != '\n' {
+		return nil, ErrProtocol
+	}
+	return buf[:n], nil
+}
+
+// replyString convierte una respuesta simple en texto
+func replyString(reply interface{}) string {
+	switch v := reply.(type) {
+	case string:
+		return v
+	case []byte:
+		return string(v)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(reply)
+}
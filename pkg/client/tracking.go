@@ -0,0 +1,147 @@
+package client
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// La coherencia del near cache se apoya en CLIENT TRACKING: una conexión
+// dedicada en RESP3 recibe los push "invalidate" y las conexiones del pool
+// activan el seguimiento con REDIRECT hacia ella. Mientras esa conexión no
+// está disponible el near cache se vacía y no se usa.
+
+// runTracking mantiene la conexión de invalidaciones, reconectando con
+// backoff hasta que se cierre el cliente
+func (c *Client) runTracking() {
+	defer close(c.trackingDone)
+
+	for attempt := 0; ; attempt++ {
+		if c.trackingSession() {
+			attempt = 0
+		}
+
+		// Sin invalidaciones el near cache ya no es coherente
+		c.trackingID.Store(0)
+		c.near.clear()
+
+		timer := time.NewTimer(c.backoff(attempt))
+		select {
+		case <-timer.C:
+		case <-c.stop:
+			timer.Stop()
+			return
+		}
+	}
+}
+
+// trackingSession abre la conexión de invalidaciones y la atiende hasta que
+// se pierda. Retorna true si llegó a estar activa.
+func (c *Client) trackingSession() bool {
+	ctx, cancel := context.WithTimeout(context.Background(), c.config.DialTimeout)
+	defer cancel()
+
+	nc, err := c.dialNet(ctx)
+	if err != nil {
+		return false
+	}
+	defer nc.Close()
+
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return false
+	}
+	c.trackingConn = nc
+	c.mu.Unlock()
+
+	cn := &conn{nc: nc, reader: bufio.NewReader(nc), writer: bufio.NewWriter(nc)}
+	hello := []interface{}{"HELLO", "3"}
+	if c.config.Password != "" {
+		hello = append(hello, "AUTH", c.username(), c.config.Password)
+	}
+	replies, err := cn.roundTrip(ctx, c.config.Timeout, [][]interface{}{hello})
+	if err != nil {
+		return false
+	}
+	id, err := helloID(replies[0])
+	if err != nil {
+		return false
+	}
+
+	// A partir de aquí la conexión solo recibe push: sin deadline
+	nc.SetDeadline(time.Time{})
+	c.trackingID.Store(id)
+
+	for {
+		reply, err := readReply(cn.reader)
+		if err != nil {
+			return true
+		}
+		if push, ok := reply.(Push); ok {
+			c.handlePush(push)
+		}
+	}
+}
+
+// helloID extrae el id de conexión de la respuesta a HELLO 3
+func helloID(reply interface{}) (int64, error) {
+	if err := replyError(reply); err != nil {
+		return 0, err
+	}
+	fields, ok := reply.(map[string]interface{})
+	if !ok {
+		return 0, fmt.Errorf("%w: respuesta a HELLO inesperada", ErrProtocol)
+	}
+	id, ok := fields["id"].(int64)
+	if !ok {
+		return 0, errors.New("client: el servidor no informó el id de conexión")
+	}
+	return id, nil
+}
+
+// handlePush aplica un mensaje "invalidate" al near cache. Un nulo en lugar
+// de la lista de claves invalida todo (FLUSHDB, FLUSHALL).
+func (c *Client) handlePush(push Push) {
+	if len(push) != 2 || replyString(push[0]) != "invalidate" {
+		return
+	}
+
+	keys, ok := push[1].([]interface{})
+	if !ok {
+		c.near.clear()
+		return
+	}
+	for _, key := range keys {
+		c.near.invalidate(replyString(key))
+	}
+}
+
+// enableTracking activa el seguimiento en una conexión nueva del pool,
+// redirigiendo las invalidaciones a la conexión dedicada
+func (c *Client) enableTracking(ctx context.Context, cn *conn) error {
+	id := c.trackingID.Load()
+	cn.trackingID = id
+	if id == 0 {
+		return nil
+	}
+
+	replies, err := cn.roundTrip(ctx, c.config.Timeout, [][]interface{}{{"CLIENT", "TRACKING", "ON", "REDIRECT", id}})
+	if err != nil {
+		return err
+	}
+	if err := replyError(replies[0]); err != nil {
+		// La conexión de invalidaciones pudo caer entre medias: no es un
+		// error del comando del usuario, así que se permite reintentar
+		return fmt.Errorf("client: no se pudo activar el seguimiento: %v", err)
+	}
+	return nil
+}
+
+// stale indica si una conexión del pool se configuró con otra conexión de
+// invalidaciones distinta de la actual
+func (c *Client) stale(cn *conn) bool {
+	return c.near != nil && cn.trackingID != c.trackingID.Load()
+}